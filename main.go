@@ -22,6 +22,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/GeertJohan/go.rice"
@@ -413,10 +414,9 @@ func main() {
 	}()
 
 	hostSwitch, mailDaemon, taskScheduler, configStore, certManager,
-		ftpServer, imapServerInstance, olricDb = server.Main(boxRoot, db, *localStoragePath, olricDb)
-	rhs := RestartHandlerServer{
-		HostSwitch: &hostSwitch,
-	}
+		ftpServer, imapServerInstance, olricDb = server.Main(boxRoot, db, *localStoragePath, olricDb, nil)
+	rhs := RestartHandlerServer{}
+	rhs.SetHostSwitch(hostSwitch)
 
 	if *runtimeMode == "profile" {
 
@@ -448,61 +448,56 @@ func main() {
 
 	}
 
-	err = trigger.On("restart", func() {
-		log.Printf("Trigger restart")
+	// reload rebuilds tables, routes, actions and tasks in place while the SMTP, IMAP and FTP servers keep running,
+	// requests are served by the old router until the new one is swapped in
+	reload := func() {
+		log.Printf("Reload in place")
 		restartLock.Lock()
 		defer restartLock.Unlock()
 
-		if membersTopic != nil {
-			membersTopic.Publish(context.Background(), "members",
-				fmt.Sprintf("I need to restart: %v", olricConfig1.MemberlistConfig.Name))
+		// the running router is kept when the database was migrated by a newer binary
+		err := resource.CheckSchemaSystemVersion(db)
+		if err != nil {
+			log.Errorf("Refusing to reload: %v", err)
+			return
 		}
 
 		startTime := time.Now()
 
-		log.Printf("Close down services and db connection")
 		taskScheduler.StopTasks()
-		if ftpServer != nil {
-			ftpServer.Stop()
-		}
-
-		if mailDaemon != nil {
-			mailDaemon.Shutdown()
-		}
-
-		if imapServerInstance != nil {
-			err = imapServerInstance.Close()
-			if err != nil {
-				log.Printf("Failed to close imap server connections: %v", err)
-			}
-		}
-
-		//err = db.Close()
-		//resource.CheckErr(err, "Failed to close database connection")
-
-		log.Printf("All connections closed")
-		log.Printf("Create new connections")
-		//db1, err := server.GetDbConnection(*dbType, *connectionString)
-		//auth.CheckErr(err, "Failed to create new db connection")
-		//if err != nil {
-		//	return
-		//}
-
-		//transaction := db1.MustBegin()
-		//_ = transaction.Rollback()
-		log.Printf("Connection acquired from database [%s]", *dbType)
 
 		hostSwitch, mailDaemon, taskScheduler, configStore, certManager,
-			ftpServer, imapServerInstance, olricDb = server.Main(boxRoot, db, *localStoragePath, olricDb)
-		rhs.HostSwitch = &hostSwitch
+			ftpServer, imapServerInstance, olricDb = server.Main(boxRoot, db, *localStoragePath, olricDb, &server.RunningServices{
+			MailDaemon: mailDaemon,
+			FtpServer:  ftpServer,
+			ImapServer: imapServerInstance,
+		})
+		rhs.SetHostSwitch(hostSwitch)
 
 		secondsToRestart := float64(time.Now().UnixNano()-startTime.UnixNano()) / float64(1000000000)
-		log.Printf("Restart complete, took %f seconds", secondsToRestart)
+		log.Printf("Reload complete, took %f seconds", secondsToRestart)
 		if membersTopic != nil {
 			membersTopic.Publish(context.Background(), "members",
-				fmt.Sprintf("I am back: %v, took me [%v] seconds to restart", olricConfig1.MemberlistConfig.Name, secondsToRestart))
+				fmt.Sprintf("I am reloaded: %v, took me [%v] seconds", olricConfig1.MemberlistConfig.Name, secondsToRestart))
 		}
+	}
 
+	reloadCoordinator, err := server.NewReloadCoordinator(olricDb, olricConfig1.MemberlistConfig.Name, reload)
+	if err != nil {
+		log.Errorf("Failed to create reload coordinator, changes will not be picked up from other cluster members: %v", err)
+	} else {
+		reloadCoordinator.Listen()
+	}
+
+	err = trigger.On("restart", func() {
+		log.Printf("Trigger restart")
+		if reloadCoordinator == nil {
+			reload()
+			return
+		}
+		// the rows changed with the restart also schedule a reload, both end in a single debounced reload
+		reloadCoordinator.Broadcast()
+		reloadCoordinator.Schedule()
 	})
 
 	resource.CheckErr(err, "Error while adding restart trigger function")
@@ -588,9 +583,14 @@ func main() {
 
 // RestartHandlerServer helps in switching the new router with old router with restart is triggered
 type RestartHandlerServer struct {
-	HostSwitch *server.HostSwitch
+	hostSwitch atomic.Pointer[server.HostSwitch]
+}
+
+// SetHostSwitch atomically replaces the router serving new requests, in-flight requests finish on the old one
+func (rhs *RestartHandlerServer) SetHostSwitch(hostSwitch server.HostSwitch) {
+	rhs.hostSwitch.Store(&hostSwitch)
 }
 
 func (rhs *RestartHandlerServer) ServeHTTP(rew http.ResponseWriter, req *http.Request) {
-	rhs.HostSwitch.ServeHTTP(rew, req)
+	rhs.hostSwitch.Load().ServeHTTP(rew, req)
 }
@@ -55,6 +55,26 @@ func GetActionPerformers(initConfig *resource.CmsConfig, configStore *resource.C
 	resource.CheckErr(err, "Failed to create data import performer")
 	performers = append(performers, importDataPerformer)
 
+	importJobResumePerformer, err := resource.NewImportJobResumePerformer(initConfig, cruds)
+	resource.CheckErr(err, "Failed to create import job resume performer")
+	performers = append(performers, importJobResumePerformer)
+
+	importJobErrorReportPerformer, err := resource.NewImportJobErrorReportPerformer(initConfig, cruds)
+	resource.CheckErr(err, "Failed to create import job error report performer")
+	performers = append(performers, importJobErrorReportPerformer)
+
+	schemaMigrationListPerformer, err := resource.NewSchemaMigrationListPerformer(initConfig, cruds)
+	resource.CheckErr(err, "Failed to create schema migration list performer")
+	performers = append(performers, schemaMigrationListPerformer)
+
+	schemaRollbackPerformer, err := resource.NewSchemaRollbackPerformer(initConfig, cruds)
+	resource.CheckErr(err, "Failed to create schema rollback performer")
+	performers = append(performers, schemaRollbackPerformer)
+
+	gitOpsApplyPerformer, err := resource.NewGitOpsApplyPerformer(initConfig, cruds, LoadGitOpsConfigFromFolder)
+	resource.CheckErr(err, "Failed to create gitops apply performer")
+	performers = append(performers, gitOpsApplyPerformer)
+
 	oauth2redirect, err := resource.NewOauthLoginBeginActionPerformer(initConfig, cruds, configStore, transaction)
 	resource.CheckErr(err, "Failed to create oauth2 request performer")
 	performers = append(performers, oauth2redirect)
@@ -127,6 +147,18 @@ func GetActionPerformers(initConfig *resource.CmsConfig, configStore *resource.C
 	resource.CheckErr(err, "Failed to create csv upload performer")
 	performers = append(performers, csvUploadPerformer)
 
+	backupInstancePerformer, err := resource.NewBackupInstancePerformer(initConfig, cruds)
+	resource.CheckErr(err, "Failed to create backup instance performer")
+	performers = append(performers, backupInstancePerformer)
+
+	restoreInstancePerformer, err := resource.NewRestoreInstancePerformer(initConfig, cruds)
+	resource.CheckErr(err, "Failed to create restore instance performer")
+	performers = append(performers, restoreInstancePerformer)
+
+	dataFileUploadPerformer, err := resource.NewUploadDataFileToEntityPerformer(initConfig, cruds)
+	resource.CheckErr(err, "Failed to create data file upload performer")
+	performers = append(performers, dataFileUploadPerformer)
+
 	columnDeletePerformer, err := resource.NewDeleteWorldColumnPerformer(initConfig, cruds)
 	resource.CheckErr(err, "Failed to create column delete performer")
 	performers = append(performers, columnDeletePerformer)
@@ -2,6 +2,7 @@ package apiblueprint
 
 import (
 	"bytes"
+	"encoding/json"
 	"github.com/artpar/api2go"
 	"github.com/daptin/daptin/server/resource"
 	"github.com/iancoleman/strcase"
@@ -69,6 +70,12 @@ func CreateColumnLine(colInfo api2go.ColumnInfo) map[string]interface{} {
 	m := map[string]interface{}{
 		"type": typ,
 	}
+	if enumValues := resource.EnumValues(colInfo); len(enumValues) > 0 {
+		m["enum"] = enumValues
+	}
+	if columnType == "money" {
+		m["format"] = "decimal"
+	}
 	//if !colInfo.IsNullable {
 	//	m["required"] = true
 	//}
@@ -277,9 +284,16 @@ func BuildApiBlueprint(config *resource.CmsConfig, cruds map[string]*resource.Db
 
 	for _, action := range config.Actions {
 		ramlActionType := make(map[string]interface{})
+		actionProperties := make(map[string]interface{})
+		if len(action.InputSchema) > 0 {
+			// the declared schema describes the inputs, the in fields it leaves out are added to it
+			ramlActionType = CopySchema(action.InputSchema)
+			if properties, ok := ramlActionType["properties"].(map[string]interface{}); ok {
+				actionProperties = properties
+			}
+		}
 		ramlActionType["type"] = "object"
 
-		actionProperties := make(map[string]interface{})
 		for _, colInfo := range action.InFields {
 			if colInfo.IsForeignKey {
 				continue
@@ -287,6 +301,9 @@ func BuildApiBlueprint(config *resource.CmsConfig, cruds map[string]*resource.Db
 			if skipColumns[colInfo.ColumnName] {
 				continue
 			}
+			if _, ok := actionProperties[colInfo.ColumnName]; ok {
+				continue
+			}
 
 			actionProperties[colInfo.ColumnName] = CreateColumnLine(colInfo)
 		}
@@ -301,6 +318,19 @@ func BuildApiBlueprint(config *resource.CmsConfig, cruds map[string]*resource.Db
 		ramlActionType["properties"] = actionProperties
 		typeMap[fmt.Sprintf("%sOn%sRequestObject", strcase.ToCamel(action.Name), strcase.ToCamel(action.OnType))] = ramlActionType
 
+		if len(action.OutputSchema) > 0 {
+			typeMap[fmt.Sprintf("%sOn%sResponse", strcase.ToCamel(action.Name), strcase.ToCamel(action.OnType))] = map[string]interface{}{
+				"type":        "object",
+				"description": "response of " + action.Label,
+				"properties": map[string]interface{}{
+					"ResponseType": map[string]interface{}{
+						"type": "string",
+					},
+					"Attributes": CopySchema(action.OutputSchema),
+				},
+			}
+		}
+
 	}
 
 	resourcesMap := map[string]map[string]interface{}{}
@@ -462,6 +492,11 @@ func BuildApiBlueprint(config *resource.CmsConfig, cruds map[string]*resource.Db
 
 	for _, action := range config.Actions {
 
+		responseSchema := "#/components/schemas/ActionResponse"
+		if len(action.OutputSchema) > 0 {
+			responseSchema = fmt.Sprintf("#/components/schemas/%sOn%sResponse", strcase.ToCamel(action.Name), strcase.ToCamel(action.OnType))
+		}
+
 		resourcesMap[fmt.Sprintf("/action/%s/%s", action.OnType, action.Name)] = map[string]interface{}{
 			"post": map[string]interface{}{
 				"tags":        []string{action.OnType, "action"},
@@ -485,7 +520,7 @@ func BuildApiBlueprint(config *resource.CmsConfig, cruds map[string]*resource.Db
 								"schema": map[string]interface{}{
 									"type": "array",
 									"items": map[string]interface{}{
-										"$ref": "#/components/schemas/ActionResponse",
+										"$ref": responseSchema,
 									},
 								},
 							},
@@ -525,6 +560,17 @@ func BuildApiBlueprint(config *resource.CmsConfig, cruds map[string]*resource.Db
 
 }
 
+// CopySchema gives a copy of a schema declared in the config, which is not changed when the copy is
+func CopySchema(schema map[string]interface{}) map[string]interface{} {
+	copied := make(map[string]interface{})
+	schemaJson, err := json.Marshal(schema)
+	if InfoError(err, "Failed to copy schema") {
+		return copied
+	}
+	InfoError(json.Unmarshal(schemaJson, &copied), "Failed to copy schema")
+	return copied
+}
+
 func CreateDataInResponse(tableInfo resource.TableInfo) map[string]interface{} {
 	relationshipMap := make(map[string]interface{}, 0)
 	for _, relation := range tableInfo.Relations {
@@ -67,110 +67,138 @@ func LoadConfigFiles() (resource.CmsConfig, []error) {
 	for _, fileName := range files {
 		log.Printf("Process file: %v", fileName)
 
-		fileBytes, err := os.ReadFile(fileName)
+		initConfig, err := loadSchemaFile(fileName, schemaPath)
 		if err != nil {
 			errs = append(errs, err)
 			continue
 		}
+		mergeSchemaConfig(&globalInitConfig, initConfig, fileName)
 
-		initConfig := resource.CmsConfig{}
-		//fmt.Printf("Loaded config: \n%v", string(fileBytes))
-
-		switch {
-		case EndsWithCheck(fileName, "yml"):
-			fallthrough
-		case EndsWithCheck(fileName, "yaml"):
-			jsonBytes, err := yaml2.YAMLToJSON(fileBytes)
-			log.Printf("JSON: %v", string(jsonBytes))
-			if err != nil {
-				errs = append(errs, err)
-				continue
-			}
-			err = json1.Unmarshal(jsonBytes, &initConfig)
-			//err = yaml.UnmarshalStrict(fileBytes, &initConfig)
-		case EndsWithCheck(fileName, "json"):
-			err = json1.Unmarshal(fileBytes, &initConfig)
-		case EndsWithCheck(fileName, "toml"):
-			err = toml.Unmarshal(fileBytes, &initConfig)
+	}
 
-		}
+	// the gitops files are applied when asked to, by the apply action or by the watcher with gitops.auto_apply,
+	// every other load keeps the schema in the database
+	gitOpsConfig, gitOpsFiles, gitOpsErrs := LoadGitOpsConfig(files)
+	errs = append(errs, gitOpsErrs...)
+	if resource.TakeGitOpsApplyRequest() && len(gitOpsFiles) > 0 {
+		log.Printf("Applying schema files from [%v]", GitOpsFolder())
+		mergeSchemaConfig(&globalInitConfig, gitOpsConfig, GitOpsFolder())
+	} else {
+		// limits and webhooks are not kept in the database, they come from the files on every load
+		globalInitConfig.Limits = append(globalInitConfig.Limits, gitOpsConfig.Limits...)
+		globalInitConfig.Webhooks = append(globalInitConfig.Webhooks, gitOpsConfig.Webhooks...)
+	}
+	resource.SetGitOpsManagedObjects(gitOpsConfig)
 
-		//js, _ := json.Marshal(initConfig)
-		//log.Printf("Loaded config: %v", string(js))
+	return globalInitConfig, errs
 
-		if err != nil {
-			log.Errorf("Failed to load config file: %v", err)
-			errs = append(errs, err)
-			continue
-		}
+}
 
-		tables := make([]resource.TableInfo, 0)
-		for _, table := range initConfig.Tables {
-			table.TableName = flect.Underscore(table.TableName)
-			if len(table.TableName) < 1 {
-				continue
-			}
+// loadSchemaFile reads a single schema file, relative import paths are resolved against schemaPath. The names of the
+// tables and columns are normalised here, so the config applied and the config compared with the database for drift
+// are the same.
+func loadSchemaFile(fileName string, schemaPath string) (resource.CmsConfig, error) {
 
-			for j, col := range table.Columns {
-				table.Columns[j].ColumnName = flect.Underscore(col.ColumnName)
-			}
-			tables = append(tables, table)
-		}
-		initConfig.Tables = tables
+	initConfig := resource.CmsConfig{}
 
-		globalInitConfig.Tables = append(globalInitConfig.Tables, initConfig.Tables...)
+	fileBytes, err := os.ReadFile(fileName)
+	if err != nil {
+		return initConfig, err
+	}
 
-		//globalInitConfig.Relations = append(globalInitConfig.Relations, initConfig.Relations...)
-		globalInitConfig.AddRelations(initConfig.Relations...)
+	//fmt.Printf("Loaded config: \n%v", string(fileBytes))
 
-		for i, importPath := range initConfig.Imports {
-			if importPath.FilePath[0] != '/' {
-				importPath.FilePath = schemaPath + importPath.FilePath
-				initConfig.Imports[i] = importPath
-			}
+	switch {
+	case EndsWithCheck(fileName, "yml"):
+		fallthrough
+	case EndsWithCheck(fileName, "yaml"):
+		jsonBytes, err := yaml2.YAMLToJSON(fileBytes)
+		log.Printf("JSON: %v", string(jsonBytes))
+		if err != nil {
+			return initConfig, err
 		}
+		err = json1.Unmarshal(jsonBytes, &initConfig)
+		//err = yaml.UnmarshalStrict(fileBytes, &initConfig)
+	case EndsWithCheck(fileName, "json"):
+		err = json1.Unmarshal(fileBytes, &initConfig)
+	case EndsWithCheck(fileName, "toml"):
+		err = toml.Unmarshal(fileBytes, &initConfig)
+
+	}
 
-		globalInitConfig.Imports = append(globalInitConfig.Imports, initConfig.Imports...)
-		globalInitConfig.Streams = append(globalInitConfig.Streams, initConfig.Streams...)
-		//globalInitConfig.Marketplaces = append(globalInitConfig.Marketplaces, initConfig.Marketplaces...)
-		globalInitConfig.Tasks = append(globalInitConfig.Tasks, initConfig.Tasks...)
-		globalInitConfig.Actions = append(globalInitConfig.Actions, initConfig.Actions...)
-		globalInitConfig.StateMachineDescriptions = append(globalInitConfig.StateMachineDescriptions, initConfig.StateMachineDescriptions...)
-		globalInitConfig.ExchangeContracts = append(globalInitConfig.ExchangeContracts, initConfig.ExchangeContracts...)
+	//js, _ := json.Marshal(initConfig)
+	//log.Printf("Loaded config: %v", string(js))
 
-		for _, action := range initConfig.Actions {
-			log.Printf("Action [%v][%v]", fileName, action.Name)
+	if err != nil {
+		log.Errorf("Failed to load config file: %v", err)
+		return initConfig, err
+	}
+
+	tables := make([]resource.TableInfo, 0)
+	for _, table := range initConfig.Tables {
+		table.TableName = flect.Underscore(table.TableName)
+		if len(table.TableName) < 1 {
+			continue
 		}
 
-		for _, table := range initConfig.Tables {
-			for i, col := range table.Columns {
-				if col.Name == "" && col.ColumnName != "" {
-					col.Name = col.ColumnName
-				} else if col.Name != "" && col.ColumnName == "" {
-					col.ColumnName = col.Name
-				} else if col.Name == "" && col.ColumnName == "" {
-					log.Printf("Error, column without name: %v", table)
-				}
-				table.Columns[i] = col
+		for j, col := range table.Columns {
+			col.ColumnName = flect.Underscore(col.ColumnName)
+			if col.Name == "" && col.ColumnName != "" {
+				col.Name = col.ColumnName
+			} else if col.Name != "" && col.ColumnName == "" {
+				col.ColumnName = col.Name
+			} else if col.Name == "" && col.ColumnName == "" {
+				log.Printf("Error, column without name: %v", table)
 			}
+			table.Columns[j] = col
 		}
+		tables = append(tables, table)
+	}
+	initConfig.Tables = tables
 
-		//for _, marketplace := range initConfig.Marketplaces {
-		//	log.Printf("Marketplace [%v][%v]", fileName, marketplace.Endpoint)
-		//}
-
-		for _, smd := range initConfig.StateMachineDescriptions {
-			log.Printf("SMD  [%v][%v][%v]", fileName, smd.Name, smd.InitialState)
+	for i, importPath := range initConfig.Imports {
+		if importPath.FilePath[0] != '/' {
+			importPath.FilePath = schemaPath + importPath.FilePath
+			initConfig.Imports[i] = importPath
 		}
+	}
 
-		if initConfig.EnableGraphQL {
-			globalInitConfig.EnableGraphQL = true
-		}
+	return initConfig, nil
+}
+
+// mergeSchemaConfig adds everything defined in initConfig to globalInitConfig
+func mergeSchemaConfig(globalInitConfig *resource.CmsConfig, initConfig resource.CmsConfig, fileName string) {
+
+	globalInitConfig.Tables = append(globalInitConfig.Tables, initConfig.Tables...)
 
-		//log.Printf("File added to config, deleting %v", fileName)
+	//globalInitConfig.Relations = append(globalInitConfig.Relations, initConfig.Relations...)
+	globalInitConfig.AddRelations(initConfig.Relations...)
 
+	globalInitConfig.Imports = append(globalInitConfig.Imports, initConfig.Imports...)
+	globalInitConfig.Streams = append(globalInitConfig.Streams, initConfig.Streams...)
+	//globalInitConfig.Marketplaces = append(globalInitConfig.Marketplaces, initConfig.Marketplaces...)
+	globalInitConfig.Tasks = append(globalInitConfig.Tasks, initConfig.Tasks...)
+	globalInitConfig.Actions = append(globalInitConfig.Actions, initConfig.Actions...)
+	globalInitConfig.StateMachineDescriptions = append(globalInitConfig.StateMachineDescriptions, initConfig.StateMachineDescriptions...)
+	globalInitConfig.ExchangeContracts = append(globalInitConfig.ExchangeContracts, initConfig.ExchangeContracts...)
+	globalInitConfig.Limits = append(globalInitConfig.Limits, initConfig.Limits...)
+	globalInitConfig.Webhooks = append(globalInitConfig.Webhooks, initConfig.Webhooks...)
+
+	for _, action := range initConfig.Actions {
+		log.Printf("Action [%v][%v]", fileName, action.Name)
 	}
 
-	return globalInitConfig, errs
+	//for _, marketplace := range initConfig.Marketplaces {
+	//	log.Printf("Marketplace [%v][%v]", fileName, marketplace.Endpoint)
+	//}
+
+	for _, smd := range initConfig.StateMachineDescriptions {
+		log.Printf("SMD  [%v][%v][%v]", fileName, smd.Name, smd.InitialState)
+	}
+
+	if initConfig.EnableGraphQL {
+		globalInitConfig.EnableGraphQL = true
+	}
 
+	//log.Printf("File added to config, deleting %v", fileName)
 }
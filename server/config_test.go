@@ -0,0 +1,91 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/daptin/daptin/server/resource"
+)
+
+const testGitOpsSchema = `Tables:
+- TableName: book
+  Columns:
+  - Name: title
+    DataType: varchar(100)
+    ColumnType: label
+  - ColumnName: PageCount
+    DataType: int(11)
+    ColumnType: measurement
+Limits:
+- Name: books
+  Table: book
+  Quota: 100
+`
+
+func TestLoadSchemaFileNormalisesColumns(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "schema_books.yaml")
+	if err := os.WriteFile(fileName, []byte(testGitOpsSchema), 0644); err != nil {
+		t.Fatalf("failed to write schema file: %v", err)
+	}
+	config, err := loadSchemaFile(fileName, "")
+	if err != nil {
+		t.Fatalf("failed to load schema file: %v", err)
+	}
+	columns := config.Tables[0].Columns
+	if columns[0].Name != "title" || columns[0].ColumnName != "title" {
+		t.Errorf("expected the column name from the name, got [%v][%v]", columns[0].Name, columns[0].ColumnName)
+	}
+	if columns[1].Name != "page_count" || columns[1].ColumnName != "page_count" {
+		t.Errorf("expected the name from the column name, got [%v][%v]", columns[1].Name, columns[1].ColumnName)
+	}
+}
+
+func TestLoadConfigFilesAppliesGitOpsOnlyWhenAsked(t *testing.T) {
+	folder := t.TempDir()
+	if err := os.WriteFile(filepath.Join(folder, "schema_books.yaml"), []byte(testGitOpsSchema), 0644); err != nil {
+		t.Fatalf("failed to write schema file: %v", err)
+	}
+	t.Setenv("DAPTIN_GITOPS_FOLDER", folder)
+	defer resource.SetGitOpsManagedObjects(resource.CmsConfig{})
+
+	hasBook := func(config resource.CmsConfig) bool {
+		for _, table := range config.Tables {
+			if table.TableName == "book" {
+				return true
+			}
+		}
+		return false
+	}
+
+	config, errs := LoadConfigFiles()
+	if len(errs) > 0 {
+		t.Fatalf("failed to load config: %v", errs)
+	}
+	if hasBook(config) {
+		t.Errorf("expected the schema files not to be applied without a request")
+	}
+	if len(config.Limits) != 1 {
+		t.Errorf("expected the limits of the schema files, got %v", config.Limits)
+	}
+	if !resource.IsGitOpsManaged("world", "book") {
+		t.Errorf("expected the table of the schema files to be managed")
+	}
+
+	resource.RequestGitOpsApply()
+	config, _ = LoadConfigFiles()
+	if !hasBook(config) || len(config.Limits) != 1 {
+		t.Errorf("expected the schema files to be applied once requested")
+	}
+	if config, _ = LoadConfigFiles(); hasBook(config) {
+		t.Errorf("expected the request to be used by a single load")
+	}
+}
+
+func TestRateConfigDropsInvalidLimits(t *testing.T) {
+	rateConfig := RateConfig{Version: "1", Limits: map[string]int{"/api/book": 10, "/api/author": 0, "/action/x": -5}}
+	rateConfig.dropInvalidLimits()
+	if len(rateConfig.Limits) != 1 || rateConfig.Limits["/api/book"] != 10 {
+		t.Errorf("expected only the positive rate to be kept, got %v", rateConfig.Limits)
+	}
+}
@@ -31,7 +31,7 @@ type DaptinFtpDriver struct {
 	nbClients               int32                   // Number of clients
 	Sites                   map[string]SubSiteAssetCache
 	CertManager             *resource.CertificateManager
-	cruds                   map[string]*resource.DbResource
+	resources               func() map[string]*resource.DbResource // Resources of the latest load
 }
 
 // ClientDriver defines a very basic client driver
@@ -48,7 +48,7 @@ type DaptinFtpServerSettings struct {
 }
 
 // NewDaptinFtpDriver creates a new driver
-func NewDaptinFtpDriver(cruds map[string]*resource.DbResource,
+func NewDaptinFtpDriver(resources func() map[string]*resource.DbResource,
 	certManager *resource.CertificateManager, ftp_interface string, sites []SubSiteAssetCache) (*DaptinFtpDriver, error) {
 
 	siteMap := make(map[string]SubSiteAssetCache)
@@ -62,7 +62,7 @@ func NewDaptinFtpDriver(cruds map[string]*resource.DbResource,
 		BaseDir:     "/",
 		Sites:       siteMap,
 		CertManager: certManager,
-		cruds:       cruds,
+		resources:   resources,
 		DaptinFtpServerSettings: DaptinFtpServerSettings{
 			MaxConnections: 100,
 			Server: server.Settings{
@@ -131,7 +131,7 @@ func (driver *DaptinFtpDriver) GetTLSConfig() (*tls.Config, error) {
 		break
 	}
 
-	transaction, err := driver.cruds["world"].Connection.Beginx()
+	transaction, err := driver.resources()["world"].Connection.Beginx()
 	if err != nil {
 		resource.CheckErr(err, "Failed to begin transaction [134]")
 		return nil, err
@@ -171,14 +171,14 @@ func (driver *DaptinFtpDriver) WelcomeUser(cc server.ClientContext) (string, err
 // AuthUser authenticates the user and selects an handling driver
 func (driver *DaptinFtpDriver) AuthUser(cc server.ClientContext, user, pass string) (server.ClientHandlingDriver, error) {
 
-	transaction, err := driver.cruds["user_account"].Connection.Beginx()
+	transaction, err := driver.resources()["user_account"].Connection.Beginx()
 	if err != nil {
 		resource.CheckErr(err, "Failed to begin transaction [174]")
 		return nil, err
 	}
 
 	defer transaction.Rollback()
-	userAccount, err := driver.cruds["user_account"].GetUserAccountRowByEmail(user, transaction)
+	userAccount, err := driver.resources()["user_account"].GetUserAccountRowByEmail(user, transaction)
 	if err != nil {
 		return nil, err
 	}
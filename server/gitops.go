@@ -0,0 +1,301 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/daptin/daptin/server/auth"
+	"github.com/daptin/daptin/server/database"
+	"github.com/daptin/daptin/server/resource"
+	"github.com/gin-gonic/gin"
+	"github.com/sadlil/go-trigger"
+	log "github.com/sirupsen/logrus"
+)
+
+// GitOpsFolder is the folder holding the schema files which are the source of truth for the schema,
+// set by DAPTIN_GITOPS_FOLDER. Empty when gitops is not enabled.
+func GitOpsFolder() string {
+	folder, ok := os.LookupEnv("DAPTIN_GITOPS_FOLDER")
+	if !ok || folder == "" {
+		return ""
+	}
+	if folder[len(folder)-1] != os.PathSeparator {
+		folder = folder + string(os.PathSeparator)
+	}
+	return folder
+}
+
+func gitOpsSchemaFiles() []string {
+	folder := GitOpsFolder()
+	if folder == "" {
+		return nil
+	}
+	files, err := filepath.Glob(folder + "schema_*.*")
+	resource.CheckErr(err, "Failed to list schema files in [%v]", folder)
+	sort.Strings(files)
+	return files
+}
+
+// LoadGitOpsConfig reads the schema files in the gitops folder, files listed in alreadyLoaded are skipped
+func LoadGitOpsConfig(alreadyLoaded []string) (resource.CmsConfig, []string, []error) {
+	gitOpsConfig := resource.CmsConfig{}
+	errs := make([]error, 0)
+
+	skip := make(map[string]bool)
+	for _, fileName := range alreadyLoaded {
+		absolutePath, _ := filepath.Abs(fileName)
+		skip[absolutePath] = true
+	}
+
+	loadedFiles := make([]string, 0)
+	for _, fileName := range gitOpsSchemaFiles() {
+		absolutePath, _ := filepath.Abs(fileName)
+		if skip[absolutePath] {
+			continue
+		}
+		log.Printf("Process gitops file: %v", fileName)
+		initConfig, err := loadSchemaFile(fileName, GitOpsFolder())
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%v: %v", fileName, err))
+			continue
+		}
+		gitOpsConfig.Tables = append(gitOpsConfig.Tables, initConfig.Tables...)
+		gitOpsConfig.AddRelations(initConfig.Relations...)
+		gitOpsConfig.Imports = append(gitOpsConfig.Imports, initConfig.Imports...)
+		gitOpsConfig.Streams = append(gitOpsConfig.Streams, initConfig.Streams...)
+		gitOpsConfig.Tasks = append(gitOpsConfig.Tasks, initConfig.Tasks...)
+		gitOpsConfig.Actions = append(gitOpsConfig.Actions, initConfig.Actions...)
+		gitOpsConfig.StateMachineDescriptions = append(gitOpsConfig.StateMachineDescriptions, initConfig.StateMachineDescriptions...)
+		gitOpsConfig.ExchangeContracts = append(gitOpsConfig.ExchangeContracts, initConfig.ExchangeContracts...)
+		gitOpsConfig.Limits = append(gitOpsConfig.Limits, initConfig.Limits...)
+		gitOpsConfig.Webhooks = append(gitOpsConfig.Webhooks, initConfig.Webhooks...)
+		gitOpsConfig.EnableGraphQL = gitOpsConfig.EnableGraphQL || initConfig.EnableGraphQL
+		loadedFiles = append(loadedFiles, fileName)
+	}
+
+	return gitOpsConfig, loadedFiles, errs
+}
+
+// LoadGitOpsConfigFromFolder reads every schema file in the gitops folder
+func LoadGitOpsConfigFromFolder() (resource.CmsConfig, []string, []error) {
+	return LoadGitOpsConfig(nil)
+}
+
+func gitOpsFilesChecksum(files []string) string {
+	hash := sha256.New()
+	for _, fileName := range files {
+		contents, err := os.ReadFile(fileName)
+		if err != nil {
+			continue
+		}
+		hash.Write([]byte(fileName))
+		hash.Write(contents)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// GitOpsWatcher periodically pulls the schema files from the cloud store (if configured), and reports drift between
+// the files and the database whenever the files change. With gitops.auto_apply set to true the files are applied
+// right away.
+type GitOpsWatcher struct {
+	cruds          map[string]*resource.DbResource
+	db             database.DatabaseConnection
+	cloudStoreName string
+	cloudPath      string
+	autoApply      bool
+	interval       time.Duration
+	lock           sync.Mutex
+	lastChecksum   string
+	stop           chan bool
+}
+
+var gitOpsWatcher *GitOpsWatcher
+
+// gitOpsStartChecked is set once the files were compared with the database after the process started, files changed
+// while the server was down are applied then with gitops.auto_apply
+var gitOpsStartChecked bool
+
+// StartGitOpsWatcher replaces the watcher started by a previous run of Main, nothing is started when
+// DAPTIN_GITOPS_FOLDER is not set
+func StartGitOpsWatcher(cruds map[string]*resource.DbResource, configStore *resource.ConfigStore, db database.DatabaseConnection) *GitOpsWatcher {
+	if gitOpsWatcher != nil {
+		gitOpsWatcher.stop <- true
+		gitOpsWatcher = nil
+	}
+
+	if GitOpsFolder() == "" {
+		return nil
+	}
+
+	transaction, err := db.Beginx()
+	if err != nil {
+		resource.CheckErr(err, "Failed to begin transaction [152]")
+		return nil
+	}
+	defer transaction.Commit()
+
+	autoApply, err := configStore.GetConfigValueFor("gitops.auto_apply", "backend", transaction)
+	if err != nil {
+		autoApply = "false"
+		err = configStore.SetConfigValueFor("gitops.auto_apply", autoApply, "backend", transaction)
+		resource.CheckErr(err, "Failed to store default value for gitops.auto_apply")
+	}
+
+	interval, err := configStore.GetConfigIntValueFor("gitops.interval", "backend", transaction)
+	if err != nil || interval < 1 {
+		interval = 60
+		err = configStore.SetConfigIntValueFor("gitops.interval", interval, "backend", transaction)
+		resource.CheckErr(err, "Failed to store default value for gitops.interval")
+	}
+
+	cloudStoreName, _ := configStore.GetConfigValueFor("gitops.cloud_store", "backend", transaction)
+	cloudPath, _ := configStore.GetConfigValueFor("gitops.cloud_path", "backend", transaction)
+
+	gitOpsWatcher = &GitOpsWatcher{
+		cruds:          cruds,
+		db:             db,
+		cloudStoreName: cloudStoreName,
+		cloudPath:      cloudPath,
+		autoApply:      autoApply == "true",
+		interval:       time.Duration(interval) * time.Second,
+		lastChecksum:   gitOpsFilesChecksum(gitOpsSchemaFiles()),
+		stop:           make(chan bool, 1),
+	}
+	log.Infof("Watching schema files in [%v] every %v, auto apply: %v", GitOpsFolder(), gitOpsWatcher.interval, gitOpsWatcher.autoApply)
+
+	watcher := gitOpsWatcher
+	go watcher.run()
+	if !gitOpsStartChecked {
+		gitOpsStartChecked = true
+		go watcher.checkDrift()
+	}
+	return watcher
+}
+
+func (gw *GitOpsWatcher) run() {
+	ticker := time.NewTicker(gw.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-gw.stop:
+			return
+		case <-ticker.C:
+			gw.check()
+		}
+	}
+}
+
+func (gw *GitOpsWatcher) check() {
+	if gw.cloudStoreName != "" {
+		transaction, err := gw.db.Beginx()
+		if err != nil {
+			resource.CheckErr(err, "Failed to begin transaction [200]")
+			return
+		}
+		err = resource.SyncGitOpsCloudStore(gw.cruds, gw.cloudStoreName, gw.cloudPath, GitOpsFolder(), transaction)
+		transaction.Rollback()
+		if err != nil {
+			log.Errorf("Failed to pull schema files from cloud store [%v]: %v", gw.cloudStoreName, err)
+			return
+		}
+	}
+
+	checksum := gitOpsFilesChecksum(gitOpsSchemaFiles())
+	gw.lock.Lock()
+	changed := checksum != gw.lastChecksum
+	gw.lastChecksum = checksum
+	gw.lock.Unlock()
+	if !changed {
+		return
+	}
+
+	log.Infof("Schema files in [%v] changed", GitOpsFolder())
+	gw.checkDrift()
+}
+
+// checkDrift reports the drift between the files and the database, and applies the files with gitops.auto_apply
+func (gw *GitOpsWatcher) checkDrift() {
+	report, err := gw.Drift()
+	if err != nil {
+		log.Errorf("Failed to compute schema drift: %v", err)
+		return
+	}
+
+	if report.InSync {
+		return
+	}
+	log.Warnf("Database schema has drifted from schema files in [%v]: %d differences", GitOpsFolder(), len(report.Drift))
+
+	if gw.autoApply {
+		log.Infof("Applying schema files from [%v]", GitOpsFolder())
+		resource.RequestGitOpsApply()
+		resource.SetSchemaMigrationSource("gitops")
+		// the reload replaces this watcher, so it cannot run on the watcher goroutine
+		go func() {
+			_, err := trigger.Fire("restart")
+			resource.CheckErr(err, "Failed to trigger reload for schema files")
+		}()
+	}
+}
+
+// Drift compares the schema files with the database right now
+func (gw *GitOpsWatcher) Drift() (resource.SchemaDriftReport, error) {
+	gitOpsConfig, files, errs := LoadGitOpsConfigFromFolder()
+	if len(errs) > 0 {
+		return resource.SchemaDriftReport{}, errs[0]
+	}
+
+	transaction, err := gw.db.Beginx()
+	if err != nil {
+		return resource.SchemaDriftReport{}, err
+	}
+	defer transaction.Rollback()
+
+	report, err := resource.ComputeSchemaDrift(gitOpsConfig, transaction)
+	if err != nil {
+		return report, err
+	}
+	report.Source = GitOpsFolder()
+	if gw.cloudStoreName != "" {
+		report.Source = gw.cloudStoreName + ":" + gw.cloudPath
+	}
+	report.Files = files
+	return report, nil
+}
+
+// CreateGitOpsDriftHandler reports the difference between the schema files and the database to administrators
+func CreateGitOpsDriftHandler(watcher *GitOpsWatcher) func(*gin.Context) {
+	return func(c *gin.Context) {
+		user := c.Request.Context().Value("user")
+		sessionUser := &auth.SessionUser{}
+		if user != nil {
+			sessionUser = user.(*auth.SessionUser)
+		}
+
+		transaction, err := watcher.db.Beginx()
+		if err != nil {
+			resource.CheckErr(err, "Failed to begin transaction [273]")
+			c.AbortWithStatus(500)
+			return
+		}
+		isAdmin := resource.IsAdminWithTransaction(sessionUser.UserReferenceId, transaction)
+		transaction.Rollback()
+		if !isAdmin {
+			c.AbortWithError(403, fmt.Errorf("unauthorized"))
+			return
+		}
+
+		report, err := watcher.Drift()
+		if err != nil {
+			c.AbortWithStatusJSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, report)
+	}
+}
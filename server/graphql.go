@@ -13,6 +13,7 @@ import (
 	log "github.com/sirupsen/logrus"
 	"golang.org/x/net/context"
 	"net/http"
+	"regexp"
 	"strings"
 	//	"encoding/base64"
 	"errors"
@@ -28,6 +29,72 @@ var nodeDefinitions *relay.NodeDefinitions
 
 var Schema graphql.Schema
 
+// graphqlEnumTypes holds the enum type created for each enum column, a type can be defined only once in the schema
+type graphqlEnumTypes map[string]*graphql.Enum
+
+var graphqlEnumValueNameRegex = regexp.MustCompile("[^_0-9A-Za-z]+")
+
+// columnType is a graphql enum for enum columns which list their options, and the column type otherwise
+func (enumTypes graphqlEnumTypes) columnType(typeName string, column api2go.ColumnInfo) graphql.Type {
+	enumValues := resource.EnumValues(column)
+	if len(enumValues) == 0 {
+		return resource.ColumnManager.GetGraphqlType(column.ColumnType)
+	}
+
+	name := strcase.ToCamel(typeName + "_" + column.ColumnName)
+	if enumType, ok := enumTypes[name]; ok {
+		return enumType
+	}
+
+	values := make(graphql.EnumValueConfigMap)
+	for _, enumValue := range enumValues {
+		valueName := strings.ToUpper(graphqlEnumValueNameRegex.ReplaceAllString(enumValue, "_"))
+		if valueName == "" || (valueName[0] >= '0' && valueName[0] <= '9') {
+			valueName = "_" + valueName
+		}
+		for i := 1; values[valueName] != nil; i++ {
+			valueName = fmt.Sprintf("%s_%d", strings.TrimRight(valueName, "_0123456789"), i)
+		}
+		values[valueName] = &graphql.EnumValueConfig{
+			Value: enumValue,
+		}
+	}
+
+	enumType := graphql.NewEnum(graphql.EnumConfig{
+		Name:        name,
+		Values:      values,
+		Description: column.ColumnDescription,
+	})
+	enumTypes[name] = enumType
+	return enumType
+}
+
+var graphqlLanguageArgument = &graphql.ArgumentConfig{
+	Type:        graphql.String,
+	Description: "Language of the values read or written, the Accept-Language header is used when not set",
+}
+
+// graphqlTranslationsEnabled tells if the queries and mutations of a table take a language argument
+func graphqlTranslationsEnabled(table resource.TableInfo) bool {
+	_, hasLanguageColumn := table.GetColumnByName("language")
+	return table.TranslationsEnabled && !hasLanguageColumn
+}
+
+// graphqlLanguageContext applies the language argument, which is removed from the arguments since it is not a value
+// of the row
+func graphqlLanguageContext(params graphql.ResolveParams) (context.Context, error) {
+	languageArgument, ok := params.Args["language"].(string)
+	delete(params.Args, "language")
+	if !ok || languageArgument == "" {
+		return params.Context, nil
+	}
+	baseLanguage, err := ParseLanguage(languageArgument)
+	if err != nil {
+		return nil, err
+	}
+	return resource.WithTranslationLanguage(params.Context, baseLanguage), nil
+}
+
 func MakeGraphqlSchema(cmsConfig *resource.CmsConfig, resources map[string]*resource.DbResource) *graphql.Schema {
 
 	//mutations := make(graphql.InputObjectConfigFieldMap)
@@ -35,6 +102,7 @@ func MakeGraphqlSchema(cmsConfig *resource.CmsConfig, resources map[string]*reso
 	//done := make(map[string]bool)
 
 	inputTypesMap := make(map[string]*graphql.Object)
+	enumTypes := make(graphqlEnumTypes)
 	//outputTypesMap := make(map[string]graphql.Output)
 	//connectionMap := make(map[string]*relay.GraphQLConnectionDefinitions)
 
@@ -212,14 +280,14 @@ func MakeGraphqlSchema(cmsConfig *resource.CmsConfig, resources map[string]*reso
 		for _, column := range table.Columns {
 
 			allFields[table.TableName+"."+column.ColumnName] = &graphql.ArgumentConfig{
-				Type:         resource.ColumnManager.GetGraphqlType(column.ColumnType),
+				Type:         enumTypes.columnType(table.TableName, column),
 				DefaultValue: column.DefaultValue,
 				Description:  column.ColumnDescription,
 			}
 
 			if column.IsUnique || column.IsPrimaryKey {
 				uniqueFields[table.TableName+"."+column.ColumnName] = &graphql.ArgumentConfig{
-					Type:         resource.ColumnManager.GetGraphqlType(column.ColumnType),
+					Type:         enumTypes.columnType(table.TableName, column),
 					DefaultValue: column.DefaultValue,
 					Description:  column.ColumnDescription,
 				}
@@ -240,7 +308,7 @@ func MakeGraphqlSchema(cmsConfig *resource.CmsConfig, resources map[string]*reso
 			//		log.Errorf("Unknown data source of column [%s] in table [%v] cannot be defined in graphql schema %s", column.ColumnName, table.TableName, column.ForeignKeyData)
 			//	}
 			//} else {
-			graphqlType = resource.ColumnManager.GetGraphqlType(column.ColumnType)
+			graphqlType = enumTypes.columnType(table.TableName, column)
 			//}
 
 			fields[column.ColumnName] = &graphql.Field{
@@ -342,7 +410,11 @@ func MakeGraphqlSchema(cmsConfig *resource.CmsConfig, resources map[string]*reso
 					pr := &http.Request{
 						Method: "GET",
 					}
-					pr = pr.WithContext(params.Context)
+					ctx, err := graphqlLanguageContext(params)
+					if err != nil {
+						return nil, err
+					}
+					pr = pr.WithContext(ctx)
 
 					pageNumber := 1
 					pageSize := 10
@@ -431,6 +503,9 @@ func MakeGraphqlSchema(cmsConfig *resource.CmsConfig, resources map[string]*reso
 				}
 			}(table),
 		}
+		if graphqlTranslationsEnabled(table) {
+			rootFields[table.TableName].Args["language"] = graphqlLanguageArgument
+		}
 
 		rootFields["aggregate"+strcase.ToCamel(table.TableName)] = &graphql.Field{
 			Type:        graphql.NewList(inputTypesMap[table.TableName]),
@@ -575,7 +650,7 @@ func MakeGraphqlSchema(cmsConfig *resource.CmsConfig, resources map[string]*reso
 
 				var finalGraphqlType graphql.Type
 				var finalGraphqlType1 graphql.Type
-				finalGraphqlType = resource.ColumnManager.GetGraphqlType(col.ColumnType)
+				finalGraphqlType = enumTypes.columnType(table.TableName, col)
 				finalGraphqlType1 = finalGraphqlType
 
 				updateFields[col.ColumnName] = &graphql.ArgumentConfig{
@@ -596,18 +671,27 @@ func MakeGraphqlSchema(cmsConfig *resource.CmsConfig, resources map[string]*reso
 
 			}
 
+			if graphqlTranslationsEnabled(table) {
+				inputFields["language"] = graphqlLanguageArgument
+				updateFields["language"] = graphqlLanguageArgument
+			}
+
 			mutationFields["add"+strcase.ToCamel(table.TableName)] = &graphql.Field{
 				Type:        inputTypesMap[table.TableName],
 				Description: "Create new " + strings.ReplaceAll(table.TableName, "_", " "),
 				Args:        inputFields,
 				Resolve: func(params graphql.ResolveParams) (interface{}, error) {
+					ctx, err := graphqlLanguageContext(params)
+					if err != nil {
+						return nil, err
+					}
 					obj := api2go.NewApi2GoModelWithData(table.TableName, nil, 0, nil, params.Args)
 
 					pr := &http.Request{
 						Method: "POST",
 					}
 
-					pr = pr.WithContext(params.Context)
+					pr = pr.WithContext(ctx)
 
 					req := api2go.Request{
 						PlainRequest: pr,
@@ -644,6 +728,10 @@ func MakeGraphqlSchema(cmsConfig *resource.CmsConfig, resources map[string]*reso
 				Args:        updateInputFields,
 				Resolve: func(params graphql.ResolveParams) (interface{}, error) {
 
+					ctx, err := graphqlLanguageContext(params)
+					if err != nil {
+						return nil, err
+					}
 					referenceIdInf, ok := params.Args["reference_id"]
 					var referenceId daptinid.DaptinReferenceId
 					if ok {
@@ -701,7 +789,7 @@ func MakeGraphqlSchema(cmsConfig *resource.CmsConfig, resources map[string]*reso
 						Method: "PATCH",
 					}
 
-					pr = pr.WithContext(params.Context)
+					pr = pr.WithContext(ctx)
 
 					req := api2go.Request{
 						PlainRequest: pr,
@@ -736,7 +824,11 @@ func MakeGraphqlSchema(cmsConfig *resource.CmsConfig, resources map[string]*reso
 						Method: "DELETE",
 					}
 
-					pr = pr.WithContext(params.Context)
+					ctx, err := graphqlLanguageContext(params)
+					if err != nil {
+						return nil, err
+					}
+					pr = pr.WithContext(ctx)
 
 					req := api2go.Request{
 						PlainRequest: pr,
@@ -762,11 +854,15 @@ func MakeGraphqlSchema(cmsConfig *resource.CmsConfig, resources map[string]*reso
 												}`, flect.Capitalize(table.TableName)), err
 				},
 			}
+			if graphqlTranslationsEnabled(table) {
+				mutationFields["delete"+strcase.ToCamel(table.TableName)].Args["language"] = graphqlLanguageArgument
+			}
 
 		}(t)
 
 	}
 
+	schemaTypes := newGraphqlSchemaTypes()
 	for _, a := range cmsConfig.Actions {
 
 		func(action resource.Action) {
@@ -776,7 +872,7 @@ func MakeGraphqlSchema(cmsConfig *resource.CmsConfig, resources map[string]*reso
 			for _, col := range action.InFields {
 
 				var finalGraphqlType graphql.Type
-				finalGraphqlType = resource.ColumnManager.GetGraphqlType(col.ColumnType)
+				finalGraphqlType = enumTypes.columnType(action.Name, col)
 
 				if !col.IsNullable {
 					finalGraphqlType = graphql.NewNonNull(finalGraphqlType)
@@ -790,6 +886,13 @@ func MakeGraphqlSchema(cmsConfig *resource.CmsConfig, resources map[string]*reso
 
 			}
 
+			schemaTypes.actionArguments(action, inputFields)
+
+			responseType := actionResponseType
+			if len(action.OutputSchema) > 0 {
+				responseType = schemaTypes.actionResponseType(action)
+			}
+
 			//if !action.InstanceOptional {
 			//	inputFields[action.OnType+"_id"] = &graphql.ArgumentConfig{
 			//		Type:        graphql.NewNonNull(graphql.String),
@@ -798,7 +901,7 @@ func MakeGraphqlSchema(cmsConfig *resource.CmsConfig, resources map[string]*reso
 			//}
 
 			mutationFields["execute"+strcase.ToCamel(action.Name)+"On"+strcase.ToCamel(action.OnType)] = &graphql.Field{
-				Type:        graphql.NewList(actionResponseType),
+				Type:        graphql.NewList(responseType),
 				Description: "Execute " + strings.ReplaceAll(action.Name, "_", " ") + " on " + action.OnType,
 				Args:        inputFields,
 				Resolve: func(params graphql.ResolveParams) (interface{}, error) {
@@ -807,7 +910,7 @@ func MakeGraphqlSchema(cmsConfig *resource.CmsConfig, resources map[string]*reso
 						Method: "EXECUTE",
 					}
 
-					pr = pr.WithContext(params.Context)
+					pr = pr.WithContext(resource.WithActionChannel(params.Context, resource.ActionChannelGraphql))
 
 					req := api2go.Request{
 						PlainRequest: pr,
@@ -0,0 +1,219 @@
+package server
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/daptin/daptin/server/resource"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/iancoleman/strcase"
+)
+
+// graphqlJsonType holds values a schema does not describe field by field: objects without properties, values of any
+// type, or of more than one type
+var graphqlJsonType = graphql.NewScalar(graphql.ScalarConfig{
+	Name:        "JSON",
+	Description: "A json value",
+	Serialize: func(value interface{}) interface{} {
+		return value
+	},
+	ParseValue: func(value interface{}) interface{} {
+		return value
+	},
+	ParseLiteral: graphqlJsonLiteral,
+})
+
+func graphqlJsonLiteral(valueAST ast.Value) interface{} {
+	switch value := valueAST.(type) {
+	case *ast.StringValue:
+		return value.Value
+	case *ast.EnumValue:
+		return value.Value
+	case *ast.BooleanValue:
+		return value.Value
+	case *ast.IntValue:
+		intValue, err := strconv.ParseInt(value.Value, 10, 64)
+		if err != nil {
+			return nil
+		}
+		return intValue
+	case *ast.FloatValue:
+		floatValue, err := strconv.ParseFloat(value.Value, 64)
+		if err != nil {
+			return nil
+		}
+		return floatValue
+	case *ast.ListValue:
+		values := make([]interface{}, 0, len(value.Values))
+		for _, item := range value.Values {
+			values = append(values, graphqlJsonLiteral(item))
+		}
+		return values
+	case *ast.ObjectValue:
+		values := make(map[string]interface{}, len(value.Fields))
+		for _, field := range value.Fields {
+			values[field.Name.Value] = graphqlJsonLiteral(field.Value)
+		}
+		return values
+	}
+	return nil
+}
+
+// graphqlSchemaTypes makes the graphql types of the input and output schemas of actions, each object of a schema is
+// a type named after the action and the path to it
+type graphqlSchemaTypes struct {
+	names map[string]bool
+}
+
+func newGraphqlSchemaTypes() *graphqlSchemaTypes {
+	return &graphqlSchemaTypes{
+		names: make(map[string]bool),
+	}
+}
+
+// typeName is a name no other schema type has taken
+func (schemaTypes *graphqlSchemaTypes) typeName(name string) string {
+	name = strcase.ToCamel(name)
+	typeName := name
+	for i := 2; schemaTypes.names[typeName]; i++ {
+		typeName = name + strconv.Itoa(i)
+	}
+	schemaTypes.names[typeName] = true
+	return typeName
+}
+
+// schemaType is the graphql type of the schema, an input type when input is true
+func (schemaTypes *graphqlSchemaTypes) schemaType(name string, schema map[string]interface{}, input bool) graphql.Type {
+	schemaTypeName, _ := schema["type"].(string)
+	switch schemaTypeName {
+	case "string":
+		return graphql.String
+	case "integer":
+		return graphql.Int
+	case "number":
+		return graphql.Float
+	case "boolean":
+		return graphql.Boolean
+	case "array":
+		items, ok := schema["items"].(map[string]interface{})
+		if !ok {
+			return graphql.NewList(graphqlJsonType)
+		}
+		return graphql.NewList(schemaTypes.schemaType(name+"_item", items, input))
+	case "object":
+		properties, ok := schema["properties"].(map[string]interface{})
+		if !ok || len(properties) == 0 {
+			return graphqlJsonType
+		}
+		if input {
+			return schemaTypes.inputObject(name, schema, properties)
+		}
+		return schemaTypes.outputObject(name, schema, properties)
+	}
+	return graphqlJsonType
+}
+
+func (schemaTypes *graphqlSchemaTypes) inputObject(name string, schema map[string]interface{},
+	properties map[string]interface{}) graphql.Type {
+	required := schemaRequired(schema)
+	fields := make(graphql.InputObjectConfigFieldMap)
+	for _, propertyName := range sortedSchemaProperties(properties) {
+		property, _ := properties[propertyName].(map[string]interface{})
+		var fieldType = schemaTypes.schemaType(name+"_"+propertyName, property, true)
+		if required[propertyName] {
+			fieldType = graphql.NewNonNull(fieldType)
+		}
+		description, _ := property["description"].(string)
+		fields[propertyName] = &graphql.InputObjectFieldConfig{
+			Type:        fieldType,
+			Description: description,
+		}
+	}
+	description, _ := schema["description"].(string)
+	return graphql.NewInputObject(graphql.InputObjectConfig{
+		Name:        schemaTypes.typeName(name + "_input"),
+		Fields:      fields,
+		Description: description,
+	})
+}
+
+func (schemaTypes *graphqlSchemaTypes) outputObject(name string, schema map[string]interface{},
+	properties map[string]interface{}) graphql.Type {
+	fields := make(graphql.Fields)
+	for _, propertyName := range sortedSchemaProperties(properties) {
+		property, _ := properties[propertyName].(map[string]interface{})
+		description, _ := property["description"].(string)
+		fields[propertyName] = &graphql.Field{
+			Type:        schemaTypes.schemaType(name+"_"+propertyName, property, false),
+			Description: description,
+		}
+	}
+	description, _ := schema["description"].(string)
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name:        schemaTypes.typeName(name),
+		Fields:      fields,
+		Description: description,
+	})
+}
+
+// actionArguments adds the properties of the InputSchema of the action to the arguments of its mutation, a property
+// described by the schema replaces the in field of the same name
+func (schemaTypes *graphqlSchemaTypes) actionArguments(action resource.Action, arguments graphql.FieldConfigArgument) {
+	properties, ok := action.InputSchema["properties"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	required := schemaRequired(action.InputSchema)
+	name := action.Name + "_on_" + action.OnType
+	for _, propertyName := range sortedSchemaProperties(properties) {
+		property, _ := properties[propertyName].(map[string]interface{})
+		var argumentType = schemaTypes.schemaType(name+"_"+propertyName, property, true)
+		if required[propertyName] {
+			argumentType = graphql.NewNonNull(argumentType)
+		}
+		description, _ := property["description"].(string)
+		arguments[propertyName] = &graphql.ArgumentConfig{
+			Type:         argumentType,
+			Description:  description,
+			DefaultValue: property["default"],
+		}
+	}
+}
+
+// actionResponseType is the type of the responses of the action, with Attributes of the OutputSchema
+func (schemaTypes *graphqlSchemaTypes) actionResponseType(action resource.Action) *graphql.Object {
+	name := action.Name + "_on_" + action.OnType
+	return graphql.NewObject(graphql.ObjectConfig{
+		Name:        schemaTypes.typeName(name + "_response"),
+		Description: "Response of " + action.Label,
+		Fields: graphql.Fields{
+			"ResponseType": &graphql.Field{
+				Type: graphql.String,
+			},
+			"Attributes": &graphql.Field{
+				Type: schemaTypes.schemaType(name+"_attributes", action.OutputSchema, false),
+			},
+		},
+	})
+}
+
+func schemaRequired(schema map[string]interface{}) map[string]bool {
+	required := make(map[string]bool)
+	names, _ := schema["required"].([]interface{})
+	for _, name := range names {
+		if nameString, ok := name.(string); ok {
+			required[nameString] = true
+		}
+	}
+	return required
+}
+
+func sortedSchemaProperties(properties map[string]interface{}) []string {
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -2,6 +2,11 @@ package server
 
 import (
 	"context"
+	"fmt"
+	"strings"
+
+	"github.com/daptin/daptin/server/auth"
+	"github.com/daptin/daptin/server/database"
 	"github.com/daptin/daptin/server/resource"
 	"github.com/gin-gonic/gin"
 	"github.com/jmoiron/sqlx"
@@ -10,8 +15,9 @@ import (
 
 // CorsMiddleware provides a configurable CORS implementation.
 type LanguageMiddleware struct {
-	configStore     *resource.ConfigStore
-	defaultLanguage string
+	configStore       *resource.ConfigStore
+	defaultLanguage   string
+	fallbackLanguages []string
 }
 
 func NewLanguageMiddleware(configStore *resource.ConfigStore, transaction *sqlx.Tx) *LanguageMiddleware {
@@ -23,9 +29,18 @@ func NewLanguageMiddleware(configStore *resource.ConfigStore, transaction *sqlx.
 		resource.CheckErr(err, "Failed to store default value for default language")
 	}
 
+	// languages to try, in order, when a value is not translated in any of the languages asked for, eg "es,fr"
+	fallback, err := configStore.GetConfigValueFor("language.fallback", "backend", transaction)
+	if err != nil {
+		fallback = ""
+		err = configStore.SetConfigValueFor("language.fallback", fallback, "backend", transaction)
+		resource.CheckErr(err, "Failed to store default value for language fallback")
+	}
+
 	return &LanguageMiddleware{
-		configStore:     configStore,
-		defaultLanguage: defaultLanguage,
+		configStore:       configStore,
+		defaultLanguage:   defaultLanguage,
+		fallbackLanguages: resource.TranslationFallbackChain(strings.Split(fallback, ","), defaultLanguage),
 	}
 }
 
@@ -33,12 +48,38 @@ func (lm *LanguageMiddleware) LanguageMiddlewareFunc(c *gin.Context) {
 	//log.Printf("middleware ")
 
 	pref := GetLanguagePreference(c.GetHeader("Accept-Language"), lm.defaultLanguage)
+	if len(pref) > 0 {
+		pref = resource.TranslationFallbackChain(append(pref, lm.fallbackLanguages...), lm.defaultLanguage)
+	}
 
 	//c.Request.Context("language_preference", pref)
-	c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), "language_preference", pref))
+	ctx := context.WithValue(c.Request.Context(), "language_default", lm.defaultLanguage)
+	ctx = context.WithValue(ctx, "language_fallback", lm.fallbackLanguages)
+	ctx = context.WithValue(ctx, "language_preference", pref)
+
+	// the language parameter picks the language of the values read, and of the values written
+	if languageParameter := c.Query("language"); languageParameter != "" {
+		baseLanguage, err := ParseLanguage(languageParameter)
+		if err != nil {
+			c.AbortWithStatusJSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		ctx = resource.WithTranslationLanguage(ctx, baseLanguage)
+	}
+	c.Request = c.Request.WithContext(ctx)
 
 }
 
+// ParseLanguage reads a language tag, translations are stored by base language, eg fr for fr-CA
+func ParseLanguage(languageTag string) (string, error) {
+	tag, err := language.Parse(languageTag)
+	if err != nil {
+		return "", fmt.Errorf("invalid language [%v]", languageTag)
+	}
+	base, _ := tag.Base()
+	return base.String(), nil
+}
+
 func GetLanguagePreference(header string, defaultLanguage string) []string {
 	preferredLanguage := header
 
@@ -70,3 +111,49 @@ func GetLanguagePreference(header string, defaultLanguage string) []string {
 	}
 	return pref
 }
+
+// CreateTranslationCoverageHandler reports, for each table with translations enabled, how many rows and values are
+// translated into each language
+func CreateTranslationCoverageHandler(initConfig *resource.CmsConfig, db database.DatabaseConnection) func(*gin.Context) {
+	return func(c *gin.Context) {
+		user := c.Request.Context().Value("user")
+		sessionUser := &auth.SessionUser{}
+		if user != nil {
+			sessionUser = user.(*auth.SessionUser)
+		}
+
+		transaction, err := db.Beginx()
+		if err != nil {
+			resource.CheckErr(err, "Failed to begin transaction [111]")
+			c.AbortWithStatus(500)
+			return
+		}
+		defer transaction.Rollback()
+
+		if !resource.IsAdminWithTransaction(sessionUser.UserReferenceId, transaction) {
+			c.AbortWithError(403, fmt.Errorf("unauthorized"))
+			return
+		}
+
+		typeName := c.Param("typename")
+		coverages := make([]resource.TranslationCoverage, 0)
+		for _, table := range initConfig.Tables {
+			if !table.TranslationsEnabled || (typeName != "" && table.TableName != typeName) {
+				continue
+			}
+			coverage, err := resource.GetTranslationCoverage(table, transaction)
+			if err != nil {
+				c.AbortWithStatusJSON(500, gin.H{"error": err.Error()})
+				return
+			}
+			coverages = append(coverages, coverage)
+		}
+
+		if typeName != "" && len(coverages) == 0 {
+			c.AbortWithStatusJSON(404, gin.H{"error": "translations are not enabled for [" + typeName + "]"})
+			return
+		}
+
+		c.JSON(200, coverages)
+	}
+}
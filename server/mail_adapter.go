@@ -88,8 +88,8 @@ func trimToLimit(str string, limit int) string {
 }
 
 type DaptinSmtpAuthenticator struct {
-	dbResource *resource.DbResource
-	config     backends.BackendConfig
+	mailResource func() *resource.DbResource
+	config       backends.BackendConfig
 }
 
 func (dsa *DaptinSmtpAuthenticator) VerifyLOGIN(login, passwordBase64 string) bool {
@@ -98,14 +98,15 @@ func (dsa *DaptinSmtpAuthenticator) VerifyLOGIN(login, passwordBase64 string) bo
 	if err != nil {
 		return false
 	}
-	transaction, err := dsa.dbResource.Connection.Beginx()
+	dbResource := dsa.mailResource()
+	transaction, err := dbResource.Connection.Beginx()
 	if err != nil {
 		resource.CheckErr(err, "Failed to begin transaction [102]")
 		return false
 	}
 
 	defer transaction.Rollback()
-	mailAccount, err := dsa.dbResource.GetUserMailAccountRowByEmail(string(username), transaction)
+	mailAccount, err := dbResource.GetUserMailAccountRowByEmail(string(username), transaction)
 	if err != nil {
 		return false
 	}
@@ -148,16 +149,16 @@ func (dsa *DaptinSmtpAuthenticator) GetMailSize(login string, defaultSize int64)
 	return 10000
 }
 
-func DaptinSmtpAuthenticatorCreator(dbResource *resource.DbResource) func(config backends.BackendConfig) authenticators.Authenticator {
+func DaptinSmtpAuthenticatorCreator(mailResource func() *resource.DbResource) func(config backends.BackendConfig) authenticators.Authenticator {
 	return func(config backends.BackendConfig) authenticators.Authenticator {
 		return &DaptinSmtpAuthenticator{
-			dbResource: dbResource,
-			config:     config,
+			mailResource: mailResource,
+			config:       config,
 		}
 	}
 }
 
-func DaptinSmtpDbResource(dbResource *resource.DbResource, certificateManager *resource.CertificateManager) func() backends.Decorator {
+func DaptinSmtpDbResource(mailResource func() *resource.DbResource, certificateManager *resource.CertificateManager) func() backends.Decorator {
 
 	return func() backends.Decorator {
 		var config *SQLProcessorConfig
@@ -186,6 +187,7 @@ func DaptinSmtpDbResource(dbResource *resource.DbResource, certificateManager *r
 
 		return func(p backends.Processor) backends.Processor {
 			mailSender := func(e *mail.Envelope, task backends.SelectTask) (backends.Result, error) {
+				dbResource := mailResource()
 
 				if task == backends.TaskSaveMail {
 					var to, body string
@@ -498,7 +500,7 @@ func DaptinSmtpDbResource(dbResource *resource.DbResource, certificateManager *r
 				}
 			}
 
-			dbResource.MailSender = mailSender
+			mailResource().MailSender = mailSender
 
 			return backends.ProcessWith(mailSender)
 		}
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/artpar/go-guerrilla"
+	"github.com/artpar/go-imap/server"
+	"github.com/buraksezer/olric"
+	"github.com/daptin/daptin/server/resource"
+	server2 "github.com/fclairamb/ftpserver/server"
+	"github.com/go-redis/redis/v8"
+	log "github.com/sirupsen/logrus"
+)
+
+// ReloadChannel is the olric pubsub channel on which a cluster member asks every other member to reload
+const ReloadChannel = "daptin.reload"
+
+// reloadDebounce coalesces the burst of row events generated by a single schema change into one reload
+const reloadDebounce = 2 * time.Second
+
+// ReloadTables are the tables whose rows are used to build the routes, graphql schema, actions and tasks.
+// Any change to these rows rebuilds the router in place.
+var ReloadTables = []string{"world", "action", "task", "stream"}
+
+// RunningServices are the listeners which keep running when the server is reloaded in place.
+// They are started by Main when nil and handed back to Main on reload so they are not torn down, the resources they
+// use are replaced through runningResources.
+type RunningServices struct {
+	MailDaemon *guerrilla.Daemon
+	FtpServer  *server2.FtpServer
+	ImapServer *server.Server
+}
+
+// serviceResources are the resources of the latest load. The SMTP, IMAP and FTP servers keep running across reloads
+// and read the resources through it, so they use the tables and actions of the reload and not of their start.
+type serviceResources struct {
+	lock  sync.RWMutex
+	cruds map[string]*resource.DbResource
+}
+
+var runningResources = &serviceResources{}
+
+// set replaces the resources, the mail sender of the running SMTP server is kept on the new mail resource
+func (sr *serviceResources) set(cruds map[string]*resource.DbResource) {
+	sr.lock.Lock()
+	defer sr.lock.Unlock()
+	if previous, ok := sr.cruds["mail"]; ok {
+		if mail, ok := cruds["mail"]; ok && mail.MailSender == nil {
+			mail.MailSender = previous.MailSender
+		}
+	}
+	sr.cruds = cruds
+}
+
+func (sr *serviceResources) get() map[string]*resource.DbResource {
+	sr.lock.RLock()
+	defer sr.lock.RUnlock()
+	return sr.cruds
+}
+
+func (sr *serviceResources) mail() *resource.DbResource {
+	return sr.get()["mail"]
+}
+
+// ReloadCoordinator listens for changes to ReloadTables and reload requests from other cluster members,
+// and calls reload once things settle down
+type ReloadCoordinator struct {
+	pubsub     *olric.PubSub
+	memberName string
+	reload     func()
+	lock       sync.Mutex
+	timer      *time.Timer
+}
+
+func NewReloadCoordinator(olricDb *olric.EmbeddedClient, memberName string, reload func()) (*ReloadCoordinator, error) {
+	pubsub, err := olricDb.NewPubSub()
+	if err != nil {
+		return nil, err
+	}
+	return &ReloadCoordinator{
+		pubsub:     pubsub,
+		memberName: memberName,
+		reload:     reload,
+	}, nil
+}
+
+// Listen subscribes to row events of the reload tables and to the cluster reload channel
+func (rc *ReloadCoordinator) Listen() {
+	channels := append([]string{ReloadChannel}, ReloadTables...)
+	sub := rc.pubsub.Subscribe(context.Background(), channels...)
+
+	go func(pubsub *redis.PubSub) {
+		channel := pubsub.Channel()
+		for msg := range channel {
+			var eventMessage resource.EventMessage
+			err := eventMessage.UnmarshalBinary([]byte(msg.Payload))
+			if err != nil {
+				resource.CheckErr(err, "Failed to read message on channel "+msg.Channel)
+				continue
+			}
+
+			if msg.Channel == ReloadChannel {
+				if eventMessage.MessageSource == rc.memberName {
+					continue
+				}
+				log.Infof("Reload requested by cluster member [%v]", eventMessage.MessageSource)
+			} else {
+				log.Debugf("Row [%v] on [%v], scheduling reload", eventMessage.EventType, eventMessage.ObjectType)
+			}
+			rc.Schedule()
+		}
+	}(sub)
+}
+
+// Schedule reloads this member after reloadDebounce, postponing any reload already scheduled
+func (rc *ReloadCoordinator) Schedule() {
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+	if rc.timer != nil {
+		rc.timer.Stop()
+	}
+	rc.timer = time.AfterFunc(reloadDebounce, rc.reload)
+}
+
+// Broadcast asks every other cluster member to reload
+func (rc *ReloadCoordinator) Broadcast() {
+	_, err := rc.pubsub.Publish(context.Background(), ReloadChannel, resource.EventMessage{
+		MessageSource: rc.memberName,
+		EventType:     "reload",
+		ObjectType:    "world",
+		EventData:     map[string]interface{}{},
+	})
+	resource.CheckErr(err, "Failed to publish reload message")
+}
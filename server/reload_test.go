@@ -0,0 +1,51 @@
+package server
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/artpar/go-guerrilla/backends"
+	"github.com/artpar/go-guerrilla/mail"
+	"github.com/daptin/daptin/server/resource"
+)
+
+func TestServiceResourcesKeepTheMailSender(t *testing.T) {
+	resources := &serviceResources{}
+	sent := 0
+	first := map[string]*resource.DbResource{"mail": {}}
+	resources.set(first)
+	// the running smtp server sets the mail sender on the mail resource once, when it starts
+	resources.mail().MailSender = func(e *mail.Envelope, task backends.SelectTask) (backends.Result, error) {
+		sent++
+		return nil, nil
+	}
+
+	reloaded := map[string]*resource.DbResource{"mail": {}}
+	resources.set(reloaded)
+	if resources.mail() != reloaded["mail"] {
+		t.Fatalf("expected the resources of the reload")
+	}
+	if reloaded["mail"].MailSender == nil {
+		t.Fatalf("expected the mail sender of the running smtp server to be kept")
+	}
+	reloaded["mail"].MailSender(nil, backends.TaskSaveMail)
+	if sent != 1 {
+		t.Errorf("expected the mail to be sent by the running smtp server")
+	}
+}
+
+func TestReloadScheduleCoalesces(t *testing.T) {
+	var reloads int32
+	coordinator := &ReloadCoordinator{reload: func() {
+		atomic.AddInt32(&reloads, 1)
+	}}
+	// a restart trigger and the row events of the same change schedule a reload each
+	coordinator.Schedule()
+	coordinator.Schedule()
+	coordinator.Schedule()
+	time.Sleep(reloadDebounce + 500*time.Millisecond)
+	if count := atomic.LoadInt32(&reloads); count != 1 {
+		t.Errorf("expected a single reload, got %d", count)
+	}
+}
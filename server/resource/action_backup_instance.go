@@ -0,0 +1,76 @@
+package resource
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/artpar/api2go"
+	daptinid "github.com/daptin/daptin/server/id"
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+)
+
+type backupInstancePerformer struct {
+	cmsConfig *CmsConfig
+	cruds     map[string]*DbResource
+}
+
+func (d *backupInstancePerformer) Name() string {
+	return "__backup_instance"
+}
+
+// DoAction starts the backup and returns the name of the archive it will write. The snapshot is taken once the
+// action is committed, in a transaction of its own.
+func (d *backupInstancePerformer) DoAction(request Outcome, inFields map[string]interface{}, transaction *sqlx.Tx) (api2go.Responder, []ActionResponse, []error) {
+
+	referenceId, ok := referenceIdBytes(inFields["cloud_store_id"])
+	if !ok {
+		return nil, nil, []error{errors.New("cloud store to write the backup to is required")}
+	}
+	var cloudStoreReferenceId daptinid.DaptinReferenceId
+	copy(cloudStoreReferenceId[:], referenceId)
+
+	cloudStore, err := d.cruds["cloud_store"].GetCloudStoreByReferenceId(cloudStoreReferenceId, transaction)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+	if cloudStore.RootPath == "" {
+		return nil, nil, []error{fmt.Errorf("no such cloud store: %v", inFields["cloud_store_id"])}
+	}
+
+	path, _ := inFields["path"].(string)
+	if path == "" {
+		path = DefaultBackupPath
+	}
+	archiveName := NewBackupArchiveName()
+
+	masker, err := NewDataMaskerFromInput(d.cmsConfig.Tables, inFields)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+
+	go func() {
+		log.Infof("Backing up instance to [%v] %v/%v", cloudStore.Name, path, archiveName)
+		manifest, err := d.cruds["cloud_store"].BackupInstance(cloudStore, path, archiveName, d.cmsConfig, masker)
+		if err != nil {
+			log.Errorf("Failed to back up instance to [%v] %v/%v: %v", cloudStore.Name, path, archiveName, err)
+			return
+		}
+		log.Infof("Backed up %d tables and %d file folders to [%v] %v/%v", len(manifest.Tables),
+			len(manifest.BlobFolders), cloudStore.Name, path, archiveName)
+	}()
+
+	return nil, []ActionResponse{NewActionResponse("client.notify", NewClientNotification("success",
+		fmt.Sprintf("Writing backup %v to %v", archiveName, cloudStorePath(cloudStore, path)), "Backup started"))}, nil
+}
+
+func NewBackupInstancePerformer(initConfig *CmsConfig, cruds map[string]*DbResource) (ActionPerformerInterface, error) {
+
+	handler := backupInstancePerformer{
+		cmsConfig: initConfig,
+		cruds:     cruds,
+	}
+
+	return &handler, nil
+
+}
@@ -8,7 +8,6 @@ import (
 	"strings"
 
 	"github.com/artpar/api2go"
-	fieldtypes "github.com/daptin/daptin/server/columntypes"
 	"github.com/daptin/daptin/server/csvmap"
 	"github.com/jmoiron/sqlx"
 	"github.com/sadlil/go-trigger"
@@ -41,6 +40,11 @@ func (d *uploadCsvFileToEntityPerformer) DoAction(request Outcome, inFields map[
 	if !ok {
 		add_missing_columns = false
 	}
+	if create_if_not_exists || add_missing_columns {
+		if err := checkGitOpsWritable("world", entityName); err != nil {
+			return nil, nil, []error{err}
+		}
+	}
 
 	table := TableInfo{}
 	table.TableName = entityName
@@ -50,6 +54,7 @@ func (d *uploadCsvFileToEntityPerformer) DoAction(request Outcome, inFields map[
 	allSt := make(map[string]interface{})
 
 	sources := make([]DataFileImport, 0)
+	uploadedFiles := make([]uploadedImportFile, 0)
 
 	completed := false
 
@@ -145,31 +150,13 @@ func (d *uploadCsvFileToEntityPerformer) DoAction(request Outcome, inFields map[
 				count -= 1
 			}
 
-			eType, _, err := fieldtypes.DetectType(datas)
-			if err != nil {
-				column.ColumnType = "label"
-				column.DataType = "varchar(100)"
-			} else {
-				column.ColumnType = EntityTypeToColumnTypeMap[eType]
-				column.DataType = entityTypeToDataTypeMap[eType]
-			}
-
-			if len(datas) > (recordCount / 10) {
-				column.IsIndexed = true
-			}
-
-			if len(datas) == recordCount {
-				column.IsUnique = true
-			}
-
-			column.IsNullable = isNullable
-			column.Name = colName
-			column.ColumnName = SmallSnakeCaseText(colName)
+			column = InferColumn(colName, datas, recordCount, isNullable)
 
 			columns = append(columns, column)
 		}
 		table.Columns = columns
 		completed = true
+		uploadedFiles = append(uploadedFiles, uploadedImportFile{Name: file["name"].(string), Contents: fileBytes})
 		sources = append(sources, DataFileImport{
 			FilePath: fileName,
 			Entity:   table.TableName,
@@ -202,7 +189,14 @@ func (d *uploadCsvFileToEntityPerformer) DoAction(request Outcome, inFields map[
 		if create_if_not_exists || add_missing_columns {
 			go restart()
 		} else {
-			ImportDataFiles(sources, transaction, d.cruds)
+			batchSize := ImportBatchSize(inFields["batch_size"], d.cruds["world"].configStore, transaction)
+			user, _ := inFields["user"].(map[string]interface{})
+			responses, errs := startImportJobs(entityName, "csv", uploadedFiles, batchSize, user, d.cruds, transaction)
+			if len(errs) > 0 {
+				return nil, nil, errs
+			}
+			trigger.Fire("clean_up_uploaded_files")
+			return nil, responses, nil
 		}
 		trigger.Fire("clean_up_uploaded_files")
 
@@ -0,0 +1,245 @@
+package resource
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/artpar/api2go"
+	"github.com/jmoiron/sqlx"
+	"github.com/sadlil/go-trigger"
+	log "github.com/sirupsen/logrus"
+)
+
+type uploadDataFileToEntityPerformer struct {
+	cruds     map[string]*DbResource
+	cmsConfig *CmsConfig
+}
+
+func (d *uploadDataFileToEntityPerformer) Name() string {
+	return "__upload_data_file_to_entity"
+}
+
+// DoAction imports ndjson and parquet files into an entity. The columns of a new entity, or the missing columns of an
+// existing one, are identified from the values in the file the same way as for csv files.
+func (d *uploadDataFileToEntityPerformer) DoAction(request Outcome, inFields map[string]interface{}, transaction *sqlx.Tx) (api2go.Responder, []ActionResponse, []error) {
+
+	log.Printf("Do action: %v", d.Name())
+
+	files := inFields["data_file"].([]interface{})
+
+	entityName := inFields["entity_name"].(string)
+	create_if_not_exists, ok := inFields["create_if_not_exists"].(bool)
+	if !ok {
+		create_if_not_exists = false
+	}
+	add_missing_columns, ok := inFields["add_missing_columns"].(bool)
+	if !ok {
+		add_missing_columns = false
+	}
+	if create_if_not_exists || add_missing_columns {
+		if err := checkGitOpsWritable("world", entityName); err != nil {
+			return nil, nil, []error{err}
+		}
+	}
+	columnNames := SelectedColumns(inFields["columns"])
+
+	table := TableInfo{}
+	table.TableName = entityName
+
+	allSt := make(map[string]interface{})
+	sources := make([]DataFileImport, 0)
+	uploadedFiles := make([]uploadedImportFile, 0)
+	fileFormat := ""
+
+	var existingEntity *TableInfo
+	if !create_if_not_exists {
+		dbr, ok := d.cruds[entityName]
+		if !ok {
+			return nil, nil, []error{fmt.Errorf("no such entity: %v", entityName)}
+		}
+		existingEntity = dbr.tableInfo
+	}
+
+	schemaFolderDefinedByEnv, _ := os.LookupEnv("DAPTIN_SCHEMA_FOLDER")
+
+	// distinct values of each column, the columns are identified from these
+	columnValues := make(map[string]map[string]bool)
+	nullableColumns := make(map[string]bool)
+	recordCount := 0
+
+	for _, fileInterface := range files {
+		file, ok := fileInterface.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		fileName := "_uploaded_" + file["name"].(string)
+		format := DataFormatFromFileName(fileName)
+		if format == DataFormatJson {
+			return nil, nil, []error{fmt.Errorf("[%v] is not a ndjson or parquet file", file["name"])}
+		}
+
+		fileContentsBase64 := file["file"].(string)
+		fileBytes, err := base64.StdEncoding.DecodeString(strings.Split(fileContentsBase64, ",")[1])
+		log.Printf("Processing file: %v", fileName)
+		if err != nil {
+			return nil, nil, []error{err}
+		}
+
+		if len(columnNames) > 0 {
+			fileBytes, err = selectFileColumns(fileBytes, format, columnNames)
+			if err != nil {
+				return nil, nil, []error{err}
+			}
+			if format != DataFormatNdjson {
+				format = DataFormatNdjson
+				fileName = fileName + ".ndjson"
+			}
+		}
+		if fileFormat != "" && fileFormat != format {
+			return nil, nil, []error{fmt.Errorf("all uploaded files should be %v files", fileFormat)}
+		}
+		fileFormat = format
+
+		fileRows := 0
+		err = ReadDataFileRows(fileBytes, format, func(row map[string]interface{}) error {
+			fileRows += 1
+			for colName := range columnValues {
+				if _, ok := row[colName]; !ok {
+					nullableColumns[colName] = true
+				}
+			}
+			for colName, value := range row {
+				values, ok := columnValues[colName]
+				if !ok {
+					values = make(map[string]bool)
+					columnValues[colName] = values
+					if recordCount+fileRows > 1 {
+						nullableColumns[colName] = true
+					}
+				}
+				value, err := plainValue(value)
+				if err != nil {
+					return err
+				}
+				if value == nil || fmt.Sprintf("%v", value) == "" {
+					nullableColumns[colName] = true
+					continue
+				}
+				if len(values) < 100000 {
+					values[fmt.Sprintf("%v", value)] = true
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, nil, []error{fmt.Errorf("failed to read [%v]: %v", file["name"], err)}
+		}
+		recordCount += fileRows
+
+		err = os.WriteFile(schemaFolderDefinedByEnv+string(os.PathSeparator)+fileName, fileBytes, 0644)
+		if err != nil {
+			log.Errorf("Failed to write %v file to disk: %v", format, err)
+		}
+
+		uploadedFiles = append(uploadedFiles, uploadedImportFile{Name: file["name"].(string), Contents: fileBytes})
+		sources = append(sources, DataFileImport{FilePath: fileName, Entity: table.TableName, FileType: format})
+	}
+
+	if len(uploadedFiles) == 0 {
+		return nil, failedResponses, nil
+	}
+
+	sortedColumnNames := make([]string, 0, len(columnValues))
+	for colName := range columnValues {
+		if colName == "" || colName == "__type" {
+			continue
+		}
+		sortedColumnNames = append(sortedColumnNames, colName)
+	}
+	sort.Strings(sortedColumnNames)
+
+	columns := make([]api2go.ColumnInfo, 0)
+	for _, colName := range sortedColumnNames {
+		if add_missing_columns && existingEntity != nil {
+			_, ok := existingEntity.GetColumnByName(colName)
+			if !ok {
+				// ignore column if it doesn't exists
+				continue
+			}
+		}
+
+		values := make([]string, 0, len(columnValues[colName]))
+		for value := range columnValues[colName] {
+			values = append(values, value)
+		}
+		sort.Strings(values)
+		columns = append(columns, InferColumn(colName, values, recordCount, nullableColumns[colName]))
+	}
+	table.Columns = columns
+
+	if create_if_not_exists {
+		allSt["tables"] = []TableInfo{table}
+	}
+
+	allSt["imports"] = sources
+
+	jsonStr, err := json.Marshal(allSt)
+	if err != nil {
+		InfoErr(err, "Failed to convert object to json")
+		return nil, nil, []error{err}
+	}
+
+	jsonFileName := fmt.Sprintf(schemaFolderDefinedByEnv+string(os.PathSeparator)+"schema_uploaded_%v_daptin.json", entityName)
+	err = os.WriteFile(jsonFileName, jsonStr, 0644)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+	log.Printf("File %v written to disk for upload", jsonFileName)
+
+	if create_if_not_exists || add_missing_columns {
+		go restart()
+	} else {
+		batchSize := ImportBatchSize(inFields["batch_size"], d.cruds["world"].configStore, transaction)
+		user, _ := inFields["user"].(map[string]interface{})
+		responses, errs := startImportJobs(entityName, fileFormat, uploadedFiles, batchSize, user, d.cruds, transaction)
+		if len(errs) > 0 {
+			return nil, nil, errs
+		}
+		trigger.Fire("clean_up_uploaded_files")
+		return nil, responses, nil
+	}
+	trigger.Fire("clean_up_uploaded_files")
+
+	return nil, successResponses, nil
+}
+
+// selectFileColumns rewrites the file as ndjson holding only the selected columns
+func selectFileColumns(fileBytes []byte, format string, columnNames []string) ([]byte, error) {
+	var output strings.Builder
+	rowWriter, err := NewDataRowWriter(&output, DataFormatNdjson, nil)
+	if err != nil {
+		return nil, err
+	}
+	err = ReadDataFileRows(fileBytes, format, func(row map[string]interface{}) error {
+		return rowWriter.Write(selectColumns(row, columnNames))
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = rowWriter.Close()
+	return []byte(output.String()), err
+}
+
+func NewUploadDataFileToEntityPerformer(initConfig *CmsConfig, cruds map[string]*DbResource) (ActionPerformerInterface, error) {
+
+	handler := uploadDataFileToEntityPerformer{
+		cruds:     cruds,
+		cmsConfig: initConfig,
+	}
+
+	return &handler, nil
+
+}
@@ -24,6 +24,9 @@ func (d *deleteWorldColumnPerformer) DoAction(request Outcome, inFields map[stri
 
 	worldName := inFields["world_name"].(string)
 	columnToDelete := inFields["column_name"].(string)
+	if err := checkGitOpsWritable("world", worldName); err != nil {
+		return nil, nil, []error{err}
+	}
 
 	sessionUser := request.Attributes["user"]
 	httpReq := &http.Request{
@@ -67,10 +70,19 @@ func (d *deleteWorldColumnPerformer) DoAction(request Outcome, inFields map[stri
 
 	schemaJson, err = json.Marshal(tableSchema)
 
-	_, err = transaction.Exec("alter table " + tableSchema.TableName + " drop column " + columnToDelete)
+	// sqlite refuses to drop a column used by a trigger
+	err = DropEnumConstraints(tableSchema.TableName, columnToDelete, transaction)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+
+	dropColumnQuery := "alter table " + tableSchema.TableName + " drop column " + columnToDelete
+	_, err = transaction.Exec(dropColumnQuery)
 	if err != nil {
 		return nil, nil, []error{err}
 	}
+	RecordSchemaChange(dropColumnQuery, "")
+	SetSchemaMigrationAuthor(SchemaMigrationAuthorFromOutcome(request))
 
 	updateObj := api2go.NewApi2GoModelWithData(tableSchema.TableName, nil, 0, nil, tableData)
 	updateObj.SetAttributes(map[string]interface{}{
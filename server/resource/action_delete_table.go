@@ -55,6 +55,9 @@ func (d *deleteWorldPerformer) DoAction(request Outcome, inFields map[string]int
 	if err != nil {
 		return nil, nil, []error{err}
 	}
+	if err := checkGitOpsWritable("world", tableSchema.TableName); err != nil {
+		return nil, nil, []error{err}
+	}
 	relations := tableSchema.Relations
 
 	var tablesToRemove []daptinid.DaptinReferenceId
@@ -151,11 +154,14 @@ func (d *deleteWorldPerformer) DoAction(request Outcome, inFields map[string]int
 	uuidVal := uuid.MustParse(tableData.GetID())
 	tablesToRemove = append(tablesToRemove, daptinid.DaptinReferenceId(uuidVal))
 
-	_, err = transaction.Exec("drop table " + tableData.GetAttributes()["table_name"].(string))
+	dropTableQuery := "drop table " + tableData.GetAttributes()["table_name"].(string)
+	_, err = transaction.Exec(dropTableQuery)
 	if err != nil {
 		errorsList = append(errorsList, err)
 		return nil, nil, errorsList
 	}
+	RecordSchemaChange(dropTableQuery, "")
+	SetSchemaMigrationAuthor(SchemaMigrationAuthorFromOutcome(request))
 
 	for _, table := range tablesToRemove {
 		err = d.cruds["world"].DeleteWithoutFilters(table, *req, transaction)
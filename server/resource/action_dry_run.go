@@ -0,0 +1,121 @@
+package resource
+
+import (
+	"context"
+	"time"
+
+	"github.com/artpar/api2go"
+)
+
+// An action called with the dry_run flag is run as usual, validating the inputs, evaluating the conditions and
+// building every outcome, but its transaction is always rolled back. Outcomes on tables run inside that transaction,
+// without publishing events, syncing data exchanges or uploading and deleting files of cloud store columns. Outcomes
+// run by action performers, which may send mails, call other services or run commands, are not run at all, they
+// respond with the attributes they would have been given. Compensations are not run.
+
+const actionDryRunContextKey = "action_dry_run"
+
+// OutcomeTrace is what an outcome did, or would have done in a dry run. Step is the position of the outcome in the
+// action, with the item and position inside a loop added for the outcomes of a loop, like "2.0.1". Duration is the
+// time the outcome took, in milliseconds.
+type OutcomeTrace struct {
+	Step       string                 `json:"step"`
+	Type       string                 `json:"type,omitempty"`
+	Method     string                 `json:"method,omitempty"`
+	Reference  string                 `json:"reference,omitempty"`
+	ForEach    string                 `json:"for_each,omitempty"`
+	Condition  string                 `json:"condition,omitempty"`
+	RunAs      string                 `json:"run_as,omitempty"`
+	Skipped    bool                   `json:"skipped"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+	Stubbed    bool                   `json:"stubbed,omitempty"`
+	Responses  []ActionResponse       `json:"responses,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+	Duration   float64                `json:"duration_ms"`
+}
+
+// ActionDryRun collects the trace of an action run with the dry_run flag
+type ActionDryRun struct {
+	Outcomes         []OutcomeTrace   `json:"outcomes"`
+	Responses        []ActionResponse `json:"responses"`
+	Error            string           `json:"error,omitempty"`
+	ValidationErrors []api2go.Error   `json:"validation_errors,omitempty"`
+}
+
+// WithActionDryRun marks the requests made with the context as a dry run, collecting the trace in dryRun
+func WithActionDryRun(ctx context.Context, dryRun *ActionDryRun) context.Context {
+	return context.WithValue(ctx, actionDryRunContextKey, dryRun)
+}
+
+func actionDryRunFrom(ctx context.Context) *ActionDryRun {
+	dryRun, _ := ctx.Value(actionDryRunContextKey).(*ActionDryRun)
+	return dryRun
+}
+
+// IsActionDryRun is true for the requests made by an action run with the dry_run flag, nothing they do outside the
+// database should happen
+func IsActionDryRun(ctx context.Context) bool {
+	return actionDryRunFrom(ctx) != nil
+}
+
+// isDryRunRequest is IsActionDryRun for a request which may have no http request
+func isDryRunRequest(req api2go.Request) bool {
+	return req.PlainRequest != nil && IsActionDryRun(req.PlainRequest.Context())
+}
+
+// traceOutcome adds the trace of an outcome to the run and gives its position in the trace, -1 once the trace is full
+func (run *outcomeRun) traceOutcome(trace OutcomeTrace) int {
+	if len(run.trace) >= maxOutcomeTraces {
+		return -1
+	}
+	run.trace = append(run.trace, trace)
+	return len(run.trace) - 1
+}
+
+func (run *outcomeRun) traceError(traceIndex int, err error) {
+	if traceIndex < 0 {
+		return
+	}
+	run.trace[traceIndex].Error = err.Error()
+}
+
+func (run *outcomeRun) traceDuration(traceIndex int, duration time.Duration) {
+	if traceIndex < 0 {
+		return
+	}
+	run.trace[traceIndex].Duration = durationMilliseconds(duration)
+}
+
+func durationMilliseconds(duration time.Duration) float64 {
+	return float64(duration.Microseconds()) / 1000
+}
+
+// isPerformerOutcome is true for the outcomes run by action performers rather than on a table
+func isPerformerOutcome(method string) bool {
+	switch method {
+	case "POST", "GET", "GET_BY_ID", "PATCH", "DELETE", "ACTIONRESPONSE":
+		return false
+	}
+	return true
+}
+
+// traceAttributes are the attributes of an outcome as shown in a dry run, with the values of sensitive keys and of
+// the password and encrypted columns of the outcome table masked
+func (run *outcomeRun) traceAttributes(outcome Outcome, attributes map[string]interface{}) map[string]interface{} {
+	traced, _ := sanitiseLogValue(attributes).(map[string]interface{})
+	crud, ok := run.db.Cruds[outcome.Type]
+	if !ok || crud == nil || crud.TableInfo() == nil {
+		return traced
+	}
+	for _, column := range crud.TableInfo().Columns {
+		if _, ok := traced[column.ColumnName]; ok && (column.ColumnType == "password" || column.ColumnType == "encrypted") {
+			traced[column.ColumnName] = "***"
+		}
+	}
+	return traced
+}
+
+// stubOutcome is the response of an outcome which is not run in a dry run
+func stubOutcome(outcome Outcome, attributes map[string]interface{}) []ActionResponse {
+	return []ActionResponse{NewActionResponse(outcome.Type, attributes)}
+}
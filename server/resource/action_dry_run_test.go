@@ -0,0 +1,121 @@
+package resource
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/artpar/api2go"
+	"github.com/jmoiron/sqlx"
+)
+
+func TestDryRunTracesAndStubsOutcomes(t *testing.T) {
+	db, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	db.MustExec("create table step (name varchar(20))")
+
+	dryRun := &ActionDryRun{}
+	ctx := WithActionDryRun(context.Background(), dryRun)
+	if !IsActionDryRun(ctx) || IsActionDryRun(context.Background()) {
+		t.Fatalf("expected only the dry run context to be a dry run")
+	}
+
+	run := &outcomeRun{
+		db: &DbResource{
+			Connection:       db,
+			ActionHandlerMap: map[string]ActionPerformerInterface{"test_step": testStepPerformer{}},
+		},
+		req:         api2go.Request{PlainRequest: (&http.Request{Method: "POST"}).WithContext(ctx)},
+		transaction: db.MustBegin(),
+		dryRun:      dryRun,
+	}
+	_, _, err = run.runOutcomes([]Outcome{
+		testStep("~name"),
+		{Type: "test_step", Condition: "!false", Attributes: map[string]interface{}{}},
+		{
+			ForEach: "$items",
+			Outcomes: []Outcome{
+				{Type: "test_step", Attributes: map[string]interface{}{"name": "$item"}},
+			},
+		},
+	}, map[string]interface{}{"name": "first", "items": []interface{}{"a", "b"}})
+	run.transaction.Rollback()
+	if err != nil {
+		t.Fatalf("failed to dry run outcomes: %v", err)
+	}
+
+	var count int
+	if err = db.Get(&count, "select count(*) from step"); err != nil || count != 0 {
+		t.Errorf("expected the performer not to run, got %d rows %v", count, err)
+	}
+
+	steps := make([]string, 0)
+	for _, trace := range run.trace {
+		steps = append(steps, trace.Step)
+	}
+	if len(steps) != 5 || steps[0] != "0" || steps[1] != "1" || steps[2] != "2" || steps[3] != "2.0.0" || steps[4] != "2.1.0" {
+		t.Fatalf("unexpected trace steps %v", steps)
+	}
+	first := run.trace[0]
+	if !first.Stubbed || first.Attributes["name"] != "first" || len(first.Responses) != 1 {
+		t.Errorf("expected the first outcome to be stubbed with its resolved attributes, got %+v", first)
+	}
+	if !run.trace[1].Skipped {
+		t.Errorf("expected the outcome with a false condition to be skipped")
+	}
+	if run.trace[4].Attributes["name"] != "b" {
+		t.Errorf("expected the loop item to be resolved, got %v", run.trace[4].Attributes)
+	}
+}
+
+func TestDryRunMasksSensitiveAttributes(t *testing.T) {
+	db, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	dryRun := &ActionDryRun{}
+	run := &outcomeRun{
+		db: &DbResource{
+			Connection:       db,
+			ActionHandlerMap: map[string]ActionPerformerInterface{"test_step": testStepPerformer{}},
+			Cruds: map[string]*DbResource{
+				"user_account": {tableInfo: &TableInfo{TableName: "user_account", Columns: []api2go.ColumnInfo{
+					{Name: "pin", ColumnName: "pin", ColumnType: "password"},
+					{Name: "email", ColumnName: "email", ColumnType: "email"},
+				}}},
+			},
+		},
+		req: api2go.Request{PlainRequest: (&http.Request{Method: "POST"}).WithContext(
+			WithActionDryRun(context.Background(), dryRun))},
+		transaction: db.MustBegin(),
+		dryRun:      dryRun,
+	}
+	defer run.transaction.Rollback()
+
+	_, _, err = run.runOutcomes([]Outcome{
+		{Type: "test_step", Attributes: map[string]interface{}{"name": "~name", "api_token": "~token"}},
+	}, map[string]interface{}{"name": "first", "token": "s3cr3t"})
+	if err != nil {
+		t.Fatalf("failed to dry run outcomes: %v", err)
+	}
+	trace := run.trace[0]
+	if trace.Attributes["api_token"] != "***" || trace.Attributes["name"] != "first" {
+		t.Errorf("expected the token to be masked in the trace, got %v", trace.Attributes)
+	}
+	stubbed, _ := trace.Responses[0].Attributes.(map[string]interface{})
+	if stubbed["api_token"] != "***" {
+		t.Errorf("expected the token to be masked in the stubbed response, got %v", trace.Responses[0].Attributes)
+	}
+
+	attributes := run.traceAttributes(Outcome{Type: "user_account"},
+		map[string]interface{}{"pin": "1234", "email": "a@example.com"})
+	if attributes["pin"] != "***" || attributes["email"] != "a@example.com" {
+		t.Errorf("expected the password column to be masked, got %v", attributes)
+	}
+}
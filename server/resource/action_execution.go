@@ -0,0 +1,366 @@
+package resource
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/artpar/api2go"
+	"github.com/daptin/daptin/server/auth"
+	"github.com/daptin/daptin/server/database"
+	daptinid "github.com/daptin/daptin/server/id"
+	"github.com/daptin/daptin/server/statementbuilder"
+	"github.com/doug-martin/goqu/v9"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// Every run of an action is recorded in the action_execution table: who ran it, on what, through which channel, the
+// inputs, the trace of each outcome with its duration and error, and how it ended. Passwords, secrets and tokens are
+// masked and long values are cut before they are stored. The records are written in the background, a burst of
+// actions larger than the queue is logged and not recorded. Records older than the retention are removed.
+
+const ActionExecutionTableName = "action_execution"
+
+const (
+	ActionExecutionSucceeded = "succeeded"
+	ActionExecutionFailed    = "failed"
+	ActionExecutionDryRun    = "dry_run"
+)
+
+// The channels an action is run through
+const (
+	ActionChannelHttp     = "http"
+	ActionChannelGraphql  = "graphql"
+	ActionChannelTask     = "task"
+	ActionChannelExchange = "exchange"
+	ActionChannelJob      = "job"
+	ActionChannelWebhook  = "webhook"
+	ActionChannelInternal = "internal"
+)
+
+const DefaultActionExecutionRetentionDays = 30
+
+const actionChannelContextKey = "action_channel"
+
+// actionExecutionQueueSize is the number of records waiting to be written before new ones are dropped
+const actionExecutionQueueSize = 1000
+
+// maxOutcomeTraces is the number of outcomes traced in one run, the outcomes of long loops after it are not traced
+const maxOutcomeTraces = 1000
+
+const (
+	maxLoggedStringLength = 1000
+	maxLoggedListLength   = 100
+)
+
+// sensitiveKeyParts mark the values masked in the records, a key containing any of them is masked
+var sensitiveKeyParts = []string{"password", "passwd", "secret", "token", "private_key", "api_key", "apikey",
+	"authorization", "cookie"}
+
+// sensitiveKeys are masked when the key is exactly one of them
+var sensitiveKeys = []string{"otp"}
+
+// jwtPattern matches a json web token anywhere in a string, a token set as a cookie carries its attributes after it
+var jwtPattern = regexp.MustCompile(`eyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`)
+
+// ActionExecution is the record of one run of an action
+type ActionExecution struct {
+	ActionName      string
+	OnType          string
+	SubjectId       string
+	Channel         string
+	Status          string
+	UserId          int64
+	UserReferenceId daptinid.DaptinReferenceId
+	Inputs          map[string]interface{}
+	InFields        []api2go.ColumnInfo
+	Outcomes        []OutcomeTrace
+	Error           string
+	StartedAt       time.Time
+	Duration        time.Duration
+}
+
+// WithActionChannel tells the actions run with the context which channel they were run through
+func WithActionChannel(ctx context.Context, channel string) context.Context {
+	return context.WithValue(ctx, actionChannelContextKey, channel)
+}
+
+func actionChannelFrom(ctx context.Context) string {
+	channel, ok := ctx.Value(actionChannelContextKey).(string)
+	if !ok || channel == "" {
+		return ActionChannelInternal
+	}
+	return channel
+}
+
+func newActionExecution(actionRequest ActionRequest, req api2go.Request) *ActionExecution {
+	execution := &ActionExecution{
+		ActionName: actionRequest.Action,
+		OnType:     actionRequest.Type,
+		Channel:    actionChannelFrom(req.PlainRequest.Context()),
+		Inputs:     make(map[string]interface{}, len(actionRequest.Attributes)),
+		StartedAt:  time.Now(),
+	}
+	if sessionUser, ok := req.PlainRequest.Context().Value("user").(*auth.SessionUser); ok && sessionUser != nil {
+		execution.UserId = sessionUser.UserId
+		execution.UserReferenceId = sessionUser.UserReferenceId
+	}
+	for key, value := range actionRequest.Attributes {
+		execution.Inputs[key] = value
+	}
+	return execution
+}
+
+// finish sets how the run ended
+func (execution *ActionExecution) finish(dryRun bool, err error) {
+	execution.Duration = time.Since(execution.StartedAt)
+	execution.Status = ActionExecutionSucceeded
+	if dryRun {
+		execution.Status = ActionExecutionDryRun
+	}
+	if err != nil {
+		execution.Status = ActionExecutionFailed
+		execution.Error = err.Error()
+	}
+}
+
+// isSensitiveKey is true for the keys of values which are never stored
+func isSensitiveKey(key string) bool {
+	key = strings.ToLower(key)
+	if InStringArray(sensitiveKeys, key) {
+		return true
+	}
+	for _, part := range sensitiveKeyParts {
+		if strings.Contains(key, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// sanitiseLogValue gives a copy of the value safe to store, with sensitive values masked and long values cut. The
+// value of a key and value pair, like the token a signin stores on the client, is masked when the key is sensitive,
+// and strings holding a json web token are masked whatever their key.
+func sanitiseLogValue(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case nil, bool, int, int64, float64:
+		return typed
+	case string:
+		if jwtPattern.MatchString(typed) {
+			return "***"
+		}
+		if len(typed) > maxLoggedStringLength {
+			return fmt.Sprintf("%v... (%d bytes)", typed[:maxLoggedStringLength], len(typed))
+		}
+		return typed
+	case map[string]interface{}:
+		sanitised := make(map[string]interface{}, len(typed))
+		pairKey, _ := typed["key"].(string)
+		for key, item := range typed {
+			if isSensitiveKey(key) || (key == "value" && isSensitiveKey(pairKey)) {
+				sanitised[key] = "***"
+				continue
+			}
+			sanitised[key] = sanitiseLogValue(item)
+		}
+		return sanitised
+	case []interface{}:
+		count := len(typed)
+		if count > maxLoggedListLength {
+			typed = typed[:maxLoggedListLength]
+		}
+		sanitised := make([]interface{}, 0, len(typed)+1)
+		for _, item := range typed {
+			sanitised = append(sanitised, sanitiseLogValue(item))
+		}
+		if count > maxLoggedListLength {
+			sanitised = append(sanitised, fmt.Sprintf("... %d more", count-maxLoggedListLength))
+		}
+		return sanitised
+	}
+
+	// other values are stored the way they are sent in api responses
+	asJson, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%T", value)
+	}
+	var generic interface{}
+	if err = json.Unmarshal(asJson, &generic); err != nil {
+		return fmt.Sprintf("%T", value)
+	}
+	return sanitiseLogValue(generic)
+}
+
+// sanitisedInputs are the inputs to store, the inputs of password columns are masked whatever their name
+func (execution *ActionExecution) sanitisedInputs() interface{} {
+	inputs := make(map[string]interface{}, len(execution.Inputs))
+	for key, value := range execution.Inputs {
+		inputs[key] = value
+	}
+	for _, field := range execution.InFields {
+		if _, ok := inputs[field.ColumnName]; ok && (field.ColumnType == "password" || field.ColumnType == "encrypted") {
+			inputs[field.ColumnName] = "***"
+		}
+	}
+	return sanitiseLogValue(inputs)
+}
+
+func (execution *ActionExecution) sanitisedOutcomes() []OutcomeTrace {
+	outcomes := make([]OutcomeTrace, len(execution.Outcomes))
+	for i, trace := range execution.Outcomes {
+		if trace.Attributes != nil {
+			trace.Attributes, _ = sanitiseLogValue(trace.Attributes).(map[string]interface{})
+		}
+		responses := make([]ActionResponse, len(trace.Responses))
+		for j, response := range trace.Responses {
+			responses[j] = ActionResponse{
+				ResponseType: response.ResponseType,
+				Attributes:   sanitiseLogValue(response.Attributes),
+			}
+		}
+		trace.Responses = responses
+		outcomes[i] = trace
+	}
+	return outcomes
+}
+
+// actionExecutionLog writes the records of the runs in the background
+type actionExecutionLog struct {
+	lock      sync.RWMutex
+	db        database.DatabaseConnection
+	retention time.Duration
+	enabled   bool
+	started   bool
+	entries   chan *ActionExecution
+}
+
+var actionExecutions = &actionExecutionLog{
+	entries: make(chan *ActionExecution, actionExecutionQueueSize),
+}
+
+// StartActionExecutionLog starts recording the runs of actions, a retention of 0 days keeps the records forever.
+// Later calls only change the settings.
+func StartActionExecutionLog(db database.DatabaseConnection, enabled bool, retentionDays int) {
+	actionExecutions.lock.Lock()
+	actionExecutions.db = db
+	actionExecutions.enabled = enabled
+	actionExecutions.retention = time.Duration(retentionDays) * 24 * time.Hour
+	started := actionExecutions.started
+	actionExecutions.started = true
+	actionExecutions.lock.Unlock()
+
+	if !started {
+		go actionExecutions.write()
+	}
+}
+
+// recordActionExecution queues the record of a run, it never waits for the record to be written
+func recordActionExecution(execution *ActionExecution) {
+	actionExecutions.lock.RLock()
+	enabled := actionExecutions.enabled
+	actionExecutions.lock.RUnlock()
+	if !enabled {
+		return
+	}
+
+	select {
+	case actionExecutions.entries <- execution:
+	default:
+		log.Warnf("Action execution log is full, not recording [%v][%v]", execution.OnType, execution.ActionName)
+	}
+}
+
+func (executionLog *actionExecutionLog) settings() (database.DatabaseConnection, time.Duration) {
+	executionLog.lock.RLock()
+	defer executionLog.lock.RUnlock()
+	return executionLog.db, executionLog.retention
+}
+
+func (executionLog *actionExecutionLog) write() {
+	purge := time.NewTicker(time.Hour)
+	defer purge.Stop()
+	for {
+		select {
+		case execution := <-executionLog.entries:
+			db, _ := executionLog.settings()
+			CheckErr(insertActionExecution(db, execution), "Failed to record execution of action [%v]", execution.ActionName)
+		case <-purge.C:
+			db, retention := executionLog.settings()
+			if retention > 0 {
+				CheckErr(purgeActionExecutions(db, time.Now().Add(-retention)), "Failed to remove old action executions")
+			}
+		}
+	}
+}
+
+func insertActionExecution(db database.DatabaseConnection, execution *ActionExecution) error {
+	inputs, err := json.Marshal(execution.sanitisedInputs())
+	if err != nil {
+		return err
+	}
+	outcomes, err := json.Marshal(execution.sanitisedOutcomes())
+	if err != nil {
+		return err
+	}
+
+	transaction, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+
+	u, _ := uuid.NewV7()
+	record := goqu.Record{
+		"action_name":  execution.ActionName,
+		"on_type":      execution.OnType,
+		"subject_id":   execution.SubjectId,
+		"channel":      execution.Channel,
+		"status":       execution.Status,
+		"inputs":       string(inputs),
+		"outcomes":     string(outcomes),
+		"error":        execution.Error,
+		"duration_ms":  execution.Duration.Milliseconds(),
+		"started_at":   execution.StartedAt,
+		"reference_id": u[:],
+		"permission":   auth.DEFAULT_PERMISSION,
+		"created_at":   time.Now(),
+	}
+	userId := execution.UserId
+	if userId == 0 && execution.UserReferenceId != daptinid.NullReferenceId {
+		userId, err = GetReferenceIdToIdWithTransaction(USER_ACCOUNT_TABLE_NAME, execution.UserReferenceId, transaction)
+		CheckErr(err, "Failed to find user [%v] of action execution", execution.UserReferenceId)
+	}
+	if userId != 0 {
+		record[USER_ACCOUNT_ID_COLUMN] = userId
+	}
+
+	s, v, err := statementbuilder.Squirrel.Insert(ActionExecutionTableName).Prepared(true).Rows(record).ToSQL()
+	if err == nil {
+		_, err = transaction.Exec(s, v...)
+	}
+	if err != nil {
+		transaction.Rollback()
+		return err
+	}
+	return transaction.Commit()
+}
+
+// purgeActionExecutions removes the records of the runs started before the time
+func purgeActionExecutions(db database.DatabaseConnection, before time.Time) error {
+	s, v, err := statementbuilder.Squirrel.Delete(ActionExecutionTableName).Prepared(true).
+		Where(goqu.C("created_at").Lt(before)).ToSQL()
+	if err != nil {
+		return err
+	}
+	result, err := db.Exec(s, v...)
+	if err != nil {
+		return err
+	}
+	if removed, _ := result.RowsAffected(); removed > 0 {
+		log.Infof("Removed %d action executions from before %v", removed, before.Format(time.RFC3339))
+	}
+	return nil
+}
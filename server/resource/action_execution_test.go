@@ -0,0 +1,125 @@
+package resource
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/artpar/api2go"
+	"github.com/daptin/daptin/server/auth"
+)
+
+func TestActionExecutionSanitisesInputs(t *testing.T) {
+	execution := &ActionExecution{
+		Inputs: map[string]interface{}{
+			"email":        "user@example.com",
+			"password":     "hunter2",
+			"pin":          "1234",
+			"otp":          "987654",
+			"description":  strings.Repeat("a", maxLoggedStringLength+10),
+			"items":        make([]interface{}, maxLoggedListLength+5),
+			"credentials":  map[string]interface{}{"api_key": "key", "user": "name"},
+			"access_token": "token",
+		},
+		InFields: []api2go.ColumnInfo{
+			{ColumnName: "pin", ColumnType: "password"},
+		},
+	}
+
+	inputs := execution.sanitisedInputs().(map[string]interface{})
+	for _, key := range []string{"password", "pin", "otp", "access_token"} {
+		if inputs[key] != "***" {
+			t.Errorf("expected [%v] to be masked, got %v", key, inputs[key])
+		}
+	}
+	if inputs["email"] != "user@example.com" {
+		t.Errorf("expected email to be kept, got %v", inputs["email"])
+	}
+	if credentials := inputs["credentials"].(map[string]interface{}); credentials["api_key"] != "***" || credentials["user"] != "name" {
+		t.Errorf("expected nested api key to be masked, got %v", credentials)
+	}
+	if description := inputs["description"].(string); !strings.HasSuffix(description, "bytes)") {
+		t.Errorf("expected long string to be cut, got %d bytes", len(description))
+	}
+	if items := inputs["items"].([]interface{}); len(items) != maxLoggedListLength+1 {
+		t.Errorf("expected long list to be cut, got %d items", len(items))
+	}
+	if execution.Inputs["password"] != "hunter2" {
+		t.Errorf("expected the inputs of the run not to be changed")
+	}
+}
+
+func TestActionExecutionFinish(t *testing.T) {
+	ctx := WithActionChannel(context.Background(), ActionChannelTask)
+	if channel := actionChannelFrom(ctx); channel != ActionChannelTask {
+		t.Errorf("expected channel task, got %v", channel)
+	}
+	if channel := actionChannelFrom(context.Background()); channel != ActionChannelInternal {
+		t.Errorf("expected channel internal, got %v", channel)
+	}
+
+	execution := &ActionExecution{}
+	execution.finish(true, nil)
+	if execution.Status != ActionExecutionDryRun {
+		t.Errorf("expected status dry_run, got %v", execution.Status)
+	}
+	execution.finish(false, errors.New("failed"))
+	if execution.Status != ActionExecutionFailed || execution.Error != "failed" {
+		t.Errorf("expected failed status with error, got %v %v", execution.Status, execution.Error)
+	}
+}
+
+func TestActionExecutionMasksSigninToken(t *testing.T) {
+	db, cruds := newTestCruds(t, CmsConfig{})
+	password, err := BcryptHashString("hunter22")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.MustExec("insert into user_account (name, email, password, reference_id, permission) values (?, ?, ?, ?, ?)",
+		"user", "user@example.com", password, []byte("0123456789abcdef"), auth.DEFAULT_PERMISSION)
+
+	signin := &generateJwtTokenActionPerformer{cruds: cruds, secret: []byte("secret"), tokenLifeTime: 1, jwtTokenIssuer: "test"}
+	transaction := db.MustBegin()
+	defer transaction.Rollback()
+	_, responses, errs := signin.DoAction(Outcome{Type: "jwt.token"},
+		map[string]interface{}{"email": "user@example.com", "password": "hunter22"}, transaction)
+	if len(errs) > 0 {
+		t.Fatalf("failed to sign in: %v", errs)
+	}
+
+	token := ""
+	for _, response := range responses {
+		if response.ResponseType == "client.store.set" {
+			token, _ = response.Attributes.(map[string]interface{})["value"].(string)
+		}
+	}
+	if token == "" {
+		t.Fatalf("expected the signin to store a token, got %v", responses)
+	}
+
+	execution := &ActionExecution{Outcomes: []OutcomeTrace{{
+		Step:       "signin",
+		Type:       "jwt.token",
+		Attributes: map[string]interface{}{"email": "user@example.com", "result": "Bearer " + token},
+		Responses:  responses,
+	}}}
+	stored, err := json.Marshal(execution.sanitisedOutcomes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(token, ".")
+	if strings.Contains(string(stored), parts[len(parts)-1]) {
+		t.Errorf("expected the token to be masked, got %s", stored)
+	}
+	for _, response := range execution.sanitisedOutcomes()[0].Responses {
+		attributes, _ := response.Attributes.(map[string]interface{})
+		if (response.ResponseType == "client.store.set" || response.ResponseType == "client.cookie.set") &&
+			(attributes["value"] != "***" || attributes["key"] != "token") {
+			t.Errorf("expected the value of the token pair to be masked, got %v", attributes)
+		}
+	}
+	if !strings.Contains(string(stored), "user@example.com") {
+		t.Errorf("expected the other values to be kept, got %s", stored)
+	}
+}
@@ -2,10 +2,15 @@ package resource
 
 import (
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"github.com/artpar/api2go"
+	"github.com/daptin/daptin/server/statementbuilder"
+	"github.com/doug-martin/goqu/v9"
 	"github.com/jmoiron/sqlx"
 	log "github.com/sirupsen/logrus"
+	"os"
+	"strings"
 )
 
 type exportDataPerformer struct {
@@ -23,7 +28,21 @@ func (d *exportDataPerformer) DoAction(request Outcome, inFields map[string]inte
 
 	tableName, ok := inFields["table_name"]
 
+	masker, err := NewDataMaskerFromInput(d.cmsConfig.Tables, inFields)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+
+	format, _ := inFields["format"].(string)
+	if format != "" && format != DataFormatJson {
+		tableNameStr, _ := tableName.(string)
+		return d.exportRows(tableNameStr, format, SelectedColumns(inFields["columns"]), masker, transaction)
+	}
+
 	finalName := "complete"
+	if masker != nil {
+		finalName = "anonymised_complete"
+	}
 
 	var finalString []byte
 	result := make(map[string]interface{})
@@ -38,8 +57,12 @@ func (d *exportDataPerformer) DoAction(request Outcome, inFields map[string]inte
 			log.Errorf("Failed to get all objects of type [%v] : %v", tableNameStr, err)
 		}
 
+		maskRows(masker, tableNameStr, objects)
 		result[tableNameStr] = objects
 		finalName = tableNameStr
+		if masker != nil {
+			finalName = "anonymised_" + tableNameStr
+		}
 	} else {
 
 		for _, tableInfo := range d.cmsConfig.Tables {
@@ -48,12 +71,13 @@ func (d *exportDataPerformer) DoAction(request Outcome, inFields map[string]inte
 				log.Errorf("Failed to export objects of type [%v]: %v", tableInfo.TableName, err)
 				continue
 			}
+			maskRows(masker, tableInfo.TableName, data)
 			result[tableInfo.TableName] = data
 		}
 
 	}
 
-	finalString, err := json.Marshal(result)
+	finalString, err = json.Marshal(result)
 	if err != nil {
 		log.Errorf("Failed to marshal objects as json: %v", err)
 	}
@@ -71,6 +95,165 @@ func (d *exportDataPerformer) DoAction(request Outcome, inFields map[string]inte
 	return nil, responses, nil
 }
 
+// maskRows anonymises the rows when the export has a masker
+func maskRows(masker *DataMasker, tableName string, rows []map[string]interface{}) {
+	if masker == nil {
+		return
+	}
+	for _, row := range rows {
+		masker.MaskRow(tableName, row)
+	}
+}
+
+// exportRows streams the rows of one table, or of all tables, into a ndjson or parquet file. Rows of all tables
+// carry their table name in __type, a parquet file holds the rows of one table.
+func (d *exportDataPerformer) exportRows(tableName string, format string, columnNames []string, masker *DataMasker, transaction *sqlx.Tx) (api2go.Responder, []ActionResponse, []error) {
+
+	tables := make([]TableInfo, 0)
+	finalName := "complete"
+	if tableName != "" {
+		dbResource, ok := d.cruds[tableName]
+		if !ok {
+			return nil, nil, []error{fmt.Errorf("no such entity: %v", tableName)}
+		}
+		tables = append(tables, *dbResource.TableInfo())
+		finalName = tableName
+	} else if format == DataFormatParquet {
+		return nil, nil, []error{errors.New("table_name is required for a parquet export")}
+	} else {
+		tables = append(tables, d.cmsConfig.Tables...)
+	}
+
+	output, err := os.CreateTemp("", "daptin_export_*."+format)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+	defer os.Remove(output.Name())
+	defer output.Close()
+
+	var rowWriter DataRowWriter
+	for _, table := range tables {
+		columns := make([]api2go.ColumnInfo, 0)
+		for _, col := range table.Columns {
+			if len(columnNames) > 0 && !InStringArray(columnNames, col.ColumnName) {
+				continue
+			}
+			columns = append(columns, col)
+		}
+		if len(columns) == 0 {
+			if tableName != "" {
+				return nil, nil, []error{fmt.Errorf("none of the columns [%v] are in [%v]", strings.Join(columnNames, ","), tableName)}
+			}
+			continue
+		}
+
+		if rowWriter == nil {
+			rowWriter, err = NewDataRowWriter(output, format, columns)
+			if err != nil {
+				return nil, nil, []error{err}
+			}
+		}
+
+		log.Printf("Export %v data for table: %v", format, table.TableName)
+		err = exportTableRows(table.TableName, columns, tableName == "", rowWriter, masker, transaction)
+		if err != nil {
+			log.Errorf("Failed to export objects of type [%v]: %v", table.TableName, err)
+			if tableName != "" {
+				return nil, nil, []error{err}
+			}
+		}
+	}
+	if rowWriter == nil {
+		return nil, nil, []error{errors.New("nothing to export")}
+	}
+
+	err = rowWriter.Close()
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+
+	finalString, err := os.ReadFile(output.Name())
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+
+	contentType := "application/x-ndjson"
+	if format == DataFormatParquet {
+		contentType = "application/vnd.apache.parquet"
+	}
+
+	responseAttrs := make(map[string]interface{})
+	responseAttrs["content"] = base64.StdEncoding.EncodeToString(finalString)
+	if masker != nil {
+		finalName = "anonymised_" + finalName
+	}
+	responseAttrs["name"] = fmt.Sprintf("daptin_dump_%v.%v", finalName, format)
+	responseAttrs["contentType"] = contentType
+	responseAttrs["message"] = "Downloading data"
+
+	return nil, []ActionResponse{NewActionResponse("client.file.download", responseAttrs)}, nil
+}
+
+// exportTableRows writes the columns of each row of the table, reading one row at a time, masked by the masker when
+// there is one
+func exportTableRows(tableName string, columns []api2go.ColumnInfo, withType bool, rowWriter DataRowWriter, masker *DataMasker, transaction *sqlx.Tx) error {
+
+	cols := make([]interface{}, 0, len(columns))
+	for _, col := range columns {
+		cols = append(cols, col.ColumnName)
+	}
+
+	s, q, err := statementbuilder.Squirrel.Select(cols...).Prepared(true).From(tableName).Order(goqu.C("id").Asc()).ToSQL()
+	if err != nil {
+		return err
+	}
+
+	stmt, err := transaction.Preparex(s)
+	if err != nil {
+		log.Errorf("[127] failed to prepare statment [%v]: %v", s, err)
+		return err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Queryx(q...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columnNames, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	for rows.Next() {
+		scanner := NewMapStringScan(columnNames)
+		err = scanner.Update(rows)
+		if err != nil {
+			return err
+		}
+
+		dbRow := scanner.Get()
+		row := make(map[string]interface{}, len(columns)+1)
+		for _, col := range columns {
+			row[col.ColumnName] = ExportValue(col, dbRow[col.ColumnName])
+		}
+		if masker != nil {
+			masker.MaskRow(tableName, row)
+		}
+		if withType {
+			row["__type"] = tableName
+		}
+
+		err = rowWriter.Write(row)
+		if err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
 func NewExportDataPerformer(initConfig *CmsConfig, cruds map[string]*DbResource) (ActionPerformerInterface, error) {
 
 	handler := exportDataPerformer{
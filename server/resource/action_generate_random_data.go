@@ -2,7 +2,7 @@ package resource
 
 import (
 	"context"
-	"errors"
+	"fmt"
 	"github.com/artpar/api2go"
 	"github.com/daptin/daptin/server/auth"
 	daptinid "github.com/daptin/daptin/server/id"
@@ -10,7 +10,10 @@ import (
 	"github.com/jmoiron/sqlx"
 	log "github.com/sirupsen/logrus"
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type randomDataGeneratePerformer struct {
@@ -23,23 +26,21 @@ func (actionPerformer *randomDataGeneratePerformer) Name() string {
 	return "generate.random.data"
 }
 
+// DoAction generates rows for the table along with the rows they refer to. The same seed generates the same rows
+// on the same schema, without a seed every run is different.
 func (actionPerformer *randomDataGeneratePerformer) DoAction(request Outcome, inFields map[string]interface{}, transaction *sqlx.Tx) (api2go.Responder, []ActionResponse, []error) {
 
 	responses := make([]ActionResponse, 0)
 
 	log.Printf("Generate random data for table %s", inFields["table_name"])
-	//subjectInstance := inFields["subject"].(map[string]interface{})
-	var userReferenceId daptinid.DaptinReferenceId //userIdInt := uint64(1)
+	var userReferenceId daptinid.DaptinReferenceId
 	var err error
-	log.Printf("%v", inFields)
 
 	if inFields["user_reference_id"] != nil {
 		userReferenceId = daptinid.DaptinReferenceId(uuid.MustParse(inFields["user_reference_id"].(string)))
 	}
 
-	userIdInt, err := strconv.ParseInt(inFields[USER_ACCOUNT_ID_COLUMN].(string), 10, 32)
-
-	//userIdInt, err = actionPerformer.Cruds["user"].GetReferenceIdToId("user", userReferenceId)
+	userIdInt, err := strconv.ParseInt(fmt.Sprintf("%v", inFields[USER_ACCOUNT_ID_COLUMN]), 10, 64)
 	if err != nil {
 		log.Errorf("Failed to get user id from user reference id: %v", err)
 	}
@@ -48,32 +49,18 @@ func (actionPerformer *randomDataGeneratePerformer) DoAction(request Outcome, in
 	tableResource := actionPerformer.cruds[tableName]
 	if tableResource == nil {
 		log.Errorf("Table [%v] is not created yet", tableName)
-		return nil, nil, []error{errors.New("table not found")}
+		return nil, nil, []error{fmt.Errorf("table not found")}
 	}
 
 	count := int(inFields["count"].(float64))
 
-	rows := make([]map[string]interface{}, 0)
-	for i := 0; i < count; i++ {
-		columns := actionPerformer.tableMap[tableName]
-		row := GetFakeRow(columns)
-		for _, column := range columns {
-			if column.IsForeignKey {
-				if column.ForeignKeyData.DataSource == "self" {
-					foreignRow, err := actionPerformer.cruds[column.ForeignKeyData.Namespace].GetRandomRow(column.ForeignKeyData.Namespace, 1, transaction)
-					if len(foreignRow) < 1 || err != nil {
-						log.Printf("no rows to select from for type %v", column.ForeignKeyData.Namespace)
-						continue
-					}
-					row[column.ColumnName] = foreignRow[0]["reference_id"].(daptinid.DaptinReferenceId).String()
-				}
-			}
-		}
-
-		u, _ := uuid.NewV7()
-		row["reference_id"] = u.String()
-		row["permission"] = auth.DEFAULT_PERMISSION
-		rows = append(rows, row)
+	seed := time.Now().UnixMilli()
+	if seedValue, ok := inFields["seed"].(float64); ok {
+		seed = int64(seedValue)
+	}
+	relatedCount := 0
+	if relatedCountValue, ok := inFields["related_count"].(float64); ok {
+		relatedCount = int(relatedCountValue)
 	}
 
 	httpRequest := &http.Request{
@@ -91,18 +78,37 @@ func (actionPerformer *randomDataGeneratePerformer) DoAction(request Outcome, in
 		PlainRequest: httpRequest,
 	}
 
-	for _, row := range rows {
+	generator := NewRandomDataGenerator(seed, relatedCount, actionPerformer.cmsConfig.Tables, actionPerformer.cmsConfig.Relations,
+		actionPerformer.cruds, req, transaction)
+	defer generator.Close()
 
-		_, err := tableResource.CreateWithTransaction(api2go.NewApi2GoModelWithData(tableName, nil, 0, nil, row), req, transaction)
-		if err != nil {
-			log.Errorf("Was about to insert this fake object: %v", row)
-			log.Errorf("Failed to fake insert into table [%v] : %v", tableName, err)
-		}
+	err = generator.Generate(tableName, count)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+
+	generated := generator.Generated()
+	tableNames := make([]string, 0, len(generated))
+	for name := range generated {
+		tableNames = append(tableNames, name)
+	}
+	sort.Strings(tableNames)
+	summary := make([]string, 0, len(tableNames))
+	for _, name := range tableNames {
+		summary = append(summary, fmt.Sprintf("%v: %d", name, generated[name]))
+	}
+
+	if len(generator.Failures) > 0 {
+		responses = append(responses, NewActionResponse("client.notify",
+			NewClientNotification("warning", strings.Join(generator.Failures, "\n"), fmt.Sprintf("%d rows were not generated", len(generator.Failures)))))
 	}
+
 	responder := api2go.Response{
 		Res: api2go.NewApi2GoModelWithData(
 			"", nil, 0, nil, map[string]interface{}{
-				"message": "Random data generated",
+				"message":   "Random data generated",
+				"seed":      seed,
+				"generated": strings.Join(summary, ", "),
 			}),
 		Code: 201,
 	}
@@ -119,14 +125,7 @@ func GetFakeRow(columns []api2go.ColumnInfo) map[string]interface{} {
 			continue
 		}
 
-		isStandardColumn := false
-		for _, c := range StandardColumns {
-			if col.ColumnName == c.ColumnName {
-				isStandardColumn = true
-			}
-		}
-
-		if isStandardColumn {
+		if isStandardColumn(col.ColumnName) {
 			continue
 		}
 
@@ -0,0 +1,67 @@
+package resource
+
+import (
+	"fmt"
+
+	"github.com/artpar/api2go"
+	"github.com/jmoiron/sqlx"
+)
+
+// GitOpsConfigLoader reads the schema files which are managed by gitops, returning the files it read
+type GitOpsConfigLoader func() (CmsConfig, []string, []error)
+
+type gitOpsApplyActionPerformer struct {
+	cruds  map[string]*DbResource
+	loader GitOpsConfigLoader
+}
+
+func (d *gitOpsApplyActionPerformer) Name() string {
+	return "__gitops_apply"
+}
+
+// DoAction validates the schema files and reloads the server, which applies them. Nothing happens when the
+// database is already in sync with the files.
+func (d *gitOpsApplyActionPerformer) DoAction(request Outcome, inFields map[string]interface{}, transaction *sqlx.Tx) (api2go.Responder, []ActionResponse, []error) {
+
+	gitOpsConfig, files, errs := d.loader()
+	if len(errs) > 0 {
+		return nil, []ActionResponse{NewActionResponse("client.notify",
+			NewClientNotification("error", fmt.Sprintf("Schema files are invalid: %v", errs[0]), "Failed"))}, errs
+	}
+	if len(files) == 0 {
+		return nil, []ActionResponse{NewActionResponse("client.notify",
+			NewClientNotification("error", "No schema files found, set DAPTIN_GITOPS_FOLDER", "Failed"))}, nil
+	}
+
+	report, err := ComputeSchemaDrift(gitOpsConfig, transaction)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+	report.Files = files
+
+	if report.InSync {
+		return NewResponse(nil, report, 200, nil), []ActionResponse{NewActionResponse("client.notify",
+			NewClientNotification("success", "Database is in sync with schema files", "Success"))}, nil
+	}
+
+	RequestGitOpsApply()
+	SetSchemaMigrationSource("gitops")
+	SetSchemaMigrationAuthor(SchemaMigrationAuthorFromOutcome(request))
+
+	return NewResponse(nil, report, 200, nil), []ActionResponse{
+		NewActionResponse("client.notify", NewClientNotification("success",
+			fmt.Sprintf("Applying %d changes from %d schema files", len(report.Drift), len(files)), "Success")),
+		NewActionResponse("restart", nil),
+	}, nil
+}
+
+func NewGitOpsApplyPerformer(initConfig *CmsConfig, cruds map[string]*DbResource, loader GitOpsConfigLoader) (ActionPerformerInterface, error) {
+
+	handler := gitOpsApplyActionPerformer{
+		cruds:  cruds,
+		loader: loader,
+	}
+
+	return &handler, nil
+
+}
@@ -2,11 +2,14 @@ package resource
 
 import (
 	"encoding/base64"
+	"fmt"
 	"github.com/artpar/api2go"
 	daptinid "github.com/daptin/daptin/server/id"
+	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 	log "github.com/sirupsen/logrus"
 	"strings"
+	"time"
 )
 
 type importDataPerformer struct {
@@ -42,6 +45,9 @@ func (d *importDataPerformer) DoAction(request Outcome, inFields map[string]inte
 	//execute_middleware_chain := inFields["execute_middleware_chain"].(bool)
 
 	imports := make(map[string][]interface{})
+	truncated := make(map[string]bool)
+	columnNames := SelectedColumns(inFields["columns"])
+	failures := make([]string, 0)
 
 	for _, fileInterface := range files {
 		file := fileInterface.(map[string]interface{})
@@ -57,6 +63,47 @@ func (d *importDataPerformer) DoAction(request Outcome, inFields map[string]inte
 
 		log.Printf("Processing file: %v", fileName)
 
+		if format := DataFormatFromFileName(fileName); format != DataFormatJson {
+			subjectTableName, _ := tableName.(string)
+			rowNumber := 0
+			err = ReadDataFileRows(fileBytes, format, func(row map[string]interface{}) error {
+				rowNumber += 1
+				rowTableName := subjectTableName
+				if typeName, ok := row["__type"].(string); ok && !isSubjected {
+					rowTableName = typeName
+				}
+				dbResource, ok := d.cruds[rowTableName]
+				if !ok {
+					failures = append(failures, fmt.Sprintf("%v row %d: no such entity [%v]", fileName, rowNumber, rowTableName))
+					return nil
+				}
+
+				if truncate_before_insert && !truncated[rowTableName] {
+					truncated[rowTableName] = true
+					err := dbResource.TruncateTable(rowTableName, false, transaction)
+					if err != nil {
+						log.Errorf("Failed to truncate table before importing data: %v", err)
+					}
+				}
+
+				data, err := ImportRowValues(selectColumns(row, columnNames), dbResource.TableInfo())
+				if err == nil {
+					if isUserPresent {
+						data[USER_ACCOUNT_ID_COLUMN] = userIdInt
+					}
+					err = directInsertRow(dbResource, rowTableName, data, transaction)
+				}
+				if err != nil {
+					failures = append(failures, fmt.Sprintf("%v row %d: %v", fileName, rowNumber, err))
+				}
+				return nil
+			})
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("%v: %v", fileName, err))
+			}
+			continue
+		}
+
 		var jsonData map[string]interface{}
 
 		err = json.Unmarshal(fileBytes, &jsonData)
@@ -94,7 +141,7 @@ func (d *importDataPerformer) DoAction(request Outcome, inFields map[string]inte
 
 	for tableName, importedDatas := range imports {
 
-		if truncate_before_insert {
+		if truncate_before_insert && !truncated[tableName] {
 
 			instance, ok := d.cruds[tableName]
 
@@ -123,6 +170,7 @@ func (d *importDataPerformer) DoAction(request Outcome, inFields map[string]inte
 				if isUserPresent {
 					data[USER_ACCOUNT_TABLE_NAME] = userIdInt
 				}
+				data = selectColumns(data, columnNames)
 
 				err := d.cruds[tableName].DirectInsert(tableName, data, transaction)
 				if err != nil {
@@ -132,9 +180,39 @@ func (d *importDataPerformer) DoAction(request Outcome, inFields map[string]inte
 			}
 		}
 	}
+
+	if len(failures) > 0 {
+		responses = append(responses, NewActionResponse("client.notify",
+			NewClientNotification("warning", strings.Join(failures, "\n"), fmt.Sprintf("%d rows were not imported", len(failures)))))
+	}
 	return nil, responses, nil
 }
 
+// directInsertRow inserts the row inside a savepoint, so the transaction can go on when the row is rejected. Rows
+// exported without their reference id get a new one.
+func directInsertRow(dbResource *DbResource, tableName string, data map[string]interface{}, transaction *sqlx.Tx) error {
+	if data["reference_id"] == nil {
+		u, _ := uuid.NewV7()
+		data["reference_id"] = u[:]
+	}
+	if data["created_at"] == nil {
+		data["created_at"] = time.Now()
+	}
+
+	_, err := transaction.Exec("SAVEPOINT import_row")
+	if err != nil {
+		return err
+	}
+	err = dbResource.DirectInsert(tableName, data, transaction)
+	if err != nil {
+		_, rollbackErr := transaction.Exec("ROLLBACK TO SAVEPOINT import_row")
+		CheckErr(rollbackErr, "Failed to roll back rejected row")
+		return err
+	}
+	_, err = transaction.Exec("RELEASE SAVEPOINT import_row")
+	return err
+}
+
 func NewImportDataPerformer(initConfig *CmsConfig, cruds map[string]*DbResource) (ActionPerformerInterface, error) {
 
 	handler := importDataPerformer{
@@ -0,0 +1,61 @@
+package resource
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/artpar/api2go"
+	daptinid "github.com/daptin/daptin/server/id"
+	"github.com/jmoiron/sqlx"
+)
+
+type importJobErrorReportPerformer struct {
+	cruds map[string]*DbResource
+}
+
+func (d *importJobErrorReportPerformer) Name() string {
+	return "__import_job_error_report"
+}
+
+func (d *importJobErrorReportPerformer) DoAction(request Outcome, inFields map[string]interface{}, transaction *sqlx.Tx) (api2go.Responder, []ActionResponse, []error) {
+
+	referenceId, ok := referenceIdBytes(inFields["import_job_id"])
+	if !ok {
+		return nil, nil, []error{errors.New("import job is required")}
+	}
+	var jobReferenceId daptinid.DaptinReferenceId
+	copy(jobReferenceId[:], referenceId)
+
+	job, found, err := GetImportJob(jobReferenceId, transaction)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+	if !found {
+		return nil, nil, []error{fmt.Errorf("no such import job: %v", inFields["import_job_id"])}
+	}
+	if job.ErrorReport == "" {
+		return nil, []ActionResponse{NewActionResponse("client.notify", NewClientNotification("success",
+			"No rows failed to import", "No errors"))}, nil
+	}
+
+	responseAttrs := make(map[string]interface{})
+	responseAttrs["content"] = base64.StdEncoding.EncodeToString([]byte(job.ErrorReport))
+	responseAttrs["name"] = fmt.Sprintf("import_errors_%v_%v.csv", job.EntityName,
+		strings.TrimSuffix(job.FileName, "."+job.FileType))
+	responseAttrs["contentType"] = "text/csv"
+	responseAttrs["message"] = fmt.Sprintf("Downloading %d failed rows", job.FailedRows)
+
+	return nil, []ActionResponse{NewActionResponse("client.file.download", responseAttrs)}, nil
+}
+
+func NewImportJobErrorReportPerformer(initConfig *CmsConfig, cruds map[string]*DbResource) (ActionPerformerInterface, error) {
+
+	handler := importJobErrorReportPerformer{
+		cruds: cruds,
+	}
+
+	return &handler, nil
+
+}
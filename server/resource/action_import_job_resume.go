@@ -0,0 +1,69 @@
+package resource
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/artpar/api2go"
+	daptinid "github.com/daptin/daptin/server/id"
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+)
+
+type importJobResumePerformer struct {
+	cruds map[string]*DbResource
+}
+
+func (d *importJobResumePerformer) Name() string {
+	return "__import_job_resume"
+}
+
+func (d *importJobResumePerformer) DoAction(request Outcome, inFields map[string]interface{}, transaction *sqlx.Tx) (api2go.Responder, []ActionResponse, []error) {
+
+	referenceId, ok := referenceIdBytes(inFields["import_job_id"])
+	if !ok {
+		return nil, nil, []error{errors.New("import job to resume is required")}
+	}
+	var jobReferenceId daptinid.DaptinReferenceId
+	copy(jobReferenceId[:], referenceId)
+
+	job, found, err := GetImportJob(jobReferenceId, transaction)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+	if !found {
+		return nil, nil, []error{fmt.Errorf("no such import job: %v", inFields["import_job_id"])}
+	}
+	if job.Status != ImportJobFailed {
+		return nil, []ActionResponse{NewActionResponse("client.notify", NewClientNotification("error",
+			fmt.Sprintf("Only a failed import can be resumed, this import is %v", job.Status), "Failed"))}, nil
+	}
+
+	if inFields["batch_size"] != nil {
+		job.BatchSize = ImportBatchSize(inFields["batch_size"], nil, transaction)
+	}
+	job.Status = ImportJobPending
+	job.LastError = ""
+	err = saveImportJob(job, transaction)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+
+	if !StartImportJob(jobReferenceId, d.cruds) {
+		return nil, nil, []error{errors.New("import job is already running")}
+	}
+	log.Infof("Resuming import job [%v] into [%v] after row %d", job.FileName, job.EntityName, job.ProcessedRows)
+
+	return nil, []ActionResponse{NewActionResponse("client.notify", NewClientNotification("success",
+		fmt.Sprintf("Resuming import of %v after row %d", job.FileName, job.ProcessedRows), "Success"))}, nil
+}
+
+func NewImportJobResumePerformer(initConfig *CmsConfig, cruds map[string]*DbResource) (ActionPerformerInterface, error) {
+
+	handler := importJobResumePerformer{
+		cruds: cruds,
+	}
+
+	return &handler, nil
+
+}
@@ -0,0 +1,543 @@
+package resource
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/artpar/api2go"
+	"github.com/buraksezer/olric"
+	"github.com/daptin/daptin/server/auth"
+	"github.com/daptin/daptin/server/database"
+	daptinid "github.com/daptin/daptin/server/id"
+	"github.com/daptin/daptin/server/statementbuilder"
+	"github.com/doug-martin/goqu/v9"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+)
+
+// An action runs in the background when it is marked Async, or when the request has a "Prefer: respond-async"
+// header. The request is stored as a pending action job and answered with the job right away. A pool of workers
+// runs the pending jobs in the order they were created, each in a transaction of its own, and stores the responses
+// of the outcomes on the job. The job is read at /action/job/:id and every change of its status is published on
+// the action_job topic.
+
+const ActionJobTableName = "action_job"
+
+const (
+	ActionJobPending   = "pending"
+	ActionJobRunning   = "running"
+	ActionJobCompleted = "completed"
+	ActionJobFailed    = "failed"
+)
+
+const DefaultActionJobWorkers = 4
+
+// actionJobPollInterval is how often idle workers look for jobs queued by other cluster members
+const actionJobPollInterval = 5 * time.Second
+
+type ActionJob struct {
+	Id              int64
+	ReferenceId     daptinid.DaptinReferenceId
+	ActionName      string
+	OnType          string
+	Status          string
+	Request         ActionRequest
+	Responses       []ActionResponse
+	LastError       string
+	UserId          int64
+	UserReferenceId daptinid.DaptinReferenceId
+}
+
+// EventData is the job as published on the action_job topic and returned by the job status api
+func (job ActionJob) EventData() map[string]interface{} {
+	responses := job.Responses
+	if responses == nil {
+		responses = []ActionResponse{}
+	}
+	return map[string]interface{}{
+		"__type":       ActionJobTableName,
+		"reference_id": uuid.UUID(job.ReferenceId).String(),
+		"action_name":  job.ActionName,
+		"on_type":      job.OnType,
+		"status":       job.Status,
+		"responses":    responses,
+		"last_error":   job.LastError,
+		"status_url":   "/action/job/" + uuid.UUID(job.ReferenceId).String(),
+	}
+}
+
+// IsAsyncActionRequest is true when the action always runs in the background, or the client asked for it with a
+// "Prefer: respond-async" header
+func IsAsyncActionRequest(action Action, httpRequest *http.Request) bool {
+	if action.Async {
+		return true
+	}
+	for _, prefer := range httpRequest.Header.Values("Prefer") {
+		for _, preference := range strings.Split(prefer, ",") {
+			if strings.ToLower(strings.TrimSpace(preference)) == "respond-async" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CreateActionJob stores a pending job to run the action as the user, the workers pick it up once the transaction
+// is committed
+func CreateActionJob(actionRequest ActionRequest, sessionUser *auth.SessionUser, transaction *sqlx.Tx) (ActionJob, error) {
+	u, _ := uuid.NewV7()
+	job := ActionJob{
+		ReferenceId: daptinid.DaptinReferenceId(u),
+		ActionName:  actionRequest.Action,
+		OnType:      actionRequest.Type,
+		Status:      ActionJobPending,
+		Request:     actionRequest,
+	}
+
+	requestJson, err := json.Marshal(actionRequest)
+	if err != nil {
+		return job, fmt.Errorf("failed to store action request: %v", err)
+	}
+
+	record := goqu.Record{
+		"action_name":  job.ActionName,
+		"on_type":      job.OnType,
+		"status":       job.Status,
+		"request":      string(requestJson),
+		"reference_id": u[:],
+		"permission":   auth.DEFAULT_PERMISSION,
+		"created_at":   time.Now(),
+	}
+	if sessionUser != nil && sessionUser.UserReferenceId != daptinid.NullReferenceId {
+		// the job runs as the user who asked for it
+		userId := sessionUser.UserId
+		if userId == 0 {
+			userId, err = GetReferenceIdToIdWithTransaction(USER_ACCOUNT_TABLE_NAME, sessionUser.UserReferenceId, transaction)
+			if err != nil {
+				return job, fmt.Errorf("unknown user: %v", err)
+			}
+		}
+		record[USER_ACCOUNT_ID_COLUMN] = userId
+		job.UserId = userId
+		job.UserReferenceId = sessionUser.UserReferenceId
+	}
+
+	s, v, err := statementbuilder.Squirrel.Insert(ActionJobTableName).Prepared(true).Rows(record).ToSQL()
+	if err != nil {
+		return job, err
+	}
+	_, err = transaction.Exec(s, v...)
+	if err != nil {
+		return job, err
+	}
+	log.Infof("Created action job [%v] for [%v][%v]", u.String(), job.OnType, job.ActionName)
+	return job, nil
+}
+
+var actionJobColumns = []interface{}{"id", "reference_id", "action_name", "on_type", "status", "request",
+	"responses", "last_error", USER_ACCOUNT_ID_COLUMN}
+
+func scanActionJob(row *sqlx.Row, transaction *sqlx.Tx) (ActionJob, error) {
+	var job ActionJob
+	var referenceIdBytes []byte
+	var request, responses, lastError sql.NullString
+	var userId sql.NullInt64
+	err := row.Scan(&job.Id, &referenceIdBytes, &job.ActionName, &job.OnType, &job.Status, &request, &responses,
+		&lastError, &userId)
+	if err != nil {
+		return job, err
+	}
+
+	copy(job.ReferenceId[:], referenceIdBytes)
+	job.LastError = lastError.String
+	if request.String != "" {
+		err = json.Unmarshal([]byte(request.String), &job.Request)
+		if err != nil {
+			return job, fmt.Errorf("failed to read action request: %v", err)
+		}
+	}
+	if responses.String != "" {
+		err = json.Unmarshal([]byte(responses.String), &job.Responses)
+		if err != nil {
+			return job, fmt.Errorf("failed to read action responses: %v", err)
+		}
+	}
+	if userId.Valid {
+		job.UserId = userId.Int64
+		job.UserReferenceId, err = GetIdToReferenceIdWithTransaction(USER_ACCOUNT_TABLE_NAME, userId.Int64, transaction)
+		if err != nil {
+			log.Warnf("[151] action job [%v] user [%v] not found: %v", job.ActionName, userId.Int64, err)
+		}
+	}
+	return job, nil
+}
+
+// GetActionJob reads the job, the second return value is false when there is no such job
+func GetActionJob(referenceId daptinid.DaptinReferenceId, transaction *sqlx.Tx) (ActionJob, bool, error) {
+	s, v, err := statementbuilder.Squirrel.Select(actionJobColumns...).Prepared(true).
+		From(ActionJobTableName).Where(goqu.Ex{"reference_id": referenceId[:]}).ToSQL()
+	if err != nil {
+		return ActionJob{}, false, err
+	}
+	stmt1, err := transaction.Preparex(s)
+	if err != nil {
+		log.Errorf("[163] failed to prepare statment: %v", err)
+		return ActionJob{}, false, err
+	}
+	defer stmt1.Close()
+
+	job, err := scanActionJob(stmt1.QueryRowx(v...), transaction)
+	if err == sql.ErrNoRows {
+		return job, false, nil
+	}
+	return job, err == nil, err
+}
+
+// saveActionJob stores the status and responses of the job. The request is removed once the job is finished, it
+// may hold secrets like passwords.
+func saveActionJob(job ActionJob, transaction *sqlx.Tx) error {
+	record := goqu.Record{
+		"status":     job.Status,
+		"last_error": job.LastError,
+		"updated_at": time.Now(),
+	}
+	if job.Responses != nil {
+		responsesJson, err := json.Marshal(job.Responses)
+		if err != nil {
+			responsesJson, _ = json.Marshal([]ActionResponse{NewActionResponse("client.notify",
+				NewClientNotification("error", fmt.Sprintf("failed to store responses: %v", err), "failed"))})
+		}
+		record["responses"] = string(responsesJson)
+	}
+	if job.Status == ActionJobCompleted || job.Status == ActionJobFailed {
+		record["finished_at"] = time.Now()
+		record["request"] = nil
+	}
+
+	s, v, err := statementbuilder.Squirrel.Update(ActionJobTableName).Prepared(true).
+		Set(record).Where(goqu.Ex{"id": job.Id}).ToSQL()
+	if err != nil {
+		return err
+	}
+	_, err = transaction.Exec(s, v...)
+	return err
+}
+
+// actionJobWorkers runs the pending action jobs. The workers are started once and keep running when the server is
+// reloaded in place, each reload hands them the new resources.
+type actionJobWorkers struct {
+	lock    sync.RWMutex
+	cruds   map[string]*DbResource
+	pubsub  *olric.PubSub
+	wake    chan struct{}
+	started bool
+}
+
+var actionJobs = &actionJobWorkers{
+	wake: make(chan struct{}, 1),
+}
+
+// StartActionJobWorkers starts the workers, later calls only replace the resources the jobs run with
+func StartActionJobWorkers(cruds map[string]*DbResource, workers int) {
+	var pubsub *olric.PubSub
+	if cruds["world"].OlricDb != nil {
+		var err error
+		pubsub, err = cruds["world"].OlricDb.NewPubSub()
+		CheckErr(err, "Failed to create pubsub for action jobs")
+	}
+
+	actionJobs.lock.Lock()
+	actionJobs.cruds = cruds
+	actionJobs.pubsub = pubsub
+	started := actionJobs.started
+	actionJobs.started = true
+	actionJobs.lock.Unlock()
+
+	if started {
+		return
+	}
+	if workers < 1 {
+		workers = DefaultActionJobWorkers
+	}
+	log.Infof("Starting %d action job workers", workers)
+	for i := 0; i < workers; i++ {
+		go actionJobs.work()
+	}
+	NotifyActionJobWorkers()
+}
+
+// NotifyActionJobWorkers wakes up an idle worker to look for pending jobs
+func NotifyActionJobWorkers() {
+	select {
+	case actionJobs.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (workers *actionJobWorkers) resources() (map[string]*DbResource, *olric.PubSub) {
+	workers.lock.RLock()
+	defer workers.lock.RUnlock()
+	return workers.cruds, workers.pubsub
+}
+
+func (workers *actionJobWorkers) work() {
+	ticker := time.NewTicker(actionJobPollInterval)
+	defer ticker.Stop()
+	for {
+		for workers.runNext() {
+		}
+		select {
+		case <-workers.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// runNext claims the oldest pending job and runs it, false when there was no job to run
+func (workers *actionJobWorkers) runNext() bool {
+	cruds, pubsub := workers.resources()
+	db := cruds["world"].Connection
+
+	job, claimed, err := claimActionJob(db)
+	if err != nil {
+		CheckErr(err, "Failed to claim action job")
+		return false
+	}
+	if !claimed {
+		return false
+	}
+	// there may be more jobs waiting for another worker
+	NotifyActionJobWorkers()
+
+	publishActionJob(pubsub, job)
+	job = runActionJob(job, cruds)
+
+	transaction, err := db.Beginx()
+	if err != nil {
+		CheckErr(err, "Failed to begin transaction [304]")
+		return true
+	}
+	if CheckErr(saveActionJob(job, transaction), "Failed to save action job [%v]", job.ActionName) {
+		transaction.Rollback()
+	} else {
+		CheckErr(transaction.Commit(), "Failed to commit action job")
+	}
+	publishActionJob(pubsub, job)
+	return true
+}
+
+// claimActionJob marks the oldest pending job as running. The update only succeeds for one worker, so a job is run
+// once even when the workers of several cluster members look at the same job.
+func claimActionJob(db database.DatabaseConnection) (ActionJob, bool, error) {
+	transaction, err := db.Beginx()
+	if err != nil {
+		return ActionJob{}, false, err
+	}
+	defer transaction.Rollback()
+
+	s, v, err := statementbuilder.Squirrel.Select(actionJobColumns...).Prepared(true).From(ActionJobTableName).
+		Where(goqu.Ex{"status": ActionJobPending}).Order(goqu.C("id").Asc()).Limit(1).ToSQL()
+	if err != nil {
+		return ActionJob{}, false, err
+	}
+	job, err := scanActionJob(transaction.QueryRowx(s, v...), transaction)
+	if err == sql.ErrNoRows {
+		return job, false, nil
+	}
+	if err != nil {
+		return job, false, err
+	}
+
+	s, v, err = statementbuilder.Squirrel.Update(ActionJobTableName).Prepared(true).
+		Set(goqu.Record{"status": ActionJobRunning, "started_at": time.Now(), "updated_at": time.Now()}).
+		Where(goqu.Ex{"id": job.Id, "status": ActionJobPending}).ToSQL()
+	if err != nil {
+		return job, false, err
+	}
+	result, err := transaction.Exec(s, v...)
+	if err != nil {
+		return job, false, err
+	}
+	updated, err := result.RowsAffected()
+	if err != nil || updated != 1 {
+		return job, false, err
+	}
+	job.Status = ActionJobRunning
+	return job, true, transaction.Commit()
+}
+
+// runActionJob runs the outcomes of the action as the user who asked for it and returns the finished job
+func runActionJob(job ActionJob, cruds map[string]*DbResource) (finished ActionJob) {
+	finished = job
+	fail := func(err error) ActionJob {
+		log.Errorf("Action job [%v][%v] failed: %v", job.OnType, job.ActionName, err)
+		finished.Status = ActionJobFailed
+		finished.LastError = err.Error()
+		return finished
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			finished = fail(fmt.Errorf("%v", r))
+		}
+	}()
+
+	db := cruds["world"].Connection
+	crud, ok := cruds[job.OnType]
+	if !ok {
+		crud = cruds["world"]
+	}
+
+	sessionUser := &auth.SessionUser{
+		UserId:          job.UserId,
+		UserReferenceId: job.UserReferenceId,
+		Groups:          []auth.GroupPermission{},
+	}
+	if job.UserId != 0 {
+		transaction, err := db.Beginx()
+		if err != nil {
+			return fail(err)
+		}
+		sessionUser.Groups = crud.GetObjectUserGroupsByWhereWithTransaction(USER_ACCOUNT_TABLE_NAME, transaction,
+			"reference_id", job.UserReferenceId[:])
+		transaction.Rollback()
+	}
+	ctx := WithActionChannel(context.WithValue(context.Background(), "user", sessionUser), ActionChannelJob)
+	pr := (&http.Request{Method: "POST"}).WithContext(ctx)
+	req := api2go.Request{
+		PlainRequest: pr,
+	}
+
+	transaction, err := db.Beginx()
+	if err != nil {
+		return fail(err)
+	}
+	log.Infof("Running action job [%v][%v]", job.OnType, job.ActionName)
+	// the action commits the transaction, or rolls it back when it fails
+	responses, err := crud.HandleActionRequest(job.Request, req, transaction)
+	finished.Responses = responses
+	if err != nil {
+		// not every failure rolls back, an unknown action leaves the transaction open
+		transaction.Rollback()
+		return fail(err)
+	}
+	finished.Status = ActionJobCompleted
+	return finished
+}
+
+func publishActionJob(pubsub *olric.PubSub, job ActionJob) {
+	if pubsub == nil {
+		return
+	}
+	_, err := pubsub.Publish(context.Background(), ActionJobTableName, EventMessage{
+		MessageSource: "action",
+		EventType:     job.Status,
+		ObjectType:    ActionJobTableName,
+		EventData:     job.EventData(),
+	})
+	CheckErr(err, "Failed to publish action job status")
+}
+
+// FailInterruptedActionJobs marks the jobs which were running when the server stopped as failed, an action may have
+// done part of its work outside the database so it is not run again. Jobs still running in this process, which goes
+// on through a reload of the server, are left alone.
+func FailInterruptedActionJobs(transaction *sqlx.Tx) error {
+	actionJobs.lock.RLock()
+	started := actionJobs.started
+	actionJobs.lock.RUnlock()
+	if started {
+		return nil
+	}
+
+	s, v, err := statementbuilder.Squirrel.Update(ActionJobTableName).Prepared(true).
+		Set(goqu.Record{
+			"status":      ActionJobFailed,
+			"last_error":  "interrupted by restart",
+			"request":     nil,
+			"finished_at": time.Now(),
+		}).
+		Where(goqu.Ex{"status": ActionJobRunning}).ToSQL()
+	if err != nil {
+		return err
+	}
+	_, err = transaction.Exec(s, v...)
+	return err
+}
+
+// CreateActionJobStatusHandler returns the job to the user who asked for it and to administrators
+func CreateActionJobStatusHandler(cruds map[string]*DbResource) func(*gin.Context) {
+	return func(ginContext *gin.Context) {
+		referenceId, err := uuid.Parse(ginContext.Param("id"))
+		if err != nil {
+			ginContext.AbortWithStatusJSON(400, []ActionResponse{NewActionResponse("client.notify",
+				NewClientNotification("error", "invalid job id", "failed"))})
+			return
+		}
+
+		sessionUser := &auth.SessionUser{}
+		if user := ginContext.Request.Context().Value("user"); user != nil {
+			sessionUser = user.(*auth.SessionUser)
+		}
+
+		transaction, err := cruds["world"].Connection.Beginx()
+		if err != nil {
+			CheckErr(err, "Failed to begin transaction [455]")
+			ginContext.AbortWithStatus(500)
+			return
+		}
+		defer transaction.Rollback()
+
+		job, found, err := GetActionJob(daptinid.DaptinReferenceId(referenceId), transaction)
+		if err != nil {
+			CheckErr(err, "Failed to read action job")
+			ginContext.AbortWithStatus(500)
+			return
+		}
+		isOwner := sessionUser.UserReferenceId != daptinid.NullReferenceId && job.UserReferenceId == sessionUser.UserReferenceId
+		if !found || (!isOwner && !IsAdminWithTransaction(sessionUser.UserReferenceId, transaction)) {
+			ginContext.AbortWithStatusJSON(404, []ActionResponse{NewActionResponse("client.notify",
+				NewClientNotification("error", "no such job", "failed"))})
+			return
+		}
+
+		ginContext.JSON(200, job.EventData())
+	}
+}
+
+// createActionJob queues the action and answers with the job, the job is committed before the workers are told
+// about it
+func createActionJob(ginContext *gin.Context, actionRequest ActionRequest, cruds map[string]*DbResource) {
+	sessionUser := &auth.SessionUser{}
+	if user := ginContext.Request.Context().Value("user"); user != nil {
+		sessionUser = user.(*auth.SessionUser)
+	}
+
+	transaction, err := cruds["world"].Connection.Beginx()
+	if err != nil {
+		CheckErr(err, "Failed to begin transaction [530]")
+		ginContext.AbortWithStatus(500)
+		return
+	}
+	job, err := CreateActionJob(actionRequest, sessionUser, transaction)
+	if err == nil {
+		err = transaction.Commit()
+	} else {
+		transaction.Rollback()
+	}
+	if err != nil {
+		CheckErr(err, "Failed to create action job")
+		ginContext.AbortWithStatusJSON(500, []ActionResponse{NewActionResponse("client.notify",
+			NewClientNotification("error", err.Error(), "failed"))})
+		return
+	}
+	NotifyActionJobWorkers()
+
+	ginContext.Header("Location", "/action/job/"+uuid.UUID(job.ReferenceId).String())
+	ginContext.JSON(202, []ActionResponse{NewActionResponse(ActionJobTableName, job.EventData())})
+}
@@ -0,0 +1,77 @@
+package resource
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/artpar/api2go"
+	daptinid "github.com/daptin/daptin/server/id"
+	"github.com/google/uuid"
+)
+
+func TestIsAsyncActionRequest(t *testing.T) {
+	request, _ := http.NewRequest("POST", "/action/world/export_data", nil)
+	if IsAsyncActionRequest(Action{}, request) {
+		t.Errorf("expected a plain request to run synchronously")
+	}
+	if !IsAsyncActionRequest(Action{Async: true}, request) {
+		t.Errorf("expected an async action to run in the background")
+	}
+
+	request.Header.Set("Prefer", "return=minimal, Respond-Async")
+	if !IsAsyncActionRequest(Action{}, request) {
+		t.Errorf("expected the prefer header to run the action in the background")
+	}
+}
+
+func TestActionJobEventData(t *testing.T) {
+	u, _ := uuid.NewV7()
+	job := ActionJob{
+		ReferenceId: daptinid.DaptinReferenceId(u),
+		ActionName:  "export_data",
+		OnType:      "world",
+		Status:      ActionJobPending,
+	}
+	data := job.EventData()
+	if data["status_url"] != "/action/job/"+u.String() || data["reference_id"] != u.String() {
+		t.Errorf("unexpected job %v", data)
+	}
+	if responses, ok := data["responses"].([]ActionResponse); !ok || responses == nil {
+		t.Errorf("expected an empty list of responses, got %v", data["responses"])
+	}
+}
+
+func TestRunActionJobCompletes(t *testing.T) {
+	db, cruds := newTestCruds(t, CmsConfig{
+		Actions: []Action{{
+			Name:             "record_step",
+			Label:            "Record a step",
+			OnType:           "world",
+			InstanceOptional: true,
+			InFields:         []api2go.ColumnInfo{{Name: "name", ColumnName: "name", ColumnType: "label"}},
+			OutFields: []Outcome{{
+				Type:       "test_step",
+				Attributes: map[string]interface{}{"name": "~name"},
+			}},
+		}},
+	})
+	db.MustExec("create table step (name varchar(20))")
+	cruds["world"].ActionHandlerMap = map[string]ActionPerformerInterface{"test_step": testStepPerformer{}}
+
+	finished := runActionJob(ActionJob{
+		ActionName: "record_step",
+		OnType:     "world",
+		Request: ActionRequest{
+			Type:       "world",
+			Action:     "record_step",
+			Attributes: map[string]interface{}{"name": "first"},
+		},
+	}, cruds)
+	if finished.Status != ActionJobCompleted {
+		t.Fatalf("expected the job to complete, got %v: %v", finished.Status, finished.LastError)
+	}
+	var names []string
+	if err := db.Select(&names, "select name from step"); err != nil || len(names) != 1 || names[0] != "first" {
+		t.Errorf("expected the outcome of the job to be kept, got %v %v", names, err)
+	}
+}
@@ -31,6 +31,9 @@ func (d *renameWorldColumnPerformer) DoAction(request Outcome, inFields map[stri
 	if IsReservedWord(columnToNew) {
 		return nil, []ActionResponse{}, []error{errors.New(columnToNew + " is a reserved word")}
 	}
+	if err := checkGitOpsWritable("world", worldName); err != nil {
+		return nil, nil, []error{err}
+	}
 
 	req := api2go.Request{
 		PlainRequest: &http.Request{
@@ -68,10 +71,19 @@ func (d *renameWorldColumnPerformer) DoAction(request Outcome, inFields map[stri
 
 	schemaJson, err = json.Marshal(tableSchema)
 
-	_, err = transaction.Exec("alter table " + tableSchema.TableName + " rename column " + columnToRename + " to " + columnToNew)
+	// the enum constraints are named after the column, they are created again for the new name on restart
+	err = DropEnumConstraints(tableSchema.TableName, columnToRename, transaction)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+
+	renameColumnQuery := "alter table " + tableSchema.TableName + " rename column " + columnToRename + " to " + columnToNew
+	_, err = transaction.Exec(renameColumnQuery)
 	if err != nil {
 		return nil, nil, []error{err}
 	}
+	RecordSchemaChange(renameColumnQuery, "alter table "+tableSchema.TableName+" rename column "+columnToNew+" to "+columnToRename)
+	SetSchemaMigrationAuthor(SchemaMigrationAuthorFromOutcome(request))
 
 	tableData.SetAttributes(map[string]interface{}{
 		"world_schema_json": schemaJson,
@@ -38,6 +38,7 @@ func (d *restartSystemActionPerformer) DoAction(request Outcome, inFields map[st
 	actionResponse = NewActionResponse("client.redirect", restartAttrs)
 	responses = append(responses, actionResponse)
 
+	SetSchemaMigrationAuthor(SchemaMigrationAuthorFromOutcome(request))
 	go restart()
 
 	return nil, responses, nil
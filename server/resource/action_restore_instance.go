@@ -0,0 +1,71 @@
+package resource
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/artpar/api2go"
+	daptinid "github.com/daptin/daptin/server/id"
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+)
+
+type restoreInstancePerformer struct {
+	cruds map[string]*DbResource
+}
+
+func (d *restoreInstancePerformer) Name() string {
+	return "__restore_instance"
+}
+
+// DoAction downloads the archive, puts its schema in place and restarts. The rows and files of the archive replace
+// the existing ones while the instance starts.
+func (d *restoreInstancePerformer) DoAction(request Outcome, inFields map[string]interface{}, transaction *sqlx.Tx) (api2go.Responder, []ActionResponse, []error) {
+
+	referenceId, ok := referenceIdBytes(inFields["cloud_store_id"])
+	if !ok {
+		return nil, nil, []error{errors.New("cloud store to read the backup from is required")}
+	}
+	var cloudStoreReferenceId daptinid.DaptinReferenceId
+	copy(cloudStoreReferenceId[:], referenceId)
+
+	archiveName, _ := inFields["archive_name"].(string)
+	if archiveName == "" {
+		return nil, nil, []error{errors.New("archive_name is required")}
+	}
+
+	cloudStore, err := d.cruds["cloud_store"].GetCloudStoreByReferenceId(cloudStoreReferenceId, transaction)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+	if cloudStore.RootPath == "" {
+		return nil, nil, []error{fmt.Errorf("no such cloud store: %v", inFields["cloud_store_id"])}
+	}
+
+	path, _ := inFields["path"].(string)
+	if path == "" {
+		path = DefaultBackupPath
+	}
+
+	manifest, err := d.cruds["cloud_store"].PrepareRestore(cloudStore, path, archiveName, transaction)
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+	log.Infof("Restoring %v taken at %v, restarting to create the tables", archiveName, manifest.CreatedAt)
+
+	go restart()
+
+	return nil, []ActionResponse{NewActionResponse("client.notify", NewClientNotification("success",
+		fmt.Sprintf("Restoring %d tables from %v, the instance is restarting", len(manifest.Tables), archiveName),
+		"Restore started"))}, nil
+}
+
+func NewRestoreInstancePerformer(initConfig *CmsConfig, cruds map[string]*DbResource) (ActionPerformerInterface, error) {
+
+	handler := restoreInstancePerformer{
+		cruds: cruds,
+	}
+
+	return &handler, nil
+
+}
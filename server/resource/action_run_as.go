@@ -0,0 +1,83 @@
+package resource
+
+import (
+	"fmt"
+
+	"github.com/daptin/daptin/server/auth"
+	daptinid "github.com/daptin/daptin/server/id"
+)
+
+// RunAs of an action, a loop or an outcome is the user its outcomes run as, which decides what the table and row
+// permissions let them do:
+//   - "user", or nothing, runs as the user who invoked the action, a guest for actions invoked without signing in
+//   - "admin" runs as an administrator, allowed to do anything. Until the instance has an administrator the outcomes
+//     run as the invoking user, a guest signs up this way.
+//   - any other value is the email of a user account to run as, a service account with only the permissions given to
+//     it and its groups
+//
+// An outcome without RunAs runs as the loop it is in, or as the action. Compensations run as the outcome they undo.
+
+const (
+	RunAsUser  = "user"
+	RunAsAdmin = "admin"
+)
+
+// runAsOf gives the RunAs of the outcome, inherited from the run when the outcome does not set one
+func (run *outcomeRun) runAsOf(outcome Outcome) string {
+	if outcome.RunAs != "" {
+		return outcome.RunAs
+	}
+	if run.runAs != "" {
+		return run.runAs
+	}
+	return RunAsUser
+}
+
+// runAsUser gives the session user the outcomes of a RunAs run as
+func (run *outcomeRun) runAsUser(runAs string) (*auth.SessionUser, error) {
+	if runAs == RunAsUser {
+		return run.sessionUser, nil
+	}
+	if sessionUser, ok := run.runAsUsers[runAs]; ok {
+		return sessionUser, nil
+	}
+
+	sessionUser := &auth.SessionUser{}
+	if runAs == RunAsAdmin {
+		for id := range GetAdminReferenceIdWithTransaction(run.transaction) {
+			sessionUser.UserReferenceId = daptinid.DaptinReferenceId(id)
+			break
+		}
+		if sessionUser.UserReferenceId == daptinid.NullReferenceId {
+			// a new instance has no administrator until someone becomes one
+			return run.sessionUser, nil
+		}
+		// the rows the outcomes create are owned by the administrator
+		userId, err := GetReferenceIdToIdWithTransaction(USER_ACCOUNT_TABLE_NAME, sessionUser.UserReferenceId, run.transaction)
+		if err != nil {
+			return nil, fmt.Errorf("no administrator to run as: %v", err)
+		}
+		sessionUser.UserId = userId
+		sessionUser.Groups = run.db.GetObjectUserGroupsByWhereWithTransaction(USER_ACCOUNT_TABLE_NAME, run.transaction,
+			"reference_id", sessionUser.UserReferenceId[:])
+	} else {
+		account, err := run.db.GetObjectByWhereClauseWithTransaction(USER_ACCOUNT_TABLE_NAME, "email", runAs, run.transaction)
+		if err != nil || account == nil {
+			return nil, fmt.Errorf("no user account [%v] to run as", runAs)
+		}
+		referenceId, ok := account["reference_id"].(daptinid.DaptinReferenceId)
+		if !ok {
+			return nil, fmt.Errorf("no user account [%v] to run as", runAs)
+		}
+		sessionUser.UserReferenceId = referenceId
+		sessionUser.UserId, _ = account["id"].(int64)
+		sessionUser.Groups = run.db.GetObjectUserGroupsByWhereWithTransaction(USER_ACCOUNT_TABLE_NAME, run.transaction,
+			"reference_id", referenceId[:])
+	}
+
+	if run.runAsUsers == nil {
+		run.runAsUsers = make(map[string]*auth.SessionUser)
+	}
+	run.runAsUsers[runAs] = sessionUser
+	return sessionUser, nil
+}
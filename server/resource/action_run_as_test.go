@@ -0,0 +1,173 @@
+package resource
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/artpar/api2go"
+	"github.com/daptin/daptin/server/auth"
+	daptinid "github.com/daptin/daptin/server/id"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+func TestOutcomesRunAsInheritedUser(t *testing.T) {
+	db, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	db.MustExec("create table step (name varchar(20))")
+
+	invokingUser := &auth.SessionUser{UserId: 2}
+	serviceAccount := &auth.SessionUser{UserId: 3}
+	run := &outcomeRun{
+		db: &DbResource{
+			Connection:       db,
+			ActionHandlerMap: map[string]ActionPerformerInterface{"test_step": testStepPerformer{}},
+		},
+		req:         api2go.Request{PlainRequest: &http.Request{Method: "POST"}},
+		sessionUser: invokingUser,
+		transaction: db.MustBegin(),
+		runAsUsers:  map[string]*auth.SessionUser{"service@example.com": serviceAccount},
+		runAs:       RunAsUser,
+	}
+	defer run.transaction.Rollback()
+
+	_, _, err = run.runOutcomes([]Outcome{
+		testStep("first"),
+		{
+			ForEach: "$items",
+			RunAs:   "service@example.com",
+			Outcomes: []Outcome{
+				{Type: "test_step", Attributes: map[string]interface{}{"name": "$item"}},
+			},
+		},
+		testStep("last"),
+	}, map[string]interface{}{"items": []interface{}{"a"}})
+	if err != nil {
+		t.Fatalf("failed to run outcomes: %v", err)
+	}
+
+	expected := []string{RunAsUser, "service@example.com", "service@example.com", RunAsUser}
+	if len(run.trace) != len(expected) {
+		t.Fatalf("expected %d traced outcomes, got %d", len(expected), len(run.trace))
+	}
+	for i, trace := range run.trace {
+		if trace.RunAs != expected[i] {
+			t.Errorf("expected step [%v] to run as [%v], got [%v]", trace.Step, expected[i], trace.RunAs)
+		}
+	}
+
+	if user, _ := run.runAsUser(RunAsUser); user != invokingUser {
+		t.Errorf("expected the invoking user, got %v", user)
+	}
+	if user, _ := run.runAsUser("service@example.com"); user != serviceAccount {
+		t.Errorf("expected the service account, got %v", user)
+	}
+}
+
+func TestOutcomesRunAsInvokingUserByDefault(t *testing.T) {
+	db, cruds := newTestCruds(t, CmsConfig{})
+	invokingUser := &auth.SessionUser{UserId: 2}
+	run := &outcomeRun{
+		db:          cruds["world"],
+		sessionUser: invokingUser,
+		transaction: db.MustBegin(),
+	}
+	defer run.transaction.Rollback()
+
+	if runAs := run.runAsOf(Outcome{Type: "test_step"}); runAs != RunAsUser {
+		t.Errorf("expected an outcome of an action without RunAs to run as the invoking user, got [%v]", runAs)
+	}
+	if user, err := run.runAsUser(run.runAsOf(Outcome{Type: "test_step"})); err != nil || user != invokingUser {
+		t.Errorf("expected the invoking user, got %v %v", user, err)
+	}
+	// until someone becomes an administrator the outcomes of an action run as admin run as the invoking user
+	if user, err := run.runAsUser(RunAsAdmin); err != nil || user != invokingUser {
+		t.Errorf("expected the invoking user while there is no administrator, got %v %v", user, err)
+	}
+
+	for _, action := range SystemActions {
+		if action.RunAs == RunAsAdmin && !InStringArray([]string{"signup", "reset-password", "reset-password-verify",
+			"oauth.login.response", "add_exchange"}, action.Name) {
+			t.Errorf("expected the system action [%v] to run as the invoking user", action.Name)
+		}
+	}
+}
+
+func TestActionWithoutRunAsRunsAsCaller(t *testing.T) {
+	note := TableInfo{TableName: "note", DefaultPermission: auth.DEFAULT_PERMISSION, Columns: []api2go.ColumnInfo{
+		{Name: "title", ColumnName: "title", ColumnType: "label", DataType: "varchar(100)", IsNullable: true},
+	}}
+	writeNote := func(name string, runAs string) Action {
+		return Action{
+			Name:             name,
+			OnType:           "note",
+			InstanceOptional: true,
+			RunAs:            runAs,
+			OutFields: []Outcome{{
+				Type:       "note",
+				Method:     "POST",
+				Attributes: map[string]interface{}{"title": name},
+			}},
+		}
+	}
+	db, cruds := newTestCruds(t, CmsConfig{
+		Tables:  []TableInfo{note},
+		Actions: []Action{writeNote("write_note", ""), writeNote("write_note_as_admin", RunAsAdmin)},
+	})
+	cruds["note"].ms = &MiddlewareSet{}
+
+	userId := func(referenceId daptinid.DaptinReferenceId) int64 {
+		db.MustExec("insert into user_account (name, email, password, reference_id, permission) values (?, ?, ?, ?, ?)",
+			"user", referenceId.String()+"@example.com", "secret", referenceId[:], auth.DEFAULT_PERMISSION)
+		var id int64
+		if err := db.Get(&id, "select id from user_account where reference_id = ?", referenceId[:]); err != nil {
+			t.Fatalf("failed to read the user: %v", err)
+		}
+		return id
+	}
+	admin := daptinid.DaptinReferenceId(uuid.New())
+	adminId := userId(admin)
+	groupReferenceId := uuid.New()
+	db.MustExec("insert into usergroup (name, reference_id, permission) values ('administrators', ?, ?)",
+		groupReferenceId[:], auth.DEFAULT_PERMISSION)
+	joinReferenceId := uuid.New()
+	db.MustExec("insert into user_account_user_account_id_has_usergroup_usergroup_id "+
+		"(user_account_id, usergroup_id, reference_id, permission) "+
+		"values (?, (select id from usergroup where name = 'administrators'), ?, ?)",
+		adminId, joinReferenceId[:], auth.DEFAULT_PERMISSION)
+	caller := daptinid.DaptinReferenceId(uuid.New())
+	callerId := userId(caller)
+
+	owners := make(map[string]int64)
+	for _, action := range []string{"write_note", "write_note_as_admin"} {
+		request := httptest.NewRequest("POST", "/action/note/"+action, nil)
+		request = request.WithContext(context.WithValue(request.Context(), "user",
+			&auth.SessionUser{UserId: callerId, UserReferenceId: caller}))
+		transaction := db.MustBegin()
+		_, err := cruds["note"].HandleActionRequest(ActionRequest{Type: "note", Action: action,
+			Attributes: map[string]interface{}{}}, api2go.Request{PlainRequest: request}, transaction)
+		if err != nil {
+			transaction.Rollback()
+			t.Fatalf("failed to run [%v]: %v", action, err)
+		}
+		var owner int64
+		if err = db.Get(&owner, "select user_account_id from note where title = ?", action); err != nil {
+			t.Fatalf("failed to read the note of [%v]: %v", action, err)
+		}
+		owners[action] = owner
+	}
+
+	if owners["write_note"] != callerId {
+		t.Errorf("expected the note of an action without RunAs to be created by the caller, got user %v", owners["write_note"])
+	}
+	if owners["write_note_as_admin"] != adminId {
+		t.Errorf("expected the note of an action run as admin to be created by the administrator, got user %v",
+			owners["write_note_as_admin"])
+	}
+}
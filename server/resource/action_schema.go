@@ -0,0 +1,134 @@
+package resource
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/artpar/api2go"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+// An action can describe its inputs and its responses with JSON Schema, written in the OpenAPI flavour: types,
+// nested objects and arrays, required properties, formats (email, date, date-time, uuid, uri, ...), enums, lengths
+// and ranges. InputSchema is the object of the attributes the action is called with, it is checked before the action
+// runs, on top of the InFields. OutputSchema is the Attributes of the responses of the action. Both are published in
+// the OpenAPI document and the GraphQL mutation of the action.
+
+// actionRouteParams are added to the attributes by the action route, they are not checked against the schema
+var actionRouteParams = []string{"typename", "actionName"}
+
+// actionSchemas holds the parsed schemas by their json
+var actionSchemas sync.Map
+
+func init() {
+	if _, ok := openapi3.SchemaStringFormats["uuid"]; !ok {
+		openapi3.DefineStringFormat("uuid", `^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	}
+	if _, ok := openapi3.SchemaStringFormats["uri"]; !ok {
+		openapi3.DefineStringFormatCallback("uri", func(value string) error {
+			parsed, err := url.Parse(value)
+			if err != nil {
+				return err
+			}
+			if parsed.Scheme == "" {
+				return errors.New("uri has no scheme")
+			}
+			return nil
+		})
+	}
+}
+
+// ParseActionSchema reads a schema of an action
+func ParseActionSchema(schema map[string]interface{}) (*openapi3.Schema, error) {
+	schemaJson, err := json.Marshal(schema)
+	if err != nil {
+		return nil, err
+	}
+	if parsed, ok := actionSchemas.Load(string(schemaJson)); ok {
+		return parsed.(*openapi3.Schema), nil
+	}
+
+	parsed := openapi3.NewSchema()
+	if err = parsed.UnmarshalJSON(schemaJson); err != nil {
+		return nil, err
+	}
+	actionSchemas.Store(string(schemaJson), parsed)
+	return parsed, nil
+}
+
+// ValidateActionInputs checks the attributes against the InputSchema of the action, giving an error for each value
+// which does not match
+func ValidateActionInputs(action Action, attributes map[string]interface{}) ([]api2go.Error, error) {
+	if len(action.InputSchema) == 0 {
+		return nil, nil
+	}
+	schema, err := ParseActionSchema(action.InputSchema)
+	if err != nil {
+		return nil, fmt.Errorf("invalid input schema of action [%v]: %w", action.Name, err)
+	}
+
+	// the schema is checked against the attributes as json, the way the client sent them
+	inputs := make(map[string]interface{}, len(attributes))
+	for key, value := range attributes {
+		if !InStringArray(actionRouteParams, key) {
+			inputs[key] = value
+		}
+	}
+	inputsJson, err := json.Marshal(inputs)
+	if err != nil {
+		return nil, err
+	}
+	var document interface{}
+	if err = json.Unmarshal(inputsJson, &document); err != nil {
+		return nil, err
+	}
+
+	err = schema.VisitJSON(document, openapi3.MultiErrors())
+	if err == nil {
+		return nil, nil
+	}
+	var schemaErrors openapi3.MultiError
+	if !errors.As(err, &schemaErrors) {
+		schemaErrors = openapi3.MultiError{err}
+	}
+
+	validationErrors := make([]api2go.Error, 0, len(schemaErrors))
+	for _, schemaErr := range schemaErrors {
+		validationError := api2go.Error{
+			Status: "400",
+			Code:   "schema",
+			Title:  "invalid value",
+			Detail: schemaErr.Error(),
+			Source: &api2go.ErrorSource{
+				Pointer: "/attributes/",
+			},
+		}
+		var fieldErr *openapi3.SchemaError
+		if errors.As(schemaErr, &fieldErr) {
+			validationError.Code = fieldErr.SchemaField
+			validationError.Detail = fieldErr.Reason
+			validationError.Source.Pointer = "/attributes/" + strings.Join(fieldErr.JSONPointer(), "/")
+		}
+		validationErrors = append(validationErrors, validationError)
+	}
+	return validationErrors, nil
+}
+
+// inputSchemaProperties are the names of the attributes the InputSchema describes
+func inputSchemaProperties(action Action) []string {
+	if len(action.InputSchema) == 0 {
+		return nil
+	}
+	schema, err := ParseActionSchema(action.InputSchema)
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(schema.Properties))
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+	return names
+}
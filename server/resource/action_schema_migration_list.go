@@ -0,0 +1,49 @@
+package resource
+
+import (
+	"github.com/artpar/api2go"
+	"github.com/jmoiron/sqlx"
+)
+
+type schemaMigrationListActionPerformer struct {
+	cruds map[string]*DbResource
+}
+
+func (d *schemaMigrationListActionPerformer) Name() string {
+	return "__schema_migration_list"
+}
+
+func (d *schemaMigrationListActionPerformer) DoAction(request Outcome, inFields map[string]interface{}, transaction *sqlx.Tx) (api2go.Responder, []ActionResponse, []error) {
+
+	responses := make([]ActionResponse, 0)
+
+	migrations, err := GetSchemaMigrations(transaction)
+	if err != nil {
+		return nil, []ActionResponse{NewActionResponse("client.notify",
+			NewClientNotification("error", "Failed to read schema history: "+err.Error(), "Failed"))}, []error{err}
+	}
+
+	history := make([]map[string]interface{}, 0)
+	for _, migration := range migrations {
+		history = append(history, migration.ToMap())
+	}
+
+	listResponse := NewResponse(nil, api2go.NewApi2GoModelWithData(SchemaMigrationTableName, nil, 0, nil, map[string]interface{}{
+		"migrations": history,
+	}), 200, nil)
+	responses = append(responses, NewActionResponse(SchemaMigrationTableName, map[string]interface{}{
+		"list": history,
+	}))
+
+	return listResponse, responses, nil
+}
+
+func NewSchemaMigrationListPerformer(initConfig *CmsConfig, cruds map[string]*DbResource) (ActionPerformerInterface, error) {
+
+	handler := schemaMigrationListActionPerformer{
+		cruds: cruds,
+	}
+
+	return &handler, nil
+
+}
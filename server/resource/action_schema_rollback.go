@@ -0,0 +1,64 @@
+package resource
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/artpar/api2go"
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+)
+
+type schemaRollbackActionPerformer struct {
+	cruds map[string]*DbResource
+}
+
+func (d *schemaRollbackActionPerformer) Name() string {
+	return "__schema_rollback"
+}
+
+func (d *schemaRollbackActionPerformer) DoAction(request Outcome, inFields map[string]interface{}, transaction *sqlx.Tx) (api2go.Responder, []ActionResponse, []error) {
+
+	var version int64
+	switch versionValue := inFields["version"].(type) {
+	case float64:
+		version = int64(versionValue)
+	case int64:
+		version = versionValue
+	case int:
+		version = int64(versionValue)
+	case string:
+		parsedVersion, err := strconv.ParseInt(versionValue, 10, 64)
+		if err != nil {
+			return nil, nil, []error{fmt.Errorf("invalid version [%v]: %v", versionValue, err)}
+		}
+		version = parsedVersion
+	default:
+		return nil, nil, []error{errors.New("version to rollback to is required")}
+	}
+
+	executed, err := RollbackSchemaToVersion(version, SchemaMigrationAuthorFromOutcome(request), transaction)
+	if err != nil {
+		log.Errorf("Failed to rollback schema to version [%v]: %v", version, err)
+		return nil, []ActionResponse{NewActionResponse("client.notify",
+			NewClientNotification("error", err.Error(), "Rollback failed"))}, []error{err}
+	}
+
+	responses := make([]ActionResponse, 0)
+	responses = append(responses, NewActionResponse("client.notify", NewClientNotification("success",
+		fmt.Sprintf("Schema rolled back to version %v, %d statements executed. Restarting.", version, len(executed)), "Success")))
+	responses = append(responses, NewActionResponse("restart", nil))
+
+	return nil, responses, nil
+}
+
+func NewSchemaRollbackPerformer(initConfig *CmsConfig, cruds map[string]*DbResource) (ActionPerformerInterface, error) {
+
+	handler := schemaRollbackActionPerformer{
+		cruds: cruds,
+	}
+
+	return &handler, nil
+
+}
@@ -0,0 +1,110 @@
+package resource
+
+import (
+	"testing"
+
+	"github.com/artpar/api2go"
+)
+
+var testOrderSchema = map[string]interface{}{
+	"type":     "object",
+	"required": []interface{}{"customer", "items"},
+	"properties": map[string]interface{}{
+		"customer": map[string]interface{}{
+			"type":     "object",
+			"required": []interface{}{"email"},
+			"properties": map[string]interface{}{
+				"email": map[string]interface{}{"type": "string", "format": "email"},
+				"id":    map[string]interface{}{"type": "string", "format": "uuid"},
+			},
+		},
+		"items": map[string]interface{}{
+			"type":     "array",
+			"minItems": 1,
+			"items": map[string]interface{}{
+				"type":     "object",
+				"required": []interface{}{"sku"},
+				"properties": map[string]interface{}{
+					"sku":      map[string]interface{}{"type": "string"},
+					"quantity": map[string]interface{}{"type": "integer", "minimum": 1},
+				},
+			},
+		},
+	},
+}
+
+func TestValidateActionInputsReportsEachInvalidValue(t *testing.T) {
+	action := Action{Name: "place_order", InputSchema: testOrderSchema}
+
+	validationErrors, err := ValidateActionInputs(action, map[string]interface{}{
+		"typename":   "order",
+		"actionName": "place_order",
+		"customer":   map[string]interface{}{"email": "someone@example.com"},
+		"items":      []interface{}{map[string]interface{}{"sku": "A-1", "quantity": 2}},
+	})
+	if err != nil || len(validationErrors) != 0 {
+		t.Fatalf("expected the inputs to be valid, got %v %v", validationErrors, err)
+	}
+
+	validationErrors, err = ValidateActionInputs(action, map[string]interface{}{
+		"customer": map[string]interface{}{"email": "not an email", "id": "1"},
+		"items":    []interface{}{map[string]interface{}{"quantity": 0}},
+	})
+	if err != nil {
+		t.Fatalf("failed to validate inputs: %v", err)
+	}
+	pointers := make(map[string]string)
+	for _, validationError := range validationErrors {
+		pointers[validationError.Source.Pointer] = validationError.Code
+	}
+	expected := map[string]string{
+		"/attributes/customer/email":   "format",
+		"/attributes/customer/id":      "format",
+		"/attributes/items/0/sku":      "required",
+		"/attributes/items/0/quantity": "minimum",
+	}
+	for pointer, code := range expected {
+		if pointers[pointer] != code {
+			t.Errorf("expected a %v error on %v, got %v", code, pointer, pointers)
+		}
+	}
+
+	validationErrors, _ = ValidateActionInputs(action, map[string]interface{}{})
+	if len(validationErrors) != 2 {
+		t.Errorf("expected the two required properties to be reported, got %v", validationErrors)
+	}
+}
+
+func TestGetValidatedInFieldsPassesSchemaValues(t *testing.T) {
+	action := Action{
+		Name:        "place_order",
+		InFields:    []api2go.ColumnInfo{{Name: "note", ColumnName: "note", ColumnType: "label", IsNullable: true}},
+		InputSchema: testOrderSchema,
+	}
+	items := []interface{}{map[string]interface{}{"sku": "A-1"}}
+
+	inFields, err := GetValidatedInFields(ActionRequest{Attributes: map[string]interface{}{
+		"note":     "leave at the door",
+		"customer": map[string]interface{}{"email": "someone@example.com"},
+		"items":    items,
+		"extra":    true,
+	}}, action)
+	if err != nil {
+		t.Fatalf("expected the inputs to be valid, got %v", err)
+	}
+	if inFields["note"] != "leave at the door" || inFields["items"] == nil || inFields["customer"] == nil {
+		t.Errorf("expected the in fields and the schema values, got %v", inFields)
+	}
+	if _, ok := inFields["extra"]; ok {
+		t.Errorf("expected values outside the in fields and the schema to be left out")
+	}
+
+	_, err = GetValidatedInFields(ActionRequest{Attributes: map[string]interface{}{
+		"customer": map[string]interface{}{},
+		"items":    items,
+	}}, action)
+	httpErr, ok := err.(api2go.HTTPError)
+	if !ok || len(httpErr.Errors) != 1 || httpErr.Errors[0].Source.Pointer != "/attributes/customer/email" {
+		t.Errorf("expected a validation error on the customer email, got %v", err)
+	}
+}
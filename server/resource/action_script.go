@@ -0,0 +1,233 @@
+package resource
+
+import (
+	"encoding/base64"
+	"fmt"
+	"runtime/metrics"
+	"sync"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/google/uuid"
+)
+
+// Javascript in action conditions and outcome attributes runs in a fresh vm for every evaluation, with only the
+// context of the action and the allowed helpers defined. An evaluation is interrupted when it runs longer than the
+// timeout or when the live heap of the process grows by more than the allocation limit while it runs. goja does not
+// account the memory of a vm, so the limit is a safety net for runaway scripts and not an exact budget. The live heap
+// is what the last garbage collection kept, the garbage of other requests does not count towards it. goja cannot
+// interrupt a native function, a single huge allocation like "x".repeat(1e9) is only stopped after it returns.
+
+const (
+	ScriptErrorSyntax     = "syntax"
+	ScriptErrorException  = "exception"
+	ScriptErrorTimeout    = "timeout"
+	ScriptErrorAllocation = "allocation"
+)
+
+// ScriptError is the reason a script failed to give a value
+type ScriptError struct {
+	Script string
+	Kind   string
+	Err    error
+}
+
+func (e *ScriptError) Error() string {
+	switch e.Kind {
+	case ScriptErrorTimeout, ScriptErrorAllocation:
+		return fmt.Sprintf("script [%v] was stopped: %v", e.Script, e.Err)
+	case ScriptErrorSyntax:
+		return fmt.Sprintf("script [%v] has a syntax error: %v", e.Script, e.Err)
+	}
+	return fmt.Sprintf("script [%v] failed: %v", e.Script, e.Err)
+}
+
+func (e *ScriptError) Unwrap() error {
+	return e.Err
+}
+
+// LimitExceeded is true when the script was stopped by the sandbox rather than failing on its own
+func (e *ScriptError) LimitExceeded() bool {
+	return e.Kind == ScriptErrorTimeout || e.Kind == ScriptErrorAllocation
+}
+
+// ScriptLimits are the limits every action script runs with, a zero limit is no limit. Helpers lists the helpers
+// defined in the vm.
+type ScriptLimits struct {
+	Timeout         time.Duration
+	MaxAllocationMB int
+	Helpers         []string
+	CacheCompiled   bool
+}
+
+var DefaultScriptLimits = ScriptLimits{
+	Timeout:         time.Second,
+	MaxAllocationMB: 256,
+	Helpers:         []string{"btoa", "atob", "uuid"},
+	CacheCompiled:   true,
+}
+
+var scriptLimits = DefaultScriptLimits
+var scriptLimitsLock sync.RWMutex
+
+func SetScriptLimits(limits ScriptLimits) {
+	scriptLimitsLock.Lock()
+	defer scriptLimitsLock.Unlock()
+	scriptLimits = limits
+	compiledScripts.reset()
+}
+
+func GetScriptLimits() ScriptLimits {
+	scriptLimitsLock.RLock()
+	defer scriptLimitsLock.RUnlock()
+	return scriptLimits
+}
+
+// scriptHelpers are the go functions a script can call
+var scriptHelpers = map[string]interface{}{
+	"btoa": func(data []byte) string {
+		return base64.StdEncoding.EncodeToString(data)
+	},
+	"atob": func(data string) (string, error) {
+		decoded, err := base64.StdEncoding.DecodeString(data)
+		return string(decoded), err
+	},
+	"uuid": func() string {
+		u, _ := uuid.NewV7()
+		return u.String()
+	},
+}
+
+// maxCompiledScripts is the number of compiled scripts kept, the cache starts over when it is full
+const maxCompiledScripts = 2000
+
+// compiledScriptCache keeps the compiled program of each script of the actions, a script is parsed once however
+// many times the action runs
+type compiledScriptCache struct {
+	lock     sync.RWMutex
+	programs map[string]*goja.Program
+}
+
+var compiledScripts = &compiledScriptCache{
+	programs: make(map[string]*goja.Program),
+}
+
+func (cache *compiledScriptCache) get(script string) (*goja.Program, bool) {
+	cache.lock.RLock()
+	defer cache.lock.RUnlock()
+	program, ok := cache.programs[script]
+	return program, ok
+}
+
+func (cache *compiledScriptCache) put(script string, program *goja.Program) {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	if len(cache.programs) >= maxCompiledScripts {
+		cache.programs = make(map[string]*goja.Program)
+	}
+	cache.programs[script] = program
+}
+
+func (cache *compiledScriptCache) reset() {
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+	cache.programs = make(map[string]*goja.Program)
+}
+
+func compileScript(script string, useCache bool) (*goja.Program, error) {
+	if useCache {
+		if program, ok := compiledScripts.get(script); ok {
+			return program, nil
+		}
+	}
+	program, err := goja.Compile("", script, false)
+	if err != nil {
+		return nil, err
+	}
+	if useCache {
+		compiledScripts.put(script, program)
+	}
+	return program, nil
+}
+
+// liveHeapBytes is the size of the heap kept by the last garbage collection, or of the heap objects when the runtime
+// does not report it
+func liveHeapBytes() uint64 {
+	samples := []metrics.Sample{{Name: "/gc/heap/live:bytes"}, {Name: "/memory/classes/heap/objects:bytes"}}
+	metrics.Read(samples)
+	for _, sample := range samples {
+		if sample.Value.Kind() == metrics.KindUint64 {
+			return sample.Value.Uint64()
+		}
+	}
+	return 0
+}
+
+// watchScript interrupts the vm when the script runs out of time or the live heap grows too much, until done is closed
+func watchScript(vm *goja.Runtime, limits ScriptLimits, done chan struct{}) {
+	var timeout <-chan time.Time
+	if limits.Timeout > 0 {
+		timer := time.NewTimer(limits.Timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+
+	maxAllocation := uint64(limits.MaxAllocationMB) * 1024 * 1024
+	startHeap := liveHeapBytes()
+	for {
+		select {
+		case <-done:
+			return
+		case <-timeout:
+			vm.Interrupt(&ScriptError{Kind: ScriptErrorTimeout, Err: fmt.Errorf("did not finish in %v", limits.Timeout)})
+			return
+		case <-ticker.C:
+			if heap := liveHeapBytes(); maxAllocation > 0 && heap > startHeap && heap-startHeap > maxAllocation {
+				vm.Interrupt(&ScriptError{Kind: ScriptErrorAllocation,
+					Err: fmt.Errorf("grew the heap by more than %d MB", limits.MaxAllocationMB)})
+				return
+			}
+		}
+	}
+}
+
+func runUnsafeJavascript(unsafe string, contextMap map[string]interface{}) (interface{}, error) {
+
+	limits := GetScriptLimits()
+
+	program, err := compileScript(unsafe, limits.CacheCompiled)
+	if err != nil {
+		return nil, &ScriptError{Script: unsafe, Kind: ScriptErrorSyntax, Err: err}
+	}
+
+	vm := goja.New()
+	for key, val := range contextMap {
+		vm.Set(key, val)
+	}
+	for name, helper := range scriptHelpers {
+		if InStringArray(limits.Helpers, name) {
+			vm.Set(name, helper)
+		}
+	}
+
+	if limits.Timeout > 0 || limits.MaxAllocationMB > 0 {
+		done := make(chan struct{})
+		defer close(done)
+		go watchScript(vm, limits, done)
+	}
+
+	v, err := vm.RunProgram(program) // Here be dragons (risky code)
+	if err != nil {
+		if interrupted, ok := err.(*goja.InterruptedError); ok {
+			if scriptErr, ok := interrupted.Value().(*ScriptError); ok {
+				scriptErr.Script = unsafe
+				return nil, scriptErr
+			}
+		}
+		return nil, &ScriptError{Script: unsafe, Kind: ScriptErrorException, Err: err}
+	}
+
+	return v.Export(), nil
+}
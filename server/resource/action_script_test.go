@@ -0,0 +1,72 @@
+package resource
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRunUnsafeJavascriptLimits(t *testing.T) {
+	limits := DefaultScriptLimits
+	limits.Timeout = 50 * time.Millisecond
+	limits.Helpers = []string{"uuid"}
+	SetScriptLimits(limits)
+	defer SetScriptLimits(DefaultScriptLimits)
+
+	value, err := runUnsafeJavascript("subject.count + 1", map[string]interface{}{
+		"subject": map[string]interface{}{"count": 1},
+	})
+	if err != nil || value != int64(2) {
+		t.Errorf("expected 2, got %v %v", value, err)
+	}
+
+	started := time.Now()
+	_, err = runUnsafeJavascript("while (true) {}", nil)
+	var scriptErr *ScriptError
+	if !errors.As(err, &scriptErr) || scriptErr.Kind != ScriptErrorTimeout || !scriptErr.LimitExceeded() {
+		t.Errorf("expected the loop to time out, got %v", err)
+	}
+	if time.Since(started) > time.Second {
+		t.Errorf("expected the loop to be interrupted after the timeout, took %v", time.Since(started))
+	}
+
+	_, err = runUnsafeJavascript("1 +", nil)
+	if !errors.As(err, &scriptErr) || scriptErr.Kind != ScriptErrorSyntax {
+		t.Errorf("expected a syntax error, got %v", err)
+	}
+
+	_, err = runUnsafeJavascript("btoa('x')", nil)
+	if !errors.As(err, &scriptErr) || scriptErr.Kind != ScriptErrorException {
+		t.Errorf("expected btoa to be undefined, got %v", err)
+	}
+	if _, err = runUnsafeJavascript("uuid()", nil); err != nil {
+		t.Errorf("expected uuid to be allowed, got %v", err)
+	}
+
+	if _, ok := compiledScripts.get("subject.count + 1"); !ok {
+		t.Errorf("expected the compiled script to be cached")
+	}
+}
+
+func TestRunUnsafeJavascriptAllocationLimit(t *testing.T) {
+	limits := DefaultScriptLimits
+	limits.Timeout = 30 * time.Second
+	limits.MaxAllocationMB = 16
+	SetScriptLimits(limits)
+	defer SetScriptLimits(DefaultScriptLimits)
+
+	// the array grows without bound, the script is stopped long before the timeout
+	_, err := runUnsafeJavascript("var a = []; while (true) { a.push('item ' + a.length) }", nil)
+	var scriptErr *ScriptError
+	if !errors.As(err, &scriptErr) || scriptErr.Kind != ScriptErrorAllocation || !scriptErr.LimitExceeded() {
+		t.Errorf("expected the script to be stopped for growing the heap too much, got %v", err)
+	}
+
+	// garbage does not count, a script allocating in a loop without keeping anything runs until the timeout
+	limits.Timeout = 500 * time.Millisecond
+	SetScriptLimits(limits)
+	_, err = runUnsafeJavascript("while (true) { var a = []; for (var i = 0; i < 1000; i++) { a.push('item ' + i) } }", nil)
+	if !errors.As(err, &scriptErr) || scriptErr.Kind != ScriptErrorTimeout {
+		t.Errorf("expected a script dropping what it allocates to be stopped by the timeout, got %v", err)
+	}
+}
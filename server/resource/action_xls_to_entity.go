@@ -101,6 +101,11 @@ func (d *uploadXlsFileToEntityPerformer) DoAction(request Outcome, inFields map[
 	entityName := inFields["entity_name"].(string)
 	create_if_not_exists, _ := inFields["create_if_not_exists"].(bool)
 	add_missing_columns, _ := inFields["add_missing_columns"].(bool)
+	if create_if_not_exists || add_missing_columns {
+		if err := checkGitOpsWritable("world", entityName); err != nil {
+			return nil, nil, []error{err}
+		}
+	}
 
 	table := TableInfo{}
 	table.TableName = entityName
@@ -110,6 +115,7 @@ func (d *uploadXlsFileToEntityPerformer) DoAction(request Outcome, inFields map[
 	allSt := make(map[string]interface{})
 
 	sources := make([]DataFileImport, 0)
+	uploadedFiles := make([]uploadedImportFile, 0)
 
 	completed := false
 
@@ -233,6 +239,7 @@ nextFile:
 
 			table.Columns = columns
 			completed = true
+			uploadedFiles = append(uploadedFiles, uploadedImportFile{Name: file["name"].(string), Contents: fileBytes})
 			sources = append(sources, DataFileImport{FilePath: fileName, Entity: table.TableName, FileType: "xlsx"})
 
 			break nextFile
@@ -261,7 +268,14 @@ nextFile:
 		if create_if_not_exists || add_missing_columns {
 			go restart()
 		} else {
-			ImportDataFiles(sources, transaction, d.cruds)
+			batchSize := ImportBatchSize(inFields["batch_size"], d.cruds["world"].configStore, transaction)
+			user, _ := inFields["user"].(map[string]interface{})
+			responses, errs := startImportJobs(entityName, "xlsx", uploadedFiles, batchSize, user, d.cruds, transaction)
+			if len(errs) > 0 {
+				return nil, nil, errs
+			}
+			trigger.Fire("clean_up_uploaded_files")
+			return nil, responses, nil
 		}
 
 		trigger.Fire("clean_up_uploaded_files")
@@ -23,6 +23,12 @@ type Outcome struct {
 	Condition       string
 	Attributes      map[string]interface{}
 	ContinueOnError bool
+	ForEach         string    // List to loop over, the Outcomes are run once for each item
+	As              string    // Name of the item in the loop, "item" by default
+	IndexAs         string    // Name of the index of the item in the loop, "index" by default
+	Outcomes        []Outcome // Outcomes run for each item of ForEach
+	Compensate      []Outcome // Outcomes which undo this one when a later outcome fails the action
+	RunAs           string    // User the outcome runs as, "user", "admin" or the email of an account, see action_run_as.go
 }
 
 // Action is a set of `Outcome` based on set of Input values on a particular data type
@@ -33,12 +39,16 @@ type Action struct {
 	Label                   string
 	OnType                  string
 	InstanceOptional        bool
+	Async                   bool   // Run as a background job, the caller gets the job to poll
+	RunAs                   string // User the outcomes run as, the invoking user by default
 	RequestSubjectRelations []string
 	ReferenceId             string
 	InFields                []api2go.ColumnInfo
 	OutFields               []Outcome
 	Validations             []ColumnTag
 	Conformations           []ColumnTag
+	InputSchema             map[string]interface{} // JSON Schema of the attributes, see action_schema.go
+	OutputSchema            map[string]interface{} // JSON Schema of the Attributes of the responses
 }
 
 // ActionRow represents an action instance on the database
@@ -0,0 +1,271 @@
+package resource
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/artpar/api2go"
+	"github.com/google/uuid"
+)
+
+// Masking rules decide what an anonymised export writes in place of the value of a column
+//
+//	fake  a fake value for the column type, the same value is always replaced by the same fake value
+//	hash  a salted sha256 of the value, equal values stay equal
+//	null  no value, or an empty value when the column cannot be null
+//	keep  the value as it is
+//
+// The id, reference_id, foreign key and standard columns are always kept, so the relations between the rows still
+// hold in the export.
+const (
+	MaskFake = "fake"
+	MaskHash = "hash"
+	MaskNull = "null"
+	MaskKeep = "keep"
+)
+
+var maskingRules = []string{MaskFake, MaskHash, MaskNull, MaskKeep}
+
+// defaultColumnTypeMasks are the rules for the column types which hold personal data or secrets
+var defaultColumnTypeMasks = map[string]string{
+	"email":              MaskFake,
+	"name":               MaskFake,
+	"password":           MaskNull,
+	"bcrypt":             MaskNull,
+	"md5":                MaskNull,
+	"md5-bcrypt":         MaskNull,
+	"encrypted":          MaskNull,
+	"location":           MaskFake,
+	"location.latitude":  MaskFake,
+	"location.longitude": MaskFake,
+	"location.altitude":  MaskFake,
+}
+
+// defaultColumnMasks are the rules for columns which hold personal data in a column type used for anything
+var defaultColumnMasks = map[string]string{
+	USER_ACCOUNT_TABLE_NAME + ".name": MaskFake,
+}
+
+// personalColumnNames are faked in the text columns by default, matched like the names of the random data fakers
+var personalColumnNames = []string{"first_name", "firstname", "given_name", "last_name", "lastname", "surname",
+	"family_name", "full_name", "fullname", "username", "email", "email_address", "phone", "phone_number", "mobile",
+	"mobile_number", "telephone", "street", "street_address", "address", "zip", "zipcode", "zip_code", "postal_code",
+	"postcode", "ip", "ip_address"}
+
+// DataMasker replaces the values of the rows of an export according to the masking rules of their column
+type DataMasker struct {
+	salt    string
+	columns map[string]map[string]api2go.ColumnInfo
+	unique  map[string]bool
+	rules   map[string]string
+}
+
+// NewDataMasker checks the rules and returns a masker for the tables. A rule is keyed by table.column, or by a column
+// name for that column in every table, and overrides the default rule of the column.
+func NewDataMasker(tables []TableInfo, rules map[string]string, salt string) (*DataMasker, error) {
+	masker := &DataMasker{
+		salt:    salt,
+		columns: make(map[string]map[string]api2go.ColumnInfo),
+		unique:  make(map[string]bool),
+		rules:   make(map[string]string),
+	}
+
+	for _, table := range tables {
+		columns := make(map[string]api2go.ColumnInfo)
+		for _, column := range table.Columns {
+			columns[column.ColumnName] = column
+			masker.unique[table.TableName+"."+column.ColumnName] = column.IsUnique
+		}
+		for _, validation := range table.Validations {
+			if strings.Contains(validation.Tags, "unique_column") {
+				masker.unique[table.TableName+"."+validation.ColumnName] = true
+			}
+		}
+		masker.columns[table.TableName] = columns
+	}
+
+	for key, rule := range rules {
+		rule = strings.ToLower(strings.TrimSpace(rule))
+		if !InStringArray(maskingRules, rule) {
+			return nil, fmt.Errorf("unknown masking rule [%v] for [%v], expected one of %v", rule, key,
+				strings.Join(maskingRules, ", "))
+		}
+
+		matched := false
+		for tableName, columns := range masker.columns {
+			for columnName, column := range columns {
+				if key != columnName && key != tableName+"."+columnName {
+					continue
+				}
+				matched = true
+				if rule != MaskKeep && isLinkingColumn(column) {
+					return nil, fmt.Errorf("[%v.%v] links rows together and is always kept", tableName, columnName)
+				}
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("no column matches the masking rule for [%v]", key)
+		}
+		masker.rules[key] = rule
+	}
+
+	return masker, nil
+}
+
+// isLinkingColumn is true for the columns which identify a row or point to another row
+func isLinkingColumn(column api2go.ColumnInfo) bool {
+	return column.ColumnName == "id" || column.ColumnName == "reference_id" || column.IsForeignKey ||
+		isStandardColumn(column.ColumnName)
+}
+
+// Rule is the masking rule for the column of the table
+func (masker *DataMasker) Rule(tableName string, column api2go.ColumnInfo) string {
+	if isLinkingColumn(column) {
+		return MaskKeep
+	}
+	if rule, ok := masker.rules[tableName+"."+column.ColumnName]; ok {
+		return rule
+	}
+	if rule, ok := masker.rules[column.ColumnName]; ok {
+		return rule
+	}
+	if rule, ok := defaultColumnMasks[tableName+"."+column.ColumnName]; ok {
+		return rule
+	}
+	if rule, ok := defaultColumnTypeMasks[column.ColumnType]; ok {
+		return rule
+	}
+	if InStringArray(textColumnTypes, column.ColumnType) && columnNameMatches(column.ColumnName, personalColumnNames, false) {
+		return MaskFake
+	}
+	return MaskKeep
+}
+
+// MasksTable is true for the tables of the schema. An anonymised export leaves out every other table, they have no
+// columns to mask by and _config holds the secrets of the instance.
+func (masker *DataMasker) MasksTable(tableName string) bool {
+	_, ok := masker.columns[tableName]
+	return ok && tableName != settingsTableName
+}
+
+// MaskRow replaces the values of the row in place. Columns of tables which are not in the schema are kept.
+func (masker *DataMasker) MaskRow(tableName string, row map[string]interface{}) {
+	columns, ok := masker.columns[tableName]
+	if !ok {
+		return
+	}
+	for columnName, value := range row {
+		column, ok := columns[columnName]
+		if !ok || value == nil {
+			continue
+		}
+		row[columnName] = masker.MaskValue(tableName, column, value)
+	}
+}
+
+// MaskValue is the value written in place of value
+func (masker *DataMasker) MaskValue(tableName string, column api2go.ColumnInfo, value interface{}) interface{} {
+	original := fmt.Sprintf("%v", ExportValue(column, value))
+	switch masker.Rule(tableName, column) {
+	case MaskNull:
+		if column.IsNullable {
+			return nil
+		}
+		return ""
+	case MaskHash:
+		return masker.hashValue(column, original)
+	case MaskFake:
+		return masker.fakeValue(column, original, masker.unique[tableName+"."+column.ColumnName])
+	}
+	return value
+}
+
+func (masker *DataMasker) digest(columnType string, original string) []byte {
+	digest := sha256.Sum256([]byte(masker.salt + "\x00" + columnType + "\x00" + original))
+	return digest[:]
+}
+
+func (masker *DataMasker) hashValue(column api2go.ColumnInfo, original string) interface{} {
+	digest := masker.digest("", original)
+	switch dataTypeKind(column.DataType) {
+	case "int":
+		return int64(binary.BigEndian.Uint32(digest))
+	case "float":
+		return float64(binary.BigEndian.Uint32(digest))
+	}
+	hashed := hex.EncodeToString(digest)
+	if size := dataTypeSize(column.DataType); size > 0 && len(hashed) > size {
+		hashed = hashed[:size]
+	}
+	return hashed
+}
+
+// fakeValue is a fake value seeded by the original value, so a value copied into other rows or tables is replaced
+// by the same fake value everywhere. Values of unique columns get a part of the hash to keep them apart.
+func (masker *DataMasker) fakeValue(column api2go.ColumnInfo, original string, isUnique bool) interface{} {
+	digest := masker.digest(column.ColumnType, original)
+	generator := NewRandomDataGenerator(int64(binary.BigEndian.Uint64(digest)), 0, nil, nil, nil, api2go.Request{}, nil)
+	value := generator.FakeColumnValue(column, "")
+	generator.Close()
+
+	text, isText := value.(string)
+	if !isUnique || !isText {
+		return value
+	}
+	suffix := hex.EncodeToString(digest[:4])
+	if at := strings.Index(text, "@"); at > 0 {
+		text = text[:at] + "+" + suffix + text[at:]
+	} else {
+		text = text + "-" + suffix
+	}
+	if size := dataTypeSize(column.DataType); size > 0 && len(text) > size {
+		text = text[len(text)-size:]
+	}
+	return text
+}
+
+// MaskingRules reads rules written as table.column=rule pairs separated by commas, or as a map of them
+func MaskingRules(value interface{}) (map[string]string, error) {
+	rules := make(map[string]string)
+	switch v := value.(type) {
+	case nil:
+	case map[string]interface{}:
+		for key, rule := range v {
+			rules[strings.TrimSpace(key)] = fmt.Sprintf("%v", rule)
+		}
+	case string:
+		for _, pair := range strings.Split(v, ",") {
+			if strings.TrimSpace(pair) == "" {
+				continue
+			}
+			parts := strings.SplitN(pair, "=", 2)
+			if len(parts) != 2 {
+				return nil, fmt.Errorf("masking rule [%v] is not of the form column=rule", pair)
+			}
+			rules[strings.TrimSpace(parts[0])] = parts[1]
+		}
+	default:
+		return nil, fmt.Errorf("masking rules should be column=rule pairs, not %T", value)
+	}
+	return rules, nil
+}
+
+// NewDataMaskerFromInput is the masker asked for by the anonymise, masking_rules and salt inputs of an export
+// action, nil when the export is not anonymised. Without a salt every export hashes to different values.
+func NewDataMaskerFromInput(tables []TableInfo, inFields map[string]interface{}) (*DataMasker, error) {
+	if !toBool(inFields["anonymise"]) {
+		return nil, nil
+	}
+	rules, err := MaskingRules(inFields["masking_rules"])
+	if err != nil {
+		return nil, err
+	}
+	salt, _ := inFields["salt"].(string)
+	if salt == "" {
+		salt = uuid.New().String()
+	}
+	return NewDataMasker(tables, rules, salt)
+}
@@ -0,0 +1,95 @@
+package resource
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/artpar/api2go"
+)
+
+func TestDataMasker(t *testing.T) {
+	InitialiseColumnManager()
+
+	tables := []TableInfo{
+		{
+			TableName: USER_ACCOUNT_TABLE_NAME,
+			Columns: []api2go.ColumnInfo{
+				{ColumnName: "reference_id", ColumnType: "alias", DataType: "blob"},
+				{ColumnName: "name", ColumnType: "label", DataType: "varchar(80)"},
+				{ColumnName: "email", ColumnType: "email", DataType: "varchar(80)", IsUnique: true},
+				{ColumnName: "password", ColumnType: "password", DataType: "varchar(100)", IsNullable: true},
+			},
+		},
+		{
+			TableName: "customer",
+			Columns: []api2go.ColumnInfo{
+				{ColumnName: "contact_phone", ColumnType: "label", DataType: "varchar(40)"},
+				{ColumnName: "notes", ColumnType: "content", DataType: "text"},
+				{ColumnName: "email", ColumnType: "email", DataType: "varchar(80)"},
+				{ColumnName: "owner_id", ColumnType: "alias", DataType: "int(11)", IsForeignKey: true,
+					ForeignKeyData: api2go.ForeignKeyData{DataSource: "self", Namespace: USER_ACCOUNT_TABLE_NAME}},
+			},
+		},
+	}
+
+	_, err := NewDataMasker(tables, map[string]string{"customer.owner_id": MaskHash}, "salt")
+	if err == nil {
+		t.Errorf("expected a rule on a foreign key to be refused")
+	}
+	_, err = NewDataMasker(tables, map[string]string{"customer.missing": MaskHash}, "salt")
+	if err == nil {
+		t.Errorf("expected a rule on an unknown column to be refused")
+	}
+
+	rules, err := MaskingRules("customer.notes=hash, customer.email=keep")
+	if err != nil {
+		t.Fatalf("failed to read masking rules: %v", err)
+	}
+	masker, err := NewDataMasker(tables, rules, "salt")
+	if err != nil {
+		t.Fatalf("failed to create masker: %v", err)
+	}
+
+	user := map[string]interface{}{
+		"reference_id": "0190c9a2-8d7e-7a3b-9c4d-5e6f7a8b9c0d",
+		"name":         "Jane Doe",
+		"email":        "jane@example.com",
+		"password":     "$2a$11$abcdef",
+	}
+	masker.MaskRow(USER_ACCOUNT_TABLE_NAME, user)
+	if user["reference_id"] != "0190c9a2-8d7e-7a3b-9c4d-5e6f7a8b9c0d" {
+		t.Errorf("expected reference_id to be kept, got %v", user["reference_id"])
+	}
+	if user["name"] == "Jane Doe" || user["name"] == "" {
+		t.Errorf("expected name to be faked, got %v", user["name"])
+	}
+	email := user["email"].(string)
+	if email == "jane@example.com" || !strings.Contains(email, "@") {
+		t.Errorf("expected a fake email address, got %v", email)
+	}
+	if user["password"] != nil {
+		t.Errorf("expected password to be removed, got %v", user["password"])
+	}
+
+	customer := map[string]interface{}{
+		"contact_phone": "+1 555 0100",
+		"notes":         "called on monday",
+		"email":         "jane@example.com",
+		"owner_id":      int64(4),
+	}
+	masker.MaskRow("customer", customer)
+	if customer["contact_phone"] == "+1 555 0100" {
+		t.Errorf("expected phone number to be faked")
+	}
+	if notes := customer["notes"].(string); len(notes) != 64 || notes == "called on monday" {
+		t.Errorf("expected notes to be hashed, got %v", notes)
+	}
+	if customer["email"] != "jane@example.com" || customer["owner_id"] != int64(4) {
+		t.Errorf("expected email and owner to be kept, got %v %v", customer["email"], customer["owner_id"])
+	}
+
+	again, _ := NewDataMasker(tables, rules, "salt")
+	if value := again.MaskValue(USER_ACCOUNT_TABLE_NAME, tables[0].Columns[2], "jane@example.com"); value != email {
+		t.Errorf("expected the same value to be faked the same way, got %v and %v", value, email)
+	}
+}
@@ -0,0 +1,865 @@
+package resource
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/artpar/rclone/fs"
+	"github.com/artpar/rclone/fs/config"
+	"github.com/artpar/rclone/fs/operations"
+	"github.com/daptin/daptin/server/database"
+	daptinid "github.com/daptin/daptin/server/id"
+	"github.com/daptin/daptin/server/statementbuilder"
+	"github.com/doug-martin/goqu/v9"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+)
+
+// A backup is a zip archive holding a manifest, the schema of the instance, the rows of every table in the database
+// as ndjson and the files of the asset columns kept in local cloud stores
+//
+//	manifest.json
+//	schema.json
+//	data/<table>.ndjson
+//	files/<cloud store>/<folder>/...
+//
+// The rows are read in one transaction so the archive is a consistent snapshot. An archive is restored on a fresh
+// instance in two steps: the schema is written to the schema folder and the instance restarts to create the tables,
+// the rows and files are loaded on the next start, before anything else reads them.
+//
+// An anonymised archive has the rows passed through a DataMasker and leaves out the files, which cannot be masked.
+
+// BackupFormatVersion is the version of the archive layout, archives with a newer version cannot be restored
+const BackupFormatVersion = 1
+
+// DefaultBackupPath is the folder in the cloud store where the archives are kept
+const DefaultBackupPath = "daptin-backups"
+
+const backupManifestFileName = "manifest.json"
+const backupSchemaFileName = "schema.json"
+const restoredSchemaFileName = "schema_restored_daptin.json"
+const pendingRestoreFileName = "daptin_restore.zip"
+
+type BackupManifest struct {
+	FormatVersion int                `json:"format_version"`
+	CreatedAt     time.Time          `json:"created_at"`
+	DatabaseType  string             `json:"database_type"`
+	Anonymised    bool               `json:"anonymised,omitempty"`
+	Tables        []BackupTable      `json:"tables"`
+	BlobFolders   []BackupBlobFolder `json:"blob_folders"`
+}
+
+type BackupTable struct {
+	Name    string         `json:"name"`
+	Rows    int            `json:"rows"`
+	Columns []BackupColumn `json:"columns"`
+}
+
+// BackupColumn records how the values of a column were written, binary values are base64 encoded and times are
+// in RFC 3339
+type BackupColumn struct {
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Binary bool   `json:"binary,omitempty"`
+	Time   bool   `json:"time,omitempty"`
+}
+
+// BackupBlobFolder is a folder of a local cloud store holding the files of an asset column
+type BackupBlobFolder struct {
+	CloudStore string `json:"cloud_store"`
+	RootPath   string `json:"root_path"`
+	Folder     string `json:"folder"`
+	Files      int    `json:"files"`
+}
+
+func (folder BackupBlobFolder) archivePrefix() string {
+	return "files/" + folder.CloudStore + "/" + folder.Folder + "/"
+}
+
+// NewBackupArchiveName is a name which orders the archives of a store by the time they were taken
+func NewBackupArchiveName() string {
+	u, _ := uuid.NewV7()
+	return fmt.Sprintf("daptin-backup-%v-%v.zip", time.Now().UTC().Format("20060102T150405Z"), u.String()[len(u.String())-8:])
+}
+
+// ListDatabaseTables lists every table in the database, including the tables not described in the schema
+func ListDatabaseTables(transaction *sqlx.Tx) ([]string, error) {
+	query := ""
+	switch transaction.DriverName() {
+	case "mysql":
+		query = `select table_name from information_schema.tables where table_schema = database() and table_type = 'BASE TABLE' order by table_name`
+	case "postgres":
+		query = `select table_name from information_schema.tables where table_schema = current_schema() and table_type = 'BASE TABLE' order by table_name`
+	default:
+		query = `select name from sqlite_master where type = 'table' and name not like 'sqlite_%' order by name`
+	}
+
+	tables := make([]string, 0)
+	err := transaction.Select(&tables, query)
+	return tables, err
+}
+
+// ListForeignKeyReferences gives the tables referenced by the foreign keys of every table
+func ListForeignKeyReferences(transaction *sqlx.Tx) (map[string][]string, error) {
+	query := ""
+	switch transaction.DriverName() {
+	case "mysql":
+		query = `select table_name, referenced_table_name from information_schema.key_column_usage
+			where table_schema = database() and referenced_table_name is not null`
+	case "postgres":
+		query = `select tc.table_name, ccu.table_name from information_schema.table_constraints tc
+			join information_schema.constraint_column_usage ccu
+				on ccu.constraint_name = tc.constraint_name and ccu.constraint_schema = tc.constraint_schema
+			where tc.constraint_type = 'FOREIGN KEY' and tc.table_schema = current_schema()`
+	default:
+		query = `select m.name, f."table" from sqlite_master m join pragma_foreign_key_list(m.name) f
+			where m.type = 'table'`
+	}
+
+	rows, err := transaction.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	references := make(map[string][]string)
+	for rows.Next() {
+		var tableName, referencedTable string
+		err = rows.Scan(&tableName, &referencedTable)
+		if err != nil {
+			return nil, err
+		}
+		references[tableName] = append(references[tableName], referencedTable)
+	}
+	return references, rows.Err()
+}
+
+// restoreTableOrder orders the tables so every table comes after the tables its foreign keys refer to. The rows are
+// removed in the reverse order and inserted in this order. Tables in a cycle keep the order of the archive.
+func restoreTableOrder(tables []BackupTable, references map[string][]string) []BackupTable {
+	pending := make(map[string]bool)
+	for _, table := range tables {
+		pending[table.Name] = true
+	}
+
+	ordered := make([]BackupTable, 0, len(tables))
+	for len(ordered) < len(tables) {
+		added := false
+		for _, table := range tables {
+			if !pending[table.Name] {
+				continue
+			}
+			ready := true
+			for _, referencedTable := range references[table.Name] {
+				if referencedTable != table.Name && pending[referencedTable] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				ordered = append(ordered, table)
+				pending[table.Name] = false
+				added = true
+			}
+		}
+		if added {
+			continue
+		}
+		for _, table := range tables {
+			if pending[table.Name] {
+				log.Warnf("Table [%v] is in a cycle of foreign keys, its rows are restored in the order of the archive",
+					table.Name)
+				ordered = append(ordered, table)
+				pending[table.Name] = false
+			}
+		}
+	}
+	return ordered
+}
+
+func isBinaryColumnType(databaseType string) bool {
+	switch strings.ToUpper(databaseType) {
+	case "BLOB", "TINYBLOB", "MEDIUMBLOB", "LONGBLOB", "BYTEA", "BINARY", "VARBINARY":
+		return true
+	}
+	return false
+}
+
+func isTimeColumnType(databaseType string) bool {
+	switch strings.ToUpper(databaseType) {
+	case "DATETIME", "TIMESTAMP", "TIMESTAMPTZ", "DATE", "TIME", "TIMETZ":
+		return true
+	}
+	return false
+}
+
+// backupTableRows writes each row of the table as a line of json, masked by the masker when there is one
+func backupTableRows(tableName string, output io.Writer, masker *DataMasker, transaction *sqlx.Tx) (BackupTable, error) {
+	table := BackupTable{Name: tableName}
+
+	s, q, err := statementbuilder.Squirrel.Select(goqu.L("*")).Prepared(true).From(tableName).ToSQL()
+	if err != nil {
+		return table, err
+	}
+
+	stmt, err := transaction.Preparex(s)
+	if err != nil {
+		log.Errorf("[139] failed to prepare statment [%v]: %v", s, err)
+		return table, err
+	}
+	defer stmt.Close()
+
+	rows, err := stmt.Queryx(q...)
+	if err != nil {
+		return table, err
+	}
+	defer rows.Close()
+
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return table, err
+	}
+	for _, columnType := range columnTypes {
+		table.Columns = append(table.Columns, BackupColumn{
+			Name:   columnType.Name(),
+			Type:   columnType.DatabaseTypeName(),
+			Binary: isBinaryColumnType(columnType.DatabaseTypeName()),
+			Time:   isTimeColumnType(columnType.DatabaseTypeName()),
+		})
+	}
+
+	rowWriter, err := NewDataRowWriter(output, DataFormatNdjson, nil)
+	if err != nil {
+		return table, err
+	}
+	for rows.Next() {
+		values, err := rows.SliceScan()
+		if err != nil {
+			return table, err
+		}
+
+		row := make(map[string]interface{}, len(values))
+		for i, value := range values {
+			column := table.Columns[i]
+			switch v := value.(type) {
+			case []byte:
+				if column.Binary {
+					row[column.Name] = base64.StdEncoding.EncodeToString(v)
+				} else {
+					row[column.Name] = string(v)
+				}
+			case time.Time:
+				row[column.Name] = v.Format(time.RFC3339Nano)
+			default:
+				row[column.Name] = v
+			}
+		}
+		if masker != nil {
+			masker.MaskRow(tableName, row)
+		}
+
+		err = rowWriter.Write(row)
+		if err != nil {
+			return table, err
+		}
+		table.Rows += 1
+	}
+	if err = rows.Err(); err != nil {
+		return table, err
+	}
+
+	return table, rowWriter.Close()
+}
+
+// backupBlobFolders lists the folders of the local cloud stores used by asset columns
+func backupBlobFolders(cmsConfig *CmsConfig, cloudStores []CloudStore) []BackupBlobFolder {
+	storesByName := make(map[string]CloudStore)
+	for _, store := range cloudStores {
+		storesByName[store.Name] = store
+	}
+
+	folders := make([]BackupBlobFolder, 0)
+	added := make(map[string]bool)
+	for _, table := range cmsConfig.Tables {
+		for _, column := range table.Columns {
+			if !column.IsForeignKey || column.ForeignKeyData.DataSource != "cloud_store" {
+				continue
+			}
+			store, ok := storesByName[column.ForeignKeyData.Namespace]
+			if !ok || store.StoreProvider != "local" {
+				continue
+			}
+			folder := BackupBlobFolder{
+				CloudStore: store.Name,
+				RootPath:   store.RootPath,
+				Folder:     strings.Trim(column.ForeignKeyData.KeyName, "/"),
+			}
+			if added[folder.archivePrefix()] {
+				continue
+			}
+			added[folder.archivePrefix()] = true
+			folders = append(folders, folder)
+		}
+	}
+	return folders
+}
+
+// WriteBackupArchive writes every table of the database, the schema and the files of the local asset folders to the
+// archive. With a masker the rows are anonymised, and the files and the tables which are not in the schema, like
+// _config with the secrets of the instance, are left out.
+func WriteBackupArchive(output io.Writer, cmsConfig *CmsConfig, cloudStores []CloudStore, masker *DataMasker, transaction *sqlx.Tx) (BackupManifest, error) {
+	manifest := BackupManifest{
+		FormatVersion: BackupFormatVersion,
+		CreatedAt:     time.Now().UTC(),
+		DatabaseType:  transaction.DriverName(),
+		Anonymised:    masker != nil,
+		Tables:        make([]BackupTable, 0),
+		BlobFolders:   make([]BackupBlobFolder, 0),
+	}
+
+	archive := zip.NewWriter(output)
+
+	tables, err := ListDatabaseTables(transaction)
+	if err != nil {
+		return manifest, err
+	}
+	for _, tableName := range tables {
+		if masker != nil && !masker.MasksTable(tableName) {
+			log.Infof("Table [%v] is not in the schema and is left out of the anonymised backup", tableName)
+			continue
+		}
+		entry, err := archive.Create("data/" + tableName + ".ndjson")
+		if err != nil {
+			return manifest, err
+		}
+		table, err := backupTableRows(tableName, entry, masker, transaction)
+		if err != nil {
+			return manifest, fmt.Errorf("failed to back up [%v]: %v", tableName, err)
+		}
+		manifest.Tables = append(manifest.Tables, table)
+	}
+
+	schema, err := json.MarshalIndent(*cmsConfig, "", "  ")
+	if err != nil {
+		return manifest, err
+	}
+	entry, err := archive.Create(backupSchemaFileName)
+	if err != nil {
+		return manifest, err
+	}
+	if _, err = entry.Write(schema); err != nil {
+		return manifest, err
+	}
+
+	blobFolders := backupBlobFolders(cmsConfig, cloudStores)
+	if masker != nil {
+		blobFolders = nil
+	}
+	for _, folder := range blobFolders {
+		sourceFolder := filepath.Join(folder.RootPath, folder.Folder)
+		err = filepath.Walk(sourceFolder, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return err
+			}
+			relativePath, err := filepath.Rel(sourceFolder, path)
+			if err != nil {
+				return err
+			}
+			entry, err := archive.Create(folder.archivePrefix() + filepath.ToSlash(relativePath))
+			if err != nil {
+				return err
+			}
+			file, err := os.Open(path)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			_, err = io.Copy(entry, file)
+			folder.Files += 1
+			return err
+		})
+		if err != nil && !os.IsNotExist(err) {
+			return manifest, fmt.Errorf("failed to back up files of [%v]: %v", sourceFolder, err)
+		}
+		manifest.BlobFolders = append(manifest.BlobFolders, folder)
+	}
+
+	manifestJson, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return manifest, err
+	}
+	entry, err = archive.Create(backupManifestFileName)
+	if err != nil {
+		return manifest, err
+	}
+	if _, err = entry.Write(manifestJson); err != nil {
+		return manifest, err
+	}
+
+	return manifest, archive.Close()
+}
+
+// configureCloudStoreRemote sets up the rclone remote of the store with its oauth token
+func (dbResource *DbResource) configureCloudStoreRemote(cloudStore CloudStore, transaction *sqlx.Tx) {
+	if cloudStore.OAutoTokenId == daptinid.NullReferenceId {
+		return
+	}
+	token, oauthConf, err := dbResource.GetTokenByTokenReferenceId(cloudStore.OAutoTokenId, transaction)
+	if err != nil || oauthConf == nil {
+		CheckErr(err, "Failed to get oauth2 token for cloud store [%v]", cloudStore.Name)
+		return
+	}
+
+	jsonToken, err := json.Marshal(token)
+	CheckErr(err, "Failed to convert token to json")
+	config.FileSet(cloudStore.StoreProvider, "client_id", oauthConf.ClientID)
+	config.FileSet(cloudStore.StoreProvider, "type", cloudStore.StoreProvider)
+	config.FileSet(cloudStore.StoreProvider, "client_secret", oauthConf.ClientSecret)
+	config.FileSet(cloudStore.StoreProvider, "token", string(jsonToken))
+	config.FileSet(cloudStore.StoreProvider, "client_scopes", strings.Join(oauthConf.Scopes, ","))
+	config.FileSet(cloudStore.StoreProvider, "redirect_url", oauthConf.RedirectURL)
+}
+
+func cloudStorePath(cloudStore CloudStore, path string) string {
+	rootPath := cloudStore.RootPath
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return rootPath
+	}
+	if !strings.HasSuffix(rootPath, "/") && !strings.HasSuffix(rootPath, ":") {
+		rootPath = rootPath + "/"
+	}
+	return rootPath + path
+}
+
+// copyCloudStoreFile copies a single file between two folders, either of which may be on a cloud store
+func copyCloudStoreFile(sourceFolder string, targetFolder string, fileName string) error {
+	ctx := context.Background()
+	fsrc, err := fs.NewFs(ctx, sourceFolder)
+	if err != nil {
+		return err
+	}
+	fdst, err := fs.NewFs(ctx, targetFolder)
+	if err != nil {
+		return err
+	}
+	return operations.CopyFile(ctx, fdst, fsrc, fileName, fileName)
+}
+
+// BackupInstance takes a consistent snapshot of the instance and uploads the archive to the path in the cloud store,
+// the snapshot is anonymised when there is a masker
+func (dbResource *DbResource) BackupInstance(cloudStore CloudStore, path string, archiveName string, cmsConfig *CmsConfig, masker *DataMasker) (BackupManifest, error) {
+	var manifest BackupManifest
+
+	tempFolder, err := os.MkdirTemp(os.Getenv("DAPTIN_CACHE_FOLDER"), "backup")
+	if err != nil {
+		return manifest, err
+	}
+	defer os.RemoveAll(tempFolder)
+
+	archiveFile, err := os.Create(filepath.Join(tempFolder, archiveName))
+	if err != nil {
+		return manifest, err
+	}
+	defer archiveFile.Close()
+
+	transaction, err := dbResource.Connection.Beginx()
+	if err != nil {
+		return manifest, err
+	}
+	defer transaction.Rollback()
+	if transaction.DriverName() == "postgres" {
+		// every statement of the transaction reads the same snapshot
+		_, err = transaction.Exec("SET TRANSACTION ISOLATION LEVEL REPEATABLE READ READ ONLY")
+		if err != nil {
+			return manifest, err
+		}
+	}
+
+	cloudStores, err := dbResource.GetAllCloudStores(transaction)
+	if err != nil {
+		return manifest, err
+	}
+
+	manifest, err = WriteBackupArchive(archiveFile, cmsConfig, cloudStores, masker, transaction)
+	if err != nil {
+		return manifest, err
+	}
+	err = archiveFile.Close()
+	if err != nil {
+		return manifest, err
+	}
+
+	dbResource.configureCloudStoreRemote(cloudStore, transaction)
+	err = copyCloudStoreFile(tempFolder, cloudStorePath(cloudStore, path), archiveName)
+	return manifest, err
+}
+
+func pendingRestorePath() string {
+	schemaFolder, _ := os.LookupEnv("DAPTIN_SCHEMA_FOLDER")
+	return filepath.Join(schemaFolder, pendingRestoreFileName)
+}
+
+func readBackupManifest(archive *zip.Reader) (BackupManifest, error) {
+	var manifest BackupManifest
+	file, err := archive.Open(backupManifestFileName)
+	if err != nil {
+		return manifest, errors.New("archive has no manifest, it is not a daptin backup")
+	}
+	defer file.Close()
+	err = json.NewDecoder(file).Decode(&manifest)
+	if err != nil {
+		return manifest, err
+	}
+	if manifest.FormatVersion < 1 || manifest.FormatVersion > BackupFormatVersion {
+		return manifest, fmt.Errorf("backup format version %d is not supported, this instance reads up to version %d",
+			manifest.FormatVersion, BackupFormatVersion)
+	}
+	return manifest, nil
+}
+
+// isBackupArchiveName is true for the name of a file, the name is joined to the local and cloud store paths and must
+// not lead out of them
+func isBackupArchiveName(archiveName string) bool {
+	return archiveName != "" && archiveName != "." && !strings.Contains(archiveName, "..") &&
+		!strings.ContainsAny(archiveName, "/\\")
+}
+
+// PrepareRestore downloads the archive from the cloud store and writes its schema to the schema folder. The rows
+// and files are restored by RestorePendingBackup when the instance starts again.
+func (dbResource *DbResource) PrepareRestore(cloudStore CloudStore, path string, archiveName string, transaction *sqlx.Tx) (BackupManifest, error) {
+	var manifest BackupManifest
+	if !isBackupArchiveName(archiveName) {
+		return manifest, fmt.Errorf("invalid archive name [%v], it has to be the name of a file in the backup path", archiveName)
+	}
+
+	tempFolder, err := os.MkdirTemp(os.Getenv("DAPTIN_CACHE_FOLDER"), "restore")
+	if err != nil {
+		return manifest, err
+	}
+	defer os.RemoveAll(tempFolder)
+
+	dbResource.configureCloudStoreRemote(cloudStore, transaction)
+	err = copyCloudStoreFile(cloudStorePath(cloudStore, path), tempFolder, archiveName)
+	if err != nil {
+		return manifest, fmt.Errorf("failed to download [%v]: %v", archiveName, err)
+	}
+
+	archive, err := zip.OpenReader(filepath.Join(tempFolder, archiveName))
+	if err != nil {
+		return manifest, err
+	}
+	defer archive.Close()
+
+	manifest, err = readBackupManifest(&archive.Reader)
+	if err != nil {
+		return manifest, err
+	}
+
+	schemaFile, err := archive.Open(backupSchemaFileName)
+	if err != nil {
+		return manifest, errors.New("archive has no schema")
+	}
+	schema, err := io.ReadAll(schemaFile)
+	schemaFile.Close()
+	if err != nil {
+		return manifest, err
+	}
+
+	archiveBytes, err := os.ReadFile(filepath.Join(tempFolder, archiveName))
+	if err != nil {
+		return manifest, err
+	}
+	err = os.WriteFile(pendingRestorePath(), archiveBytes, 0600)
+	if err != nil {
+		return manifest, err
+	}
+
+	schemaFolder, _ := os.LookupEnv("DAPTIN_SCHEMA_FOLDER")
+	return manifest, os.WriteFile(filepath.Join(schemaFolder, restoredSchemaFileName), schema, 0644)
+}
+
+// restoreValue converts a value of the archive back to the value stored in the column
+func restoreValue(column BackupColumn, value interface{}) (interface{}, error) {
+	if number, ok := value.(interface {
+		Int64() (int64, error)
+		Float64() (float64, error)
+	}); ok {
+		if i, err := number.Int64(); err == nil {
+			return i, nil
+		}
+		return number.Float64()
+	}
+
+	stringValue, ok := value.(string)
+	if !ok {
+		return value, nil
+	}
+	if column.Binary {
+		return base64.StdEncoding.DecodeString(stringValue)
+	}
+	if column.Time {
+		if parsed, err := time.Parse(time.RFC3339Nano, stringValue); err == nil {
+			return parsed, nil
+		}
+	}
+	return value, nil
+}
+
+// restoreTableRows inserts the rows of the table in the archive, the table is expected to be empty
+func restoreTableRows(archive *zip.Reader, table BackupTable, transaction *sqlx.Tx) error {
+	file, err := archive.Open("data/" + table.Name + ".ndjson")
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	columns := make(map[string]BackupColumn)
+	for _, column := range table.Columns {
+		columns[column.Name] = column
+	}
+
+	hasId := false
+	rowNumber := 0
+	err = ReadNdjsonRows(file, func(row map[string]interface{}) error {
+		rowNumber += 1
+		record := goqu.Record{}
+		for columnName, value := range row {
+			restoredValue, err := restoreValue(columns[columnName], value)
+			if err != nil {
+				return fmt.Errorf("row %d column [%v]: %v", rowNumber, columnName, err)
+			}
+			record[columnName] = restoredValue
+		}
+		_, hasId = record["id"]
+
+		s, q, err := statementbuilder.Squirrel.Insert(table.Name).Prepared(true).Rows(record).ToSQL()
+		if err != nil {
+			return err
+		}
+		_, err = transaction.Exec(s, q...)
+		if err != nil {
+			return fmt.Errorf("row %d: %v", rowNumber, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if hasId && transaction.DriverName() == "postgres" {
+		// the ids were inserted as they were, the sequence has to continue after them
+		_, err = transaction.Exec(fmt.Sprintf("select setval(pg_get_serial_sequence('%s', 'id'), coalesce(max(id), 1)) from %s",
+			table.Name, table.Name))
+	}
+	return err
+}
+
+// localCloudStoreRoots reads the root path of each local cloud store of this instance by the name of the store
+func localCloudStoreRoots(transaction *sqlx.Tx) (map[string]string, error) {
+	s, v, err := statementbuilder.Squirrel.Select("name", "root_path").Prepared(true).From("cloud_store").
+		Where(goqu.Ex{"store_provider": "local"}).ToSQL()
+	if err != nil {
+		return nil, err
+	}
+	stores := make([]struct {
+		Name     string `db:"name"`
+		RootPath string `db:"root_path"`
+	}, 0)
+	err = transaction.Select(&stores, s, v...)
+	if err != nil {
+		return nil, err
+	}
+	roots := make(map[string]string)
+	for _, store := range stores {
+		roots[store.Name] = store.RootPath
+	}
+	return roots, nil
+}
+
+// restoreBlobFolder writes the files of an asset folder below the root path of the cloud store on this instance, the
+// root path in the manifest is not used. A file whose path leaves the root path fails the restore.
+func restoreBlobFolder(archive *zip.Reader, folder BackupBlobFolder, rootPath string) error {
+	prefix := folder.archivePrefix()
+	root := filepath.Clean(rootPath)
+	for _, file := range archive.File {
+		if !strings.HasPrefix(file.Name, prefix) || strings.HasSuffix(file.Name, "/") {
+			continue
+		}
+		relativePath := filepath.FromSlash(strings.TrimPrefix(file.Name, "files/"+folder.CloudStore+"/"))
+		targetPath := filepath.Join(root, relativePath)
+		if !strings.HasPrefix(targetPath, root+string(os.PathSeparator)) {
+			return fmt.Errorf("file [%v] is outside of [%v]", file.Name, root)
+		}
+
+		err := os.MkdirAll(filepath.Dir(targetPath), 0755)
+		if err != nil {
+			return err
+		}
+		source, err := file.Open()
+		if err != nil {
+			return err
+		}
+		target, err := os.Create(targetPath)
+		if err != nil {
+			source.Close()
+			return err
+		}
+		_, err = io.Copy(target, source)
+		source.Close()
+		target.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RestorePendingBackup replaces the rows of every table in the archive waiting to be restored, and puts back the
+// files of the local asset folders. It runs at startup once the tables of the restored schema exist. The archive is
+// renamed after the attempt so a failed restore is not retried on every start.
+func RestorePendingBackup(db database.DatabaseConnection) error {
+	archivePath := pendingRestorePath()
+	if _, err := os.Stat(archivePath); os.IsNotExist(err) {
+		return nil
+	}
+
+	err := restoreBackupArchive(archivePath, db)
+	doneSuffix := ".restored"
+	if err != nil {
+		doneSuffix = ".failed"
+	}
+	CheckErr(os.Rename(archivePath, archivePath+doneSuffix), "Failed to rename restored archive [%v]", archivePath)
+	return err
+}
+
+func restoreBackupArchive(archivePath string, db database.DatabaseConnection) error {
+	archive, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return err
+	}
+	defer archive.Close()
+
+	manifest, err := readBackupManifest(&archive.Reader)
+	if err != nil {
+		return err
+	}
+	log.Infof("Restoring backup taken at %v with %d tables", manifest.CreatedAt, len(manifest.Tables))
+
+	transaction, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+
+	existingTables, err := ListDatabaseTables(transaction)
+	if err != nil {
+		transaction.Rollback()
+		return err
+	}
+
+	references, err := ListForeignKeyReferences(transaction)
+	if err != nil {
+		transaction.Rollback()
+		return err
+	}
+
+	// the rows of the backup replace the cloud stores, the files go to the stores as they are on this instance
+	storeRoots := make(map[string]string)
+	if InStringArray(existingTables, "cloud_store") {
+		storeRoots, err = localCloudStoreRoots(transaction)
+		if err != nil {
+			transaction.Rollback()
+			return err
+		}
+	}
+
+	switch transaction.DriverName() {
+	case "mysql":
+		_, err = transaction.Exec("SET FOREIGN_KEY_CHECKS = 0")
+		if err != nil {
+			transaction.Rollback()
+			return err
+		}
+	case "postgres":
+		// only a superuser can skip the foreign keys, everyone else relies on the order of the tables
+		_, err = transaction.Exec("SAVEPOINT restore_replica")
+		if err == nil {
+			_, err = transaction.Exec("SET LOCAL session_replication_role = replica")
+			if err != nil {
+				log.Infof("Foreign keys are checked while restoring: %v", err)
+				_, err = transaction.Exec("ROLLBACK TO SAVEPOINT restore_replica")
+			}
+		}
+		if err != nil {
+			transaction.Rollback()
+			return err
+		}
+	}
+
+	tables := make([]BackupTable, 0, len(manifest.Tables))
+	for _, table := range manifest.Tables {
+		if !InStringArray(existingTables, table.Name) {
+			log.Warnf("Table [%v] of the backup does not exist, its %d rows are not restored", table.Name, table.Rows)
+			continue
+		}
+		tables = append(tables, table)
+	}
+	tables = restoreTableOrder(tables, references)
+
+	// the rows referring to a table are removed before the rows of the table
+	for i := len(tables) - 1; i >= 0; i-- {
+		s, q, err := statementbuilder.Squirrel.Delete(tables[i].Name).ToSQL()
+		if err == nil {
+			_, err = transaction.Exec(s, q...)
+		}
+		if err != nil {
+			transaction.Rollback()
+			return fmt.Errorf("failed to clear [%v]: %v", tables[i].Name, err)
+		}
+	}
+
+	for _, table := range tables {
+		err = restoreTableRows(&archive.Reader, table, transaction)
+		if err != nil {
+			transaction.Rollback()
+			return fmt.Errorf("failed to restore [%v]: %v", table.Name, err)
+		}
+		log.Infof("Restored %d rows of [%v]", table.Rows, table.Name)
+	}
+
+	if transaction.DriverName() == "mysql" {
+		_, err = transaction.Exec("SET FOREIGN_KEY_CHECKS = 1")
+		if err != nil {
+			transaction.Rollback()
+			return err
+		}
+	}
+
+	err = transaction.Commit()
+	if err != nil {
+		return err
+	}
+
+	for _, folder := range manifest.BlobFolders {
+		rootPath, ok := storeRoots[folder.CloudStore]
+		if !ok {
+			log.Warnf("Cloud store [%v] of the backup is not a local store of this instance, its %d files are not restored",
+				folder.CloudStore, folder.Files)
+			continue
+		}
+		err = restoreBlobFolder(&archive.Reader, folder, rootPath)
+		if err != nil {
+			return fmt.Errorf("failed to restore files of [%v]: %v", folder.CloudStore, err)
+		}
+	}
+	return nil
+}
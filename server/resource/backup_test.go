@@ -0,0 +1,242 @@
+package resource
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+func TestRestoreValue(t *testing.T) {
+
+	rows := make([]map[string]interface{}, 0)
+	err := ReadNdjsonRows(bytes.NewReader([]byte(`{"id":7,"price":1.25,"reference_id":"AQID","created_at":"2024-03-01T10:00:00.5Z","title":"x"}`)),
+		func(row map[string]interface{}) error {
+			rows = append(rows, row)
+			return nil
+		})
+	if err != nil || len(rows) != 1 {
+		t.Fatalf("failed to read row: %v", err)
+	}
+	row := rows[0]
+
+	id, _ := restoreValue(BackupColumn{Name: "id"}, row["id"])
+	if id != int64(7) {
+		t.Errorf("expected id 7, got %v", id)
+	}
+	price, _ := restoreValue(BackupColumn{Name: "price"}, row["price"])
+	if price != 1.25 {
+		t.Errorf("expected price 1.25, got %v", price)
+	}
+	referenceId, err := restoreValue(BackupColumn{Name: "reference_id", Binary: true}, row["reference_id"])
+	if err != nil || !bytes.Equal(referenceId.([]byte), []byte{1, 2, 3}) {
+		t.Errorf("expected binary value to be decoded, got %v %v", referenceId, err)
+	}
+	createdAt, _ := restoreValue(BackupColumn{Name: "created_at", Time: true}, row["created_at"])
+	if !createdAt.(time.Time).Equal(time.Date(2024, 3, 1, 10, 0, 0, 500000000, time.UTC)) {
+		t.Errorf("unexpected time %v", createdAt)
+	}
+	title, _ := restoreValue(BackupColumn{Name: "title"}, row["title"])
+	if title != "x" {
+		t.Errorf("expected text to be kept, got %v", title)
+	}
+}
+
+func TestRestoreBackupArchiveFollowsForeignKeys(t *testing.T) {
+	folder := t.TempDir()
+	db, err := sqlx.Open("sqlite3", filepath.Join(folder, "daptin.db")+"?_foreign_keys=1")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	db.MustExec("create table author (id integer primary key, name varchar(20))")
+	db.MustExec("create table book (id integer primary key, title varchar(20), author_id integer references author(id))")
+	db.MustExec("insert into author (id, name) values (1, 'old')")
+	db.MustExec("insert into book (id, title, author_id) values (1, 'old', 1)")
+	if _, err = db.Exec("insert into book (id, title, author_id) values (2, 'none', 9)"); err == nil {
+		t.Fatalf("expected the foreign keys to be checked")
+	}
+
+	// the books are in the archive before the authors they refer to
+	archivePath := filepath.Join(folder, "backup.zip")
+	archiveFile, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatalf("failed to create archive: %v", err)
+	}
+	archive := zip.NewWriter(archiveFile)
+	write := func(name string, content string) {
+		writer, err := archive.Create(name)
+		if err != nil {
+			t.Fatalf("failed to write [%v]: %v", name, err)
+		}
+		writer.Write([]byte(content))
+	}
+	manifest, _ := json.Marshal(BackupManifest{
+		FormatVersion: BackupFormatVersion,
+		Tables: []BackupTable{
+			{Name: "book", Rows: 1, Columns: []BackupColumn{{Name: "id"}, {Name: "title"}, {Name: "author_id"}}},
+			{Name: "author", Rows: 1, Columns: []BackupColumn{{Name: "id"}, {Name: "name"}}},
+		},
+	})
+	write(backupManifestFileName, string(manifest))
+	write("data/book.ndjson", `{"id": 5, "title": "restored", "author_id": 2}`+"\n")
+	write("data/author.ndjson", `{"id": 2, "name": "restored"}`+"\n")
+	archive.Close()
+	archiveFile.Close()
+
+	if err = restoreBackupArchive(archivePath, db); err != nil {
+		t.Fatalf("failed to restore: %v", err)
+	}
+	var titles []string
+	if err = db.Select(&titles, "select title from book"); err != nil || len(titles) != 1 || titles[0] != "restored" {
+		t.Errorf("expected only the restored book, got %v %v", titles, err)
+	}
+	var names []string
+	if err = db.Select(&names, "select name from author"); err != nil || len(names) != 1 || names[0] != "restored" {
+		t.Errorf("expected only the restored author, got %v %v", names, err)
+	}
+}
+
+func TestRestoreTableOrder(t *testing.T) {
+	tables := []BackupTable{{Name: "comment"}, {Name: "post"}, {Name: "user_account"}, {Name: "tag"}}
+	ordered := restoreTableOrder(tables, map[string][]string{
+		"comment":      {"post", "user_account"},
+		"post":         {"user_account"},
+		"user_account": {"user_account"},
+	})
+	names := make([]string, 0)
+	for _, table := range ordered {
+		names = append(names, table.Name)
+	}
+	if expected := "user_account,tag,post,comment"; strings.Join(names, ",") != expected {
+		t.Errorf("expected %v, got %v", expected, names)
+	}
+
+	// tables in a cycle are all kept
+	cycle := restoreTableOrder(tables[:2], map[string][]string{"comment": {"post"}, "post": {"comment"}})
+	if len(cycle) != 2 || cycle[0].Name != "comment" || cycle[1].Name != "post" {
+		t.Errorf("expected the order of the archive for a cycle, got %v", cycle)
+	}
+}
+
+func TestBackupArchiveName(t *testing.T) {
+	for _, name := range []string{"daptin-backup-2024.zip", "backup.zip"} {
+		if !isBackupArchiveName(name) {
+			t.Errorf("expected [%v] to be accepted", name)
+		}
+	}
+	for _, name := range []string{"", ".", "..", "../schema_daptin.json", "backups/backup.zip", "/etc/passwd",
+		`backups\backup.zip`} {
+		if isBackupArchiveName(name) {
+			t.Errorf("expected [%v] to be refused", name)
+		}
+	}
+}
+
+func TestRestoreBlobFolderStaysInLocalStore(t *testing.T) {
+	folder := t.TempDir()
+	db, err := sqlx.Open("sqlite3", filepath.Join(folder, "daptin.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	localRoot := filepath.Join(folder, "store")
+	db.MustExec("create table cloud_store (name varchar(20), root_path varchar(100), store_provider varchar(20))")
+	db.MustExec("insert into cloud_store (name, root_path, store_provider) values ('images', ?, 'local')", localRoot)
+
+	archivePath := filepath.Join(folder, "backup.zip")
+	archiveFile, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatalf("failed to create archive: %v", err)
+	}
+	archive := zip.NewWriter(archiveFile)
+	write := func(name string, content string) {
+		writer, err := archive.Create(name)
+		if err != nil {
+			t.Fatalf("failed to write [%v]: %v", name, err)
+		}
+		writer.Write([]byte(content))
+	}
+	// the manifest of the archive points somewhere else
+	manifestRoot := filepath.Join(folder, "elsewhere")
+	manifest, _ := json.Marshal(BackupManifest{
+		FormatVersion: BackupFormatVersion,
+		BlobFolders:   []BackupBlobFolder{{CloudStore: "images", RootPath: manifestRoot, Folder: "uploads", Files: 2}},
+	})
+	write(backupManifestFileName, string(manifest))
+	write("files/images/uploads/photo.png", "photo")
+	write("files/images/uploads/../../escaped.txt", "escaped")
+	archive.Close()
+	archiveFile.Close()
+
+	if err = restoreBackupArchive(archivePath, db); err == nil || !strings.Contains(err.Error(), "outside of") {
+		t.Errorf("expected the file leaving the store to fail the restore, got %v", err)
+	}
+	if content, err := os.ReadFile(filepath.Join(localRoot, "uploads", "photo.png")); err != nil || string(content) != "photo" {
+		t.Errorf("expected the file in the local store, got %v", err)
+	}
+	if _, err = os.Stat(manifestRoot); !os.IsNotExist(err) {
+		t.Errorf("expected the root path of the manifest not to be used")
+	}
+	if _, err = os.Stat(filepath.Join(folder, "escaped.txt")); !os.IsNotExist(err) {
+		t.Errorf("expected the file leaving the store not to be written")
+	}
+}
+
+func TestAnonymisedBackupLeavesOutSecrets(t *testing.T) {
+	InitialiseColumnManager()
+	db, cruds := newTestCruds(t, CmsConfig{})
+	config := CmsConfig{}
+	for _, crud := range cruds {
+		config.Tables = append(config.Tables, *crud.tableInfo)
+	}
+	db.MustExec(MakeCreateTableQuery(&ConfigTableStructure, "sqlite3"))
+	db.MustExec("insert into _config (name, configstate, configtype, configenv, value) values " +
+		"('jwt.secret', 'enabled', 'backend', 'release', 'jwt-secret-value'), " +
+		"('encryption.secret', 'enabled', 'backend', 'release', 'encryption-secret-value')")
+
+	masker, err := NewDataMasker(config.Tables, nil, "salt")
+	if err != nil {
+		t.Fatalf("failed to create masker: %v", err)
+	}
+	var output bytes.Buffer
+	transaction := db.MustBegin()
+	manifest, err := WriteBackupArchive(&output, &config, nil, masker, transaction)
+	transaction.Rollback()
+	if err != nil {
+		t.Fatalf("failed to write backup: %v", err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(output.Bytes()), int64(output.Len()))
+	if err != nil {
+		t.Fatalf("failed to read backup: %v", err)
+	}
+	for _, file := range archive.File {
+		reader, err := file.Open()
+		if err != nil {
+			t.Fatalf("failed to open [%v]: %v", file.Name, err)
+		}
+		var content bytes.Buffer
+		content.ReadFrom(reader)
+		reader.Close()
+		for _, secret := range []string{"jwt.secret", "jwt-secret-value", "encryption-secret-value"} {
+			if strings.Contains(content.String(), secret) {
+				t.Errorf("expected [%v] not to be in [%v] of an anonymised backup", secret, file.Name)
+			}
+		}
+	}
+	tableNames := make([]string, 0)
+	for _, table := range manifest.Tables {
+		tableNames = append(tableNames, table.Name)
+	}
+	if InStringArray(tableNames, settingsTableName) || !InStringArray(tableNames, USER_ACCOUNT_TABLE_NAME) {
+		t.Errorf("expected the tables of the schema without _config, got %v", tableNames)
+	}
+}
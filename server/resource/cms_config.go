@@ -27,6 +27,8 @@ type CmsConfig struct {
 	Tasks                    []Task
 	Streams                  []StreamContract
 	ActionPerformers         []ActionPerformerInterface
+	Limits                   []RateLimit
+	Webhooks                 []Webhook
 }
 
 var ValidatorInstance = validator.New()
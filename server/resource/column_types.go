@@ -3,6 +3,7 @@ package resource
 import (
 	"crypto/md5"
 	"fmt"
+	"github.com/artpar/api2go"
 	uuid "github.com/google/uuid"
 	"github.com/graphql-go/graphql"
 	"github.com/icrowley/fake"
@@ -110,6 +111,10 @@ func (ct ColumnType) Fake() interface{} {
 		return randomGenerator.Intn(11)
 	case "measurement":
 		return randomGenerator.Intn(5000)
+	case "money":
+		return fmt.Sprintf("%d.%02d", randomGenerator.Intn(10000), randomGenerator.Intn(100))
+	case "currency":
+		return fake.CurrencyCode()
 	case "label":
 		return fake.ProductName()
 	case "content":
@@ -372,6 +377,27 @@ var ColumnTypes = []ColumnType{
 		DataTypes:     []string{"float(7,4)"},
 		GraphqlType:   graphql.Float,
 	},
+	{
+		Name:          "money",
+		ReclineType:   "number",
+		BlueprintType: "string",
+		DataTypes:     []string{"decimal(19,4)", "decimal(19,2)"},
+		GraphqlType:   graphql.String,
+	},
+	{
+		Name:          "currency",
+		ReclineType:   "string",
+		BlueprintType: "string",
+		DataTypes:     []string{"varchar(3)"},
+		GraphqlType:   graphql.String,
+	},
+	{
+		Name:          "sequence",
+		ReclineType:   "string",
+		BlueprintType: "string",
+		DataTypes:     []string{"varchar(50)"},
+		GraphqlType:   graphql.String,
+	},
 	{
 		Name:          "label",
 		ReclineType:   "string",
@@ -494,3 +520,33 @@ func (ctm *ColumnTypeManager) IsValidValue(val string, colType string, validator
 	return validator.Var(val, ctm.ColumnMap[colType].Validations[0])
 
 }
+
+// EnumValues lists the values allowed in an enum column, empty when the column does not restrict its values
+func EnumValues(column api2go.ColumnInfo) []string {
+	if column.ColumnType != "enum" {
+		return nil
+	}
+	values := make([]string, 0, len(column.Options))
+	for _, option := range column.Options {
+		if option.Value == nil {
+			continue
+		}
+		values = append(values, fmt.Sprintf("%v", option.Value))
+	}
+	return values
+}
+
+// EnumOption finds the option matching the value, ignoring case, and returns the value as it is declared in the
+// column options. No value is accepted by an enum column which does not list its options.
+func EnumOption(column api2go.ColumnInfo, value interface{}) (string, bool) {
+	valString, ok := value.(string)
+	if !ok {
+		valString = fmt.Sprintf("%v", value)
+	}
+	for _, enumValue := range EnumValues(column) {
+		if strings.EqualFold(valString, enumValue) {
+			return enumValue, true
+		}
+	}
+	return valString, false
+}
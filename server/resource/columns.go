@@ -90,6 +90,9 @@ var StandardRelations = []api2go.TableRelation{
 var SystemSmds []LoopbookFsmDescription
 var SystemExchanges []ExchangeContract
 
+// SystemActions run their outcomes as the invoking user, like any other action. Only the actions which read or
+// create rows the invoking user cannot, to sign up, reset a password, log in with oauth or add a data exchange, run
+// as an administrator.
 var SystemActions = []Action{
 	{
 		Name:             "import_files_from_store",
@@ -490,6 +493,57 @@ var SystemActions = []Action{
 			},
 		},
 	},
+	{
+		Name:             "list_schema_migrations",
+		Label:            "Schema migration history",
+		OnType:           "world",
+		InstanceOptional: true,
+		InFields:         []api2go.ColumnInfo{},
+		OutFields: []Outcome{
+			{
+				Type:       "__schema_migration_list",
+				Method:     "EXECUTE",
+				Attributes: map[string]interface{}{},
+			},
+		},
+	},
+	{
+		Name:             "rollback_schema",
+		Label:            "Rollback schema to version",
+		OnType:           "world",
+		InstanceOptional: true,
+		InFields: []api2go.ColumnInfo{
+			{
+				Name:       "Version",
+				ColumnName: "version",
+				ColumnType: "measurement",
+				IsNullable: false,
+			},
+		},
+		OutFields: []Outcome{
+			{
+				Type:   "__schema_rollback",
+				Method: "EXECUTE",
+				Attributes: map[string]interface{}{
+					"version": "~version",
+				},
+			},
+		},
+	},
+	{
+		Name:             "apply_gitops_schema",
+		Label:            "Apply schema files",
+		OnType:           "world",
+		InstanceOptional: true,
+		InFields:         []api2go.ColumnInfo{},
+		OutFields: []Outcome{
+			{
+				Type:       "__gitops_apply",
+				Method:     "EXECUTE",
+				Attributes: map[string]interface{}{},
+			},
+		},
+	},
 	{
 		Name:             "generate_random_data",
 		Label:            "Generate random data",
@@ -506,6 +560,18 @@ var SystemActions = []Action{
 				ColumnName: "table_name",
 				ColumnType: "label",
 			},
+			{
+				Name:       "Related records",
+				ColumnName: "related_count",
+				ColumnType: "measurement",
+				IsNullable: true,
+			},
+			{
+				Name:       "Seed",
+				ColumnName: "seed",
+				ColumnType: "measurement",
+				IsNullable: true,
+			},
 		},
 		OutFields: []Outcome{
 			{
@@ -514,6 +580,8 @@ var SystemActions = []Action{
 				Attributes: map[string]interface{}{
 					"count":             "~count",
 					"table_name":        "~table_name",
+					"related_count":     "~related_count",
+					"seed":              "~seed",
 					"user_reference_id": "$user.reference_id",
 					"user_account_id":   "$user.id",
 				},
@@ -524,6 +592,10 @@ var SystemActions = []Action{
 				ColumnName: "count",
 				Tags:       "gt=0",
 			},
+			{
+				ColumnName: "related_count",
+				Tags:       "omitempty,gte=0",
+			},
 		},
 	},
 	{
@@ -537,6 +609,25 @@ var SystemActions = []Action{
 				Name:       "table_name",
 				ColumnType: "label",
 			},
+			{
+				ColumnName:   "format",
+				Name:         "Format",
+				ColumnType:   "label",
+				DefaultValue: "json",
+				IsNullable:   true,
+			},
+			{
+				ColumnName: "columns",
+				Name:       "Columns",
+				ColumnType: "label",
+				IsNullable: true,
+			},
+		},
+		Validations: []ColumnTag{
+			{
+				ColumnName: "format",
+				Tags:       "omitempty,oneof=json ndjson parquet",
+			},
 		},
 		OutFields: []Outcome{
 			{
@@ -544,6 +635,66 @@ var SystemActions = []Action{
 				Method: "EXECUTE",
 				Attributes: map[string]interface{}{
 					"table_name": "~table_name",
+					"format":     "~format",
+					"columns":    "~columns",
+				},
+			},
+		},
+	},
+	{
+		Name:             "export_anonymised_data",
+		Label:            "Export anonymised data",
+		OnType:           "world",
+		InstanceOptional: true,
+		InFields: []api2go.ColumnInfo{
+			{
+				ColumnName: "table_name",
+				Name:       "table_name",
+				ColumnType: "label",
+			},
+			{
+				ColumnName:   "format",
+				Name:         "Format",
+				ColumnType:   "label",
+				DefaultValue: "json",
+				IsNullable:   true,
+			},
+			{
+				ColumnName: "columns",
+				Name:       "Columns",
+				ColumnType: "label",
+				IsNullable: true,
+			},
+			{
+				ColumnName: "masking_rules",
+				Name:       "Masking rules",
+				ColumnType: "label",
+				IsNullable: true,
+			},
+			{
+				ColumnName: "salt",
+				Name:       "Salt",
+				ColumnType: "label",
+				IsNullable: true,
+			},
+		},
+		Validations: []ColumnTag{
+			{
+				ColumnName: "format",
+				Tags:       "omitempty,oneof=json ndjson parquet",
+			},
+		},
+		OutFields: []Outcome{
+			{
+				Type:   "__data_export",
+				Method: "EXECUTE",
+				Attributes: map[string]interface{}{
+					"table_name":    "~table_name",
+					"format":        "~format",
+					"columns":       "~columns",
+					"masking_rules": "~masking_rules",
+					"salt":          "~salt",
+					"anonymise":     true,
 				},
 			},
 		},
@@ -579,7 +730,7 @@ var SystemActions = []Action{
 			{
 				Name:       "JSON Dump file",
 				ColumnName: "dump_file",
-				ColumnType: "file.json|yaml|toml|hcl",
+				ColumnType: "file.json|yaml|toml|hcl|ndjson|jsonl|parquet",
 				IsNullable: false,
 			},
 			{
@@ -587,6 +738,12 @@ var SystemActions = []Action{
 				ColumnName: "truncate_before_insert",
 				ColumnType: "truefalse",
 			},
+			{
+				Name:       "Columns",
+				ColumnName: "columns",
+				ColumnType: "label",
+				IsNullable: true,
+			},
 		},
 		OutFields: []Outcome{
 			{
@@ -597,6 +754,7 @@ var SystemActions = []Action{
 					"truncate_before_insert": "~truncate_before_insert",
 					"dump_file":              "~dump_file",
 					"table_name":             "$.table_name",
+					"columns":                "~columns",
 					"user":                   "~user",
 				},
 			},
@@ -636,6 +794,109 @@ var SystemActions = []Action{
 			},
 		},
 	},
+	{
+		Name:             "backup_instance",
+		Label:            "Back up everything to this store",
+		OnType:           "cloud_store",
+		InstanceOptional: false,
+		InFields: []api2go.ColumnInfo{
+			{
+				Name:         "Path",
+				ColumnName:   "path",
+				ColumnType:   "label",
+				IsNullable:   true,
+				DefaultValue: DefaultBackupPath,
+			},
+		},
+		OutFields: []Outcome{
+			{
+				Type:   "__backup_instance",
+				Method: "EXECUTE",
+				Attributes: map[string]interface{}{
+					"cloud_store_id": "$.reference_id",
+					"path":           "~path",
+				},
+			},
+		},
+	},
+	{
+		Name:             "backup_anonymised_instance",
+		Label:            "Back up everything anonymised to this store",
+		OnType:           "cloud_store",
+		InstanceOptional: false,
+		InFields: []api2go.ColumnInfo{
+			{
+				Name:         "Path",
+				ColumnName:   "path",
+				ColumnType:   "label",
+				IsNullable:   true,
+				DefaultValue: DefaultBackupPath,
+			},
+			{
+				ColumnName: "masking_rules",
+				Name:       "Masking rules",
+				ColumnType: "label",
+				IsNullable: true,
+			},
+			{
+				ColumnName: "salt",
+				Name:       "Salt",
+				ColumnType: "label",
+				IsNullable: true,
+			},
+		},
+		OutFields: []Outcome{
+			{
+				Type:   "__backup_instance",
+				Method: "EXECUTE",
+				Attributes: map[string]interface{}{
+					"cloud_store_id": "$.reference_id",
+					"path":           "~path",
+					"masking_rules":  "~masking_rules",
+					"salt":           "~salt",
+					"anonymise":      true,
+				},
+			},
+		},
+	},
+	{
+		Name:             "restore_instance",
+		Label:            "Restore everything from a backup on this store",
+		OnType:           "cloud_store",
+		InstanceOptional: false,
+		InFields: []api2go.ColumnInfo{
+			{
+				Name:       "Archive name",
+				ColumnName: "archive_name",
+				ColumnType: "label",
+				IsNullable: false,
+			},
+			{
+				Name:         "Path",
+				ColumnName:   "path",
+				ColumnType:   "label",
+				IsNullable:   true,
+				DefaultValue: DefaultBackupPath,
+			},
+		},
+		Validations: []ColumnTag{
+			{
+				ColumnName: "archive_name",
+				Tags:       "required",
+			},
+		},
+		OutFields: []Outcome{
+			{
+				Type:   "__restore_instance",
+				Method: "EXECUTE",
+				Attributes: map[string]interface{}{
+					"cloud_store_id": "$.reference_id",
+					"archive_name":   "~archive_name",
+					"path":           "~path",
+				},
+			},
+		},
+	},
 	{
 		Name:             "create_site",
 		Label:            "Create new site on this store",
@@ -901,6 +1162,12 @@ var SystemActions = []Action{
 				ColumnType: "truefalse",
 				IsNullable: false,
 			},
+			{
+				Name:       "Batch size",
+				ColumnName: "batch_size",
+				ColumnType: "measurement",
+				IsNullable: true,
+			},
 		},
 		Validations: []ColumnTag{
 			{
@@ -917,6 +1184,48 @@ var SystemActions = []Action{
 					"entity_name":          "~entity_name",
 					"add_missing_columns":  "~add_missing_columns",
 					"create_if_not_exists": "~create_if_not_exists",
+					"batch_size":           "~batch_size",
+					"user":                 "~user",
+				},
+			},
+		},
+	},
+	{
+		Name:             "resume_import",
+		Label:            "Resume import",
+		OnType:           "import_job",
+		InstanceOptional: false,
+		InFields: []api2go.ColumnInfo{
+			{
+				Name:       "Batch size",
+				ColumnName: "batch_size",
+				ColumnType: "measurement",
+				IsNullable: true,
+			},
+		},
+		OutFields: []Outcome{
+			{
+				Type:   "__import_job_resume",
+				Method: "EXECUTE",
+				Attributes: map[string]interface{}{
+					"import_job_id": "$.reference_id",
+					"batch_size":    "~batch_size",
+				},
+			},
+		},
+	},
+	{
+		Name:             "download_import_errors",
+		Label:            "Download failed rows",
+		OnType:           "import_job",
+		InstanceOptional: false,
+		InFields:         []api2go.ColumnInfo{},
+		OutFields: []Outcome{
+			{
+				Type:   "__import_job_error_report",
+				Method: "EXECUTE",
+				Attributes: map[string]interface{}{
+					"import_job_id": "$.reference_id",
 				},
 			},
 		},
@@ -953,6 +1262,12 @@ var SystemActions = []Action{
 				DefaultValue: "false",
 				IsNullable:   true,
 			},
+			{
+				Name:       "Batch size",
+				ColumnName: "batch_size",
+				ColumnType: "measurement",
+				IsNullable: true,
+			},
 		},
 		Validations: []ColumnTag{
 			{
@@ -969,55 +1284,125 @@ var SystemActions = []Action{
 					"entity_name":          "~entity_name",
 					"add_missing_columns":  "~add_missing_columns",
 					"create_if_not_exists": "~create_if_not_exists",
+					"batch_size":           "~batch_size",
+					"user":                 "~user",
 				},
 			},
 		},
 	},
 	{
-		Name:             "download_system_schema",
-		Label:            "Download system schema",
+		Name:             "upload_data_to_system_schema",
+		Label:            "Upload NDJSON or Parquet to entity",
 		OnType:           "world",
 		InstanceOptional: true,
-		InFields:         []api2go.ColumnInfo{},
-		OutFields: []Outcome{
-			{
-				Type:       "__download_cms_config",
-				Method:     "EXECUTE",
-				Attributes: map[string]interface{}{},
-			},
-		},
-	},
-	{
-		Name:             "become_an_administrator",
-		Label:            "Become Daptin Administrator",
-		InstanceOptional: true,
-		OnType:           "world",
-		InFields:         []api2go.ColumnInfo{},
-		OutFields: []Outcome{
+		InFields: []api2go.ColumnInfo{
 			{
-				Type:   "__become_admin",
-				Method: "EXECUTE",
-				Attributes: map[string]interface{}{
-					"user_account_id": "$user.id",
-					"user":            "~user",
-				},
+				Name:       "Data file",
+				ColumnName: "data_file",
+				ColumnType: "file.ndjson|jsonl|parquet",
+				IsNullable: false,
 			},
-		},
-	},
-	{
-		Name:             "signup",
-		Label:            "Sign up",
-		InstanceOptional: true,
-		OnType:           USER_ACCOUNT_TABLE_NAME,
-		InFields: []api2go.ColumnInfo{
 			{
-				Name:       "name",
-				ColumnName: "name",
+				Name:       "Entity name",
+				ColumnName: "entity_name",
 				ColumnType: "label",
 				IsNullable: false,
 			},
 			{
-				Name:       "email",
+				Name:         "Create entity if not exists",
+				ColumnName:   "create_if_not_exists",
+				ColumnType:   "truefalse",
+				DefaultValue: "false",
+				IsNullable:   true,
+			},
+			{
+				Name:         "Add missing columns",
+				ColumnName:   "add_missing_columns",
+				ColumnType:   "truefalse",
+				DefaultValue: "false",
+				IsNullable:   true,
+			},
+			{
+				Name:       "Columns",
+				ColumnName: "columns",
+				ColumnType: "label",
+				IsNullable: true,
+			},
+			{
+				Name:       "Batch size",
+				ColumnName: "batch_size",
+				ColumnType: "measurement",
+				IsNullable: true,
+			},
+		},
+		Validations: []ColumnTag{
+			{
+				ColumnName: "entity_name",
+				Tags:       "required",
+			},
+		},
+		OutFields: []Outcome{
+			{
+				Type:   "__upload_data_file_to_entity",
+				Method: "EXECUTE",
+				Attributes: map[string]interface{}{
+					"data_file":            "~data_file",
+					"entity_name":          "~entity_name",
+					"add_missing_columns":  "~add_missing_columns",
+					"create_if_not_exists": "~create_if_not_exists",
+					"columns":              "~columns",
+					"batch_size":           "~batch_size",
+					"user":                 "~user",
+				},
+			},
+		},
+	},
+	{
+		Name:             "download_system_schema",
+		Label:            "Download system schema",
+		OnType:           "world",
+		InstanceOptional: true,
+		InFields:         []api2go.ColumnInfo{},
+		OutFields: []Outcome{
+			{
+				Type:       "__download_cms_config",
+				Method:     "EXECUTE",
+				Attributes: map[string]interface{}{},
+			},
+		},
+	},
+	{
+		Name:             "become_an_administrator",
+		Label:            "Become Daptin Administrator",
+		InstanceOptional: true,
+		OnType:           "world",
+		InFields:         []api2go.ColumnInfo{},
+		OutFields: []Outcome{
+			{
+				Type:   "__become_admin",
+				Method: "EXECUTE",
+				Attributes: map[string]interface{}{
+					"user_account_id": "$user.id",
+					"user":            "~user",
+				},
+			},
+		},
+	},
+	{
+		Name:             "signup",
+		Label:            "Sign up",
+		InstanceOptional: true,
+		OnType:           USER_ACCOUNT_TABLE_NAME,
+		RunAs:            RunAsAdmin,
+		InFields: []api2go.ColumnInfo{
+			{
+				Name:       "name",
+				ColumnName: "name",
+				ColumnType: "label",
+				IsNullable: false,
+			},
+			{
+				Name:       "email",
 				ColumnName: "email",
 				ColumnType: "email",
 				IsNullable: false,
@@ -1118,6 +1503,7 @@ var SystemActions = []Action{
 		Label:            "Reset password",
 		InstanceOptional: true,
 		OnType:           USER_ACCOUNT_TABLE_NAME,
+		RunAs:            RunAsAdmin,
 		InFields: []api2go.ColumnInfo{
 			{
 				Name:       "email",
@@ -1175,6 +1561,7 @@ var SystemActions = []Action{
 		Label:            "Reset password verify code",
 		InstanceOptional: true,
 		OnType:           USER_ACCOUNT_TABLE_NAME,
+		RunAs:            RunAsAdmin,
 		InFields: []api2go.ColumnInfo{
 			{
 				Name:       "email",
@@ -1300,6 +1687,7 @@ var SystemActions = []Action{
 		Label:            "Handle OAuth login response code and state",
 		InstanceOptional: true,
 		OnType:           "oauth_token",
+		RunAs:            RunAsAdmin,
 		InFields: []api2go.ColumnInfo{
 			{
 				Name:       "code",
@@ -1423,6 +1811,7 @@ var SystemActions = []Action{
 		Name:             "add_exchange",
 		Label:            "Add new data exchange",
 		OnType:           "world",
+		RunAs:            RunAsAdmin,
 		InstanceOptional: false,
 		InFields: []api2go.ColumnInfo{
 			{
@@ -2915,6 +3304,470 @@ var StandardTables = []TableInfo{
 			},
 		},
 	},
+	{
+		TableName:     "schema_migration",
+		IsHidden:      true,
+		DefaultGroups: adminsGroup,
+		Icon:          "fa-history",
+		Columns: []api2go.ColumnInfo{
+			{
+				Name:       "version",
+				ColumnName: "version",
+				DataType:   "int(11)",
+				ColumnType: "measurement",
+				IsIndexed:  true,
+				IsUnique:   true,
+			},
+			{
+				Name:       "checksum",
+				ColumnName: "checksum",
+				DataType:   "varchar(64)",
+				ColumnType: "label",
+			},
+			{
+				Name:       "author",
+				ColumnName: "author",
+				DataType:   "varchar(200)",
+				ColumnType: "label",
+			},
+			{
+				Name:       "source",
+				ColumnName: "source",
+				DataType:   "varchar(200)",
+				ColumnType: "label",
+			},
+			{
+				Name:         "status",
+				ColumnName:   "status",
+				DataType:     "varchar(20)",
+				ColumnType:   "label",
+				DefaultValue: "'applied'",
+			},
+			{
+				Name:         "system_version",
+				ColumnName:   "system_version",
+				DataType:     "int(11)",
+				ColumnType:   "measurement",
+				DefaultValue: "1",
+			},
+			{
+				Name:       "schema_json",
+				ColumnName: "schema_json",
+				DataType:   "text",
+				ColumnType: "json",
+				IsNullable: true,
+			},
+			{
+				Name:       "up_sql",
+				ColumnName: "up_sql",
+				DataType:   "text",
+				ColumnType: "content",
+				IsNullable: true,
+			},
+			{
+				Name:       "down_sql",
+				ColumnName: "down_sql",
+				DataType:   "text",
+				ColumnType: "content",
+				IsNullable: true,
+			},
+		},
+	},
+	{
+		TableName:     "sequence_counter",
+		IsHidden:      true,
+		DefaultGroups: adminsGroup,
+		Icon:          "fa-sort-numeric-asc",
+		Columns: []api2go.ColumnInfo{
+			{
+				Name:       "sequence_key",
+				ColumnName: "sequence_key",
+				DataType:   "varchar(300)",
+				ColumnType: "label",
+				IsIndexed:  true,
+				IsUnique:   true,
+			},
+			{
+				Name:       "table_name",
+				ColumnName: "table_name",
+				DataType:   "varchar(100)",
+				ColumnType: "label",
+			},
+			{
+				Name:       "column_name",
+				ColumnName: "column_name",
+				DataType:   "varchar(100)",
+				ColumnType: "label",
+			},
+			{
+				Name:       "period",
+				ColumnName: "period",
+				DataType:   "varchar(10)",
+				ColumnType: "label",
+				IsNullable: true,
+			},
+			{
+				Name:         "last_value",
+				ColumnName:   "last_value",
+				DataType:     "int(11)",
+				ColumnType:   "measurement",
+				DefaultValue: "0",
+			},
+		},
+	},
+	{
+		TableName:     "import_job",
+		IsHidden:      true,
+		DefaultGroups: adminsGroup,
+		Icon:          "fa-upload",
+		Columns: []api2go.ColumnInfo{
+			{
+				Name:       "entity_name",
+				ColumnName: "entity_name",
+				DataType:   "varchar(100)",
+				ColumnType: "label",
+			},
+			{
+				Name:       "file_name",
+				ColumnName: "file_name",
+				DataType:   "varchar(500)",
+				ColumnType: "label",
+			},
+			{
+				Name:       "file_path",
+				ColumnName: "file_path",
+				DataType:   "varchar(1000)",
+				ColumnType: "label",
+			},
+			{
+				Name:       "file_type",
+				ColumnName: "file_type",
+				DataType:   "varchar(10)",
+				ColumnType: "label",
+			},
+			{
+				Name:         "status",
+				ColumnName:   "status",
+				DataType:     "varchar(20)",
+				ColumnType:   "label",
+				IsIndexed:    true,
+				DefaultValue: "'pending'",
+			},
+			{
+				Name:         "batch_size",
+				ColumnName:   "batch_size",
+				DataType:     "int(11)",
+				ColumnType:   "measurement",
+				DefaultValue: "1000",
+			},
+			{
+				Name:         "total_rows",
+				ColumnName:   "total_rows",
+				DataType:     "int(11)",
+				ColumnType:   "measurement",
+				DefaultValue: "0",
+			},
+			{
+				Name:         "processed_rows",
+				ColumnName:   "processed_rows",
+				DataType:     "int(11)",
+				ColumnType:   "measurement",
+				DefaultValue: "0",
+			},
+			{
+				Name:         "imported_rows",
+				ColumnName:   "imported_rows",
+				DataType:     "int(11)",
+				ColumnType:   "measurement",
+				DefaultValue: "0",
+			},
+			{
+				Name:         "failed_rows",
+				ColumnName:   "failed_rows",
+				DataType:     "int(11)",
+				ColumnType:   "measurement",
+				DefaultValue: "0",
+			},
+			{
+				Name:       "error_report",
+				ColumnName: "error_report",
+				DataType:   "text",
+				ColumnType: "content",
+				IsNullable: true,
+			},
+			{
+				Name:       "last_error",
+				ColumnName: "last_error",
+				DataType:   "text",
+				ColumnType: "content",
+				IsNullable: true,
+			},
+			{
+				Name:       "finished_at",
+				ColumnName: "finished_at",
+				DataType:   "timestamp",
+				ColumnType: "datetime",
+				IsNullable: true,
+			},
+		},
+	},
+	{
+		TableName:     "action_job",
+		IsHidden:      true,
+		DefaultGroups: adminsGroup,
+		Icon:          "fa-tasks",
+		Columns: []api2go.ColumnInfo{
+			{
+				Name:       "action_name",
+				ColumnName: "action_name",
+				DataType:   "varchar(100)",
+				ColumnType: "label",
+			},
+			{
+				Name:       "on_type",
+				ColumnName: "on_type",
+				DataType:   "varchar(100)",
+				ColumnType: "label",
+			},
+			{
+				Name:         "status",
+				ColumnName:   "status",
+				DataType:     "varchar(20)",
+				ColumnType:   "label",
+				IsIndexed:    true,
+				DefaultValue: "'pending'",
+			},
+			{
+				Name:           "request",
+				ColumnName:     "request",
+				DataType:       "text",
+				ColumnType:     "json",
+				IsNullable:     true,
+				ExcludeFromApi: true,
+			},
+			{
+				Name:       "responses",
+				ColumnName: "responses",
+				DataType:   "text",
+				ColumnType: "json",
+				IsNullable: true,
+			},
+			{
+				Name:       "last_error",
+				ColumnName: "last_error",
+				DataType:   "text",
+				ColumnType: "content",
+				IsNullable: true,
+			},
+			{
+				Name:       "started_at",
+				ColumnName: "started_at",
+				DataType:   "timestamp",
+				ColumnType: "datetime",
+				IsNullable: true,
+			},
+			{
+				Name:       "finished_at",
+				ColumnName: "finished_at",
+				DataType:   "timestamp",
+				ColumnType: "datetime",
+				IsNullable: true,
+			},
+		},
+	},
+	{
+		TableName:     "idempotency_key",
+		IsHidden:      true,
+		DefaultGroups: adminsGroup,
+		Icon:          "fa-key",
+		Columns: []api2go.ColumnInfo{
+			{
+				Name:       "idempotency_key",
+				ColumnName: "idempotency_key",
+				DataType:   "varchar(64)",
+				ColumnType: "label",
+				IsUnique:   true,
+				IsIndexed:  true,
+			},
+			{
+				Name:       "request_hash",
+				ColumnName: "request_hash",
+				DataType:   "varchar(64)",
+				ColumnType: "label",
+			},
+			{
+				Name:         "response_status",
+				ColumnName:   "response_status",
+				DataType:     "int(11)",
+				ColumnType:   "measurement",
+				DefaultValue: "0",
+			},
+			{
+				Name:       "content_type",
+				ColumnName: "content_type",
+				DataType:   "varchar(100)",
+				ColumnType: "label",
+				IsNullable: true,
+			},
+			{
+				Name:           "response",
+				ColumnName:     "response",
+				DataType:       "text",
+				ColumnType:     "content",
+				IsNullable:     true,
+				ExcludeFromApi: true,
+			},
+			{
+				Name:       "expires_at",
+				ColumnName: "expires_at",
+				DataType:   "timestamp",
+				ColumnType: "datetime",
+				IsIndexed:  true,
+			},
+		},
+	},
+	{
+		TableName:     "action_execution",
+		IsHidden:      true,
+		DefaultGroups: adminsGroup,
+		Icon:          "fa-history",
+		Columns: []api2go.ColumnInfo{
+			{
+				Name:       "action_name",
+				ColumnName: "action_name",
+				DataType:   "varchar(100)",
+				ColumnType: "label",
+				IsIndexed:  true,
+			},
+			{
+				Name:       "on_type",
+				ColumnName: "on_type",
+				DataType:   "varchar(100)",
+				ColumnType: "label",
+			},
+			{
+				Name:       "subject_id",
+				ColumnName: "subject_id",
+				DataType:   "varchar(100)",
+				ColumnType: "label",
+				IsNullable: true,
+			},
+			{
+				Name:       "channel",
+				ColumnName: "channel",
+				DataType:   "varchar(20)",
+				ColumnType: "label",
+				IsIndexed:  true,
+			},
+			{
+				Name:       "status",
+				ColumnName: "status",
+				DataType:   "varchar(20)",
+				ColumnType: "label",
+				IsIndexed:  true,
+			},
+			{
+				Name:       "inputs",
+				ColumnName: "inputs",
+				DataType:   "text",
+				ColumnType: "json",
+				IsNullable: true,
+			},
+			{
+				Name:       "outcomes",
+				ColumnName: "outcomes",
+				DataType:   "text",
+				ColumnType: "json",
+				IsNullable: true,
+			},
+			{
+				Name:       "error",
+				ColumnName: "error",
+				DataType:   "text",
+				ColumnType: "content",
+				IsNullable: true,
+			},
+			{
+				Name:         "duration_ms",
+				ColumnName:   "duration_ms",
+				DataType:     "int(11)",
+				ColumnType:   "measurement",
+				DefaultValue: "0",
+			},
+			{
+				Name:       "started_at",
+				ColumnName: "started_at",
+				DataType:   "timestamp",
+				ColumnType: "datetime",
+				IsNullable: true,
+			},
+		},
+	},
+	{
+		TableName:     "webhook_delivery",
+		IsHidden:      true,
+		DefaultGroups: adminsGroup,
+		Icon:          "fa-inbox",
+		Columns: []api2go.ColumnInfo{
+			{
+				Name:       "webhook_name",
+				ColumnName: "webhook_name",
+				DataType:   "varchar(100)",
+				ColumnType: "label",
+				IsIndexed:  true,
+			},
+			{
+				Name:       "delivery_id",
+				ColumnName: "delivery_id",
+				DataType:   "varchar(255)",
+				ColumnType: "label",
+				IsNullable: true,
+			},
+			{
+				Name:       "delivery_key",
+				ColumnName: "delivery_key",
+				DataType:   "varchar(64)",
+				ColumnType: "label",
+				IsUnique:   true,
+				IsNullable: true,
+			},
+			{
+				Name:       "status",
+				ColumnName: "status",
+				DataType:   "varchar(20)",
+				ColumnType: "label",
+				IsIndexed:  true,
+			},
+			{
+				Name:         "response_status",
+				ColumnName:   "response_status",
+				DataType:     "int(11)",
+				ColumnType:   "measurement",
+				DefaultValue: "0",
+			},
+			{
+				Name:       "payload",
+				ColumnName: "payload",
+				DataType:   "text",
+				ColumnType: "json",
+				IsNullable: true,
+			},
+			{
+				Name:       "error",
+				ColumnName: "error",
+				DataType:   "text",
+				ColumnType: "content",
+				IsNullable: true,
+			},
+			{
+				Name:         "duration_ms",
+				ColumnName:   "duration_ms",
+				DataType:     "int(11)",
+				ColumnType:   "measurement",
+				DefaultValue: "0",
+			},
+		},
+	},
 }
 
 //var StandardMarketplaces = []Marketplace{
@@ -0,0 +1,473 @@
+package resource
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/artpar/api2go"
+	fieldtypes "github.com/daptin/daptin/server/columntypes"
+	daptinid "github.com/daptin/daptin/server/id"
+	"github.com/google/uuid"
+	"github.com/xitongsys/parquet-go-source/buffer"
+	"github.com/xitongsys/parquet-go-source/local"
+	"github.com/xitongsys/parquet-go/reader"
+	"github.com/xitongsys/parquet-go/source"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+// Rows are exported and imported as a json dump, newline delimited json (one object per line) or parquet. Values are
+// mapped to and from the data type of their column, and rows are read and written one at a time so whole tables are
+// never held in memory.
+
+const (
+	DataFormatJson    = "json"
+	DataFormatNdjson  = "ndjson"
+	DataFormatParquet = "parquet"
+)
+
+// parquetReadBatchSize is the number of rows read from a parquet file at once
+const parquetReadBatchSize = 1000
+
+// DataFormatFromFileName identifies the format of a data file by its extension
+func DataFormatFromFileName(fileName string) string {
+	fileName = strings.ToLower(fileName)
+	switch {
+	case strings.HasSuffix(fileName, ".ndjson"), strings.HasSuffix(fileName, ".jsonl"):
+		return DataFormatNdjson
+	case strings.HasSuffix(fileName, ".parquet"):
+		return DataFormatParquet
+	}
+	return DataFormatJson
+}
+
+// SelectedColumns reads a comma separated list of column names, empty when all columns are wanted
+func SelectedColumns(value interface{}) []string {
+	columnsString, ok := value.(string)
+	if !ok {
+		return nil
+	}
+	columns := make([]string, 0)
+	for _, column := range strings.Split(columnsString, ",") {
+		column = strings.TrimSpace(column)
+		if column != "" {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+// dataTypeKind groups the database data types of columns into the kinds of values they hold
+func dataTypeKind(dataType string) string {
+	dataType = strings.ToLower(dataType)
+	if i := strings.Index(dataType, "("); i > -1 {
+		dataType = dataType[:i]
+	}
+	switch strings.TrimSpace(dataType) {
+	case "int", "integer", "bigint", "smallint", "tinyint", "mediumint", "serial", "bigserial":
+		return "int"
+	case "float", "double", "decimal", "numeric", "real", "double precision":
+		return "float"
+	case "bool", "boolean":
+		return "bool"
+	case "blob", "longblob", "mediumblob", "binary", "varbinary", "bytea":
+		return "binary"
+	}
+	return "string"
+}
+
+// ParquetSchema is the parquet schema of the columns, every column is optional
+func ParquetSchema(columns []api2go.ColumnInfo) (string, error) {
+	fields := make([]map[string]interface{}, 0)
+	for _, col := range columns {
+		if strings.ContainsAny(col.ColumnName, ",=") {
+			return "", fmt.Errorf("column name [%v] cannot be written to parquet", col.ColumnName)
+		}
+		tag := fmt.Sprintf("name=%v, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL", col.ColumnName)
+		switch dataTypeKind(col.DataType) {
+		case "int":
+			tag = fmt.Sprintf("name=%v, type=INT64, repetitiontype=OPTIONAL", col.ColumnName)
+		case "float":
+			tag = fmt.Sprintf("name=%v, type=DOUBLE, repetitiontype=OPTIONAL", col.ColumnName)
+		case "bool":
+			tag = fmt.Sprintf("name=%v, type=BOOLEAN, repetitiontype=OPTIONAL", col.ColumnName)
+		}
+		fields = append(fields, map[string]interface{}{"Tag": tag})
+	}
+
+	schema, err := json.Marshal(map[string]interface{}{
+		"Tag":    "name=daptin_row, repetitiontype=REQUIRED",
+		"Fields": fields,
+	})
+	return string(schema), err
+}
+
+// ExportValue converts a value read from the database into the value written to a file for its column. Reference
+// ids are written as uuid strings and times in RFC 3339.
+func ExportValue(col api2go.ColumnInfo, value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case daptinid.DaptinReferenceId:
+		return uuid.UUID(v).String()
+	case uuid.UUID:
+		return v.String()
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case []byte:
+		if col.ColumnName == "reference_id" && len(v) == 16 {
+			return uuid.UUID(*(*[16]byte)(v)).String()
+		}
+		value = string(v)
+	}
+
+	switch dataTypeKind(col.DataType) {
+	case "int":
+		if i, err := strconv.ParseInt(fmt.Sprintf("%v", value), 10, 64); err == nil {
+			return i
+		}
+	case "float":
+		if f, err := strconv.ParseFloat(fmt.Sprintf("%v", value), 64); err == nil {
+			return f
+		}
+	case "bool":
+		return toBool(value)
+	}
+	return value
+}
+
+// plainValue keeps nested objects and lists as json text and numbers decoded from json text as the text
+func plainValue(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case map[string]interface{}, []interface{}:
+		return json.MarshalToString(v)
+	case fmt.Stringer:
+		return v.String(), nil
+	}
+	return value, nil
+}
+
+// ImportValue converts a value read from a file into the type of data held by its column
+func ImportValue(col api2go.ColumnInfo, value interface{}) (interface{}, error) {
+	value, err := plainValue(value)
+	if value == nil || err != nil {
+		return nil, err
+	}
+
+	stringValue := fmt.Sprintf("%v", value)
+	switch dataTypeKind(col.DataType) {
+	case "int":
+		if stringValue == "" {
+			return nil, nil
+		}
+		i, err := strconv.ParseInt(stringValue, 10, 64)
+		if err != nil {
+			f, floatErr := strconv.ParseFloat(stringValue, 64)
+			if floatErr != nil || f != float64(int64(f)) {
+				return nil, fmt.Errorf("%v must be a whole number, got [%v]", col.ColumnName, stringValue)
+			}
+			i = int64(f)
+		}
+		return i, nil
+	case "float":
+		if stringValue == "" {
+			return nil, nil
+		}
+		f, err := strconv.ParseFloat(stringValue, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%v must be a number, got [%v]", col.ColumnName, stringValue)
+		}
+		return f, nil
+	case "bool":
+		return toBool(value), nil
+	}
+	return value, nil
+}
+
+func toBool(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case int64:
+		return v != 0
+	case int:
+		return v != 0
+	case float64:
+		return v != 0
+	}
+	parsed, _ := strconv.ParseBool(strings.TrimSpace(fmt.Sprintf("%v", value)))
+	return parsed
+}
+
+// selectColumns keeps the values of the selected columns, all values when no columns are selected
+func selectColumns(row map[string]interface{}, columns []string) map[string]interface{} {
+	if len(columns) == 0 {
+		return row
+	}
+	selected := make(map[string]interface{}, len(columns))
+	for _, column := range columns {
+		if value, ok := row[column]; ok {
+			selected[column] = value
+		}
+	}
+	return selected
+}
+
+// ndjsonMaxLineSize is the size of the longest line read from a ndjson file
+const ndjsonMaxLineSize = 64 * 1024 * 1024
+
+// ReadNdjsonRows calls handleRow for each object of a newline delimited json stream, blank lines are skipped
+func ReadNdjsonRows(input io.Reader, handleRow func(row map[string]interface{}) error) error {
+	scanner := bufio.NewScanner(input)
+	scanner.Buffer(make([]byte, 0, 64*1024), ndjsonMaxLineSize)
+	for line := 1; scanner.Scan(); line++ {
+		lineBytes := bytes.TrimSpace(scanner.Bytes())
+		if len(lineBytes) == 0 {
+			continue
+		}
+
+		row := make(map[string]interface{})
+		decoder := json.NewDecoder(bytes.NewReader(lineBytes))
+		decoder.UseNumber()
+		err := decoder.Decode(&row)
+		if err != nil {
+			return fmt.Errorf("line %d is not a json object: %v", line, err)
+		}
+		err = handleRow(row)
+		if err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// ReadParquetRows calls handleRow for each row of a parquet file, reading the file a batch of rows at a time
+func ReadParquetRows(file source.ParquetFile, handleRow func(row map[string]interface{}) error) error {
+	parquetReader, err := reader.NewParquetReader(file, nil, 1)
+	if err != nil {
+		return err
+	}
+	defer parquetReader.ReadStop()
+
+	// the rows are read into structs with exported field names, the columns have the names in the file
+	columnNames := make(map[string]string)
+	for _, info := range parquetReader.SchemaHandler.Infos {
+		columnNames[info.InName] = info.ExName
+	}
+
+	remaining := int(parquetReader.GetNumRows())
+	for remaining > 0 {
+		count := parquetReadBatchSize
+		if count > remaining {
+			count = remaining
+		}
+		rows, err := parquetReader.ReadByNumber(count)
+		if err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return errors.New("parquet file has fewer rows than its footer says")
+		}
+		remaining -= len(rows)
+
+		for _, parquetRow := range rows {
+			row := make(map[string]interface{})
+			structValue := reflect.ValueOf(parquetRow)
+			for i := 0; i < structValue.NumField(); i++ {
+				field := structValue.Field(i)
+				if field.Kind() == reflect.Ptr {
+					if field.IsNil() {
+						continue
+					}
+					field = field.Elem()
+				}
+				name := structValue.Type().Field(i).Name
+				if columnName, ok := columnNames[name]; ok {
+					name = columnName
+				}
+				row[name] = field.Interface()
+			}
+			err = handleRow(row)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ReadDataFileRows calls handleRow for each row of a ndjson or parquet file
+func ReadDataFileRows(fileBytes []byte, format string, handleRow func(row map[string]interface{}) error) error {
+	switch format {
+	case DataFormatNdjson:
+		return ReadNdjsonRows(bytes.NewReader(fileBytes), handleRow)
+	case DataFormatParquet:
+		file, err := buffer.NewBufferFile(fileBytes)
+		if err != nil {
+			return err
+		}
+		return ReadParquetRows(file, handleRow)
+	}
+	return fmt.Errorf("rows cannot be streamed from [%v]", format)
+}
+
+// ReadDataFileRowsFromFile calls handleRow for each row of a ndjson or parquet file, without reading the whole file
+// into memory
+func ReadDataFileRowsFromFile(filePath string, format string, handleRow func(row map[string]interface{}) error) error {
+	switch format {
+	case DataFormatNdjson:
+		file, err := os.Open(filePath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		return ReadNdjsonRows(file, handleRow)
+	case DataFormatParquet:
+		file, err := local.NewLocalFileReader(filePath)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		return ReadParquetRows(file, handleRow)
+	}
+	return fmt.Errorf("rows cannot be streamed from [%v]", format)
+}
+
+// ImportRowValues converts the values of a row read from a file to the data types of the columns of the table. Values
+// of unknown columns are dropped and reference ids are read from their uuid text.
+func ImportRowValues(row map[string]interface{}, table *TableInfo) (map[string]interface{}, error) {
+	values := make(map[string]interface{}, len(row))
+	for columnName, value := range row {
+		col, ok := table.GetColumnByName(columnName)
+		if !ok {
+			continue
+		}
+		if columnName == "reference_id" {
+			if referenceId, isString := value.(string); isString {
+				u, err := uuid.Parse(referenceId)
+				if err != nil {
+					return nil, fmt.Errorf("reference_id [%v] is not a uuid", referenceId)
+				}
+				values[columnName] = u[:]
+				continue
+			}
+		}
+		importValue, err := ImportValue(*col, value)
+		if err != nil {
+			return nil, err
+		}
+		values[columnName] = importValue
+	}
+	return values, nil
+}
+
+// DataRowWriter writes rows to a file in one of the data formats
+type DataRowWriter interface {
+	Write(row map[string]interface{}) error
+	Close() error
+}
+
+type ndjsonRowWriter struct {
+	writer *bufio.Writer
+}
+
+func (w *ndjsonRowWriter) Write(row map[string]interface{}) error {
+	line, err := json.Marshal(row)
+	if err != nil {
+		return err
+	}
+	_, err = w.writer.Write(append(line, '\n'))
+	return err
+}
+
+func (w *ndjsonRowWriter) Close() error {
+	return w.writer.Flush()
+}
+
+type parquetRowWriter struct {
+	writer  *writer.JSONWriter
+	columns []api2go.ColumnInfo
+}
+
+func (w *parquetRowWriter) Write(row map[string]interface{}) error {
+	values := make(map[string]interface{}, len(w.columns))
+	for _, col := range w.columns {
+		value := ExportValue(col, row[col.ColumnName])
+		if value == nil {
+			continue
+		}
+		if dataTypeKind(col.DataType) == "string" || dataTypeKind(col.DataType) == "binary" {
+			if _, isString := value.(string); !isString {
+				jsonValue, err := json.MarshalToString(value)
+				if err != nil {
+					return err
+				}
+				value = jsonValue
+			}
+		}
+		values[col.ColumnName] = value
+	}
+	jsonRow, err := json.MarshalToString(values)
+	if err != nil {
+		return err
+	}
+	return w.writer.Write(jsonRow)
+}
+
+func (w *parquetRowWriter) Close() error {
+	return w.writer.WriteStop()
+}
+
+// NewDataRowWriter writes rows of the columns in the format, a parquet file has the schema of the columns
+func NewDataRowWriter(output io.Writer, format string, columns []api2go.ColumnInfo) (DataRowWriter, error) {
+	switch format {
+	case DataFormatNdjson:
+		return &ndjsonRowWriter{writer: bufio.NewWriter(output)}, nil
+	case DataFormatParquet:
+		schema, err := ParquetSchema(columns)
+		if err != nil {
+			return nil, err
+		}
+		parquetWriter, err := writer.NewJSONWriterFromWriter(schema, output, 1)
+		if err != nil {
+			return nil, err
+		}
+		return &parquetRowWriter{writer: parquetWriter, columns: columns}, nil
+	}
+	return nil, fmt.Errorf("rows cannot be streamed as [%v]", format)
+}
+
+// InferColumn identifies the type of a column from the distinct values found in it, the same way for every kind
+// of uploaded file
+func InferColumn(colName string, values []string, recordCount int, isNullable bool) api2go.ColumnInfo {
+	var column api2go.ColumnInfo
+
+	eType, _, err := fieldtypes.DetectType(values)
+	if err != nil {
+		column.ColumnType = "label"
+		column.DataType = "varchar(100)"
+	} else {
+		column.ColumnType = EntityTypeToColumnTypeMap[eType]
+		column.DataType = entityTypeToDataTypeMap[eType]
+	}
+
+	if len(values) > (recordCount / 10) {
+		column.IsIndexed = true
+	}
+
+	if len(values) == recordCount {
+		column.IsUnique = true
+	}
+
+	column.IsNullable = isNullable
+	column.Name = colName
+	column.ColumnName = SmallSnakeCaseText(colName)
+	return column
+}
@@ -0,0 +1,96 @@
+package resource
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/artpar/api2go"
+)
+
+func TestParquetRoundTrip(t *testing.T) {
+
+	columns := []api2go.ColumnInfo{
+		{ColumnName: "title", DataType: "varchar(100)"},
+		{ColumnName: "quantity", DataType: "int(11)"},
+		{ColumnName: "price", DataType: "float(7,4)"},
+		{ColumnName: "in_stock", DataType: "bool"},
+	}
+
+	var output bytes.Buffer
+	rowWriter, err := NewDataRowWriter(&output, DataFormatParquet, columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = rowWriter.Write(map[string]interface{}{"title": "first", "quantity": "3", "price": 1.5, "in_stock": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = rowWriter.Write(map[string]interface{}{"title": "second"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = rowWriter.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows := make([]map[string]interface{}, 0)
+	err = ReadDataFileRows(output.Bytes(), DataFormatParquet, func(row map[string]interface{}) error {
+		rows = append(rows, row)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rows) != 2 {
+		t.Fatalf("expected 2 rows, got %d", len(rows))
+	}
+	if rows[0]["title"] != "first" || rows[0]["quantity"] != int64(3) || rows[0]["price"] != 1.5 || rows[0]["in_stock"] != true {
+		t.Errorf("unexpected first row %v", rows[0])
+	}
+	if _, ok := rows[1]["quantity"]; ok || rows[1]["title"] != "second" {
+		t.Errorf("missing values should be left out, got %v", rows[1])
+	}
+}
+
+func TestImportNdjsonValues(t *testing.T) {
+
+	table := &TableInfo{
+		Columns: []api2go.ColumnInfo{
+			{ColumnName: "reference_id", DataType: "blob"},
+			{ColumnName: "quantity", DataType: "int(11)"},
+			{ColumnName: "tags", DataType: "text"},
+		},
+	}
+	input := "{\"reference_id\":\"0190a0b6-4c3c-7c8e-9a3e-2f3d4a5b6c7d\",\"quantity\":4.0,\"tags\":[\"a\"],\"other\":1}\n\n" +
+		"{\"quantity\":\"many\"}\n"
+
+	results := make([]map[string]interface{}, 0)
+	errs := make([]error, 0)
+	err := ReadDataFileRows([]byte(input), DataFormatNdjson, func(row map[string]interface{}) error {
+		values, err := ImportRowValues(row, table)
+		results = append(results, values)
+		errs = append(errs, err)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("expected 2 rows, got %d", len(results))
+	}
+	if errs[0] != nil {
+		t.Fatal(errs[0])
+	}
+	if results[0]["quantity"] != int64(4) || results[0]["tags"] != "[\"a\"]" || len(results[0]["reference_id"].([]byte)) != 16 {
+		t.Errorf("unexpected values %v", results[0])
+	}
+	if _, ok := results[0]["other"]; ok {
+		t.Errorf("unknown columns should be dropped, got %v", results[0])
+	}
+	if errs[1] == nil {
+		t.Errorf("expected an error for a quantity which is not a number")
+	}
+}
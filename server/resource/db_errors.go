@@ -0,0 +1,34 @@
+package resource
+
+import (
+	"errors"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+)
+
+// mysqlDuplicateEntry is the error number of mysql for a duplicate value of a unique key
+const mysqlDuplicateEntry = 1062
+
+// postgresUniqueViolation is the sql state of postgres for a duplicate value of a unique key
+const postgresUniqueViolation = "23505"
+
+// IsUniqueConstraintError is true when the statement failed because a row with the same unique value exists, for
+// each of the databases daptin runs on
+func IsUniqueConstraintError(err error) bool {
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) {
+		return sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique ||
+			sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey
+	}
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == mysqlDuplicateEntry
+	}
+	var postgresErr *pq.Error
+	if errors.As(err, &postgresErr) {
+		return postgresErr.Code == postgresUniqueViolation
+	}
+	return false
+}
@@ -284,7 +284,7 @@ func PrintRelations(relations []api2go.TableRelation) {
 
 }
 
-func CheckAllTableStatus(initConfig *CmsConfig, db database.DatabaseConnection) {
+func CheckAllTableStatus(initConfig *CmsConfig, db database.DatabaseConnection, schemaChanges *SchemaChangeLog) {
 
 	var tables []TableInfo
 	tableCreatedMap := map[string]bool{}
@@ -296,7 +296,7 @@ func CheckAllTableStatus(initConfig *CmsConfig, db database.DatabaseConnection)
 
 		if !tableCreatedMap[table.TableName] {
 			log.Tracef("Check table %v", table.TableName)
-			err := CheckTable(&table, db)
+			err := CheckTable(&table, db, schemaChanges)
 			if err != nil {
 				CheckErr(err, "Failed to check and create table: [%v]", table.TableName)
 			} else {
@@ -343,7 +343,7 @@ func CreateAMapOfColumnsWeWantInTheFinalTable(tableInfo *TableInfo) (map[string]
 	return columnsWeWant, colInfoMap
 }
 
-func CheckTable(tableInfo *TableInfo, db database.DatabaseConnection) error {
+func CheckTable(tableInfo *TableInfo, db database.DatabaseConnection, schemaChanges *SchemaChangeLog) error {
 
 	for i, c := range tableInfo.Columns {
 		if c.ColumnType == "truefalse" {
@@ -366,7 +366,7 @@ func CheckTable(tableInfo *TableInfo, db database.DatabaseConnection) error {
 	if err != nil {
 		// expected error, no need to log
 		log.Tracef("Failed to select * from %v: %v", tableInfo.TableName, err)
-		err = CreateTable(tableInfo, db)
+		err = CreateTable(tableInfo, db, schemaChanges)
 		return err
 	} else {
 		defer stmt1.Close()
@@ -410,6 +410,7 @@ func CheckTable(tableInfo *TableInfo, db database.DatabaseConnection) error {
 				log.Errorf("Failed to add column [%s] to table [%v]: %v", col, tableInfo.TableName, err)
 				return fmt.Errorf("failed to add column [%s] to table [%v]: %v", col, tableInfo.TableName, err)
 			}
+			schemaChanges.Record(query, fmt.Sprintf("alter table %v drop column %v", tableInfo.TableName, info.ColumnName))
 		}
 	}
 	return nil
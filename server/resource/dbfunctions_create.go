@@ -11,7 +11,7 @@ import (
 	"strings"
 )
 
-func CreateUniqueConstraints(initConfig *CmsConfig, db *sqlx.Tx) {
+func CreateUniqueConstraints(initConfig *CmsConfig, db *sqlx.Tx, schemaChanges *SchemaChangeLog) {
 	log.Printf("Create constraints and indexes")
 
 	existingIndexes := GetExistingIndexes(db)
@@ -45,6 +45,8 @@ func CreateUniqueConstraints(initConfig *CmsConfig, db *sqlx.Tx) {
 					log.Errorf("Table[%v] Column[%v]: Failed to create unique composite key index: %v", table.TableName, compositeKeyCols, err)
 					log.Errorf("Create unique index sql: %v", alterTable)
 					db.Exec("COMMIT ")
+				} else {
+					schemaChanges.Record(alterTable, dropIndexStatement(indexName, table.TableName, db.DriverName()))
 				}
 			}
 		}
@@ -75,12 +77,14 @@ func CreateUniqueConstraints(initConfig *CmsConfig, db *sqlx.Tx) {
 			if err != nil {
 				log.Infof("Table[%v] Column[%v]: unique join index already exists: %v", table.TableName, cols, err)
 				db.Exec("COMMIT ")
+			} else {
+				schemaChanges.Record(alterTable, dropIndexStatement(indexName, table.TableName, db.DriverName()))
 			}
 		}
 	}
 }
 
-func CreateIndexes(initConfig *CmsConfig, db database.DatabaseConnection) {
+func CreateIndexes(initConfig *CmsConfig, db database.DatabaseConnection, schemaChanges *SchemaChangeLog) {
 	log.Infof("Create indexes")
 
 	transaction, err := db.Beginx()
@@ -106,6 +110,8 @@ func CreateIndexes(initConfig *CmsConfig, db database.DatabaseConnection) {
 				_, err := db.Exec(alterTable)
 				if err != nil {
 					log.Infof("New index not created on Table[%v][%v]: %v", table.TableName, column.ColumnName, err)
+				} else {
+					schemaChanges.Record(alterTable, dropIndexStatement(indexName, table.TableName, db.DriverName()))
 				}
 			} else if column.IsIndexed {
 				indexName := "i" + GetMD5HashString("index_"+table.TableName+"_"+column.ColumnName+"_index")
@@ -118,6 +124,8 @@ func CreateIndexes(initConfig *CmsConfig, db database.DatabaseConnection) {
 				_, err := db.Exec(alterTable)
 				if err != nil {
 					log.Printf("New index not created on Table[%v] Column[%v]: %v", table.TableName, column.ColumnName, err)
+				} else {
+					schemaChanges.Record(alterTable, dropIndexStatement(indexName, table.TableName, db.DriverName()))
 				}
 			}
 		}
@@ -268,9 +276,16 @@ func CheckTranslationTables(config *CmsConfig) {
 				createTranslationTableFor = append(createTranslationTableFor, table.TableName)
 			}
 		} else {
-			if len(table.Columns) > len(existingTranslationTable.Columns) {
-				log.Printf("New columns added to the table, translation table need to be updated")
-				updateTranslationTableFor = append(updateTranslationTableFor, table.TableName)
+			translationColumns := make(map[string]bool)
+			for _, col := range existingTranslationTable.Columns {
+				translationColumns[col.ColumnName] = true
+			}
+			for _, col := range table.Columns {
+				if col.ColumnName != "id" && !translationColumns[col.ColumnName] {
+					log.Printf("New columns added to the table, translation table need to be updated")
+					updateTranslationTableFor = append(updateTranslationTableFor, table.TableName)
+					break
+				}
 			}
 		}
 
@@ -345,38 +360,37 @@ func CheckTranslationTables(config *CmsConfig) {
 	for _, tableName := range updateTranslationTableFor {
 
 		table := tableMap[tableName]
-		auditTable := tableMap[tableName+"_audit"]
-		existingColumns := auditTable.Columns
+		translationTable := tableMap[tableName+"_i18n"]
 
-		existingColumnMap := make(map[string]api2go.ColumnInfo)
-		for _, col := range existingColumns {
-			existingColumnMap[col.Name] = col
-		}
-
-		tableColumnMap := make(map[string]api2go.ColumnInfo)
-		for _, col := range table.Columns {
-			tableColumnMap[col.Name] = col
+		existingColumnMap := make(map[string]bool)
+		for _, col := range translationTable.Columns {
+			existingColumnMap[col.ColumnName] = true
 		}
 
 		newColsToAdd := make([]api2go.ColumnInfo, 0)
 
-		for _, newCols := range table.Columns {
+		for _, newCol := range table.Columns {
 
-			_, ok := existingColumnMap[newCols.Name]
-			if !ok {
-				var newAuditCol api2go.ColumnInfo
-				err := copier.Copy(&newAuditCol, &newCols)
-				CheckErr(err, "Error while copying value from new audit column")
-				newColsToAdd = append(newColsToAdd, newAuditCol)
+			if newCol.ColumnName == "id" || existingColumnMap[newCol.ColumnName] {
+				continue
 			}
 
+			var newTranslationCol api2go.ColumnInfo
+			err := copier.Copy(&newTranslationCol, &newCol)
+			CheckErr(err, "Error while copying value from new translation column")
+			newTranslationCol.IsNullable = true
+			newTranslationCol.IsUnique = false
+			newTranslationCol.IsForeignKey = false
+			newTranslationCol.ForeignKeyData = api2go.ForeignKeyData{}
+			newColsToAdd = append(newColsToAdd, newTranslationCol)
+
 		}
 
 		if len(newColsToAdd) > 0 {
 
 			for i := range config.Tables {
 
-				if config.Tables[i].TableName == auditTable.TableName {
+				if config.Tables[i].TableName == translationTable.TableName {
 					config.Tables[i].Columns = append(config.Tables[i].Columns, newColsToAdd...)
 				}
 			}
@@ -733,7 +747,7 @@ func alterTableAddColumn(tableName string, colInfo *api2go.ColumnInfo, sqlDriver
 	return sq
 }
 
-func CreateTable(tableInfo *TableInfo, db database.DatabaseConnection) error {
+func CreateTable(tableInfo *TableInfo, db database.DatabaseConnection, schemaChanges *SchemaChangeLog) error {
 
 	createTableQuery := MakeCreateTableQuery(tableInfo, db.DriverName())
 
@@ -750,6 +764,7 @@ func CreateTable(tableInfo *TableInfo, db database.DatabaseConnection) error {
 		log.Errorf("[718] Failed to create table [%v]: %v", tableInfo.TableName, err)
 		return fmt.Errorf("failed to create table [%v]: %v", tableInfo.TableName, err)
 	}
+	schemaChanges.Record(createTableQuery, fmt.Sprintf("drop table %v", tableInfo.TableName))
 	return nil
 }
 
@@ -819,6 +834,11 @@ func getColumnLine(c *api2go.ColumnInfo, sqlDriverName string) string {
 		datatype = "bytea"
 	}
 
+	// sqlite would store a decimal as a float, money is kept as text to remain exact
+	if c.ColumnType == "money" && sqlDriverName == "sqlite3" {
+		datatype = "text"
+	}
+
 	columnParams := []string{c.ColumnName, datatype}
 
 	if datatype == "timestamp" && c.DefaultValue == "" {
@@ -0,0 +1,223 @@
+package resource
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/artpar/api2go"
+	"github.com/daptin/daptin/server/database"
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+)
+
+// enumConstraint is a check constraint (postgres) or a trigger (sqlite, mysql) which rejects values of an enum
+// column which are not in its options
+type enumConstraint struct {
+	Name      string
+	TableName string
+	Create    string
+}
+
+// enumConstraintPrefix is shared by all the constraints of a column, the rest of the name is a hash of the allowed
+// values so that adding or removing a value replaces the constraint
+func enumConstraintPrefix(tableName string, columnName string) string {
+	return "enum_" + GetMD5HashString(tableName+"_"+columnName) + "_"
+}
+
+func sqlQuoteValues(values []string) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = "'" + strings.Replace(value, "'", "''", -1) + "'"
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// enumConstraintStatements are the constraints for the values allowed in the column, nil when the column is not an
+// enum or does not list its options
+func enumConstraintStatements(tableName string, column api2go.ColumnInfo, sqlDriverName string) []enumConstraint {
+	values := EnumValues(column)
+	if len(values) == 0 {
+		return nil
+	}
+
+	name := enumConstraintPrefix(tableName, column.ColumnName) + GetMD5HashString(strings.Join(values, "\n"))[:8]
+	valueList := sqlQuoteValues(values)
+	message := strings.Replace(fmt.Sprintf("invalid value for %s", column.ColumnName), "'", "''", -1)
+
+	switch sqlDriverName {
+	case "postgres":
+		return []enumConstraint{
+			{
+				Name:      name,
+				TableName: tableName,
+				Create: fmt.Sprintf("alter table %s add constraint %s check (%s is null or %s in (%s)) not valid",
+					tableName, name, column.ColumnName, column.ColumnName, valueList),
+			},
+		}
+	case "mysql":
+		// mysql update triggers fire on every update, rows holding a value which is no longer an option can still be
+		// updated as long as the column itself is not changed
+		check := fmt.Sprintf("new.%s is not null and new.%s not in (%s)", column.ColumnName, column.ColumnName, valueList)
+		changed := fmt.Sprintf("not (new.%s <=> old.%s)", column.ColumnName, column.ColumnName)
+		body := "begin if %s then signal sqlstate '45000' set message_text = '%s'; end if; end"
+		return []enumConstraint{
+			{
+				Name:      name + "_i",
+				TableName: tableName,
+				Create: fmt.Sprintf("create trigger %s_i before insert on %s for each row "+body,
+					name, tableName, check, message),
+			},
+			{
+				Name:      name + "_u",
+				TableName: tableName,
+				Create: fmt.Sprintf("create trigger %s_u before update on %s for each row "+body,
+					name, tableName, changed+" and "+check, message),
+			},
+		}
+	default:
+		check := fmt.Sprintf("new.%s is not null and new.%s not in (%s)", column.ColumnName, column.ColumnName, valueList)
+		changed := fmt.Sprintf("new.%s is not old.%s", column.ColumnName, column.ColumnName)
+		when := "when %s begin select raise(abort, '%s'); end"
+		return []enumConstraint{
+			{
+				Name:      name + "_i",
+				TableName: tableName,
+				Create: fmt.Sprintf("create trigger %s_i before insert on %s for each row "+when,
+					name, tableName, check, message),
+			},
+			{
+				Name:      name + "_u",
+				TableName: tableName,
+				Create: fmt.Sprintf("create trigger %s_u before update of %s on %s for each row "+when,
+					name, column.ColumnName, tableName, changed+" and "+check, message),
+			},
+		}
+	}
+}
+
+func dropEnumConstraintStatement(constraint enumConstraint, sqlDriverName string) string {
+	if sqlDriverName == "postgres" {
+		return fmt.Sprintf("alter table %s drop constraint %s", constraint.TableName, constraint.Name)
+	}
+	return fmt.Sprintf("drop trigger %s", constraint.Name)
+}
+
+// GetExistingEnumConstraints reads the enum constraints present in the database along with the statement which
+// created them, so that a dropped constraint can be restored by a schema rollback
+func GetExistingEnumConstraints(db *sqlx.Tx) map[string]enumConstraint {
+	existingConstraints := make(map[string]enumConstraint)
+
+	constraintQuery := ""
+	switch db.DriverName() {
+	case "mysql":
+		constraintQuery = `select TRIGGER_NAME, EVENT_OBJECT_TABLE,
+       concat('create trigger ', TRIGGER_NAME, ' ', lower(ACTION_TIMING), ' ', lower(EVENT_MANIPULATION), ' on ',
+              EVENT_OBJECT_TABLE, ' for each row ', ACTION_STATEMENT)
+from information_schema.TRIGGERS
+where TRIGGER_SCHEMA = database() and TRIGGER_NAME like 'enum_%'`
+	case "postgres":
+		constraintQuery = `select con.conname, rel.relname,
+       concat('alter table ', rel.relname, ' add constraint ', con.conname, ' ', pg_get_constraintdef(con.oid))
+from pg_catalog.pg_constraint con
+         join pg_catalog.pg_class rel on rel.oid = con.conrelid
+where con.contype = 'c' and con.conname like 'enum_%'`
+	default:
+		constraintQuery = `select name, tbl_name, sql from sqlite_master where type = 'trigger' and name like 'enum_%'`
+	}
+
+	stmt1, err := db.Preparex(constraintQuery)
+	if err != nil {
+		log.Errorf("[115] failed to prepare statment: %v", err)
+		return existingConstraints
+	}
+	defer stmt1.Close()
+
+	rows, err := stmt1.Queryx()
+	if err != nil {
+		CheckErr(err, "Failed to check existing enum constraints using sql [%v][%v]", db.DriverName(), constraintQuery)
+		return existingConstraints
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var constraint enumConstraint
+		err = rows.Scan(&constraint.Name, &constraint.TableName, &constraint.Create)
+		if err != nil {
+			CheckErr(err, "Failed to scan existing enum constraint")
+			continue
+		}
+		existingConstraints[constraint.Name] = constraint
+	}
+	return existingConstraints
+}
+
+// CreateEnumConstraints makes the database reject values of enum columns which are not one of the column options.
+// Constraints of columns whose options changed are replaced, and those of columns which are no longer an enum are
+// dropped.
+func CreateEnumConstraints(initConfig *CmsConfig, db database.DatabaseConnection, schemaChanges *SchemaChangeLog) {
+	log.Infof("Create enum constraints")
+
+	transaction, err := db.Beginx()
+	if err != nil {
+		CheckErr(err, "Failed to begin transaction [155]")
+		return
+	}
+	existingConstraints := GetExistingEnumConstraints(transaction)
+	err = transaction.Rollback()
+	CheckErr(err, "TX rollback failed")
+
+	for _, table := range initConfig.Tables {
+		for _, column := range table.Columns {
+
+			wantedConstraints := enumConstraintStatements(table.TableName, column, db.DriverName())
+			wanted := make(map[string]bool)
+			for _, constraint := range wantedConstraints {
+				wanted[constraint.Name] = true
+			}
+
+			prefix := enumConstraintPrefix(table.TableName, column.ColumnName)
+			for name, constraint := range existingConstraints {
+				if !strings.HasPrefix(name, prefix) || wanted[name] {
+					continue
+				}
+				dropStatement := dropEnumConstraintStatement(constraint, db.DriverName())
+				_, err = db.Exec(dropStatement)
+				if err != nil {
+					log.Errorf("Failed to drop enum constraint [%v] on Table[%v] Column[%v]: %v", name, table.TableName, column.ColumnName, err)
+					continue
+				}
+				schemaChanges.Record(dropStatement, constraint.Create)
+			}
+
+			for _, constraint := range wantedConstraints {
+				if _, ok := existingConstraints[constraint.Name]; ok {
+					continue
+				}
+				_, err = db.Exec(constraint.Create)
+				if err != nil {
+					log.Errorf("Failed to create enum constraint on Table[%v] Column[%v]: %v", table.TableName, column.ColumnName, err)
+					log.Errorf("Create enum constraint sql: %v", constraint.Create)
+					continue
+				}
+				schemaChanges.Record(constraint.Create, dropEnumConstraintStatement(constraint, db.DriverName()))
+			}
+		}
+	}
+}
+
+// DropEnumConstraints removes the enum constraints of a column, sqlite refuses to drop or rename a column which is
+// used by a trigger
+func DropEnumConstraints(tableName string, columnName string, transaction *sqlx.Tx) error {
+	prefix := enumConstraintPrefix(tableName, columnName)
+	for name, constraint := range GetExistingEnumConstraints(transaction) {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		dropStatement := dropEnumConstraintStatement(constraint, transaction.DriverName())
+		_, err := transaction.Exec(dropStatement)
+		if err != nil {
+			return fmt.Errorf("failed to drop enum constraint [%v] on [%v]: %v", name, tableName, err)
+		}
+		RecordSchemaChange(dropStatement, constraint.Create)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package resource
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/artpar/api2go"
+)
+
+var testEnumColumn = api2go.ColumnInfo{
+	Name:       "status",
+	ColumnName: "status",
+	ColumnType: "enum",
+	DataType:   "varchar(20)",
+	IsNullable: true,
+	Options: []api2go.ValueOptions{
+		{Value: "open", Label: "Open"},
+		{Value: "closed", Label: "Closed"},
+	},
+}
+
+func TestEnumOption(t *testing.T) {
+	if value, ok := EnumOption(testEnumColumn, "Open"); !ok || value != "open" {
+		t.Errorf("expected the declared option ignoring case, got [%v] %v", value, ok)
+	}
+	if _, ok := EnumOption(testEnumColumn, "archived"); ok {
+		t.Errorf("expected a value which is not an option to be rejected")
+	}
+	noOptions := testEnumColumn
+	noOptions.Options = nil
+	if _, ok := EnumOption(noOptions, "open"); ok {
+		t.Errorf("expected an enum column without options to reject every value")
+	}
+}
+
+func TestEnumConstraintUpdateTriggerChecksChangedValues(t *testing.T) {
+	constraints := enumConstraintStatements("ticket", testEnumColumn, "mysql")
+	if len(constraints) != 2 {
+		t.Fatalf("expected an insert and an update trigger, got %v", constraints)
+	}
+	if strings.Contains(constraints[0].Create, "old.status") {
+		t.Errorf("expected the insert trigger not to read the old row: %v", constraints[0].Create)
+	}
+	if !strings.Contains(constraints[1].Create, "not (new.status <=> old.status) and ") {
+		t.Errorf("expected the update trigger to check changed values only: %v", constraints[1].Create)
+	}
+}
+
+func TestEnumConstraintsKeepLegacyValues(t *testing.T) {
+	ticket := TableInfo{TableName: "ticket", Columns: []api2go.ColumnInfo{
+		testEnumColumn,
+		{Name: "title", ColumnName: "title", ColumnType: "label", DataType: "varchar(100)", IsNullable: true},
+	}}
+	db, _ := newTestCruds(t, CmsConfig{Tables: []TableInfo{ticket}})
+
+	// a row from before the options were enforced
+	if _, err := db.Exec("insert into ticket (reference_id, permission, status, title) values ('legacy', 0, 'archived', 'old')"); err != nil {
+		t.Fatalf("failed to insert legacy row: %v", err)
+	}
+	CreateEnumConstraints(&CmsConfig{Tables: []TableInfo{ticket}}, db, nil)
+
+	if _, err := db.Exec("insert into ticket (reference_id, permission, status) values ('new', 0, 'archived')"); err == nil {
+		t.Errorf("expected an insert of a value which is not an option to fail")
+	}
+	if _, err := db.Exec("insert into ticket (reference_id, permission, status) values ('new', 0, 'open')"); err != nil {
+		t.Errorf("expected an insert of an option to pass: %v", err)
+	}
+	if _, err := db.Exec("update ticket set title = 'renamed' where reference_id = 'legacy'"); err != nil {
+		t.Errorf("expected a row with a legacy value to be updatable: %v", err)
+	}
+	if _, err := db.Exec("update ticket set status = status, title = 'again' where reference_id = 'legacy'"); err != nil {
+		t.Errorf("expected a legacy value which is not changed to be kept: %v", err)
+	}
+	if _, err := db.Exec("update ticket set status = 'deleted' where reference_id = 'legacy'"); err == nil {
+		t.Errorf("expected an update to a value which is not an option to fail")
+	}
+	if _, err := db.Exec("update ticket set status = 'closed' where reference_id = 'legacy'"); err != nil {
+		t.Errorf("expected an update to an option to pass: %v", err)
+	}
+}
+
+func TestEnumValueMessageIsTranslated(t *testing.T) {
+	RegisterTranslations()
+	if message := enumValueMessage(testEnumColumn, GetValidationTranslator([]string{"en"})); message != "status must be one of [open, closed]" {
+		t.Errorf("unexpected english message [%v]", message)
+	}
+	if message := enumValueMessage(testEnumColumn, GetValidationTranslator([]string{"fr"})); !strings.Contains(message, "doit être") {
+		t.Errorf("expected a french message, got [%v]", message)
+	}
+}
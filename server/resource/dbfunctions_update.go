@@ -850,6 +850,23 @@ func ImportDataFiles(imports []DataFileImport, transaction *sqlx.Tx, cruds map[s
 				}
 			}
 
+		case DataFormatNdjson, DataFormatParquet:
+
+			uniqueColumns := importUniqueColumns(dbResource)
+			rowNumber := 0
+			err = ReadDataFileRows(fileBytes, importFile.FileType, func(row map[string]interface{}) error {
+				rowNumber += 1
+				data, err := ImportRowValues(row, dbResource.TableInfo())
+				if err == nil {
+					err = ImportDataRow(data, dbResource, uniqueColumns, req, transaction)
+				}
+				if err != nil {
+					log.Warnf("Warning while importing %v data row %d: %v", importFile.FileType, rowNumber, ImportErrorReason(err))
+				}
+				return nil
+			})
+			CheckErr(err, "Failed to read %v file [%v]", importFile.FileType, importFile.FilePath)
+
 		default:
 			CheckErr(errors.New("unknown file type"), "Failed to import [%v]: [%v]", importFile.FileType, importFile.FilePath)
 		}
@@ -863,132 +880,142 @@ func ImportDataFiles(imports []DataFileImport, transaction *sqlx.Tx, cruds map[s
 
 }
 
-func ImportDataMapArray(data []map[string]interface{}, crud *DbResource, req api2go.Request, transaction *sqlx.Tx) []error {
-	errs := make([]error, 0)
-
+func importUniqueColumns(crud *DbResource) []api2go.ColumnInfo {
 	uniqueColumns := make([]api2go.ColumnInfo, 0)
-
 	for _, col := range crud.TableInfo().Columns {
-
 		if col.IsUnique {
 			uniqueColumns = append(uniqueColumns, col)
 		}
+	}
+	return uniqueColumns
+}
 
+// ImportDataRow creates the row, or when that fails updates the existing row having the same value in one of the
+// unique columns. The changes of a row which can be neither created nor updated are rolled back, so the transaction
+// can go on with the next row.
+func ImportDataRow(row map[string]interface{}, crud *DbResource, uniqueColumns []api2go.ColumnInfo, req api2go.Request, transaction *sqlx.Tx) error {
+
+	_, err := transaction.Exec("SAVEPOINT import_row")
+	if err != nil {
+		return err
 	}
 
-	log.Printf("Process [%d] row import for table %v", len(data), crud.tableInfo.TableName)
-	for _, row := range data {
+	model := api2go.NewApi2GoModelWithData(crud.tableInfo.TableName, nil, int64(crud.TableInfo().DefaultPermission), nil, row)
+	_, createErr := crud.CreateWithTransaction(model, req, transaction)
+	if createErr == nil {
+		_, err = transaction.Exec("RELEASE SAVEPOINT import_row")
+		return err
+	}
+	log.Printf(" [%v] Error while importing insert data row: %v == %v", crud.tableInfo.TableName, createErr, row)
+
+	_, err = transaction.Exec("ROLLBACK TO SAVEPOINT import_row")
+	if err != nil {
+		return err
+	}
 
-		model := api2go.NewApi2GoModelWithData(crud.tableInfo.TableName, nil, int64(crud.TableInfo().DefaultPermission), nil, row)
-		_, err := crud.CreateWithTransaction(model, req, transaction)
+	for _, uniqueCol := range uniqueColumns {
+		uniqueColumnValue, ok := row[uniqueCol.ColumnName]
+		if !ok || uniqueColumnValue == nil {
+			continue
+		}
+		stringVal, isString := uniqueColumnValue.(string)
+		if isString && len(stringVal) == 0 {
+			continue
+		}
+		log.Printf("Try to update data by unique column: %v", uniqueCol.ColumnName)
+		existingRow, err := crud.GetObjectByWhereClauseWithTransaction(crud.tableInfo.TableName, uniqueCol.ColumnName, uniqueColumnValue, transaction)
 		if err != nil {
-			log.Printf(" [%v] Error while importing insert data row: %v == %v", crud.tableInfo.TableName, err, row)
-			errs = append(errs, err)
-
-			if len(uniqueColumns) > 0 {
-				for _, uniqueCol := range uniqueColumns {
-					log.Printf("Try to update data by unique column: %v", uniqueCol.ColumnName)
-					uniqueColumnValue, ok := row[uniqueCol.ColumnName]
-					if !ok || uniqueColumnValue == nil {
-						continue
-					}
-					stringVal, isString := uniqueColumnValue.(string)
-					if isString && len(stringVal) == 0 {
-						continue
-					}
-					existingRow, err := crud.GetObjectByWhereClause(crud.tableInfo.TableName, uniqueCol.ColumnName, uniqueColumnValue, transaction)
-					if err != nil {
-						continue
-					}
-					log.Printf("Existing [%v] found by unique column: %v = %v", crud.tableInfo.TableName, uniqueCol.ColumnName, uniqueColumnValue)
+			continue
+		}
+		log.Printf("Existing [%v] found by unique column: %v = %v", crud.tableInfo.TableName, uniqueCol.ColumnName, uniqueColumnValue)
 
-					//for key, val := range row {
-					//	existingRow[key] = val
-					//}
+		obj := api2go.NewApi2GoModelWithData(crud.tableInfo.TableName, nil, 0, nil, existingRow)
+		obj.SetAttributes(row)
 
-					obj := api2go.NewApi2GoModelWithData(crud.tableInfo.TableName, nil, 0, nil, existingRow)
+		_, updateErr := crud.UpdateWithTransaction(obj, req, transaction)
+		if updateErr != nil {
+			log.Errorf("Failed to update table 809 [%v] update row by unique column [%v]: %v", crud.tableInfo.TableName, uniqueCol.ColumnName, updateErr)
+			_, err = transaction.Exec("ROLLBACK TO SAVEPOINT import_row")
+			if err != nil {
+				return err
+			}
+			createErr = updateErr
+			break
+		}
 
-					obj.SetAttributes(row)
+		_, err = transaction.Exec("RELEASE SAVEPOINT import_row")
+		return err
+	}
 
-					_, err = crud.UpdateWithTransaction(obj, req, transaction)
-					if err != nil {
-						log.Errorf("Failed to update table 809 [%v] update row by unique column [%v]: %v", crud.tableInfo.TableName, uniqueCol.ColumnName, err)
-					}
-					break
+	_, err = transaction.Exec("RELEASE SAVEPOINT import_row")
+	if err != nil {
+		return err
+	}
+	return createErr
+}
 
-				}
+// ImportDataMapArray imports the rows into the table and returns an error for each row which could not be imported
+func ImportDataMapArray(data []map[string]interface{}, crud *DbResource, req api2go.Request, transaction *sqlx.Tx) []error {
+	errs := make([]error, 0)
+	uniqueColumns := importUniqueColumns(crud)
 
-			}
+	log.Printf("Process [%d] row import for table %v", len(data), crud.tableInfo.TableName)
+	for i, row := range data {
+		err := ImportDataRow(row, crud, uniqueColumns, req, transaction)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("row %d: %v", i+1, ImportErrorReason(err)))
 		}
 	}
 	return errs
 }
 
+// ImportDataStringArray imports the rows, values in the order of the headers, into the table and returns an error for
+// each row which could not be imported
 func ImportDataStringArray(data [][]string, headers []string, entityName string, crud *DbResource, req api2go.Request, transaction *sqlx.Tx) []error {
 	errs := make([]error, 0)
+	uniqueColumns := importUniqueColumns(crud)
 
-	uniqueColumns := make([]api2go.ColumnInfo, 0)
-
-	for _, col := range crud.TableInfo().Columns {
-
-		if col.IsUnique {
-			uniqueColumns = append(uniqueColumns, col)
-		}
-
-	}
-
-	for _, rowArray := range data {
+	log.Printf("Process [%d] row import for table %v", len(data), entityName)
+	for i, rowArray := range data {
 
 		rowMap := make(map[string]interface{})
-
-		for i, header := range headers {
-			rowMap[header] = rowArray[i]
-		}
-		model := api2go.NewApi2GoModelWithData(entityName, nil, int64(crud.TableInfo().DefaultPermission), nil, rowMap)
-		_, err := crud.CreateWithTransaction(model, req, transaction)
-		if err != nil {
-			errs = append(errs, err)
+		for j, header := range headers {
+			if j < len(rowArray) {
+				rowMap[header] = rowArray[j]
+			}
 		}
 
+		err := ImportDataRow(rowMap, crud, uniqueColumns, req, transaction)
 		if err != nil {
-			// create row failed, try to update row by unique columns
-
-			if len(uniqueColumns) > 0 {
-				for _, uniqueCol := range uniqueColumns {
-					log.Printf("Try to update data by unique column: %v", uniqueCol.ColumnName)
-					uniqueColumnValue, ok := rowMap[uniqueCol.ColumnName]
-					if !ok || uniqueColumnValue == nil {
-						continue
-					}
-					stringVal, isString := uniqueColumnValue.(string)
-					if isString && len(stringVal) == 0 {
-						continue
-					}
-					existingRow, err := crud.GetObjectByWhereClauseWithTransaction(entityName, uniqueCol.ColumnName, uniqueColumnValue, transaction)
-					if err != nil {
-						continue
-					}
-
-					for _, key := range headers {
-						existingRow[key] = rowMap[key]
-					}
-
-					obj := api2go.NewApi2GoModelWithData(entityName, nil, 0, nil, existingRow)
-					_, err = crud.UpdateWithTransaction(obj, req, transaction)
-					if err != nil {
-						log.Errorf("Failed to update table 873 [%v] update row by unique column [%v]: %v", entityName, uniqueCol.ColumnName, err)
-					}
-					break
-
-				}
-			}
-
+			errs = append(errs, fmt.Errorf("row %d: %v", i+1, ImportErrorReason(err)))
 		}
-
 	}
 	return errs
 }
 
+// ImportErrorReason is the message of the error, along with the detail of each error of an api error
+func ImportErrorReason(err error) string {
+	var httpErr api2go.HTTPError
+	switch e := err.(type) {
+	case api2go.HTTPError:
+		httpErr = e
+	case *api2go.HTTPError:
+		httpErr = *e
+	}
+	if len(httpErr.Errors) == 0 {
+		return err.Error()
+	}
+	reasons := make([]string, 0)
+	for _, e := range httpErr.Errors {
+		reason := e.Detail
+		if reason == "" {
+			reason = e.Title
+		}
+		reasons = append(reasons, reason)
+	}
+	return strings.Join(reasons, "; ")
+}
+
 func UpdateWorldTable(initConfig *CmsConfig, transaction *sqlx.Tx) error {
 
 	var err error
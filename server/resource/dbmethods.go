@@ -1454,7 +1454,7 @@ func (dbResource *DbResource) GetRowPermissionWithTransaction(row map[string]int
 func (dbResource *DbResource) GetRowsByWhereClause(typeName string, includedRelations map[string]bool, transaction *sqlx.Tx, where ...goqu.Ex) (
 	[]map[string]interface{}, [][]map[string]interface{}, error) {
 
-	stmt := statementbuilder.Squirrel.Select("*").From(typeName)
+	stmt := statementbuilder.Squirrel.Select("*").Prepared(true).From(typeName)
 
 	for _, w := range where {
 		stmt = stmt.Where(w)
@@ -1504,7 +1504,7 @@ func (dbResource *DbResource) GetRowsByWhereClauseWithTransaction(typeName strin
 	includedRelations map[string]bool, transaction *sqlx.Tx, where ...goqu.Ex) (
 	[]map[string]interface{}, [][]map[string]interface{}, error) {
 
-	stmt := statementbuilder.Squirrel.Select("*").From(typeName)
+	stmt := statementbuilder.Squirrel.Select("*").Prepared(true).From(typeName)
 
 	for _, w := range where {
 		stmt = stmt.Where(w)
@@ -2136,7 +2136,11 @@ func (dbResource *DbResource) DirectInsert(typeName string, data map[string]inte
 			log.Printf("No column named [%v]", columnName)
 			continue
 		}
-		value := data[columnName]
+		value, ok := data[columnName]
+		if !ok && columnName != "permission" {
+			// left to the column default
+			continue
+		}
 		switch colInfo.ColumnType {
 		case "datetime":
 			if value != nil {
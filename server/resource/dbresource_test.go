@@ -0,0 +1,99 @@
+package resource
+
+import (
+	"context"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/artpar/api2go"
+	"github.com/buraksezer/olric"
+	daptinid "github.com/daptin/daptin/server/id"
+	"github.com/jmoiron/sqlx"
+)
+
+// testCache is a cache which never holds anything, every value is read from the database
+type testCache struct {
+	olric.DMap
+}
+
+func (cache testCache) Get(ctx context.Context, key string) (*olric.GetResponse, error) {
+	return nil, olric.ErrKeyNotFound
+}
+
+func (cache testCache) Put(ctx context.Context, key string, value interface{}, options ...olric.PutOption) error {
+	return nil
+}
+
+// newTestCruds creates the standard tables, the tables and the actions of the config in a new sqlite database, the
+// way the server does at startup, and gives the resources of every table
+func newTestCruds(t *testing.T, config CmsConfig) (*sqlx.DB, map[string]*DbResource) {
+	t.Helper()
+	db, err := sqlx.Open("sqlite3", filepath.Join(t.TempDir(), "daptin.db")+"?_busy_timeout=5000")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+	})
+	db.SetMaxOpenConns(1)
+	cache := OlricCache
+	OlricCache = testCache{}
+	t.Cleanup(func() {
+		OlricCache = cache
+	})
+
+	initConfig := CmsConfig{
+		Relations: make([]api2go.TableRelation, 0),
+		Actions:   config.Actions,
+	}
+	// the standard tables are changed while the relations are added, each test works on its own copy
+	for _, table := range append(StandardTables, config.Tables...) {
+		table.Columns = append([]api2go.ColumnInfo{}, table.Columns...)
+		table.Relations = append([]api2go.TableRelation{}, table.Relations...)
+		initConfig.Tables = append(initConfig.Tables, table)
+	}
+	CheckRelations(&initConfig)
+	CheckAllTableStatus(&initConfig, db, nil)
+
+	transaction := db.MustBegin()
+	CreateUniqueConstraints(&initConfig, transaction, nil)
+	if err = transaction.Commit(); err != nil {
+		t.Fatalf("failed to create unique constraints: %v", err)
+	}
+	transaction = db.MustBegin()
+	if err = UpdateWorldTable(&initConfig, transaction); err != nil {
+		t.Fatalf("failed to update world table: %v", err)
+	}
+	if err = transaction.Commit(); err != nil {
+		t.Fatalf("failed to update world table: %v", err)
+	}
+	// the action table update commits the transaction itself
+	if err = UpdateActionTable(&initConfig, db.MustBegin()); err != nil {
+		t.Fatalf("failed to update action table: %v", err)
+	}
+
+	cruds := make(map[string]*DbResource)
+	for i := range initConfig.Tables {
+		table := initConfig.Tables[i]
+		defaultGroups, err := GroupNamesToIds(db, table.DefaultGroups)
+		if err != nil {
+			t.Fatalf("failed to read default groups of [%v]: %v", table.TableName, err)
+		}
+		cruds[table.TableName] = &DbResource{
+			model: api2go.NewApi2GoModel(table.TableName, table.Columns, int64(table.DefaultPermission),
+				table.Relations),
+			db:                 db,
+			Connection:         db,
+			Cruds:              cruds,
+			tableInfo:          &table,
+			defaultGroups:      defaultGroups,
+			defaultRelations:   map[string][]int64{},
+			contextCache:       make(map[string]interface{}),
+			contextLock:        sync.RWMutex{},
+			AssetFolderCache:   make(map[string]map[string]*AssetFolderCache),
+			SubsiteFolderCache: make(map[daptinid.DaptinReferenceId]*AssetFolderCache),
+		}
+	}
+	return db, cruds
+}
@@ -95,7 +95,8 @@ func (exchangeHandler *ActionExchangeHandler) ExecuteTarget(row map[string]inter
 		Groups:          userGroups,
 	}
 
-	req.PlainRequest = req.PlainRequest.WithContext(context.WithValue(context.Background(), "user", &sessionUser))
+	ctx := WithActionChannel(context.WithValue(context.Background(), "user", &sessionUser), ActionChannelExchange)
+	req.PlainRequest = req.PlainRequest.WithContext(ctx)
 
 	request.Attributes["subject"] = row
 	request.Attributes[tableName+"_id"] = row["reference_id"]
@@ -0,0 +1,509 @@
+package resource
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/artpar/api2go"
+	"github.com/artpar/rclone/cmd"
+	"github.com/artpar/rclone/fs/config"
+	rcloneSync "github.com/artpar/rclone/fs/sync"
+	daptinid "github.com/daptin/daptin/server/id"
+	"github.com/daptin/daptin/server/statementbuilder"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/oauth2"
+)
+
+// gitOpsNameColumns is the column identifying an object, for each table holding objects which can be managed by
+// schema files
+var gitOpsNameColumns = map[string]string{
+	"world":         "table_name",
+	"action":        "action_name",
+	"stream":        "stream_name",
+	"smd":           "name",
+	"data_exchange": "name",
+}
+
+type gitOpsManagedObjects struct {
+	lock    sync.RWMutex
+	objects map[string]map[string]bool
+}
+
+var gitOpsManaged = &gitOpsManagedObjects{
+	objects: map[string]map[string]bool{},
+}
+
+// SetGitOpsManagedObjects marks every table, action, stream, state machine and exchange defined in the config as
+// managed by schema files, these cannot be changed from the API
+func SetGitOpsManagedObjects(managedConfig CmsConfig) {
+	objects := map[string]map[string]bool{}
+	for tableName := range gitOpsNameColumns {
+		objects[tableName] = map[string]bool{}
+	}
+	for _, table := range managedConfig.Tables {
+		objects["world"][table.TableName] = true
+	}
+	for _, action := range managedConfig.Actions {
+		objects["action"][action.Name] = true
+	}
+	for _, stream := range managedConfig.Streams {
+		objects["stream"][stream.StreamName] = true
+	}
+	for _, smd := range managedConfig.StateMachineDescriptions {
+		objects["smd"][smd.Name] = true
+	}
+	for _, exchange := range managedConfig.ExchangeContracts {
+		objects["data_exchange"][exchange.Name] = true
+	}
+
+	gitOpsManaged.lock.Lock()
+	defer gitOpsManaged.lock.Unlock()
+	gitOpsManaged.objects = objects
+}
+
+// IsGitOpsManaged tells if the object named `name` stored in table `tableName` is defined by a schema file
+func IsGitOpsManaged(tableName string, name string) bool {
+	gitOpsManaged.lock.RLock()
+	defer gitOpsManaged.lock.RUnlock()
+	return gitOpsManaged.objects[tableName][name]
+}
+
+// gitOpsApplyRequest is set when the next load applies the schema files, every other load keeps the schema which is
+// in the database
+type gitOpsApplyRequest struct {
+	lock      sync.Mutex
+	requested bool
+}
+
+var gitOpsApply = &gitOpsApplyRequest{}
+
+// RequestGitOpsApply has the schema files applied by the next load
+func RequestGitOpsApply() {
+	gitOpsApply.lock.Lock()
+	defer gitOpsApply.lock.Unlock()
+	gitOpsApply.requested = true
+}
+
+// TakeGitOpsApplyRequest tells if the schema files are to be applied by this load, the request is cleared
+func TakeGitOpsApplyRequest() bool {
+	gitOpsApply.lock.Lock()
+	defer gitOpsApply.lock.Unlock()
+	requested := gitOpsApply.requested
+	gitOpsApply.requested = false
+	return requested
+}
+
+// SchemaDrift is a single difference between the schema files and the live database
+// Change is one of "missing" (defined in files, not in database), "changed" or "unmanaged" (only in database)
+type SchemaDrift struct {
+	Kind    string   `json:"kind"`
+	Name    string   `json:"name"`
+	Change  string   `json:"change"`
+	Details []string `json:"details,omitempty"`
+}
+
+type SchemaDriftReport struct {
+	Source    string        `json:"source"`
+	Files     []string      `json:"files"`
+	CheckedAt time.Time     `json:"checked_at"`
+	InSync    bool          `json:"in_sync"`
+	Drift     []SchemaDrift `json:"drift"`
+}
+
+// ComputeSchemaDrift compares the tables, actions, streams, state machines and exchanges defined in desired with
+// the ones stored in the database
+func ComputeSchemaDrift(desired CmsConfig, transaction *sqlx.Tx) (SchemaDriftReport, error) {
+	report := SchemaDriftReport{
+		CheckedAt: time.Now(),
+		Drift:     make([]SchemaDrift, 0),
+	}
+
+	liveTables, err := gitOpsLiveObjects("world", "world_schema_json", transaction)
+	if err != nil {
+		return report, err
+	}
+	for _, table := range desired.Tables {
+		liveSchema, ok := liveTables[table.TableName]
+		if !ok {
+			report.Drift = append(report.Drift, SchemaDrift{Kind: "table", Name: table.TableName, Change: "missing"})
+			continue
+		}
+		var liveTable TableInfo
+		err = json.Unmarshal([]byte(liveSchema), &liveTable)
+		if err != nil {
+			return report, fmt.Errorf("failed to read schema of table [%v]: %v", table.TableName, err)
+		}
+		details := diffTableColumns(table, liveTable)
+		if len(details) > 0 {
+			report.Drift = append(report.Drift, SchemaDrift{Kind: "table", Name: table.TableName, Change: "changed", Details: details})
+		}
+	}
+
+	liveActions, err := gitOpsLiveObjects("action", "action_schema", transaction)
+	if err != nil {
+		return report, err
+	}
+	for _, action := range desired.Actions {
+		liveSchema, ok := liveActions[action.Name]
+		if !ok {
+			report.Drift = append(report.Drift, SchemaDrift{Kind: "action", Name: action.Name, Change: "missing"})
+			continue
+		}
+		var liveAction Action
+		err = json.Unmarshal([]byte(liveSchema), &liveAction)
+		if err != nil {
+			return report, fmt.Errorf("failed to read schema of action [%v]: %v", action.Name, err)
+		}
+		details := diffJson(map[string]interface{}{
+			"OnType":           action.OnType,
+			"Label":            action.Label,
+			"InstanceOptional": action.InstanceOptional,
+			"InFields":         action.InFields,
+			"OutFields":        action.OutFields,
+			"Validations":      action.Validations,
+			"Conformations":    action.Conformations,
+		}, map[string]interface{}{
+			"OnType":           liveAction.OnType,
+			"Label":            liveAction.Label,
+			"InstanceOptional": liveAction.InstanceOptional,
+			"InFields":         liveAction.InFields,
+			"OutFields":        liveAction.OutFields,
+			"Validations":      liveAction.Validations,
+			"Conformations":    liveAction.Conformations,
+		})
+		if len(details) > 0 {
+			report.Drift = append(report.Drift, SchemaDrift{Kind: "action", Name: action.Name, Change: "changed", Details: details})
+		}
+	}
+
+	liveStreams, err := gitOpsLiveObjects("stream", "stream_contract", transaction)
+	if err != nil {
+		return report, err
+	}
+	for _, stream := range desired.Streams {
+		liveSchema, ok := liveStreams[stream.StreamName]
+		if !ok {
+			report.Drift = append(report.Drift, SchemaDrift{Kind: "stream", Name: stream.StreamName, Change: "missing"})
+			continue
+		}
+		var liveStream StreamContract
+		err = json.Unmarshal([]byte(liveSchema), &liveStream)
+		if err != nil {
+			return report, fmt.Errorf("failed to read contract of stream [%v]: %v", stream.StreamName, err)
+		}
+		details := diffJson(map[string]interface{}{
+			"RootEntityName":  stream.RootEntityName,
+			"Columns":         stream.Columns,
+			"Transformations": stream.Transformations,
+			"QueryParams":     stream.QueryParams,
+		}, map[string]interface{}{
+			"RootEntityName":  liveStream.RootEntityName,
+			"Columns":         liveStream.Columns,
+			"Transformations": liveStream.Transformations,
+			"QueryParams":     liveStream.QueryParams,
+		})
+		if len(details) > 0 {
+			report.Drift = append(report.Drift, SchemaDrift{Kind: "stream", Name: stream.StreamName, Change: "changed", Details: details})
+		}
+	}
+
+	liveStateMachines, err := gitOpsLiveObjects("smd", "events", transaction)
+	if err != nil {
+		return report, err
+	}
+	for _, smd := range desired.StateMachineDescriptions {
+		liveEvents, ok := liveStateMachines[smd.Name]
+		if !ok {
+			report.Drift = append(report.Drift, SchemaDrift{Kind: "state_machine", Name: smd.Name, Change: "missing"})
+			continue
+		}
+		var events []LoopbackEventDesc
+		err = json.Unmarshal([]byte(liveEvents), &events)
+		if err != nil {
+			return report, fmt.Errorf("failed to read events of state machine [%v]: %v", smd.Name, err)
+		}
+		details := diffJson(map[string]interface{}{"Events": smd.Events}, map[string]interface{}{"Events": events})
+		if len(details) > 0 {
+			report.Drift = append(report.Drift, SchemaDrift{Kind: "state_machine", Name: smd.Name, Change: "changed", Details: details})
+		}
+	}
+
+	liveExchanges, err := gitOpsLiveObjects("data_exchange", "target_type", transaction)
+	if err != nil {
+		return report, err
+	}
+	for _, exchange := range desired.ExchangeContracts {
+		liveTargetType, ok := liveExchanges[exchange.Name]
+		if !ok {
+			report.Drift = append(report.Drift, SchemaDrift{Kind: "exchange", Name: exchange.Name, Change: "missing"})
+			continue
+		}
+		if liveTargetType != exchange.TargetType {
+			report.Drift = append(report.Drift, SchemaDrift{Kind: "exchange", Name: exchange.Name, Change: "changed",
+				Details: []string{fmt.Sprintf("TargetType: [%v] in database, [%v] in files", liveTargetType, exchange.TargetType)}})
+		}
+	}
+
+	report.Drift = append(report.Drift, unmanagedObjects("table", liveTables, IsGitOpsManagedTable)...)
+	report.Drift = append(report.Drift, unmanagedObjects("action", liveActions, func(name string) bool {
+		return IsGitOpsManaged("action", name) || isSystemAction(name)
+	})...)
+
+	report.InSync = true
+	for _, drift := range report.Drift {
+		if drift.Change != "unmanaged" {
+			report.InSync = false
+		}
+	}
+	return report, nil
+}
+
+// IsGitOpsManagedTable is true for tables defined in schema files and for tables daptin creates on its own
+func IsGitOpsManagedTable(tableName string) bool {
+	if IsGitOpsManaged("world", tableName) {
+		return true
+	}
+	if strings.Contains(tableName, "_has_") || EndsWithCheck(tableName, "_audit") || EndsWithCheck(tableName, "_i18n") {
+		return true
+	}
+	for _, table := range StandardTables {
+		if table.TableName == tableName {
+			return true
+		}
+	}
+	return false
+}
+
+func isSystemAction(actionName string) bool {
+	for _, action := range SystemActions {
+		if action.Name == actionName {
+			return true
+		}
+	}
+	return false
+}
+
+func unmanagedObjects(kind string, liveObjects map[string]string, isManaged func(string) bool) []SchemaDrift {
+	drift := make([]SchemaDrift, 0)
+	names := make([]string, 0)
+	for name := range liveObjects {
+		if !isManaged(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		drift = append(drift, SchemaDrift{Kind: kind, Name: name, Change: "unmanaged"})
+	}
+	return drift
+}
+
+// diffTableColumns lists the columns which are missing or defined differently in the live table.
+// Only the properties set in the schema file are compared, so defaults filled in by daptin are not reported.
+func diffTableColumns(desired TableInfo, live TableInfo) []string {
+	details := make([]string, 0)
+	liveColumns := make(map[string]api2go.ColumnInfo)
+	for _, col := range live.Columns {
+		liveColumns[col.ColumnName] = col
+	}
+
+	for _, col := range desired.Columns {
+		columnName := col.ColumnName
+		if columnName == "" {
+			columnName = col.Name
+		}
+		liveColumn, ok := liveColumns[columnName]
+		if !ok {
+			details = append(details, fmt.Sprintf("column [%v] is missing", columnName))
+			continue
+		}
+		if col.DataType != "" && col.DataType != liveColumn.DataType {
+			details = append(details, fmt.Sprintf("column [%v] DataType: [%v] in database, [%v] in files", columnName, liveColumn.DataType, col.DataType))
+		}
+		if col.ColumnType != "" && col.ColumnType != liveColumn.ColumnType {
+			details = append(details, fmt.Sprintf("column [%v] ColumnType: [%v] in database, [%v] in files", columnName, liveColumn.ColumnType, col.ColumnType))
+		}
+		if col.IsNullable != liveColumn.IsNullable {
+			details = append(details, fmt.Sprintf("column [%v] IsNullable: [%v] in database, [%v] in files", columnName, liveColumn.IsNullable, col.IsNullable))
+		}
+		if col.IsUnique != liveColumn.IsUnique {
+			details = append(details, fmt.Sprintf("column [%v] IsUnique: [%v] in database, [%v] in files", columnName, liveColumn.IsUnique, col.IsUnique))
+		}
+		if col.DefaultValue != "" && col.DefaultValue != liveColumn.DefaultValue {
+			details = append(details, fmt.Sprintf("column [%v] DefaultValue: [%v] in database, [%v] in files", columnName, liveColumn.DefaultValue, col.DefaultValue))
+		}
+	}
+	return details
+}
+
+// diffJson compares the json representation of each key
+func diffJson(desired map[string]interface{}, live map[string]interface{}) []string {
+	details := make([]string, 0)
+	keys := make([]string, 0)
+	for key := range desired {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		desiredJson, _ := json.MarshalToString(desired[key])
+		liveJson, _ := json.MarshalToString(live[key])
+		if desiredJson != liveJson {
+			details = append(details, fmt.Sprintf("%v differs", key))
+		}
+	}
+	return details
+}
+
+// gitOpsLiveObjects returns the value of valueColumn for every row in tableName, keyed by its name column
+func gitOpsLiveObjects(tableName string, valueColumn string, transaction *sqlx.Tx) (map[string]string, error) {
+	nameColumn := gitOpsNameColumns[tableName]
+	s, v, err := statementbuilder.Squirrel.Select(nameColumn, valueColumn).Prepared(true).From(tableName).ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	stmt1, err := transaction.Preparex(s)
+	if err != nil {
+		log.Errorf("[364] failed to prepare statment: %v", err)
+		return nil, err
+	}
+	defer stmt1.Close()
+
+	rows, err := stmt1.Queryx(v...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	objects := make(map[string]string)
+	for rows.Next() {
+		var name interface{}
+		var value interface{}
+		err = rows.Scan(&name, &value)
+		if err != nil {
+			return nil, err
+		}
+		objects[asString(name)] = asString(value)
+	}
+	return objects, nil
+}
+
+func asString(value interface{}) string {
+	switch typed := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return typed
+	case []uint8:
+		return string(typed)
+	default:
+		return fmt.Sprintf("%v", typed)
+	}
+}
+
+// SyncGitOpsCloudStore copies the schema files from a path in a cloud store to the local folder watched for changes
+func SyncGitOpsCloudStore(cruds map[string]*DbResource, cloudStoreName string, cloudPath string, localPath string, transaction *sqlx.Tx) error {
+
+	cloudStore, err := cruds["cloud_store"].GetCloudStoreByNameWithTransaction(cloudStoreName, transaction)
+	if err != nil {
+		return err
+	}
+
+	if cloudStore.StoreProvider != "local" && cloudStore.OAutoTokenId != daptinid.NullReferenceId {
+		var token *oauth2.Token
+		var oauthConf *oauth2.Config
+		token, oauthConf, err = cruds["oauth_token"].GetTokenByTokenReferenceId(cloudStore.OAutoTokenId, transaction)
+		if err != nil {
+			return err
+		}
+		jsonToken, err := json.Marshal(token)
+		CheckErr(err, "Failed to convert token to json")
+		config.FileSet(cloudStore.StoreProvider, "client_id", oauthConf.ClientID)
+		config.FileSet(cloudStore.StoreProvider, "type", cloudStore.StoreProvider)
+		config.FileSet(cloudStore.StoreProvider, "client_secret", oauthConf.ClientSecret)
+		config.FileSet(cloudStore.StoreProvider, "token", string(jsonToken))
+		config.FileSet(cloudStore.StoreProvider, "client_scopes", strings.Join(oauthConf.Scopes, ","))
+		config.FileSet(cloudStore.StoreProvider, "redirect_url", oauthConf.RedirectURL)
+	}
+
+	if !EndsWithCheck(cloudStore.RootPath, "/") && !BeginsWith(cloudPath, "/") {
+		cloudPath = "/" + cloudPath
+	}
+
+	fsrc, fdst := cmd.NewFsSrcDst([]string{cloudStore.RootPath + cloudPath, localPath})
+	if fsrc == nil || fdst == nil {
+		return fmt.Errorf("failed to open [%v%v] or [%v]", cloudStore.RootPath, cloudPath, localPath)
+	}
+	log.Debugf("Sync schema files from [%v] to [%v]", fsrc.String(), fdst.String())
+	return rcloneSync.Sync(context.Background(), fdst, fsrc, false)
+}
+
+type gitOpsReadOnlyMiddleware struct {
+}
+
+// checkGitOpsWritable rejects a change to an object which is managed by schema files, the world actions changing
+// columns and tables check it as well as the api
+func checkGitOpsWritable(tableName string, name string) error {
+	if !IsGitOpsManaged(tableName, name) {
+		return nil
+	}
+	return api2go.NewHTTPError(fmt.Errorf("[%v] is managed by schema files", name),
+		fmt.Sprintf("[%v] is managed by schema files and is read-only, change the schema files instead", name), 403)
+}
+
+func NewGitOpsReadOnlyMiddleware() DatabaseRequestInterceptor {
+	return &gitOpsReadOnlyMiddleware{}
+}
+
+func (g *gitOpsReadOnlyMiddleware) String() string {
+	return "GitOpsReadOnly"
+}
+
+func (g *gitOpsReadOnlyMiddleware) InterceptAfter(dr *DbResource, req *api2go.Request, results []map[string]interface{}, transaction *sqlx.Tx) ([]map[string]interface{}, error) {
+	return results, nil
+}
+
+// InterceptBefore rejects any create, update or delete on an object which is managed by schema files,
+// such changes have to be made in the files instead
+func (g *gitOpsReadOnlyMiddleware) InterceptBefore(dr *DbResource, req *api2go.Request, objects []map[string]interface{}, transaction *sqlx.Tx) ([]map[string]interface{}, error) {
+
+	tableName := dr.model.GetTableName()
+	nameColumn, ok := gitOpsNameColumns[tableName]
+	if !ok || req.PlainRequest == nil || req.PlainRequest.Method == "GET" {
+		return objects, nil
+	}
+
+	for _, object := range objects {
+		name, ok := object[nameColumn]
+		if (!ok || name == nil) && req.PlainRequest.Method != "POST" {
+			referenceId, isReferenceId := object["reference_id"].(daptinid.DaptinReferenceId)
+			if !isReferenceId {
+				if uuidValue, isUuid := object["reference_id"].(uuid.UUID); isUuid {
+					referenceId = daptinid.DaptinReferenceId(uuidValue)
+				}
+			}
+			if referenceId == daptinid.NullReferenceId {
+				continue
+			}
+			existingRow, _, err := dr.GetSingleRowByReferenceIdWithTransaction(tableName, referenceId,
+				map[string]bool{nameColumn: true}, transaction)
+			if err != nil {
+				continue
+			}
+			name = existingRow[nameColumn]
+		}
+		if name != nil {
+			if err := checkGitOpsWritable(tableName, asString(name)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return objects, nil
+}
@@ -0,0 +1,70 @@
+package resource
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/artpar/api2go"
+)
+
+func TestComputeSchemaDrift(t *testing.T) {
+	book := func(titleType string) TableInfo {
+		return TableInfo{TableName: "book", Columns: []api2go.ColumnInfo{
+			{Name: "title", ColumnName: "title", ColumnType: "label", DataType: titleType},
+			{Name: "page_count", ColumnName: "page_count", ColumnType: "measurement", DataType: "int(11)"},
+		}}
+	}
+	db, _ := newTestCruds(t, CmsConfig{Tables: []TableInfo{book("varchar(100)")}})
+	SetGitOpsManagedObjects(CmsConfig{Tables: []TableInfo{book("varchar(100)")}})
+	defer SetGitOpsManagedObjects(CmsConfig{})
+
+	transaction := db.MustBegin()
+	defer transaction.Rollback()
+
+	// the table as applied from the files is in sync, daptin adds its own columns to it
+	report, err := ComputeSchemaDrift(CmsConfig{Tables: []TableInfo{book("varchar(100)")}}, transaction)
+	if err != nil {
+		t.Fatalf("failed to compute drift: %v", err)
+	}
+	if !report.InSync {
+		t.Errorf("expected the applied table to be in sync, got %v", report.Drift)
+	}
+
+	report, err = ComputeSchemaDrift(CmsConfig{Tables: []TableInfo{book("varchar(200)"), {TableName: "author"}}}, transaction)
+	if err != nil {
+		t.Fatalf("failed to compute drift: %v", err)
+	}
+	if report.InSync || len(report.Drift) != 2 {
+		t.Fatalf("expected the changed and the missing table, got %v", report.Drift)
+	}
+	if report.Drift[0].Name != "book" || report.Drift[0].Change != "changed" || len(report.Drift[0].Details) != 1 {
+		t.Errorf("expected the data type of the title to differ, got %v", report.Drift[0])
+	}
+	if report.Drift[1].Name != "author" || report.Drift[1].Change != "missing" {
+		t.Errorf("expected the author table to be missing, got %v", report.Drift[1])
+	}
+}
+
+func TestGitOpsManagedTableRefusesWorldActions(t *testing.T) {
+	SetGitOpsManagedObjects(CmsConfig{Tables: []TableInfo{{TableName: "book"}}})
+	defer SetGitOpsManagedObjects(CmsConfig{})
+
+	_, _, errs := (&deleteWorldColumnPerformer{}).DoAction(Outcome{},
+		map[string]interface{}{"world_name": "book", "column_name": "title"}, nil)
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "managed by schema files") {
+		t.Errorf("expected removing a column of a managed table to be refused, got %v", errs)
+	}
+	_, _, errs = (&renameWorldColumnPerformer{}).DoAction(Outcome{},
+		map[string]interface{}{"world_name": "book", "column_name": "title", "new_column_name": "headline"}, nil)
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "managed by schema files") {
+		t.Errorf("expected renaming a column of a managed table to be refused, got %v", errs)
+	}
+	_, _, errs = (&uploadCsvFileToEntityPerformer{}).DoAction(Outcome{}, map[string]interface{}{
+		"data_csv_file": []interface{}{}, "entity_name": "book", "add_missing_columns": true}, nil)
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "managed by schema files") {
+		t.Errorf("expected adding columns to a managed table to be refused, got %v", errs)
+	}
+	if err := checkGitOpsWritable("world", "author"); err != nil {
+		t.Errorf("expected a table which is not managed to be writable, got %v", err)
+	}
+}
@@ -12,7 +12,6 @@ import (
 
 	"github.com/artpar/api2go"
 	"github.com/daptin/daptin/server/auth"
-	"github.com/dop251/goja"
 	"github.com/gin-gonic/gin"
 	uuid "github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
@@ -28,9 +27,9 @@ import (
 	"io"
 	"net/url"
 	"strconv"
+	"time"
 
 	"github.com/artpar/conform"
-	"gopkg.in/go-playground/validator.v9"
 )
 
 var guestActions = map[string]Action{}
@@ -111,13 +110,23 @@ func CreatePostActionHandler(initConfig *CmsConfig,
 			},
 		}
 
-		req.PlainRequest = req.PlainRequest.WithContext(ginContext.Request.Context())
+		req.PlainRequest = req.PlainRequest.WithContext(WithActionChannel(ginContext.Request.Context(), ActionChannelHttp))
 
 		actionCrudResource, ok := cruds[actionType]
 		if !ok {
 			actionCrudResource = cruds["world"]
 		}
 
+		var dryRun *ActionDryRun
+		if dryRunFlag, _ := strconv.ParseBool(ginContext.Query("dry_run")); dryRunFlag {
+			delete(actionRequest.Attributes, "dry_run")
+			dryRun = &ActionDryRun{Outcomes: []OutcomeTrace{}}
+			req.PlainRequest = req.PlainRequest.WithContext(WithActionDryRun(req.PlainRequest.Context(), dryRun))
+		} else if IsAsyncActionRequest(actionMap[actionType+":"+actionName], ginContext.Request) {
+			createActionJob(ginContext, actionRequest, cruds)
+			return
+		}
+
 		transaction, err := cruds["world"].Connection.Beginx()
 		if err != nil {
 			CheckErr(err, "Failed to begin transaction [121]")
@@ -126,6 +135,26 @@ func CreatePostActionHandler(initConfig *CmsConfig,
 		defer transaction.Commit()
 		responses, err := actionCrudResource.HandleActionRequest(actionRequest, req, transaction)
 
+		if dryRun != nil {
+			// HandleActionRequest rolls back a dry run, this makes sure of it on every path
+			transaction.Rollback()
+			dryRun.Responses = responses
+			if dryRun.Responses == nil {
+				dryRun.Responses = []ActionResponse{}
+			}
+			dryRunStatus := 200
+			if err != nil {
+				dryRun.Error = err.Error()
+				dryRunStatus = 400
+				if httpErr, ok := err.(api2go.HTTPError); ok {
+					dryRunStatus = httpErr.Status()
+					dryRun.ValidationErrors = httpErr.Errors
+				}
+			}
+			ginContext.JSON(dryRunStatus, dryRun)
+			return
+		}
+
 		responseStatus := 200
 		for _, response := range responses {
 			if response.ResponseType == "client.header.set" {
@@ -190,7 +219,17 @@ func CreatePostActionHandler(initConfig *CmsConfig,
 	}
 }
 
+// HandleActionRequest runs the action and records the run in the action execution log
 func (db *DbResource) HandleActionRequest(actionRequest ActionRequest, req api2go.Request, transaction *sqlx.Tx) ([]ActionResponse, error) {
+	execution := newActionExecution(actionRequest, req)
+	responses, err := db.handleActionRequest(actionRequest, req, transaction, execution)
+	execution.finish(isDryRunRequest(req), err)
+	recordActionExecution(execution)
+	return responses, err
+}
+
+func (db *DbResource) handleActionRequest(actionRequest ActionRequest, req api2go.Request, transaction *sqlx.Tx,
+	execution *ActionExecution) ([]ActionResponse, error) {
 
 	user := req.PlainRequest.Context().Value("user")
 	sessionUser := &auth.SessionUser{}
@@ -209,6 +248,7 @@ func (db *DbResource) HandleActionRequest(actionRequest ActionRequest, req api2g
 		//CheckErr(rollbackErr, "failed to rollback")
 		return nil, api2go.NewHTTPError(err, "no such action", 400)
 	}
+	execution.InFields = action.InFields
 
 	isAdmin := IsAdminWithTransaction(sessionUser.UserReferenceId, transaction)
 
@@ -220,6 +260,7 @@ func (db *DbResource) HandleActionRequest(actionRequest ActionRequest, req api2g
 		subjectInstanceReferenceUuid = daptinid.DaptinReferenceId(uuid.MustParse(subjectInstanceReferenceStringVal))
 	}
 	if ok {
+		execution.SubjectId = subjectInstanceReferenceUuid.String()
 		req.PlainRequest.Method = "GET"
 		req.QueryParams = make(map[string][]string)
 		req.QueryParams["included_relations"] = action.RequestSubjectRelations
@@ -243,7 +284,11 @@ func (db *DbResource) HandleActionRequest(actionRequest ActionRequest, req api2g
 		}
 
 		subjectInstanceMap["__type"] = subjectInstance.GetName()
-		permission := db.GetRowPermissionWithTransaction(subjectInstanceMap, transaction)
+		// the attributes of the subject carry its reference id as a string, the permission is read by its reference id
+		permission := db.GetRowPermissionWithTransaction(map[string]interface{}{
+			"__type":       subjectInstance.GetName(),
+			"reference_id": subjectInstanceReferenceUuid,
+		}, transaction)
 
 		if !permission.CanExecute(sessionUser.UserReferenceId, sessionUser.Groups) {
 			log.Warnf("user not allowed action on this object: %v - %v", actionRequest.Action, subjectInstanceReferenceString)
@@ -280,16 +325,20 @@ func (db *DbResource) HandleActionRequest(actionRequest ActionRequest, req api2g
 		}
 	}
 
-	for _, validation := range action.Validations {
-		errs := ValidatorInstance.VarWithValue(actionRequest.Attributes[validation.ColumnName], actionRequest.Attributes, validation.Tags)
-		if errs != nil {
-			log.Warnf("validation on input fields failed: %v - %v", actionRequest.Action, actionRequest.Type)
-			validationErrors := errs.(validator.ValidationErrors)
-			firstError := validationErrors[0]
-			rollbackErr := transaction.Rollback()
-			CheckErr(rollbackErr, "failed to rollback")
-			return nil, api2go.NewHTTPError(errors.New(fmt.Sprintf("invalid value for %s", validation.ColumnName)), firstError.Tag(), 400)
+	var languagePreferences []string
+	if prefs, ok := req.PlainRequest.Context().Value("language_preference").([]string); ok {
+		languagePreferences = prefs
+	}
+	validationErrors, err := ValidateObject(db.TableInfo(), action.Validations, actionRequest.Attributes, false, "/attributes/",
+		GetValidationTranslator(languagePreferences), transaction)
+	if err != nil || len(validationErrors) > 0 {
+		log.Warnf("validation on input fields failed: %v - %v", actionRequest.Action, actionRequest.Type)
+		rollbackErr := transaction.Rollback()
+		CheckErr(rollbackErr, "failed to rollback")
+		if err != nil {
+			return nil, api2go.NewHTTPError(err, "failed to validate fields", 400)
 		}
+		return nil, NewValidationError(validationErrors)
 	}
 
 	for _, conformations := range action.Conformations {
@@ -307,13 +356,15 @@ func (db *DbResource) HandleActionRequest(actionRequest ActionRequest, req api2g
 	}
 
 	inFieldMap, err := GetValidatedInFields(actionRequest, action)
-	inFieldMap["attributes"] = actionRequest.Attributes
-
 	if err != nil {
 		rollbackErr := transaction.Rollback()
 		CheckErr(rollbackErr, "failed to rollback")
+		if validationErr, ok := err.(api2go.HTTPError); ok {
+			return nil, validationErr
+		}
 		return nil, api2go.NewHTTPError(err, "failed to validate fields", 400)
 	}
+	inFieldMap["attributes"] = actionRequest.Attributes
 
 	if sessionUser.UserReferenceId != daptinid.NullReferenceId {
 		user, err := db.GetReferenceIdToObjectWithTransaction(USER_ACCOUNT_TABLE_NAME, sessionUser.UserReferenceId, transaction)
@@ -330,290 +381,403 @@ func (db *DbResource) HandleActionRequest(actionRequest ActionRequest, req api2g
 		inFieldMap["subject"] = subjectInstanceMap
 	}
 
-	responses := make([]ActionResponse, 0)
-	var restartOnCompletion = false
-
-OutFields:
-	for _, outcome := range action.OutFields {
-		var responseObjects interface{}
-		responseObjects = nil
-		var responses1 []ActionResponse
-		var errors1 []error
-		var actionResponse ActionResponse
-
-		log.Debugf("Action [%v][%v] => Outcome [%v][%v] ", actionRequest.Action, subjectInstanceReferenceString, outcome.Type, outcome.Method)
-
-		if len(outcome.Condition) > 0 {
-			var outcomeResult interface{}
-			outcomeResult, err = evaluateString(outcome.Condition, inFieldMap)
-			CheckErr(err, "Failed to evaluate condition, assuming false by default")
-			if err != nil {
-				continue
-			}
+	run := &outcomeRun{
+		db:            db,
+		actionRequest: actionRequest,
+		req:           req,
+		sessionUser:   sessionUser,
+		transaction:   transaction,
+		dryRun:        actionDryRunFrom(req.PlainRequest.Context()),
+		runAs:         action.RunAs,
+	}
+	responses, _, err := run.runOutcomes(action.OutFields, inFieldMap)
+	execution.Outcomes = run.trace
+	if run.dryRun != nil {
+		run.dryRun.Outcomes = run.trace
+	}
+	if err != nil {
+		transaction.Rollback()
+		if run.dryRun == nil {
+			run.compensateAfterRollback()
+		}
+		return nil, err
+	}
+	if run.dryRun != nil {
+		// nothing done in a dry run is kept
+		return responses, transaction.Rollback()
+	}
+	commitErr := transaction.Commit()
+	CheckErr(commitErr, "Failed to commit")
+	if run.restart {
+		SetSchemaMigrationAuthor(sessionUser.UserReferenceId.String())
+		go restart()
+	}
 
-			log.Tracef("Evaluated condition [%v] result: %v", outcome.Condition, outcomeResult)
-			boolValue, ok := outcomeResult.(bool)
-			if !ok {
+	return responses, commitErr
+}
 
-				strVal, ok := outcomeResult.(string)
-				if ok {
-					if strVal == "1" || strings.ToLower(strings.TrimSpace(strVal)) == "true" {
-						log.Printf("Condition is true")
-						// condition is true
-					} else {
-						// condition isn't true
-						log.Printf("Condition is false, skipping outcome")
-						continue
-					}
+// outcomeRun holds what the outcomes of one action request share
+type outcomeRun struct {
+	db            *DbResource
+	actionRequest ActionRequest
+	req           api2go.Request
+	sessionUser   *auth.SessionUser
+	transaction   *sqlx.Tx
+	restart       bool
+	savepoints    int
+	compensations []compensation
+	compensating  bool
+	dryRun        *ActionDryRun
+	step          string
+	trace         []OutcomeTrace
+	runAs         string
+	runAsUsers    map[string]*auth.SessionUser
+}
 
-				} else {
+// runOutcomes runs the outcomes in order, the result of each outcome with a reference is added to inFieldMap for
+// the outcomes after it. stop is true when an outcome ended the action early, keeping the work done before it.
+func (run *outcomeRun) runOutcomes(outcomes []Outcome, inFieldMap map[string]interface{}) ([]ActionResponse, bool, error) {
+	responses := make([]ActionResponse, 0)
+	stepPrefix := run.step
 
-					log.Printf("Failed to convert value to bool, assuming false")
-					continue
-				}
+	for i, outcome := range outcomes {
 
-			} else if !boolValue {
-				log.Debugf("Outcome [%v][%v] skipped because condition failed [%v]", outcome.Method, outcome.Type, outcome.Condition)
-				continue
+		log.Debugf("Action [%v][%v] => Outcome [%v][%v] ", run.actionRequest.Action, run.actionRequest.Type, outcome.Type, outcome.Method)
+
+		trace := OutcomeTrace{
+			Step:      stepPrefix + strconv.Itoa(i),
+			Type:      outcome.Type,
+			Method:    outcome.Method,
+			Reference: outcome.Reference,
+			ForEach:   outcome.ForEach,
+			Condition: outcome.Condition,
+			RunAs:     run.runAsOf(outcome),
+		}
+
+		if outcome.ForEach != "" {
+			traceIndex := run.traceOutcome(trace)
+			loopStart := time.Now()
+			runAs := run.runAs
+			run.runAs = trace.RunAs
+			loopResponses, stop, err := run.runLoop(outcome, inFieldMap, trace.Step)
+			run.step = stepPrefix
+			run.runAs = runAs
+			run.traceDuration(traceIndex, time.Since(loopStart))
+			if err != nil {
+				run.traceError(traceIndex, err)
 			}
+			responses = append(responses, loopResponses...)
+			if err != nil || stop {
+				return responses, stop, err
+			}
+			run.addCompensation(outcome, inFieldMap)
+			continue
 		}
 
-		var model api2go.Api2GoModel
-		var modelPointer *api2go.Api2GoModel
-		var request api2go.Request
-		modelPointer, request, err = BuildOutcome(inFieldMap, outcome)
+		holds, err := outcomeConditionHolds(outcome, inFieldMap)
+		if err != nil {
+			trace.Error = err.Error()
+			run.traceOutcome(trace)
+			return responses, false, err
+		}
+		if !holds {
+			trace.Skipped = true
+			run.traceOutcome(trace)
+			continue
+		}
+
+		modelPointer, request, err := BuildOutcome(inFieldMap, outcome)
 		if err != nil {
 			log.Errorf("Failed to build outcome: %v", err)
 			log.Errorf("Infields - %v", toJson(inFieldMap))
-			responses = append(responses, NewActionResponse("error", "Failed to build outcome "+outcome.Type))
+			trace.Error = err.Error()
+			run.traceOutcome(trace)
+			responses = append(responses, NewActionResponse("error", fmt.Sprintf("Failed to build outcome %v: %v", outcome.Type, err)))
 			if outcome.ContinueOnError {
 				continue
 			} else {
-				return []ActionResponse{}, fmt.Errorf("invalid input for %v", outcome.Type)
+				return responses, false, fmt.Errorf("invalid input for %v: %w", outcome.Type, err)
 			}
 		}
-		model = *modelPointer
+		trace.Attributes = run.traceAttributes(outcome, modelPointer.GetAttributes())
+		trace.Stubbed = run.dryRun != nil && isPerformerOutcome(outcome.Method)
 
-		requestContext := req.PlainRequest.Context()
-		var adminUserReferenceId daptinid.DaptinReferenceId
-		adminUserReferenceIds := GetAdminReferenceIdWithTransaction(transaction)
-		for id := range adminUserReferenceIds {
-			adminUserReferenceId = daptinid.DaptinReferenceId(id)
-			break
+		// the writes of an outcome which may fail are rolled back on their own
+		savepoint := ""
+		if outcome.ContinueOnError {
+			savepoint, err = run.savepoint()
+			if err != nil {
+				return responses, false, fmt.Errorf("failed to start savepoint for outcome [%v]: %w", outcome.Type, err)
+			}
 		}
 
-		if adminUserReferenceId != daptinid.NullReferenceId {
-			requestContext = context.WithValue(requestContext, "user", &auth.SessionUser{
-				UserReferenceId: adminUserReferenceId,
-			})
+		outcomeStart := time.Now()
+		actionResponses, responseObjects, stop, err := run.runOutcome(outcome, *modelPointer, request)
+		trace.Duration = durationMilliseconds(time.Since(outcomeStart))
+		trace.Responses = actionResponses
+		if err != nil {
+			trace.Error = err.Error()
 		}
-		request.PlainRequest = request.PlainRequest.WithContext(requestContext)
-		dbResource, _ := db.Cruds[outcome.Type]
-
-		actionResponses := make([]ActionResponse, 0)
-		//log.Printf("Next outcome method: [%v][%v]", outcome.Method, outcome.Type)
-		switch outcome.Method {
-		case "POST":
-			responseObjects, err = dbResource.CreateWithTransaction(model, request, transaction)
-			CheckErr(err, "Failed to post from action")
+		run.traceOutcome(trace)
+		if err != nil && savepoint != "" {
+			log.Warnf("Outcome [%v][%v] failed, rolled back and continuing: %v", outcome.Type, outcome.Method, err)
+			rollbackErr := run.rollbackToSavepoint(savepoint)
+			if rollbackErr != nil {
+				return responses, false, fmt.Errorf("failed to roll back outcome [%v] after %v: %w", outcome.Type, err, rollbackErr)
+			}
+			responses = append(responses, NewActionResponse("client.notify",
+				NewClientNotification("error", fmt.Sprintf("Failed to run %v: %v", outcome.Type, err), "Failed")))
+			continue
+		}
+		if err != nil {
+			log.Errorf("failed to execute outcome [%v] => %v", outcome.Type, err)
+			return responses, false, err
+		}
+		if savepoint != "" {
+			err = run.releaseSavepoint(savepoint)
 			if err != nil {
-
-				actionResponse = NewActionResponse("client.notify", NewClientNotification("error", "Failed to create "+model.GetName()+". "+err.Error(), "Failed"))
-				responses = append(responses, actionResponse)
-				break OutFields
-			} else {
-				createdRow := responseObjects.(api2go.Response).Result().(api2go.Api2GoModel).GetAttributes()
-				actionResponse = NewActionResponse(createdRow["__type"].(string), createdRow)
+				return responses, false, fmt.Errorf("failed to release savepoint for outcome [%v]: %w", outcome.Type, err)
 			}
-			actionResponses = append(actionResponses, actionResponse)
-		case "GET":
+		}
+		if stop {
+			return responses, true, nil
+		}
+
+		if !outcome.SkipInResponse {
+			responses = append(responses, actionResponses...)
+		}
+		bindOutcomeResult(outcome.Reference, actionResponses, responseObjects, inFieldMap)
+		run.addCompensation(outcome, inFieldMap)
+	}
 
-			request.QueryParams = make(map[string][]string)
+	return responses, false, nil
+}
 
-			for k, val := range model.GetAttributes() {
-				if k == "query" {
-					request.QueryParams[k] = []string{toJson(val)}
-				} else {
-					request.QueryParams[k] = []string{fmt.Sprintf("%v", val)}
-				}
-			}
+// outcomeConditionHolds evaluates the condition of the outcome, a condition which cannot be evaluated is false. The
+// error is only set when the script of the condition was stopped, which fails the action.
+func outcomeConditionHolds(outcome Outcome, inFieldMap map[string]interface{}) (bool, error) {
+	if len(outcome.Condition) == 0 {
+		return true, nil
+	}
 
-			responseObjects, _, _, _, err = dbResource.PaginatedFindAllWithoutFilters(request, transaction)
-			CheckErr(err, "Failed to get inside action")
-			if err != nil {
-				actionResponse = NewActionResponse("client.notify",
-					NewClientNotification("error", "Failed to get "+model.GetName()+". "+err.Error(), "Failed"))
-				responses = append(responses, actionResponse)
-				break OutFields
-			} else {
-				actionResponse = NewActionResponse(actionRequest.Type, responseObjects)
-			}
-			actionResponses = append(actionResponses, actionResponse)
-		case "GET_BY_ID":
-
-			referenceIdString, ok := model.GetAttributes()["reference_id"]
-			referenceIdUuid, err := uuid.Parse(referenceIdString.(string))
-			if referenceIdString == "" || !ok || err != nil {
-				err = api2go.NewHTTPError(err, "no reference id provided for GET_BY_ONE", 400)
-				break OutFields
-			}
+	outcomeResult, err := evaluateString(outcome.Condition, inFieldMap)
+	CheckErr(err, "Failed to evaluate condition, assuming false by default")
+	var scriptErr *ScriptError
+	if errors.As(err, &scriptErr) && scriptErr.LimitExceeded() {
+		return false, api2go.NewHTTPError(err, fmt.Sprintf("condition of outcome [%v] was stopped", outcome.Type), 400)
+	}
+	if err != nil {
+		return false, nil
+	}
 
-			includedRelations := make(map[string]bool, 0)
-			if model.GetAttributes()["included_relations"] != nil {
-				//included := req.QueryParams["included_relations"][0]
-				//includedRelationsList := strings.Split(included, ",")
-				for _, incl := range strings.Split(model.GetAttributes()["included_relations"].(string), ",") {
-					includedRelations[incl] = true
-				}
+	log.Tracef("Evaluated condition [%v] result: %v", outcome.Condition, outcomeResult)
+	boolValue, ok := outcomeResult.(bool)
+	if !ok {
 
+		strVal, ok := outcomeResult.(string)
+		if ok {
+			if strVal == "1" || strings.ToLower(strings.TrimSpace(strVal)) == "true" {
+				log.Printf("Condition is true")
+				// condition is true
 			} else {
-				includedRelations = nil
+				// condition isn't true
+				log.Printf("Condition is false, skipping outcome")
+				return false, nil
 			}
 
-			responseObjects, _, err = dbResource.GetSingleRowByReferenceIdWithTransaction(outcome.Type, daptinid.DaptinReferenceId(referenceIdUuid), nil, transaction)
-			CheckErr(err, "Failed to get by id")
+		} else {
 
-			if err != nil {
-				actionResponse = NewActionResponse("client.notify",
-					NewClientNotification("error", "Failed to create "+model.GetName()+". "+err.Error(), "Failed"))
-				responses = append(responses, actionResponse)
-				break OutFields
-			} else {
-				actionResponse = NewActionResponse(actionRequest.Type, responseObjects)
-			}
-			actionResponses = append(actionResponses, actionResponse)
-		case "PATCH":
-			responseObjects, err = dbResource.UpdateWithTransaction(model, request, transaction)
-			CheckErr(err, "Failed to update inside action")
-			if err != nil {
-				actionResponse = NewActionResponse("client.notify", NewClientNotification("error", "Failed to update "+model.GetName()+". "+err.Error(), "Failed"))
-				responses = append(responses, actionResponse)
-				break OutFields
-			} else {
-				createdRow := responseObjects.(api2go.Response).Result().(api2go.Api2GoModel).GetAttributes()
-				actionResponse = NewActionResponse(createdRow["__type"].(string), createdRow)
-			}
-			actionResponses = append(actionResponses, actionResponse)
-		case "DELETE":
-			idString := model.GetID()
-			idUUid := uuid.MustParse(idString)
-			err = dbResource.DeleteWithoutFilters(daptinid.DaptinReferenceId(idUUid), request, transaction)
-			CheckErr(err, "Failed to delete inside action")
-			if err != nil {
-				actionResponse = NewActionResponse("client.notify", NewClientNotification("error", "Failed to delete "+model.GetName(), "Failed"))
-				responses = append(responses, actionResponse)
-				break OutFields
-			} else {
-				actionResponse = NewActionResponse("client.notify", NewClientNotification("success", "Deleted "+model.GetName(), "Success"))
-			}
-			actionResponses = append(actionResponses, actionResponse)
-		case "EXECUTE":
-			//res, err = Cruds[outcome.Type].Create(model, actionRequest)
+			log.Printf("Failed to convert value to bool, assuming false")
+			return false, nil
+		}
 
-			actionName := model.GetName()
-			performer, ok := db.ActionHandlerMap[actionName]
-			if !ok {
-				log.Errorf("Invalid outcome method: [%v]%v", outcome.Method, model.GetName())
-				//return ginContext.AbortWithError(500, errors.New("Invalid outcome"))
-			} else {
-				var responder api2go.Responder
-				outcome.Attributes["user"] = sessionUser
-				responder, responses1, errors1 = performer.DoAction(outcome, model.GetAttributes(), transaction)
-				for _, res := range responses1 {
-					if res.ResponseType == "restart" {
-						restartOnCompletion = true
-					}
-				}
-				actionResponses = append(actionResponses, responses1...)
-				if len(errors1) > 0 {
-					err = errors1[0]
-					break OutFields
-				}
-				if responder != nil {
-					responseObjects = responder.Result().(api2go.Api2GoModel).GetAttributes()
-				}
-			}
+	} else if !boolValue {
+		log.Debugf("Outcome [%v][%v] skipped because condition failed [%v]", outcome.Method, outcome.Type, outcome.Condition)
+		return false, nil
+	}
+	return true, nil
+}
 
-		case "ACTIONRESPONSE":
-			//res, err = Cruds[outcome.Type].Create(model, actionRequest)
-			log.Debugf("Create action response: %v", model.GetName())
-			var actionResponse ActionResponse
-			actionResponse = NewActionResponse(model.GetName(), model.GetAttributes())
-			actionResponses = append(actionResponses, actionResponse)
-		default:
-			handler, ok := db.ActionHandlerMap[outcome.Type]
+// runOutcome executes a built outcome. stop is true when the outcome could not run and no more outcomes should.
+func (run *outcomeRun) runOutcome(outcome Outcome, model api2go.Api2GoModel, request api2go.Request) (
+	actionResponses []ActionResponse, responseObjects interface{}, stop bool, err error) {
 
-			if !ok {
-				log.Errorf("Unknown method invoked onn %v: %v", outcome.Type, outcome.Method)
-				continue
-			}
-			responder, responses1, err1 := handler.DoAction(outcome, model.GetAttributes(), transaction)
-			if err1 != nil {
-				err = err1[0]
-			} else {
-				actionResponses = append(actionResponses, responses1...)
-				responseObjects = responder
-			}
+	db := run.db
+	transaction := run.transaction
+	var actionResponse ActionResponse
 
-		}
+	// the table and row permissions of the outcome are checked for the user it runs as
+	runAsUser, err := run.runAsUser(run.runAsOf(outcome))
+	if err != nil {
+		return nil, nil, true, err
+	}
+	requestContext := context.WithValue(run.req.PlainRequest.Context(), "user", runAsUser)
+	request.PlainRequest = request.PlainRequest.WithContext(requestContext)
+	dbResource, _ := db.Cruds[outcome.Type]
+
+	actionResponses = make([]ActionResponse, 0)
+	//log.Printf("Next outcome method: [%v][%v]", outcome.Method, outcome.Type)
+	switch outcome.Method {
+	case "POST":
+		responseObjects, err = dbResource.CreateWithTransaction(model, request, transaction)
+		CheckErr(err, "Failed to post from action")
 		if err != nil {
-			log.Errorf("failed to execute outcome [%v] => %v", outcome.Type, err)
-			rollbackErr := transaction.Rollback()
-			CheckErr(rollbackErr, "failed to rollback")
-			return nil, err
+			return nil, nil, true, err
 		}
+		createdRow := responseObjects.(api2go.Response).Result().(api2go.Api2GoModel).GetAttributes()
+		actionResponse = NewActionResponse(createdRow["__type"].(string), createdRow)
+		actionResponses = append(actionResponses, actionResponse)
+	case "GET":
 
-		if !outcome.SkipInResponse {
-			responses = append(responses, actionResponses...)
-		}
+		request.QueryParams = make(map[string][]string)
 
-		if len(actionResponses) > 0 && outcome.Reference != "" {
-			lst := make([]interface{}, 0)
-			for i, res := range actionResponses {
-				inFieldMap[fmt.Sprintf("response.%v[%v]", outcome.Reference, i)] = res.Attributes
-				lst = append(lst, res.Attributes)
+		for k, val := range model.GetAttributes() {
+			if k == "query" {
+				request.QueryParams[k] = []string{toJson(val)}
+			} else {
+				request.QueryParams[k] = []string{fmt.Sprintf("%v", val)}
 			}
-			inFieldMap[fmt.Sprintf("%v", outcome.Reference)] = lst
 		}
 
-		if responseObjects != nil && outcome.Reference != "" {
-
-			api2goModel, ok := responseObjects.(api2go.Response)
-			if ok {
-				responseObjects = api2goModel.Result().(api2go.Api2GoModel).GetAttributes()
-			}
+		responseObjects, _, _, _, err = dbResource.PaginatedFindAllWithoutFilters(request, transaction)
+		CheckErr(err, "Failed to get inside action")
+		if err != nil {
+			return nil, nil, true, err
+		}
+		actionResponse = NewActionResponse(run.actionRequest.Type, responseObjects)
+		actionResponses = append(actionResponses, actionResponse)
+	case "GET_BY_ID":
+
+		referenceIdString, ok := model.GetAttributes()["reference_id"]
+		referenceIdUuid, parseErr := uuid.Parse(fmt.Sprintf("%v", referenceIdString))
+		if referenceIdString == "" || !ok || parseErr != nil {
+			log.Errorf("no reference id provided for GET_BY_ONE: %v", parseErr)
+			return nil, nil, true, nil
+		}
 
-			singleResult, isSingleResult := responseObjects.(map[string]interface{})
+		responseObjects, _, err = dbResource.GetSingleRowByReferenceIdWithTransaction(outcome.Type, daptinid.DaptinReferenceId(referenceIdUuid), nil, transaction)
+		CheckErr(err, "Failed to get by id")
 
-			if isSingleResult {
-				inFieldMap[outcome.Reference] = singleResult
-			} else {
-				resultArray, ok := responseObjects.([]map[string]interface{})
+		if err != nil {
+			return nil, nil, true, err
+		}
+		actionResponse = NewActionResponse(run.actionRequest.Type, responseObjects)
+		actionResponses = append(actionResponses, actionResponse)
+	case "PATCH":
+		responseObjects, err = dbResource.UpdateWithTransaction(model, request, transaction)
+		CheckErr(err, "Failed to update inside action")
+		if err != nil {
+			return nil, nil, true, err
+		}
+		createdRow := responseObjects.(api2go.Response).Result().(api2go.Api2GoModel).GetAttributes()
+		actionResponse = NewActionResponse(createdRow["__type"].(string), createdRow)
+		actionResponses = append(actionResponses, actionResponse)
+	case "DELETE":
+		idString := model.GetID()
+		idUUid := uuid.MustParse(idString)
+		err = dbResource.DeleteWithoutFilters(daptinid.DaptinReferenceId(idUUid), request, transaction)
+		CheckErr(err, "Failed to delete inside action")
+		if err != nil {
+			return nil, nil, true, err
+		}
+		actionResponse = NewActionResponse("client.notify", NewClientNotification("success", "Deleted "+model.GetName(), "Success"))
+		actionResponses = append(actionResponses, actionResponse)
+	case "EXECUTE":
+		//res, err = Cruds[outcome.Type].Create(model, actionRequest)
+		if run.dryRun != nil {
+			actionResponses = stubOutcome(outcome, run.traceAttributes(outcome, model.GetAttributes()))
+			break
+		}
 
-				finalArray := make([]map[string]interface{}, 0)
-				if ok {
-					for i, item := range resultArray {
-						finalArray = append(finalArray, item)
-						inFieldMap[fmt.Sprintf("%v[%v]", outcome.Reference, i)] = item
-					}
+		actionName := model.GetName()
+		performer, ok := db.ActionHandlerMap[actionName]
+		if !ok {
+			log.Errorf("Invalid outcome method: [%v]%v", outcome.Method, model.GetName())
+			//return ginContext.AbortWithError(500, errors.New("Invalid outcome"))
+		} else {
+			outcome.Attributes["user"] = run.sessionUser
+			responder, responses1, errors1 := performer.DoAction(outcome, model.GetAttributes(), transaction)
+			for _, res := range responses1 {
+				if res.ResponseType == "restart" {
+					run.restart = true
 				}
-				inFieldMap[outcome.Reference] = finalArray
-
 			}
+			actionResponses = append(actionResponses, responses1...)
+			if len(errors1) > 0 {
+				return nil, nil, true, errors1[0]
+			}
+			if responder != nil {
+				responseObjects = responder.Result().(api2go.Api2GoModel).GetAttributes()
+			}
+		}
+
+	case "ACTIONRESPONSE":
+		//res, err = Cruds[outcome.Type].Create(model, actionRequest)
+		log.Debugf("Create action response: %v", model.GetName())
+		actionResponse = NewActionResponse(model.GetName(), model.GetAttributes())
+		actionResponses = append(actionResponses, actionResponse)
+	default:
+		if run.dryRun != nil {
+			actionResponses = stubOutcome(outcome, run.traceAttributes(outcome, model.GetAttributes()))
+			break
+		}
+		handler, ok := db.ActionHandlerMap[outcome.Type]
+
+		if !ok {
+			log.Errorf("Unknown method invoked onn %v: %v", outcome.Type, outcome.Method)
+			return nil, nil, false, nil
+		}
+		responder, responses1, err1 := handler.DoAction(outcome, model.GetAttributes(), transaction)
+		if err1 != nil {
+			return nil, nil, false, err1[0]
 		}
+		actionResponses = append(actionResponses, responses1...)
+		responseObjects = responder
 
 	}
-	if err != nil {
-		transaction.Rollback()
-		return nil, err
+	return actionResponses, responseObjects, false, nil
+}
+
+// bindOutcomeResult adds the result of an outcome to the fields under its reference name
+func bindOutcomeResult(reference string, actionResponses []ActionResponse, responseObjects interface{}, inFieldMap map[string]interface{}) {
+	if reference == "" {
+		return
 	}
-	commitErr := transaction.Commit()
-	CheckErr(commitErr, "Failed to commit")
-	if restartOnCompletion {
-		go restart()
+
+	if len(actionResponses) > 0 {
+		lst := make([]interface{}, 0)
+		for i, res := range actionResponses {
+			inFieldMap[fmt.Sprintf("response.%v[%v]", reference, i)] = res.Attributes
+			lst = append(lst, res.Attributes)
+		}
+		inFieldMap[fmt.Sprintf("%v", reference)] = lst
 	}
 
-	return responses, commitErr
+	if responseObjects != nil {
+
+		api2goModel, ok := responseObjects.(api2go.Response)
+		if ok {
+			responseObjects = api2goModel.Result().(api2go.Api2GoModel).GetAttributes()
+		}
+
+		singleResult, isSingleResult := responseObjects.(map[string]interface{})
+
+		if isSingleResult {
+			inFieldMap[reference] = singleResult
+		} else {
+			resultArray, ok := responseObjects.([]map[string]interface{})
+
+			finalArray := make([]map[string]interface{}, 0)
+			if ok {
+				for i, item := range resultArray {
+					finalArray = append(finalArray, item)
+					inFieldMap[fmt.Sprintf("%v[%v]", reference, i)] = item
+				}
+			}
+			inFieldMap[reference] = finalArray
+
+		}
+	}
 }
 
 func BuildActionRequest(closer io.ReadCloser, actionType, actionName string,
@@ -831,32 +995,6 @@ func BuildOutcome(inFieldMap map[string]interface{}, outcome Outcome) (*api2go.A
 
 }
 
-func runUnsafeJavascript(unsafe string, contextMap map[string]interface{}) (interface{}, error) {
-
-	vm := goja.New()
-
-	//vm.ToValue(contextMap)
-	for key, val := range contextMap {
-		vm.Set(key, val)
-	}
-
-	vm.Set("btoa", func(data []byte) string {
-		return base64.StdEncoding.EncodeToString(data)
-	})
-
-	vm.Set("uuid", func() string {
-		u, _ := uuid.NewV7()
-		return u.String()
-	})
-	v, err := vm.RunString(unsafe) // Here be dragons (risky code)
-
-	if err != nil {
-		return nil, err
-	}
-
-	return v.Export(), nil
-}
-
 func BuildActionContext(outcomeAttributes interface{}, inFieldMap map[string]interface{}) (interface{}, error) {
 
 	var data interface{}
@@ -960,7 +1098,7 @@ func evaluateString(fieldString string, inFieldMap map[string]interface{}) (inte
 
 		res, err := runUnsafeJavascript(fieldString[1:], inFieldMap)
 		if err != nil {
-			return nil, fmt.Errorf("failed to evaluate JS in outcome attribute for key %s: %v", fieldString, err)
+			return nil, fmt.Errorf("failed to evaluate JS in outcome attribute for key %s: %w", fieldString, err)
 		}
 		val = res
 
@@ -969,7 +1107,7 @@ func evaluateString(fieldString string, inFieldMap map[string]interface{}) (inte
 		jsString := fieldString[2 : len(fieldString)-2]
 		res, err := runUnsafeJavascript(jsString, inFieldMap)
 		if err != nil {
-			return nil, fmt.Errorf("failed to evaluate JS in outcome attribute for key %s: %v", fieldString, err)
+			return nil, fmt.Errorf("failed to evaluate JS in outcome attribute for key %s: %w", fieldString, err)
 		}
 		val = res
 
@@ -977,7 +1115,7 @@ func evaluateString(fieldString string, inFieldMap map[string]interface{}) (inte
 
 		res, err := runUnsafeJavascript(fieldString[1:], inFieldMap)
 		if err != nil {
-			return nil, fmt.Errorf("failed to evaluate JS in outcome attribute for key %s: %v", fieldString, err)
+			return nil, fmt.Errorf("failed to evaluate JS in outcome attribute for key %s: %w", fieldString, err)
 		}
 		val = res
 
@@ -1103,5 +1241,19 @@ func GetValidatedInFields(actionRequest ActionRequest, action Action) (map[strin
 		}
 	}
 
+	validationErrors, err := ValidateActionInputs(action, dataMap)
+	if err != nil {
+		return nil, err
+	}
+	if len(validationErrors) > 0 {
+		return nil, NewValidationError(validationErrors)
+	}
+	// the values described by the schema are passed on as they are, nested objects and lists included
+	for _, name := range inputSchemaProperties(action) {
+		if val, ok := dataMap[name]; ok {
+			finalDataMap[name] = val
+		}
+	}
+
 	return finalDataMap, nil
 }
@@ -0,0 +1,69 @@
+package resource
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/artpar/api2go"
+	"github.com/daptin/daptin/server/auth"
+	daptinid "github.com/daptin/daptin/server/id"
+	"github.com/google/uuid"
+)
+
+func TestActionOnSubjectChecksSubjectPermission(t *testing.T) {
+	ticket := TableInfo{TableName: "ticket", Columns: []api2go.ColumnInfo{
+		{Name: "title", ColumnName: "title", ColumnType: "label", DataType: "varchar(100)", IsNullable: true},
+	}}
+	db, cruds := newTestCruds(t, CmsConfig{
+		Tables: []TableInfo{ticket},
+		Actions: []Action{{
+			Name:   "close_ticket",
+			OnType: "ticket",
+			OutFields: []Outcome{{
+				Type:       "client.notify",
+				Method:     "ACTIONRESPONSE",
+				Attributes: map[string]interface{}{"message": "closed"},
+			}},
+		}},
+	})
+
+	cruds["ticket"].ms = &MiddlewareSet{}
+
+	owner := daptinid.DaptinReferenceId(uuid.New())
+	other := daptinid.DaptinReferenceId(uuid.New())
+	for _, user := range []daptinid.DaptinReferenceId{owner, other} {
+		db.MustExec("insert into user_account (name, email, password, reference_id, permission) values (?, ?, ?, ?, ?)",
+			"user", user.String()+"@example.com", "secret", user[:], auth.DEFAULT_PERMISSION)
+	}
+	var ownerId int64
+	if err := db.Get(&ownerId, "select id from user_account where reference_id = ?", owner[:]); err != nil {
+		t.Fatalf("failed to read the owner: %v", err)
+	}
+	subject := daptinid.DaptinReferenceId(uuid.New())
+	db.MustExec("insert into ticket (title, reference_id, permission, user_account_id) values (?, ?, ?, ?)",
+		"broken", subject[:], auth.UserRead|auth.UserExecute, ownerId)
+
+	closeTicket := func(user daptinid.DaptinReferenceId) error {
+		sessionUser := &auth.SessionUser{UserReferenceId: user}
+		request := httptest.NewRequest("POST", "/action/ticket/close_ticket", nil)
+		request = request.WithContext(context.WithValue(request.Context(), "user", sessionUser))
+		transaction := db.MustBegin()
+		defer transaction.Rollback()
+		_, err := cruds["ticket"].HandleActionRequest(ActionRequest{
+			Type:       "ticket",
+			Action:     "close_ticket",
+			Attributes: map[string]interface{}{"ticket_id": subject.String()},
+		}, api2go.Request{PlainRequest: request}, transaction)
+		return err
+	}
+
+	if err := closeTicket(owner); err != nil {
+		t.Errorf("expected the owner of the ticket to run the action, got %v", err)
+	}
+	err := closeTicket(other)
+	httpErr, ok := err.(api2go.HTTPError)
+	if !ok || httpErr.Status() != 403 {
+		t.Errorf("expected another user to be forbidden, got %v", err)
+	}
+}
@@ -0,0 +1,344 @@
+package resource
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/daptin/daptin/server/auth"
+	"github.com/daptin/daptin/server/statementbuilder"
+	"github.com/doug-martin/goqu/v9"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+)
+
+// Translations of the rows of a table with TranslationsEnabled are kept in <table>_i18n, one row per language
+// pointing to the translated row by translation_reference_id. The table itself holds the values in the default
+// language. A column left null in a translation falls back to the next language of the preferences, and in the end
+// to the value in the table.
+
+// TranslationTableName is the table holding the translations of the rows of a table
+func TranslationTableName(tableName string) string {
+	return tableName + "_i18n"
+}
+
+// TranslationFallbackChain keeps the languages up to the default language, whose values are the ones in the table
+// itself, without duplicates
+func TranslationFallbackChain(languages []string, defaultLanguage string) []string {
+	chain := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, language := range languages {
+		language = strings.TrimSpace(language)
+		if language == "" || seen[language] {
+			continue
+		}
+		if language == defaultLanguage {
+			break
+		}
+		seen[language] = true
+		chain = append(chain, language)
+	}
+	return chain
+}
+
+// WithTranslationLanguage makes the reads of the request prefer the language, and its writes go to the translation
+// of that language instead of the row itself. Asking for the default language reads and writes the row itself.
+func WithTranslationLanguage(ctx context.Context, language string) context.Context {
+	defaultLanguage, _ := ctx.Value("language_default").(string)
+	if language == defaultLanguage {
+		ctx = context.WithValue(ctx, "language_preference", []string{})
+		return context.WithValue(ctx, "language", "")
+	}
+
+	preferences := []string{language}
+	if existing, ok := ctx.Value("language_preference").([]string); ok {
+		preferences = append(preferences, existing...)
+	}
+	if fallback, ok := ctx.Value("language_fallback").([]string); ok {
+		preferences = append(preferences, fallback...)
+	}
+	ctx = context.WithValue(ctx, "language_preference", TranslationFallbackChain(preferences, defaultLanguage))
+	return context.WithValue(ctx, "language", language)
+}
+
+// TranslationLanguagePreferences are the languages to read the row in, most preferred first, empty when the values
+// in the default language are wanted or the table has no translations
+func (dbResource *DbResource) TranslationLanguagePreferences(ctx context.Context) []string {
+	if !dbResource.tableInfo.TranslationsEnabled {
+		return nil
+	}
+	preferences, _ := ctx.Value("language_preference").([]string)
+	return preferences
+}
+
+// TranslationLanguage is the language a write goes to, empty when the row itself is to be written
+func (dbResource *DbResource) TranslationLanguage(ctx context.Context) string {
+	if !dbResource.tableInfo.TranslationsEnabled {
+		return ""
+	}
+	language, _ := ctx.Value("language").(string)
+	return language
+}
+
+// translatedColumns are the columns of the table which have a value in the translation table
+func (dbResource *DbResource) translatedColumns() []string {
+	columns := make([]string, 0)
+	for _, col := range dbResource.tableInfo.Columns {
+		if IsStandardColumn(col.ColumnName) || col.IsForeignKey {
+			continue
+		}
+		columns = append(columns, col.ColumnName)
+	}
+	return columns
+}
+
+// TranslateRow replaces the values of the row with their translation in the first of the languages which has one
+func (dbResource *DbResource) TranslateRow(row map[string]interface{}, languages []string, transaction *sqlx.Tx) error {
+	if len(languages) == 0 || row["id"] == nil {
+		return nil
+	}
+
+	s, v, err := statementbuilder.Squirrel.Select(goqu.Star()).Prepared(true).
+		From(TranslationTableName(dbResource.tableInfo.TableName)).
+		Where(goqu.Ex{
+			"translation_reference_id": row["id"],
+			"language_id":              languages,
+		}).ToSQL()
+	if err != nil {
+		return err
+	}
+
+	stmt1, err := transaction.Preparex(s)
+	if err != nil {
+		log.Errorf("[95] failed to prepare statment: %v", err)
+		return err
+	}
+	defer stmt1.Close()
+
+	rows, err := stmt1.Queryx(v...)
+	if err != nil {
+		return err
+	}
+	translations, err := RowsToMap(rows, TranslationTableName(dbResource.tableInfo.TableName))
+	rows.Close()
+	if err != nil {
+		return err
+	}
+
+	translationByLanguage := make(map[string]map[string]interface{})
+	for _, translation := range translations {
+		translationByLanguage[fmt.Sprintf("%v", translation["language_id"])] = translation
+	}
+
+	for _, columnName := range dbResource.translatedColumns() {
+		for _, language := range languages {
+			translation, ok := translationByLanguage[language]
+			if !ok || translation[columnName] == nil {
+				continue
+			}
+			row[columnName] = translation[columnName]
+			break
+		}
+	}
+	return nil
+}
+
+func (dbResource *DbResource) getTranslationId(rowId interface{}, language string, transaction *sqlx.Tx) (int64, bool, error) {
+	s, v, err := statementbuilder.Squirrel.Select("id").Prepared(true).
+		From(TranslationTableName(dbResource.tableInfo.TableName)).
+		Where(goqu.Ex{
+			"translation_reference_id": rowId,
+			"language_id":              language,
+		}).ToSQL()
+	if err != nil {
+		return 0, false, err
+	}
+
+	stmt1, err := transaction.Preparex(s)
+	if err != nil {
+		log.Errorf("[139] failed to prepare statment: %v", err)
+		return 0, false, err
+	}
+	defer stmt1.Close()
+
+	rows, err := stmt1.Queryx(v...)
+	if err != nil {
+		return 0, false, err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		return 0, false, rows.Err()
+	}
+	var translationId int64
+	err = rows.Scan(&translationId)
+	return translationId, err == nil, err
+}
+
+// SaveTranslation writes the values as the translation of the row in the language, values of columns which are not
+// translated are ignored
+func (dbResource *DbResource) SaveTranslation(rowId interface{}, language string, values map[string]interface{}, transaction *sqlx.Tx) error {
+	translationTableName := TranslationTableName(dbResource.tableInfo.TableName)
+
+	record := goqu.Record{}
+	for _, columnName := range dbResource.translatedColumns() {
+		value, ok := values[columnName]
+		if !ok {
+			continue
+		}
+		record[columnName] = value
+	}
+	record["updated_at"] = time.Now()
+
+	translationId, exists, err := dbResource.getTranslationId(rowId, language, transaction)
+	if err != nil {
+		return err
+	}
+
+	var s string
+	var v []interface{}
+	if exists {
+		s, v, err = statementbuilder.Squirrel.Update(translationTableName).Prepared(true).
+			Set(record).Where(goqu.Ex{"id": translationId}).ToSQL()
+	} else {
+		u, _ := uuid.NewV7()
+		record["reference_id"] = u[:]
+		record["permission"] = auth.DEFAULT_PERMISSION
+		record["created_at"] = time.Now()
+		record["language_id"] = language
+		record["translation_reference_id"] = rowId
+		s, v, err = statementbuilder.Squirrel.Insert(translationTableName).Prepared(true).Rows(record).ToSQL()
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = transaction.Exec(s, v...)
+	if err != nil {
+		log.Errorf("Failed to save [%v] translation of [%v][%v]: %v", language, dbResource.tableInfo.TableName, rowId, err)
+	}
+	return err
+}
+
+// DeleteTranslation removes the translation of the row in the language, the row falls back to the next language
+func (dbResource *DbResource) DeleteTranslation(rowId interface{}, language string, transaction *sqlx.Tx) error {
+	s, v, err := statementbuilder.Squirrel.Delete(TranslationTableName(dbResource.tableInfo.TableName)).Prepared(true).
+		Where(goqu.Ex{
+			"translation_reference_id": rowId,
+			"language_id":              language,
+		}).ToSQL()
+	if err != nil {
+		return err
+	}
+	_, err = transaction.Exec(s, v...)
+	return err
+}
+
+// TranslationCoverage is the number of rows of a table translated into each language, along with the number of
+// rows with a translated value for each column
+type TranslationCoverage struct {
+	TableName string                      `json:"table_name"`
+	RowCount  int64                       `json:"row_count"`
+	Languages map[string]LanguageCoverage `json:"languages"`
+}
+
+type LanguageCoverage struct {
+	RowCount int64            `json:"row_count"`
+	Percent  float64          `json:"percent"`
+	Columns  map[string]int64 `json:"columns"`
+}
+
+// GetTranslationCoverage counts the translations of the rows of the table
+func GetTranslationCoverage(tableInfo TableInfo, transaction *sqlx.Tx) (TranslationCoverage, error) {
+	coverage := TranslationCoverage{
+		TableName: tableInfo.TableName,
+		Languages: make(map[string]LanguageCoverage),
+	}
+
+	s, v, err := statementbuilder.Squirrel.Select(goqu.COUNT("*")).Prepared(true).From(tableInfo.TableName).ToSQL()
+	if err != nil {
+		return coverage, err
+	}
+	stmt1, err := transaction.Preparex(s)
+	if err != nil {
+		log.Errorf("[249] failed to prepare statment: %v", err)
+		return coverage, err
+	}
+	err = stmt1.QueryRowx(v...).Scan(&coverage.RowCount)
+	stmt1.Close()
+	if err != nil {
+		return coverage, err
+	}
+
+	columnNames := make([]string, 0)
+	projections := []interface{}{goqu.C("language_id"), goqu.COUNT(goqu.DISTINCT("translation_reference_id")).As("row_count")}
+	for _, col := range tableInfo.Columns {
+		if IsStandardColumn(col.ColumnName) || col.IsForeignKey {
+			continue
+		}
+		columnNames = append(columnNames, col.ColumnName)
+		projections = append(projections, goqu.COUNT(col.ColumnName).As(col.ColumnName))
+	}
+	sort.Strings(columnNames)
+
+	s, v, err = statementbuilder.Squirrel.Select(projections...).Prepared(true).
+		From(TranslationTableName(tableInfo.TableName)).GroupBy("language_id").ToSQL()
+	if err != nil {
+		return coverage, err
+	}
+	stmt1, err = transaction.Preparex(s)
+	if err != nil {
+		log.Errorf("[275] failed to prepare statment: %v", err)
+		return coverage, err
+	}
+	defer stmt1.Close()
+
+	rows, err := stmt1.Queryx(v...)
+	if err != nil {
+		return coverage, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		row := make(map[string]interface{})
+		err = rows.MapScan(row)
+		if err != nil {
+			return coverage, err
+		}
+
+		language := fmt.Sprintf("%s", row["language_id"])
+		languageCoverage := LanguageCoverage{
+			RowCount: toInt64(row["row_count"]),
+			Columns:  make(map[string]int64),
+		}
+		if coverage.RowCount > 0 {
+			languageCoverage.Percent = float64(languageCoverage.RowCount) * 100 / float64(coverage.RowCount)
+		}
+		for _, columnName := range columnNames {
+			languageCoverage.Columns[columnName] = toInt64(row[columnName])
+		}
+		coverage.Languages[language] = languageCoverage
+	}
+
+	return coverage, rows.Err()
+}
+
+func toInt64(value interface{}) int64 {
+	switch v := value.(type) {
+	case int64:
+		return v
+	case int:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case float64:
+		return int64(v)
+	case []byte:
+		var n int64
+		fmt.Sscanf(string(v), "%d", &n)
+		return n
+	}
+	return 0
+}
@@ -0,0 +1,32 @@
+package resource
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestTranslationFallbackChain(t *testing.T) {
+
+	chain := TranslationFallbackChain([]string{"fr", " de", "fr", "", "en", "es"}, "en")
+	if !reflect.DeepEqual(chain, []string{"fr", "de"}) {
+		t.Errorf("unexpected chain %v", chain)
+	}
+
+	ctx := context.WithValue(context.Background(), "language_default", "en")
+	ctx = context.WithValue(ctx, "language_fallback", []string{"es"})
+	ctx = context.WithValue(ctx, "language_preference", []string{"de"})
+
+	translated := WithTranslationLanguage(ctx, "fr")
+	if translated.Value("language") != "fr" {
+		t.Errorf("expected writes to go to fr, got %v", translated.Value("language"))
+	}
+	if !reflect.DeepEqual(translated.Value("language_preference"), []string{"fr", "de", "es"}) {
+		t.Errorf("unexpected preferences %v", translated.Value("language_preference"))
+	}
+
+	defaultLanguage := WithTranslationLanguage(ctx, "en")
+	if defaultLanguage.Value("language") != "" || len(defaultLanguage.Value("language_preference").([]string)) != 0 {
+		t.Errorf("the default language should read and write the row itself")
+	}
+}
@@ -0,0 +1,348 @@
+package resource
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/daptin/daptin/server/auth"
+	"github.com/daptin/daptin/server/database"
+	"github.com/daptin/daptin/server/statementbuilder"
+	"github.com/doug-martin/goqu/v9"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// A POST to /action/... or /api/... with an Idempotency-Key header is run only once for that key. The key, a hash of
+// the request and the response are kept in the idempotency_key table for the window. A retry with the same key and
+// the same request gets the stored response back, with the Idempotent-Replayed header set, without running again.
+// The same key with a different request is rejected, as is a retry while the first request is still running. A running
+// request holds the key for a short lease, after a crash the key can be used again once the lease ran out. Keys are
+// scoped to the signed in user. Responses with a 5xx status, of a request which panicked, or which hand out a token,
+// like the one of signin, are not kept, the request can be retried.
+
+const (
+	IdempotencyKeyHeader          = "Idempotency-Key"
+	IdempotentReplayedHeader      = "Idempotent-Replayed"
+	IdempotencyKeyTableName       = "idempotency_key"
+	DefaultIdempotencyWindowHours = 24
+	IdempotencyRunningLease       = 5 * time.Minute
+	maxIdempotencyKeyLength       = 255
+	maxIdempotentResponseSize     = 1 << 20
+)
+
+// idempotentRequestPrefixes are the paths of the requests an idempotency key is honoured on
+var idempotentRequestPrefixes = []string{"/action/", "/api/"}
+
+type idempotencyRecord struct {
+	RequestHash    string         `db:"request_hash"`
+	ResponseStatus int            `db:"response_status"`
+	Response       sql.NullString `db:"response"`
+	ContentType    sql.NullString `db:"content_type"`
+}
+
+// idempotentResponseWriter keeps a copy of the response written to the client
+type idempotentResponseWriter struct {
+	gin.ResponseWriter
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (writer *idempotentResponseWriter) Write(data []byte) (int, error) {
+	writer.keep(data)
+	return writer.ResponseWriter.Write(data)
+}
+
+func (writer *idempotentResponseWriter) WriteString(data string) (int, error) {
+	writer.keep([]byte(data))
+	return writer.ResponseWriter.WriteString(data)
+}
+
+func (writer *idempotentResponseWriter) keep(data []byte) {
+	if writer.overflow || writer.body.Len()+len(data) > maxIdempotentResponseSize {
+		writer.overflow = true
+		return
+	}
+	writer.body.Write(data)
+}
+
+type IdempotencyMiddleware struct {
+	db     database.DatabaseConnection
+	window time.Duration
+}
+
+// idempotencyKeyPurge removes the expired keys once an hour, with the database of the latest middleware
+type idempotencyKeyPurge struct {
+	lock    sync.RWMutex
+	db      database.DatabaseConnection
+	started bool
+}
+
+var idempotencyKeys = &idempotencyKeyPurge{}
+
+// NewIdempotencyMiddleware keeps the responses of requests with an idempotency key for the window, and removes the
+// expired keys once an hour. The server is created again on every reload, the keys are removed by a single loop.
+func NewIdempotencyMiddleware(db database.DatabaseConnection, window time.Duration) *IdempotencyMiddleware {
+	middleware := &IdempotencyMiddleware{
+		db:     db,
+		window: window,
+	}
+
+	idempotencyKeys.lock.Lock()
+	idempotencyKeys.db = db
+	started := idempotencyKeys.started
+	idempotencyKeys.started = true
+	idempotencyKeys.lock.Unlock()
+
+	if !started {
+		go func() {
+			for range time.Tick(time.Hour) {
+				idempotencyKeys.lock.RLock()
+				db := idempotencyKeys.db
+				idempotencyKeys.lock.RUnlock()
+				CheckErr(purgeIdempotencyKeys(db), "Failed to remove expired idempotency keys")
+			}
+		}()
+	}
+	return middleware
+}
+
+func isIdempotentRequest(c *gin.Context) bool {
+	if c.Request.Method != "POST" || c.GetHeader(IdempotencyKeyHeader) == "" {
+		return false
+	}
+	for _, prefix := range idempotentRequestPrefixes {
+		if strings.HasPrefix(c.Request.URL.Path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (middleware *IdempotencyMiddleware) IdempotencyMiddlewareFunc(c *gin.Context) {
+	if !isIdempotentRequest(c) {
+		c.Next()
+		return
+	}
+
+	key := c.GetHeader(IdempotencyKeyHeader)
+	if len(key) > maxIdempotencyKeyLength {
+		c.AbortWithStatusJSON(400, gin.H{"error": "idempotency key is too long"})
+		return
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.AbortWithStatusJSON(400, gin.H{"error": "failed to read request: " + err.Error()})
+		return
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	scopedKey := idempotencyScopedKey(c, key)
+	requestHash := idempotencyRequestHash(c, body)
+
+	started := false
+	record, found, err := middleware.find(scopedKey)
+	if err == nil && !found {
+		started, err = middleware.start(scopedKey, requestHash)
+		if err == nil && !started {
+			// another request with the same key started first
+			record, found, err = middleware.find(scopedKey)
+		}
+	}
+	if err != nil {
+		log.Errorf("Failed to check idempotency key: %v", err)
+		c.AbortWithStatusJSON(500, gin.H{"error": "failed to check idempotency key"})
+		return
+	}
+
+	if !started {
+		if !found {
+			c.AbortWithStatusJSON(409, gin.H{"error": "a request with this idempotency key is still running"})
+			return
+		}
+		if record.RequestHash != requestHash {
+			c.AbortWithStatusJSON(422, gin.H{"error": "idempotency key was already used for a different request"})
+			return
+		}
+		if record.ResponseStatus == 0 {
+			c.AbortWithStatusJSON(409, gin.H{"error": "a request with this idempotency key is still running"})
+			return
+		}
+		if record.ContentType.Valid && record.ContentType.String != "" {
+			c.Header("Content-Type", record.ContentType.String)
+		}
+		c.Header(IdempotentReplayedHeader, "true")
+		c.Status(record.ResponseStatus)
+		_, err = c.Writer.Write([]byte(record.Response.String))
+		CheckErr(err, "Failed to write replayed response")
+		c.Abort()
+		return
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			CheckErr(middleware.forget(scopedKey), "Failed to remove idempotency key")
+			panic(r)
+		}
+	}()
+
+	writer := &idempotentResponseWriter{ResponseWriter: c.Writer}
+	c.Writer = writer
+	c.Next()
+	c.Writer = writer.ResponseWriter
+
+	status := writer.Status()
+	if status >= 500 || writer.overflow || isSensitiveResponse(writer.body.Bytes()) {
+		CheckErr(middleware.forget(scopedKey), "Failed to remove idempotency key")
+		return
+	}
+	CheckErr(middleware.finish(scopedKey, status, writer.Header().Get("Content-Type"), writer.body.String()),
+		"Failed to store response for idempotency key")
+}
+
+// idempotencyScopedKey is the key of the request for the signed in user
+func idempotencyScopedKey(c *gin.Context, key string) string {
+	scope := ""
+	if sessionUser, ok := c.Request.Context().Value("user").(*auth.SessionUser); ok && sessionUser != nil {
+		scope = sessionUser.UserReferenceId.String()
+	}
+	hash := sha256.Sum256([]byte(scope + "\n" + key))
+	return hex.EncodeToString(hash[:])
+}
+
+func idempotencyRequestHash(c *gin.Context, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(c.Request.Method + "\n" + c.Request.URL.Path + "\n" + c.Request.URL.RawQuery + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// isSensitiveResponse is true for a response which hands out a token, a json web token or the value of a key and
+// value pair with a sensitive key, which are masked in the execution log
+func isSensitiveResponse(body []byte) bool {
+	if jwtPattern.Match(body) {
+		return true
+	}
+	var value interface{}
+	if json.Unmarshal(body, &value) != nil {
+		return false
+	}
+	return hasSensitivePair(value)
+}
+
+func hasSensitivePair(value interface{}) bool {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		if pairKey, ok := typed["key"].(string); ok && isSensitiveKey(pairKey) && typed["value"] != nil {
+			return true
+		}
+		for _, item := range typed {
+			if hasSensitivePair(item) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, item := range typed {
+			if hasSensitivePair(item) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// find gives the unexpired record of the key
+func (middleware *IdempotencyMiddleware) find(key string) (idempotencyRecord, bool, error) {
+	var record idempotencyRecord
+	s, v, err := statementbuilder.Squirrel.Select("request_hash", "response_status", "response", "content_type").
+		Prepared(true).From(IdempotencyKeyTableName).
+		Where(goqu.Ex{"idempotency_key": key}, goqu.C("expires_at").Gt(time.Now())).ToSQL()
+	if err != nil {
+		return record, false, err
+	}
+	err = middleware.db.Get(&record, s, v...)
+	if err == sql.ErrNoRows {
+		return record, false, nil
+	}
+	return record, err == nil, err
+}
+
+// start keeps the key for a request which is running, false when another request holds the key. The key is held
+// for the lease, finish keeps it for the window.
+func (middleware *IdempotencyMiddleware) start(key string, requestHash string) (bool, error) {
+	now := time.Now()
+	s, v, err := statementbuilder.Squirrel.Delete(IdempotencyKeyTableName).Prepared(true).
+		Where(goqu.Ex{"idempotency_key": key}, goqu.C("expires_at").Lte(now)).ToSQL()
+	if err != nil {
+		return false, err
+	}
+	if _, err = middleware.db.Exec(s, v...); err != nil {
+		return false, err
+	}
+
+	u, _ := uuid.NewV7()
+	s, v, err = statementbuilder.Squirrel.Insert(IdempotencyKeyTableName).Prepared(true).Rows(goqu.Record{
+		"idempotency_key": key,
+		"request_hash":    requestHash,
+		"response_status": 0,
+		"expires_at":      now.Add(IdempotencyRunningLease),
+		"reference_id":    u[:],
+		"permission":      auth.DEFAULT_PERMISSION,
+		"created_at":      now,
+	}).ToSQL()
+	if err != nil {
+		return false, err
+	}
+	if _, err = middleware.db.Exec(s, v...); err != nil {
+		if IsUniqueConstraintError(err) {
+			// the key is unique, the insert fails when another request holds it
+			log.Debugf("Idempotency key already held: %v", err)
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (middleware *IdempotencyMiddleware) finish(key string, status int, contentType string, response string) error {
+	s, v, err := statementbuilder.Squirrel.Update(IdempotencyKeyTableName).Prepared(true).Set(goqu.Record{
+		"response_status": status,
+		"content_type":    contentType,
+		"response":        response,
+		"expires_at":      time.Now().Add(middleware.window),
+		"updated_at":      time.Now(),
+	}).Where(goqu.Ex{"idempotency_key": key}).ToSQL()
+	if err != nil {
+		return err
+	}
+	_, err = middleware.db.Exec(s, v...)
+	return err
+}
+
+// forget removes the key, a retry with it runs again
+func (middleware *IdempotencyMiddleware) forget(key string) error {
+	s, v, err := statementbuilder.Squirrel.Delete(IdempotencyKeyTableName).Prepared(true).
+		Where(goqu.Ex{"idempotency_key": key}).ToSQL()
+	if err != nil {
+		return err
+	}
+	_, err = middleware.db.Exec(s, v...)
+	return err
+}
+
+func purgeIdempotencyKeys(db database.DatabaseConnection) error {
+	s, v, err := statementbuilder.Squirrel.Delete(IdempotencyKeyTableName).Prepared(true).
+		Where(goqu.C("expires_at").Lte(time.Now())).ToSQL()
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(s, v...)
+	return err
+}
@@ -9,7 +9,7 @@ import (
 )
 
 type DaptinImapBackend struct {
-	cruds map[string]*DbResource
+	resources func() map[string]*DbResource
 }
 
 func (be *DaptinImapBackend) LoginMd5(conn *imap.ConnInfo, username, challenge string, response string) (backend.User, error) {
@@ -46,7 +46,8 @@ func (be *DaptinImapBackend) LoginMd5(conn *imap.ConnInfo, username, challenge s
 
 func (be *DaptinImapBackend) Login(conn *imap.ConnInfo, username, password string) (backend.User, error) {
 
-	userAccountResource := be.cruds[USER_ACCOUNT_TABLE_NAME]
+	cruds := be.resources()
+	userAccountResource := cruds[USER_ACCOUNT_TABLE_NAME]
 	transaction, err := userAccountResource.Connection.Beginx()
 	if err != nil {
 		CheckErr(err, "Failed to begin transaction [51]")
@@ -74,7 +75,7 @@ func (be *DaptinImapBackend) Login(conn *imap.ConnInfo, username, password strin
 			username:               username,
 			mailAccountId:          userMailAccount["id"].(int64),
 			mailAccountReferenceId: userMailAccount["reference_id"].(string),
-			dbResource:             be.cruds,
+			dbResource:             cruds,
 			sessionUser:            sessionUser,
 		}, nil
 	}
@@ -82,8 +83,9 @@ func (be *DaptinImapBackend) Login(conn *imap.ConnInfo, username, password strin
 	return nil, errors.New("bad username or password")
 }
 
-func NewImapServer(cruds map[string]*DbResource) *DaptinImapBackend {
+// NewImapServer creates the backend of the imap server, a user signing in gets the resources of the latest load
+func NewImapServer(resources func() map[string]*DbResource) *DaptinImapBackend {
 	return &DaptinImapBackend{
-		cruds: cruds,
+		resources: resources,
 	}
 }
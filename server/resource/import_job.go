@@ -0,0 +1,668 @@
+package resource
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/artpar/api2go"
+	"github.com/buraksezer/olric"
+	"github.com/daptin/daptin/server/auth"
+	"github.com/daptin/daptin/server/database"
+	daptinid "github.com/daptin/daptin/server/id"
+	"github.com/daptin/daptin/server/statementbuilder"
+	"github.com/doug-martin/goqu/v9"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+	"github.com/xuri/excelize/v2"
+)
+
+// Uploaded csv and xlsx files are imported in the background by an import job. The rows are imported in batches, each
+// batch is committed along with the progress of the job, so a failed job resumes after the last committed row. Rows
+// which cannot be imported are written with the reason to the error report of the job, and every committed batch is
+// published on the import_job topic.
+
+const ImportJobTableName = "import_job"
+
+const (
+	ImportJobPending   = "pending"
+	ImportJobRunning   = "running"
+	ImportJobCompleted = "completed"
+	ImportJobFailed    = "failed"
+)
+
+const DefaultImportBatchSize = 1000
+
+type ImportJob struct {
+	Id              int64
+	ReferenceId     daptinid.DaptinReferenceId
+	EntityName      string
+	FileName        string
+	FilePath        string
+	FileType        string
+	Status          string
+	BatchSize       int
+	TotalRows       int
+	ProcessedRows   int
+	ImportedRows    int
+	FailedRows      int
+	ErrorReport     string
+	LastError       string
+	UserId          int64
+	UserReferenceId daptinid.DaptinReferenceId
+}
+
+// EventData is the progress of the job as published on the import_job topic
+func (job ImportJob) EventData() map[string]interface{} {
+	return map[string]interface{}{
+		"__type":         ImportJobTableName,
+		"reference_id":   uuid.UUID(job.ReferenceId).String(),
+		"entity_name":    job.EntityName,
+		"file_name":      job.FileName,
+		"status":         job.Status,
+		"batch_size":     job.BatchSize,
+		"total_rows":     job.TotalRows,
+		"processed_rows": job.ProcessedRows,
+		"imported_rows":  job.ImportedRows,
+		"failed_rows":    job.FailedRows,
+		"last_error":     job.LastError,
+	}
+}
+
+var runningImportJobs sync.Map
+
+// importJobFolder keeps the uploaded files until their import completes, a failed import resumes from the same file
+func importJobFolder() (string, error) {
+	baseFolder, ok := os.LookupEnv("DAPTIN_SCHEMA_FOLDER")
+	if !ok || baseFolder == "" {
+		baseFolder = os.TempDir()
+	}
+	folder := filepath.Join(baseFolder, "daptin_imports")
+	return folder, os.MkdirAll(folder, 0755)
+}
+
+// ImportBatchSize is the number of rows committed together, as asked in the action or else as configured in
+// import.batch_size
+func ImportBatchSize(value interface{}, configStore *ConfigStore, transaction *sqlx.Tx) int {
+	batchSize := 0
+	switch v := value.(type) {
+	case float64:
+		batchSize = int(v)
+	case int64:
+		batchSize = int(v)
+	case int:
+		batchSize = v
+	case string:
+		batchSize, _ = strconv.Atoi(v)
+	}
+	if batchSize > 0 {
+		return batchSize
+	}
+
+	if configStore != nil {
+		configured, err := configStore.GetConfigIntValueFor("import.batch_size", "backend", transaction)
+		if err == nil && configured > 0 {
+			return configured
+		}
+	}
+	return DefaultImportBatchSize
+}
+
+// CreateImportJob stores the file and a pending job to import it into the entity. The job is started by
+// StartImportJob once the transaction is committed.
+func CreateImportJob(entityName string, fileName string, fileType string, fileBytes []byte, batchSize int,
+	user map[string]interface{}, transaction *sqlx.Tx) (ImportJob, error) {
+
+	if batchSize < 1 {
+		batchSize = DefaultImportBatchSize
+	}
+
+	u, _ := uuid.NewV7()
+	job := ImportJob{
+		ReferenceId: daptinid.DaptinReferenceId(u),
+		EntityName:  entityName,
+		FileName:    fileName,
+		FileType:    fileType,
+		Status:      ImportJobPending,
+		BatchSize:   batchSize,
+	}
+
+	folder, err := importJobFolder()
+	if err != nil {
+		return job, err
+	}
+	job.FilePath = filepath.Join(folder, fmt.Sprintf("%v.%v", u.String(), fileType))
+	err = os.WriteFile(job.FilePath, fileBytes, 0644)
+	if err != nil {
+		return job, err
+	}
+
+	record := goqu.Record{
+		"entity_name":    job.EntityName,
+		"file_name":      job.FileName,
+		"file_path":      job.FilePath,
+		"file_type":      job.FileType,
+		"status":         job.Status,
+		"batch_size":     job.BatchSize,
+		"total_rows":     0,
+		"processed_rows": 0,
+		"imported_rows":  0,
+		"failed_rows":    0,
+		"reference_id":   u[:],
+		"permission":     auth.DEFAULT_PERMISSION,
+		"created_at":     time.Now(),
+	}
+	if userReferenceIdBytes, ok := referenceIdBytes(user["reference_id"]); ok {
+		// the rows are imported as the user who uploaded the file
+		var userReferenceId daptinid.DaptinReferenceId
+		copy(userReferenceId[:], userReferenceIdBytes)
+		userId, err := GetReferenceIdToIdWithTransaction(USER_ACCOUNT_TABLE_NAME, userReferenceId, transaction)
+		if err != nil {
+			return job, fmt.Errorf("unknown user: %v", err)
+		}
+		record[USER_ACCOUNT_ID_COLUMN] = userId
+		job.UserId = userId
+		job.UserReferenceId = userReferenceId
+	}
+
+	s, v, err := statementbuilder.Squirrel.Insert(ImportJobTableName).Prepared(true).Rows(record).ToSQL()
+	if err != nil {
+		return job, err
+	}
+	_, err = transaction.Exec(s, v...)
+	if err != nil {
+		os.Remove(job.FilePath)
+		return job, err
+	}
+	log.Infof("Created import job [%v] for [%v] into [%v]", u.String(), fileName, entityName)
+	return job, nil
+}
+
+type uploadedImportFile struct {
+	Name     string
+	Contents []byte
+}
+
+// startImportJobs creates a job to import each of the uploaded files into the entity, the jobs start in the
+// background once the transaction of the action is committed
+func startImportJobs(entityName string, fileType string, files []uploadedImportFile, batchSize int,
+	user map[string]interface{}, cruds map[string]*DbResource, transaction *sqlx.Tx) ([]ActionResponse, []error) {
+
+	responses := make([]ActionResponse, 0)
+	for _, file := range files {
+		job, err := CreateImportJob(entityName, file.Name, fileType, file.Contents, batchSize, user, transaction)
+		if err != nil {
+			return nil, []error{fmt.Errorf("failed to create import job for [%v]: %v", file.Name, err)}
+		}
+		StartImportJob(job.ReferenceId, cruds)
+
+		responses = append(responses, NewActionResponse("client.notify", NewClientNotification("success",
+			fmt.Sprintf("Importing %v into %v in the background", file.Name, entityName), "Import started")))
+		responses = append(responses, NewActionResponse(ImportJobTableName, job.EventData()))
+	}
+	return responses, nil
+}
+
+var importJobColumns = []interface{}{"id", "reference_id", "entity_name", "file_name", "file_path", "file_type",
+	"status", "batch_size", "total_rows", "processed_rows", "imported_rows", "failed_rows", "error_report",
+	"last_error", USER_ACCOUNT_ID_COLUMN}
+
+// GetImportJob reads the job, the second return value is false when there is no such job
+func GetImportJob(referenceId daptinid.DaptinReferenceId, transaction *sqlx.Tx) (ImportJob, bool, error) {
+	var job ImportJob
+
+	s, v, err := statementbuilder.Squirrel.Select(importJobColumns...).Prepared(true).
+		From(ImportJobTableName).Where(goqu.Ex{"reference_id": referenceId[:]}).ToSQL()
+	if err != nil {
+		return job, false, err
+	}
+	stmt1, err := transaction.Preparex(s)
+	if err != nil {
+		log.Errorf("[167] failed to prepare statment: %v", err)
+		return job, false, err
+	}
+	defer stmt1.Close()
+
+	var referenceIdBytes []byte
+	var errorReport, lastError sql.NullString
+	var userId sql.NullInt64
+	err = stmt1.QueryRowx(v...).Scan(&job.Id, &referenceIdBytes, &job.EntityName, &job.FileName, &job.FilePath,
+		&job.FileType, &job.Status, &job.BatchSize, &job.TotalRows, &job.ProcessedRows, &job.ImportedRows,
+		&job.FailedRows, &errorReport, &lastError, &userId)
+	if err == sql.ErrNoRows {
+		return job, false, nil
+	}
+	if err != nil {
+		return job, false, err
+	}
+
+	job.ReferenceId = referenceId
+	job.ErrorReport = errorReport.String
+	job.LastError = lastError.String
+	if userId.Valid {
+		job.UserId = userId.Int64
+		job.UserReferenceId, err = GetIdToReferenceIdWithTransaction(USER_ACCOUNT_TABLE_NAME, userId.Int64, transaction)
+		if err != nil {
+			log.Warnf("[190] import job [%v] user [%v] not found: %v", job.EntityName, userId.Int64, err)
+		}
+	}
+	return job, true, nil
+}
+
+func saveImportJob(job ImportJob, transaction *sqlx.Tx) error {
+	record := goqu.Record{
+		"status":         job.Status,
+		"batch_size":     job.BatchSize,
+		"total_rows":     job.TotalRows,
+		"processed_rows": job.ProcessedRows,
+		"imported_rows":  job.ImportedRows,
+		"failed_rows":    job.FailedRows,
+		"error_report":   job.ErrorReport,
+		"last_error":     job.LastError,
+		"updated_at":     time.Now(),
+	}
+	if job.Status == ImportJobCompleted || job.Status == ImportJobFailed {
+		record["finished_at"] = time.Now()
+	}
+
+	s, v, err := statementbuilder.Squirrel.Update(ImportJobTableName).Prepared(true).
+		Set(record).Where(goqu.Ex{"id": job.Id}).ToSQL()
+	if err != nil {
+		return err
+	}
+	_, err = transaction.Exec(s, v...)
+	return err
+}
+
+// readImportFile calls handleRow with each row of a csv, ndjson or parquet file, or of the first sheet of a xlsx file,
+// reading the file a row at a time. Rows are numbered from 1, the header of a csv or xlsx file and the empty rows of
+// a xlsx sheet are not counted. The column names are returned once the whole file has been read.
+func readImportFile(filePath string, fileType string, handleRow func(number int, row map[string]interface{}) error) ([]string, error) {
+
+	switch fileType {
+	case "csv":
+		file, err := os.Open(filePath)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+
+		csvReader := csv.NewReader(file)
+		csvReader.FieldsPerRecord = -1
+		csvReader.ReuseRecord = true
+		header, err := csvReader.Read()
+		if err == io.EOF {
+			return nil, errors.New("csv file has no header")
+		}
+		if err != nil {
+			return nil, err
+		}
+		headers := make([]string, len(header))
+		for i, h := range header {
+			headers[i] = SmallSnakeCaseText(h)
+		}
+
+		for number := 1; ; number++ {
+			line, err := csvReader.Read()
+			if err == io.EOF {
+				return headers, nil
+			}
+			if err != nil {
+				return headers, err
+			}
+			row := make(map[string]interface{})
+			for i, value := range line {
+				if i >= len(headers) || strings.TrimSpace(value) == "" {
+					continue
+				}
+				row[headers[i]] = value
+			}
+			err = handleRow(number, row)
+			if err != nil {
+				return headers, err
+			}
+		}
+
+	case "xlsx":
+		xlsxFile, err := excelize.OpenFile(filePath)
+		if err != nil {
+			return nil, err
+		}
+		defer xlsxFile.Close()
+		sheets := xlsxFile.GetSheetList()
+		if len(sheets) == 0 {
+			return nil, errors.New("xlsx file has no sheets")
+		}
+		sheetRows, err := xlsxFile.Rows(sheets[0])
+		if err != nil {
+			return nil, err
+		}
+		defer sheetRows.Close()
+
+		headers := make([]string, 0)
+		if sheetRows.Next() {
+			header, err := sheetRows.Columns()
+			if err != nil {
+				return nil, err
+			}
+			for _, colName := range header {
+				if len(colName) < 1 {
+					break
+				}
+				headers = append(headers, SmallSnakeCaseText(colName))
+			}
+		}
+		if len(headers) == 0 {
+			return nil, errors.New("sheet has 0 columns")
+		}
+
+		number := 0
+		for sheetRows.Next() {
+			columns, err := sheetRows.Columns()
+			if err != nil {
+				return headers, err
+			}
+			row := make(map[string]interface{})
+			for i, value := range columns {
+				if i >= len(headers) || strings.TrimSpace(value) == "" {
+					continue
+				}
+				row[headers[i]] = value
+			}
+			if len(row) == 0 {
+				continue
+			}
+			number++
+			err = handleRow(number, row)
+			if err != nil {
+				return headers, err
+			}
+		}
+		return headers, sheetRows.Error()
+
+	case DataFormatNdjson, DataFormatParquet:
+		headers := make([]string, 0)
+		number := 0
+		err := ReadDataFileRowsFromFile(filePath, fileType, func(row map[string]interface{}) error {
+			for name, value := range row {
+				value, err := plainValue(value)
+				if err != nil {
+					return err
+				}
+				row[name] = value
+				if !InStringArray(headers, name) {
+					headers = append(headers, name)
+				}
+			}
+			number++
+			return handleRow(number, row)
+		})
+		return headers, err
+	}
+
+	return nil, fmt.Errorf("unknown import file type [%v]", fileType)
+}
+
+// appendImportErrors adds the failed rows to the csv error report, which has the row number, the reason and the
+// values of the row
+func appendImportErrors(report string, headers []string, failures []importFailure) string {
+	var buffer bytes.Buffer
+	buffer.WriteString(report)
+
+	writer := csv.NewWriter(&buffer)
+	if report == "" {
+		writer.Write(append([]string{"row", "reason"}, headers...))
+	}
+	for _, failure := range failures {
+		line := []string{fmt.Sprintf("%d", failure.Row), failure.Reason}
+		for _, header := range headers {
+			value, ok := failure.Values[header]
+			if !ok || value == nil {
+				line = append(line, "")
+				continue
+			}
+			line = append(line, fmt.Sprintf("%v", value))
+		}
+		writer.Write(line)
+	}
+	writer.Flush()
+	return buffer.String()
+}
+
+type importFailure struct {
+	Row    int
+	Reason string
+	Values map[string]interface{}
+}
+
+// StartImportJob runs the job in the background, unless it is already running
+func StartImportJob(referenceId daptinid.DaptinReferenceId, cruds map[string]*DbResource) bool {
+	_, running := runningImportJobs.LoadOrStore(referenceId, true)
+	if running {
+		return false
+	}
+	go func() {
+		defer runningImportJobs.Delete(referenceId)
+		runImportJob(referenceId, cruds)
+	}()
+	return true
+}
+
+// waitForImportJob reads the job once the transaction which created or resumed it is committed
+func waitForImportJob(referenceId daptinid.DaptinReferenceId, db database.DatabaseConnection) (ImportJob, error) {
+	for attempt := 0; attempt < 120; attempt++ {
+		transaction, err := db.Beginx()
+		if err != nil {
+			return ImportJob{}, err
+		}
+		job, found, err := GetImportJob(referenceId, transaction)
+		transaction.Rollback()
+		if err != nil || (found && job.Status == ImportJobPending) {
+			return job, err
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+	return ImportJob{}, fmt.Errorf("no pending import job [%v]", uuid.UUID(referenceId).String())
+}
+
+func runImportJob(referenceId daptinid.DaptinReferenceId, cruds map[string]*DbResource) {
+	db := cruds["world"].Connection
+
+	var pubsub *olric.PubSub
+	if cruds["world"].OlricDb != nil {
+		var err error
+		pubsub, err = cruds["world"].OlricDb.NewPubSub()
+		CheckErr(err, "Failed to create pubsub for import job progress")
+	}
+	publish := func(job ImportJob) {
+		if pubsub == nil {
+			return
+		}
+		_, err := pubsub.Publish(context.Background(), ImportJobTableName, EventMessage{
+			MessageSource: "import",
+			EventType:     "progress",
+			ObjectType:    ImportJobTableName,
+			EventData:     job.EventData(),
+		})
+		CheckErr(err, "Failed to publish import job progress")
+	}
+
+	job, err := waitForImportJob(referenceId, db)
+	if err != nil {
+		log.Errorf("[318] failed to start import job: %v", err)
+		return
+	}
+
+	fail := func(err error) {
+		log.Errorf("Import job [%v] into [%v] failed after %d rows: %v", job.FileName, job.EntityName, job.ProcessedRows, err)
+		job.Status = ImportJobFailed
+		job.LastError = err.Error()
+		transaction, beginErr := db.Beginx()
+		if beginErr != nil {
+			CheckErr(beginErr, "Failed to begin transaction [331]")
+			return
+		}
+		if CheckErr(saveImportJob(job, transaction), "Failed to save failed import job") {
+			transaction.Rollback()
+		} else {
+			CheckErr(transaction.Commit(), "Failed to commit failed import job")
+		}
+		publish(job)
+	}
+
+	crud, ok := cruds[job.EntityName]
+	if !ok {
+		fail(fmt.Errorf("no such entity: %v", job.EntityName))
+		return
+	}
+
+	// the rows are counted first, so the progress of the job has a total
+	totalRows := 0
+	headers, err := readImportFile(job.FilePath, job.FileType, func(number int, row map[string]interface{}) error {
+		totalRows = number
+		return nil
+	})
+	if err != nil {
+		fail(fmt.Errorf("failed to read [%v]: %v", job.FileName, err))
+		return
+	}
+
+	sessionUser := &auth.SessionUser{
+		UserId:          job.UserId,
+		UserReferenceId: job.UserReferenceId,
+		Groups:          []auth.GroupPermission{},
+	}
+	if job.UserId != 0 {
+		transaction, err := db.Beginx()
+		if err != nil {
+			fail(err)
+			return
+		}
+		sessionUser.Groups = crud.GetObjectUserGroupsByWhereWithTransaction(USER_ACCOUNT_TABLE_NAME, transaction,
+			"reference_id", job.UserReferenceId[:])
+		transaction.Rollback()
+	}
+	pr := (&http.Request{Method: "POST"}).WithContext(context.WithValue(context.Background(), "user", sessionUser))
+	req := api2go.Request{
+		PlainRequest: pr,
+	}
+
+	uniqueColumns := importUniqueColumns(crud)
+	job.TotalRows = totalRows
+	job.Status = ImportJobRunning
+	job.LastError = ""
+	log.Infof("Import job [%v] into [%v] starting at row %d of %d", job.FileName, job.EntityName, job.ProcessedRows, job.TotalRows)
+
+	type importRow struct {
+		number int
+		values map[string]interface{}
+	}
+	rows := make([]importRow, 0, job.BatchSize)
+
+	// commitBatch imports the rows read since the last batch and commits them along with the progress of the job
+	commitBatch := func(completed bool) error {
+		transaction, err := db.Beginx()
+		if err != nil {
+			return err
+		}
+
+		failures := make([]importFailure, 0)
+		for _, row := range rows {
+			err = ImportDataRow(row.values, crud, uniqueColumns, req, transaction)
+			if err != nil {
+				failures = append(failures, importFailure{Row: row.number, Reason: ImportErrorReason(err), Values: row.values})
+			}
+		}
+
+		batch := job
+		batch.ImportedRows += len(rows) - len(failures)
+		batch.FailedRows += len(failures)
+		batch.ProcessedRows += len(rows)
+		if len(failures) > 0 {
+			batch.ErrorReport = appendImportErrors(job.ErrorReport, headers, failures)
+		}
+		if completed {
+			batch.Status = ImportJobCompleted
+		}
+
+		err = saveImportJob(batch, transaction)
+		if err != nil {
+			transaction.Rollback()
+			return err
+		}
+		err = transaction.Commit()
+		if err != nil {
+			return err
+		}
+
+		job = batch
+		rows = rows[:0]
+		publish(job)
+		return nil
+	}
+
+	// a resumed job skips the rows committed before it stopped
+	_, err = readImportFile(job.FilePath, job.FileType, func(number int, values map[string]interface{}) error {
+		if number <= job.ProcessedRows {
+			return nil
+		}
+		rows = append(rows, importRow{number: number, values: values})
+		if len(rows) < job.BatchSize || job.ProcessedRows+len(rows) == job.TotalRows {
+			return nil
+		}
+		return commitBatch(false)
+	})
+	if err == nil {
+		err = commitBatch(true)
+	}
+	if err != nil {
+		fail(err)
+		return
+	}
+
+	log.Infof("Import job [%v] into [%v] completed, %d rows imported, %d failed", job.FileName, job.EntityName,
+		job.ImportedRows, job.FailedRows)
+	err = os.Remove(job.FilePath)
+	CheckErr(err, "Failed to remove imported file [%v]", job.FilePath)
+}
+
+// FailInterruptedImportJobs marks the jobs which were running when the server stopped as failed, so they can be
+// resumed. Jobs still running in this process, which goes on through a restart of the server, are left alone.
+func FailInterruptedImportJobs(transaction *sqlx.Tx) error {
+	query := statementbuilder.Squirrel.Update(ImportJobTableName).Prepared(true).
+		Set(goqu.Record{
+			"status":      ImportJobFailed,
+			"last_error":  "interrupted by restart",
+			"finished_at": time.Now(),
+		}).
+		Where(goqu.Ex{"status": []string{ImportJobPending, ImportJobRunning}})
+
+	runningJobs := make([]interface{}, 0)
+	runningImportJobs.Range(func(key, value interface{}) bool {
+		referenceId := key.(daptinid.DaptinReferenceId)
+		runningJobs = append(runningJobs, referenceId[:])
+		return true
+	})
+	if len(runningJobs) > 0 {
+		query = query.Where(goqu.C("reference_id").NotIn(runningJobs...))
+	}
+
+	s, v, err := query.ToSQL()
+	if err != nil {
+		return err
+	}
+	_, err = transaction.Exec(s, v...)
+	return err
+}
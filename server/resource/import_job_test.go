@@ -0,0 +1,89 @@
+package resource
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func TestImportErrorReport(t *testing.T) {
+
+	headers := []string{"title", "amount"}
+	report := appendImportErrors("", headers, []importFailure{
+		{Row: 2, Reason: "title is required", Values: map[string]interface{}{"amount": "10"}},
+	})
+	report = appendImportErrors(report, headers, []importFailure{
+		{Row: 7, Reason: "amount, must be a number", Values: map[string]interface{}{"title": "seven", "amount": "x"}},
+	})
+
+	expected := "row,reason,title,amount\n2,title is required,,10\n7,\"amount, must be a number\",seven,x\n"
+	if report != expected {
+		t.Errorf("unexpected report %q", report)
+	}
+}
+
+func readAllImportRows(t *testing.T, filePath string, fileType string) ([]string, map[int]map[string]interface{}) {
+	rows := make(map[int]map[string]interface{})
+	headers, err := readImportFile(filePath, fileType, func(number int, row map[string]interface{}) error {
+		rows[number] = row
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return headers, rows
+}
+
+func TestReadImportFileCsv(t *testing.T) {
+
+	filePath := filepath.Join(t.TempDir(), "rows.csv")
+	err := os.WriteFile(filePath, []byte("Title,Unit Price\nfirst,10\n,20\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	headers, rows := readAllImportRows(t, filePath, "csv")
+	if len(headers) != 2 || headers[0] != "title" || headers[1] != "unit_price" {
+		t.Errorf("unexpected headers %v", headers)
+	}
+	if len(rows) != 2 || rows[1]["title"] != "first" {
+		t.Fatalf("expected 2 rows numbered from 1, got %v", rows)
+	}
+	if _, ok := rows[2]["title"]; ok || rows[2]["unit_price"] != "20" {
+		t.Errorf("empty values should be left out, got %v", rows[2])
+	}
+}
+
+func TestReadImportFileXlsx(t *testing.T) {
+
+	file := excelize.NewFile()
+	sheet := file.GetSheetName(0)
+	for i, row := range [][]interface{}{
+		{"Title", "Unit Price"},
+		{"first", 10},
+		{},
+		{"", 20},
+	} {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		if err := file.SetSheetRow(sheet, cell, &row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	filePath := filepath.Join(t.TempDir(), "rows.xlsx")
+	if err := file.SaveAs(filePath); err != nil {
+		t.Fatal(err)
+	}
+
+	headers, rows := readAllImportRows(t, filePath, "xlsx")
+	if len(headers) != 2 || headers[0] != "title" || headers[1] != "unit_price" {
+		t.Errorf("unexpected headers %v", headers)
+	}
+	if len(rows) != 2 || rows[1]["title"] != "first" || rows[1]["unit_price"] != "10" {
+		t.Fatalf("expected the empty row to be skipped, got %v", rows)
+	}
+	if _, ok := rows[2]["title"]; ok || rows[2]["unit_price"] != "20" {
+		t.Errorf("empty values should be left out, got %v", rows[2])
+	}
+}
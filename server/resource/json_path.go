@@ -0,0 +1,336 @@
+package resource
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/artpar/api2go"
+	daptinid "github.com/daptin/daptin/server/id"
+	"github.com/daptin/daptin/server/statementbuilder"
+	"github.com/doug-martin/goqu/v9"
+	"github.com/doug-martin/goqu/v9/exp"
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+)
+
+// JsonPath points inside the document stored in a json column, it is written as column.key.key (or
+// table.column.key.key) in queries, sort orders and aggregations. Numeric keys index into arrays.
+type JsonPath struct {
+	TableName  string
+	ColumnName string
+	Keys       []string
+}
+
+// jsonPathKeyRegex keeps the keys safe to be written into the sql literal
+var jsonPathKeyRegex = regexp.MustCompile("^[A-Za-z0-9_]+$")
+
+var jsonPathNumericAggregates = map[string]bool{
+	"sum": true,
+	"avg": true,
+}
+
+// GetJsonPath identifies name as a path inside a json column of this table
+func (ti *TableInfo) GetJsonPath(name string) (*JsonPath, bool) {
+	parts := strings.Split(name, ".")
+	if len(parts) > 2 && parts[0] == ti.TableName {
+		parts = parts[1:]
+	}
+	if len(parts) < 2 {
+		return nil, false
+	}
+
+	colInfo, ok := ti.GetColumnByName(parts[0])
+	if !ok || colInfo.ColumnType != "json" {
+		return nil, false
+	}
+
+	for _, key := range parts[1:] {
+		if !jsonPathKeyRegex.MatchString(key) {
+			return nil, false
+		}
+	}
+
+	return &JsonPath{
+		TableName:  ti.TableName,
+		ColumnName: colInfo.ColumnName,
+		Keys:       parts[1:],
+	}, true
+}
+
+func (jp *JsonPath) String() string {
+	return jp.ColumnName + "." + strings.Join(jp.Keys, ".")
+}
+
+// Alias is used as the column name of the path in the selected rows
+func (jp *JsonPath) Alias() string {
+	return jp.ColumnName + "_" + strings.Join(jp.Keys, "_")
+}
+
+func (jp *JsonPath) dollarPath() string {
+	path := "$"
+	for _, key := range jp.Keys {
+		if isNumericKey(key) {
+			path += "[" + key + "]"
+		} else {
+			path += "." + key
+		}
+	}
+	return path
+}
+
+func isNumericKey(key string) bool {
+	for _, c := range key {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Sql is the value at the path as text, or as a number when numeric is set
+func (jp *JsonPath) Sql(sqlDriverName string, numeric bool) string {
+	column := jp.TableName + "." + jp.ColumnName
+	switch sqlDriverName {
+	case "postgres":
+		text := fmt.Sprintf("(%s::jsonb #>> '{%s}')", column, strings.Join(jp.Keys, ","))
+		if numeric {
+			return text + "::numeric"
+		}
+		return text
+	case "mysql":
+		if numeric {
+			return fmt.Sprintf("json_extract(%s, '%s')", column, jp.dollarPath())
+		}
+		return fmt.Sprintf("json_unquote(json_extract(%s, '%s'))", column, jp.dollarPath())
+	default:
+		return fmt.Sprintf("json_extract(%s, '%s')", column, jp.dollarPath())
+	}
+}
+
+// SortSql is the value at the path in a form which sorts numbers as numbers and strings as strings
+func (jp *JsonPath) SortSql(sqlDriverName string) string {
+	if sqlDriverName == "postgres" {
+		return fmt.Sprintf("(%s.%s::jsonb #> '{%s}')", jp.TableName, jp.ColumnName, strings.Join(jp.Keys, ","))
+	}
+	return fmt.Sprintf("json_extract(%s.%s, '%s')", jp.TableName, jp.ColumnName, jp.dollarPath())
+}
+
+// Condition compares the value at the path using one of the query operators
+func (jp *JsonPath) Condition(sqlDriverName string, operator string, value interface{}) (exp.Expression, error) {
+	numeric := false
+	switch value.(type) {
+	case float64, float32, int, int64, int32:
+		numeric = true
+	}
+	expression := goqu.L(jp.Sql(sqlDriverName, numeric))
+
+	opValue, ok := OperatorMap[operator]
+	if !ok {
+		opValue = operator
+	}
+
+	switch opValue {
+	case "=", "eq", "is":
+		return expression.Eq(value), nil
+	case "neq", "not", "isNot":
+		return expression.Neq(value), nil
+	case "lt":
+		return expression.Lt(value), nil
+	case "lte":
+		return expression.Lte(value), nil
+	case "gt":
+		return expression.Gt(value), nil
+	case "gte":
+		return expression.Gte(value), nil
+	case "like":
+		return expression.Like(value), nil
+	case "notLike":
+		return expression.NotLike(value), nil
+	case "iLike":
+		return expression.ILike(value), nil
+	case "notILike":
+		return expression.NotILike(value), nil
+	case "in", "any of":
+		return expression.In(value), nil
+	case "notIn", "notin", "none of":
+		return expression.NotIn(value), nil
+	case "is nil", "is null", "is empty":
+		return expression.IsNull(), nil
+	case "not nil", "not null", "not empty":
+		return expression.IsNotNull(), nil
+	}
+	return nil, fmt.Errorf("operator [%v] is not supported on json path [%v]", operator, jp.String())
+}
+
+// jsonPathReferenceRegex finds column.key.key references in an aggregation expression, optionally wrapped in an
+// aggregate function call
+var jsonPathReferenceRegex = regexp.MustCompile(`(?:\b([a-z]+)\(\s*)?\b([A-Za-z_][A-Za-z0-9_]*(?:\.[A-Za-z0-9_]+)+)\b`)
+
+// ReplaceJsonPaths rewrites the json paths referenced in an aggregation expression into the sql of the database,
+// paths inside sum() and avg() are read as numbers
+func (ti *TableInfo) ReplaceJsonPaths(expression string, sqlDriverName string) (string, bool) {
+	replaced := false
+	result := jsonPathReferenceRegex.ReplaceAllStringFunc(expression, func(match string) string {
+		parts := jsonPathReferenceRegex.FindStringSubmatch(match)
+		jsonPath, ok := ti.GetJsonPath(parts[2])
+		if !ok {
+			return match
+		}
+		replaced = true
+		return strings.TrimSuffix(match, parts[2]) + jsonPath.Sql(sqlDriverName, jsonPathNumericAggregates[strings.ToLower(parts[1])])
+	})
+	return result, replaced
+}
+
+// MergeJson applies a json merge patch (RFC 7396) on the document, keys set to null in the patch are removed
+func MergeJson(document interface{}, patch interface{}) interface{} {
+	patchMap, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	documentMap, ok := document.(map[string]interface{})
+	if !ok {
+		documentMap = make(map[string]interface{})
+	}
+	for key, value := range patchMap {
+		if value == nil {
+			delete(documentMap, key)
+			continue
+		}
+		documentMap[key] = MergeJson(documentMap[key], value)
+	}
+	return documentMap
+}
+
+// SetJsonPath sets the value at the path, creating the objects on the way. A nil value removes the key.
+func SetJsonPath(document interface{}, keys []string, value interface{}) interface{} {
+	if len(keys) == 0 {
+		return value
+	}
+
+	if list, ok := document.([]interface{}); ok && isNumericKey(keys[0]) {
+		var index int
+		fmt.Sscanf(keys[0], "%d", &index)
+		if index < len(list) {
+			list[index] = SetJsonPath(list[index], keys[1:], value)
+			return list
+		}
+	}
+
+	documentMap, ok := document.(map[string]interface{})
+	if !ok {
+		documentMap = make(map[string]interface{})
+	}
+	if value == nil && len(keys) == 1 {
+		delete(documentMap, keys[0])
+		return documentMap
+	}
+	documentMap[keys[0]] = SetJsonPath(documentMap[keys[0]], keys[1:], value)
+	return documentMap
+}
+
+func parseJsonDocument(value interface{}) (interface{}, error) {
+	var documentBytes []byte
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		documentBytes = []byte(v)
+	case []byte:
+		documentBytes = v
+	default:
+		return v, nil
+	}
+	if len(strings.TrimSpace(string(documentBytes))) == 0 {
+		return nil, nil
+	}
+	var document interface{}
+	err := json.Unmarshal(documentBytes, &document)
+	return document, err
+}
+
+// jsonPatchChanges builds the new documents of json columns for a PATCH which sets sub-keys (attributes written as
+// column.key.key) or asks for the document to be merged (json=merge query parameter) instead of replaced
+func (dbResource *DbResource) jsonPatchChanges(attrs map[string]interface{}, req api2go.Request,
+	referenceId daptinid.DaptinReferenceId, transaction *sqlx.Tx) (map[string]interface{}, error) {
+
+	mergeMode := len(req.QueryParams["json"]) > 0 && req.QueryParams["json"][0] == "merge"
+
+	pathCount := make(map[string]int)
+	pathNames := make([]string, 0)
+	for name := range attrs {
+		jsonPath, ok := dbResource.tableInfo.GetJsonPath(name)
+		if !ok {
+			continue
+		}
+		pathNames = append(pathNames, name)
+		pathCount[jsonPath.ColumnName]++
+	}
+	sort.Strings(pathNames)
+
+	changes := make(map[string]interface{})
+	for _, col := range dbResource.tableInfo.Columns {
+		if col.ColumnType != "json" {
+			continue
+		}
+		newValue, hasNewValue := attrs[col.ColumnName]
+		if pathCount[col.ColumnName] == 0 && !(mergeMode && hasNewValue) {
+			continue
+		}
+
+		var document interface{}
+		var err error
+		if hasNewValue && !mergeMode {
+			document, err = parseJsonDocument(newValue)
+			if err != nil {
+				return nil, fmt.Errorf("invalid json in [%v]: %v", col.ColumnName, err)
+			}
+		} else {
+			s, v, err := statementbuilder.Squirrel.Select(col.ColumnName).Prepared(true).
+				From(dbResource.tableInfo.TableName).Where(goqu.Ex{"reference_id": referenceId[:]}).ToSQL()
+			if err != nil {
+				return nil, err
+			}
+			stmt1, err := transaction.Preparex(s)
+			if err != nil {
+				log.Errorf("[290] failed to prepare statment: %v", err)
+				return nil, err
+			}
+			var currentValue interface{}
+			err = stmt1.QueryRowx(v...).Scan(&currentValue)
+			stmt1.Close()
+			if err != nil {
+				return nil, err
+			}
+			document, err = parseJsonDocument(currentValue)
+			if err != nil {
+				return nil, fmt.Errorf("existing value of [%v] is not json: %v", col.ColumnName, err)
+			}
+			if hasNewValue {
+				patch, err := parseJsonDocument(newValue)
+				if err != nil {
+					return nil, fmt.Errorf("invalid json in [%v]: %v", col.ColumnName, err)
+				}
+				document = MergeJson(document, patch)
+			}
+		}
+
+		for _, name := range pathNames {
+			jsonPath, _ := dbResource.tableInfo.GetJsonPath(name)
+			if jsonPath.ColumnName != col.ColumnName {
+				continue
+			}
+			document = SetJsonPath(document, jsonPath.Keys, attrs[name])
+		}
+
+		documentString, err := json.MarshalToString(document)
+		if err != nil {
+			return nil, err
+		}
+		changes[col.ColumnName] = documentString
+	}
+
+	return changes, nil
+}
@@ -0,0 +1,66 @@
+package resource
+
+import (
+	"github.com/artpar/api2go"
+	"testing"
+)
+
+func TestJsonPath(t *testing.T) {
+
+	tableInfo := &TableInfo{
+		TableName: "product",
+		Columns: []api2go.ColumnInfo{
+			{ColumnName: "name", ColumnType: "label"},
+			{ColumnName: "attributes", ColumnType: "json"},
+		},
+	}
+
+	if _, ok := tableInfo.GetJsonPath("name.first"); ok {
+		t.Errorf("name is not a json column")
+	}
+	if _, ok := tableInfo.GetJsonPath("attributes.color'"); ok {
+		t.Errorf("unsafe key accepted in json path")
+	}
+
+	jsonPath, ok := tableInfo.GetJsonPath("product.attributes.sizes.0")
+	if !ok {
+		t.Fatalf("json path not identified")
+	}
+
+	if sql := jsonPath.Sql("sqlite3", false); sql != "json_extract(product.attributes, '$.sizes[0]')" {
+		t.Errorf("unexpected sqlite sql: %v", sql)
+	}
+	if sql := jsonPath.Sql("postgres", true); sql != "(product.attributes::jsonb #>> '{sizes,0}')::numeric" {
+		t.Errorf("unexpected postgres sql: %v", sql)
+	}
+	if sql := jsonPath.Sql("mysql", false); sql != "json_unquote(json_extract(product.attributes, '$.sizes[0]'))" {
+		t.Errorf("unexpected mysql sql: %v", sql)
+	}
+
+	sql, ok := tableInfo.ReplaceJsonPaths("sum(attributes.price) as total", "postgres")
+	if !ok || sql != "sum((product.attributes::jsonb #>> '{price}')::numeric) as total" {
+		t.Errorf("unexpected aggregate sql: %v", sql)
+	}
+
+}
+
+func TestMergeJson(t *testing.T) {
+
+	document, _ := parseJsonDocument(`{"color": "red", "size": {"width": 10, "height": 20}}`)
+	patch, _ := parseJsonDocument(`{"color": null, "size": {"width": 15}}`)
+
+	merged := MergeJson(document, patch).(map[string]interface{})
+	if _, ok := merged["color"]; ok {
+		t.Errorf("null in patch did not remove the key")
+	}
+	size := merged["size"].(map[string]interface{})
+	if size["width"] != float64(15) || size["height"] != float64(20) {
+		t.Errorf("nested object not merged: %v", size)
+	}
+
+	updated := SetJsonPath(merged, []string{"size", "depth"}, 5).(map[string]interface{})
+	if updated["size"].(map[string]interface{})["depth"] != 5 {
+		t.Errorf("sub key not set: %v", updated)
+	}
+
+}
@@ -8,15 +8,12 @@ import (
 	//"github.com/go-playground/validator"
 	"fmt"
 	"github.com/artpar/conform"
-	"github.com/go-playground/locales/en"
-	"github.com/go-playground/universal-translator"
-	"gopkg.in/go-playground/validator.v9"
+	ut "github.com/go-playground/universal-translator"
 )
 
 type DataValidationMiddleware struct {
 	config       *CmsConfig
 	tableInfoMap map[string]TableInfo
-	translator   ut.Translator
 }
 
 func (dvm DataValidationMiddleware) String() string {
@@ -43,28 +40,47 @@ func (dvm *DataValidationMiddleware) InterceptBefore(dr *DbResource, req *api2go
 	case "patch":
 		validations := dvm.tableInfoMap[dr.model.GetName()].Validations
 		conformations := dvm.tableInfoMap[dr.model.GetName()].Conformations
+		columns := dvm.tableInfoMap[dr.model.GetName()].Columns
+
+		var languagePreferences []string
+		if prefs, ok := req.PlainRequest.Context().Value("language_preference").([]string); ok {
+			languagePreferences = prefs
+		}
+		translator := GetValidationTranslator(languagePreferences)
 
 		//log.Printf("We have %d objects to validate", len(objects))
 
 		for i, obj := range objects {
 
-			for _, validate := range validations {
-
-				colValue, ok := obj[validate.ColumnName]
-				if !ok {
+			enumErrors := make([]api2go.Error, 0)
+			for _, column := range columns {
+				colValue, ok := obj[column.ColumnName]
+				if !ok || colValue == nil || column.ColumnType != "enum" {
 					continue
 				}
-				errs := ValidatorInstance.VarWithValue(colValue, obj, validate.Tags)
-
-				if errs != nil {
-					validationErrors, ok := errs.(validator.ValidationErrors)
-					if !ok {
-						return nil, api2go.NewHTTPError(errs, "failed to validate incoming data", 400)
-					}
-					httpErr := api2go.NewHTTPError(errs, strings.Replace(validationErrors[0].Translate(dvm.translator), "for ''", fmt.Sprintf("'%v'", validate.ColumnName), 1), 400)
-					return nil, httpErr
+				if _, isEnumOption := EnumOption(column, colValue); !isEnumOption {
+					enumErrors = append(enumErrors, api2go.Error{
+						Status: "400",
+						Code:   "enum",
+						Title:  "invalid value",
+						Detail: enumValueMessage(column, translator),
+						Source: &api2go.ErrorSource{
+							Pointer: "/data/attributes/" + column.ColumnName,
+						},
+						Meta: map[string]interface{}{
+							"value":   colValue,
+							"options": EnumValues(column),
+						},
+					})
 				}
-
+			}
+			validationErrors, err := ValidateObject(dr.TableInfo(), validations, obj, true, "/data/attributes/", translator, transaction)
+			if err != nil {
+				return nil, api2go.NewHTTPError(err, "failed to validate incoming data", 400)
+			}
+			validationErrors = append(enumErrors, validationErrors...)
+			if len(validationErrors) > 0 {
+				return nil, NewValidationError(validationErrors)
 			}
 
 			for _, conformation := range conformations {
@@ -92,6 +108,16 @@ func (dvm *DataValidationMiddleware) InterceptBefore(dr *DbResource, req *api2go
 
 }
 
+// enumValueMessage is the message for a value which is not an enum option, in the language of the translator
+func enumValueMessage(column api2go.ColumnInfo, translator ut.Translator) string {
+	options := strings.Join(EnumValues(column), ", ")
+	message, err := translator.T("oneof", column.ColumnName, options)
+	if err != nil {
+		return fmt.Sprintf("%v must be one of [%v]", column.ColumnName, options)
+	}
+	return message
+}
+
 func NewDataValidationMiddleware(cmsConfig *CmsConfig, cruds *map[string]*DbResource) DatabaseRequestInterceptor {
 
 	tableInfoMap := make(map[string]TableInfo)
@@ -100,13 +126,8 @@ func NewDataValidationMiddleware(cmsConfig *CmsConfig, cruds *map[string]*DbReso
 		tableInfoMap[tabInfo.TableName] = tabInfo
 	}
 
-	e := en.New()
-	uni := ut.New(e, e)
-	en1, _ := uni.GetTranslator("en") // or fallback if fails to find 'en'
-
 	return &DataValidationMiddleware{
 		config:       cmsConfig,
 		tableInfoMap: tableInfoMap,
-		translator:   en1,
 	}
 }
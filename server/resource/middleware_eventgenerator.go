@@ -117,7 +117,7 @@ func (pc *eventHandlerMiddleware) InterceptAfter(dr *DbResource, req *api2go.Req
 
 	tableName := dr.model.GetTableName()
 	topic := (*pc.dtopicMap)[tableName]
-	if topic == nil {
+	if topic == nil || isDryRunRequest(*req) {
 		return results, nil
 	}
 
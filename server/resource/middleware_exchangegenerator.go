@@ -144,6 +144,9 @@ func (em *exchangeMiddleware) InterceptAfter(dr *DbResource, req *api2go.Request
 	reqmethod := req.PlainRequest.Method
 	reqmethod = strings.ToLower(reqmethod)
 	log.Tracef("Request to intercept in middleware exchange: %v", reqmethod)
+	if isDryRunRequest(*req) {
+		return results, nil
+	}
 
 	for _, resultRow := range results {
 
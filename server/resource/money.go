@@ -0,0 +1,222 @@
+package resource
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/artpar/api2go"
+	"github.com/doug-martin/goqu/v9"
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultMoneyScale is the number of decimal places kept by a money column which does not declare a
+// decimal(precision,scale) data type
+const DefaultMoneyScale = 4
+
+var decimalDataTypeRegex = regexp.MustCompile(`^(?:decimal|numeric)\(\s*\d+\s*,\s*(\d+)\s*\)$`)
+
+var currencyCodeRegex = regexp.MustCompile("^[A-Z]{3}$")
+
+// MoneyScale is the number of decimal places of a money column
+func MoneyScale(column api2go.ColumnInfo) int {
+	match := decimalDataTypeRegex.FindStringSubmatch(strings.ToLower(strings.TrimSpace(column.DataType)))
+	if len(match) < 2 {
+		return DefaultMoneyScale
+	}
+	scale, err := strconv.Atoi(match[1])
+	if err != nil {
+		return DefaultMoneyScale
+	}
+	return scale
+}
+
+// MoneyCurrencyColumnName is the companion column holding the ISO 4217 currency code of a money column
+func MoneyCurrencyColumnName(columnName string) string {
+	return columnName + "_currency"
+}
+
+// ParseMoney reads a decimal amount from a string or a number, and formats it with exactly scale decimal places.
+// Amounts with more decimal places than the column can store are rejected instead of being rounded.
+func ParseMoney(value interface{}, scale int) (string, error) {
+	var valueString string
+	switch v := value.(type) {
+	case string:
+		valueString = strings.TrimSpace(v)
+	case []byte:
+		valueString = strings.TrimSpace(string(v))
+	case float64:
+		valueString = strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		valueString = strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case int, int32, int64, uint, uint32, uint64:
+		valueString = fmt.Sprintf("%d", v)
+	default:
+		return "", fmt.Errorf("invalid amount [%v]", value)
+	}
+
+	amount, ok := new(big.Rat).SetString(valueString)
+	if !ok {
+		return "", fmt.Errorf("invalid amount [%v]", valueString)
+	}
+
+	scaled := new(big.Rat).Mul(amount, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(scale)), nil)))
+	if !scaled.IsInt() {
+		return "", fmt.Errorf("amount [%v] has more than %d decimal places", valueString, scale)
+	}
+
+	return amount.FloatString(scale), nil
+}
+
+// ParseCurrencyCode validates an ISO 4217 currency code
+func ParseCurrencyCode(value interface{}) (string, error) {
+	code := strings.ToUpper(strings.TrimSpace(fmt.Sprintf("%v", value)))
+	if !currencyCodeRegex.MatchString(code) {
+		return "", fmt.Errorf("invalid currency code [%v]", value)
+	}
+	return code, nil
+}
+
+// SumMoney adds up decimal amounts exactly, nil values are skipped
+func SumMoney(values []string, scale int) (string, error) {
+	total := new(big.Rat)
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		amount, ok := new(big.Rat).SetString(value)
+		if !ok {
+			return "", fmt.Errorf("invalid amount [%v]", value)
+		}
+		total.Add(total, amount)
+	}
+	return total.FloatString(scale), nil
+}
+
+// CheckMoneyColumns adds the currency column for each money column of a table which does not have one yet
+func CheckMoneyColumns(config *CmsConfig) {
+
+	for i, table := range config.Tables {
+
+		existingColumns := make(map[string]bool)
+		for _, col := range table.Columns {
+			existingColumns[col.ColumnName] = true
+		}
+
+		for _, col := range table.Columns {
+			if col.ColumnType != "money" {
+				continue
+			}
+
+			currencyColumnName := MoneyCurrencyColumnName(col.ColumnName)
+			if existingColumns[currencyColumnName] {
+				continue
+			}
+
+			log.Printf("Add currency column [%v] for money column [%v] in table [%v]", currencyColumnName, col.ColumnName, table.TableName)
+			config.Tables[i].Columns = append(config.Tables[i].Columns, api2go.ColumnInfo{
+				Name:              currencyColumnName,
+				ColumnName:        currencyColumnName,
+				ColumnDescription: "Currency of " + col.ColumnName,
+				ColumnType:        "currency",
+				DataType:          "varchar(3)",
+				IsNullable:        true,
+			})
+			existingColumns[currencyColumnName] = true
+		}
+	}
+
+}
+
+var moneySumRegex = regexp.MustCompile(`(?i)^\s*sum\(\s*(?:([A-Za-z0-9_]+)\.)?([A-Za-z0-9_]+)\s*\)(?:\s+as\s+([A-Za-z0-9_]+))?\s*$`)
+
+// MoneySum is a sum() over a money column in an aggregation. The amounts are added up exactly, and the sum is
+// refused when the rows have more than one currency.
+type MoneySum struct {
+	TableName          string
+	Column             api2go.ColumnInfo
+	Alias              string
+	GroupedByCurrency  bool
+	currencyCountAlias string
+}
+
+// GetMoneySum identifies a projection which sums a money column of this table
+func (ti *TableInfo) GetMoneySum(projection string, groupBy []string) (*MoneySum, bool) {
+	match := moneySumRegex.FindStringSubmatch(projection)
+	if len(match) == 0 || (match[1] != "" && match[1] != ti.TableName) {
+		return nil, false
+	}
+	colInfo, ok := ti.GetColumnByName(match[2])
+	if !ok || colInfo.ColumnType != "money" {
+		return nil, false
+	}
+
+	alias := match[3]
+	if alias == "" {
+		alias = "sum_" + colInfo.ColumnName
+	}
+
+	currencyColumnName := MoneyCurrencyColumnName(colInfo.ColumnName)
+	groupedByCurrency := false
+	for _, group := range groupBy {
+		if group == currencyColumnName || group == ti.TableName+"."+currencyColumnName {
+			groupedByCurrency = true
+		}
+	}
+
+	return &MoneySum{
+		TableName:          ti.TableName,
+		Column:             *colInfo,
+		Alias:              alias,
+		GroupedByCurrency:  groupedByCurrency,
+		currencyCountAlias: alias + "__currencies",
+	}, true
+}
+
+// Projections select the amounts, sqlite has no exact decimal type so its amounts are concatenated and added up
+// after the query
+func (ms *MoneySum) Projections(sqlDriverName string) []interface{} {
+	column := ms.TableName + "." + ms.Column.ColumnName
+	currencyColumn := ms.TableName + "." + MoneyCurrencyColumnName(ms.Column.ColumnName)
+
+	projections := make([]interface{}, 0)
+	if sqlDriverName == "sqlite3" {
+		projections = append(projections, goqu.L(fmt.Sprintf("group_concat(%s, ',')", column)).As(ms.Alias))
+	} else {
+		projections = append(projections, goqu.L(fmt.Sprintf("sum(%s)", column)).As(ms.Alias))
+	}
+
+	if !ms.GroupedByCurrency {
+		projections = append(projections,
+			goqu.L(fmt.Sprintf("count(distinct %s)", currencyColumn)).As(ms.currencyCountAlias),
+			goqu.L(fmt.Sprintf("max(%s)", currencyColumn)).As(ms.Alias+"_currency"),
+		)
+	}
+	return projections
+}
+
+// Complete replaces the selected amounts of a row with their exact sum
+func (ms *MoneySum) Complete(row map[string]interface{}) error {
+	if !ms.GroupedByCurrency {
+		currencyCount, _ := strconv.Atoi(fmt.Sprintf("%v", row[ms.currencyCountAlias]))
+		delete(row, ms.currencyCountAlias)
+		if currencyCount > 1 {
+			return fmt.Errorf("cannot sum [%v] over %d currencies, group by %v", ms.Column.ColumnName,
+				currencyCount, MoneyCurrencyColumnName(ms.Column.ColumnName))
+		}
+	}
+
+	value := row[ms.Alias]
+	if value == nil {
+		return nil
+	}
+	total, err := SumMoney(strings.Split(fmt.Sprintf("%v", value), ","), MoneyScale(ms.Column))
+	if err != nil {
+		return err
+	}
+	row[ms.Alias] = total
+	return nil
+}
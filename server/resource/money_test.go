@@ -0,0 +1,71 @@
+package resource
+
+import (
+	"github.com/artpar/api2go"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+
+	column := api2go.ColumnInfo{ColumnName: "price", ColumnType: "money", DataType: "decimal(12,2)"}
+	if scale := MoneyScale(column); scale != 2 {
+		t.Errorf("expected scale 2, got %d", scale)
+	}
+
+	amount, err := ParseMoney("0.1", MoneyScale(column))
+	if err != nil || amount != "0.10" {
+		t.Errorf("unexpected amount [%v]: %v", amount, err)
+	}
+
+	amount, err = ParseMoney(float64(19.99), MoneyScale(column))
+	if err != nil || amount != "19.99" {
+		t.Errorf("unexpected amount [%v]: %v", amount, err)
+	}
+
+	if _, err = ParseMoney("1.005", MoneyScale(column)); err == nil {
+		t.Errorf("amount with more decimal places than the column was accepted")
+	}
+
+	if _, err = ParseCurrencyCode("usd"); err != nil {
+		t.Errorf("valid currency code rejected: %v", err)
+	}
+	if _, err = ParseCurrencyCode("dollar"); err == nil {
+		t.Errorf("invalid currency code accepted")
+	}
+
+}
+
+func TestMoneySum(t *testing.T) {
+
+	tableInfo := &TableInfo{
+		TableName: "invoice",
+		Columns: []api2go.ColumnInfo{
+			{ColumnName: "total", ColumnType: "money", DataType: "decimal(19,2)"},
+			{ColumnName: "total_currency", ColumnType: "currency"},
+		},
+	}
+
+	moneySum, ok := tableInfo.GetMoneySum("sum(total) as amount", nil)
+	if !ok {
+		t.Fatalf("sum over money column not identified")
+	}
+
+	row := map[string]interface{}{"amount": "0.10,0.20,0.30", "amount__currencies": int64(1), "amount_currency": "EUR"}
+	if err := moneySum.Complete(row); err != nil {
+		t.Errorf("failed to complete sum: %v", err)
+	}
+	if row["amount"] != "0.60" {
+		t.Errorf("inexact sum: %v", row["amount"])
+	}
+
+	row = map[string]interface{}{"amount": "1.00,2.00", "amount__currencies": int64(2)}
+	if err := moneySum.Complete(row); err == nil {
+		t.Errorf("sum over two currencies was not refused")
+	}
+
+	moneySum, _ = tableInfo.GetMoneySum("sum(total)", []string{"total_currency"})
+	if !moneySum.GroupedByCurrency || moneySum.Alias != "sum_total" {
+		t.Errorf("unexpected money sum: %v", moneySum)
+	}
+
+}
@@ -0,0 +1,109 @@
+package resource
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// An outcome with ContinueOnError runs inside a savepoint, when it fails only its own writes are rolled back and the
+// action goes on with the next outcome. The same holds for each item of a loop with ContinueOnError.
+//
+// Writes to the database are undone by rolling back, but mails, network requests and calls to integrations are not.
+// An outcome can list Compensate outcomes which undo its effects. Once the outcome succeeded its compensation is
+// kept, with the fields as they were right after it, and when a later outcome fails the action the kept compensations
+// run in reverse order. They run after the transaction of the action was rolled back, in a transaction of their own.
+// When a failing item of a loop is skipped, the compensations of the outcomes that item already ran are run right
+// away. A failing compensation is logged and rolled back, the others still run.
+
+// compensation is the undo of an outcome which already ran
+type compensation struct {
+	outcome  Outcome
+	inFields map[string]interface{}
+	runAs    string
+}
+
+// savepoint starts a savepoint in the transaction of the action
+func (run *outcomeRun) savepoint() (string, error) {
+	run.savepoints++
+	name := fmt.Sprintf("action_outcome_%d", run.savepoints)
+	_, err := run.transaction.Exec("SAVEPOINT " + name)
+	return name, err
+}
+
+// rollbackToSavepoint undoes the writes done since the savepoint
+func (run *outcomeRun) rollbackToSavepoint(name string) error {
+	_, err := run.transaction.Exec("ROLLBACK TO SAVEPOINT " + name)
+	return err
+}
+
+func (run *outcomeRun) releaseSavepoint(name string) error {
+	_, err := run.transaction.Exec("RELEASE SAVEPOINT " + name)
+	return err
+}
+
+// addCompensation keeps the compensation of an outcome which succeeded, compensations themselves are not undone
+func (run *outcomeRun) addCompensation(outcome Outcome, inFieldMap map[string]interface{}) {
+	if len(outcome.Compensate) == 0 || run.compensating {
+		return
+	}
+	inFields := make(map[string]interface{}, len(inFieldMap))
+	for key, value := range inFieldMap {
+		inFields[key] = value
+	}
+	run.compensations = append(run.compensations, compensation{
+		outcome:  outcome,
+		inFields: inFields,
+		runAs:    run.runAsOf(outcome),
+	})
+}
+
+// compensateFrom runs the compensations kept since mark, the latest first, and forgets them
+func (run *outcomeRun) compensateFrom(mark int) {
+	if mark >= len(run.compensations) {
+		return
+	}
+	pending := run.compensations[mark:]
+	run.compensations = run.compensations[:mark]
+	if run.dryRun != nil {
+		return
+	}
+
+	run.compensating = true
+	runAs := run.runAs
+	defer func() {
+		run.compensating = false
+		run.runAs = runAs
+	}()
+	for i := len(pending) - 1; i >= 0; i-- {
+		undo := pending[i]
+		log.Infof("Action [%v][%v] => Compensate outcome [%v][%v]", run.actionRequest.Action, run.actionRequest.Type,
+			undo.outcome.Type, undo.outcome.Method)
+		savepoint, err := run.savepoint()
+		if CheckErr(err, "Failed to start savepoint to compensate outcome [%v]", undo.outcome.Type) {
+			continue
+		}
+		run.runAs = undo.runAs
+		_, _, err = run.runOutcomes(undo.outcome.Compensate, undo.inFields)
+		if CheckErr(err, "Failed to compensate outcome [%v][%v]", undo.outcome.Type, undo.outcome.Method) {
+			CheckErr(run.rollbackToSavepoint(savepoint), "Failed to roll back compensation of [%v]", undo.outcome.Type)
+			continue
+		}
+		CheckErr(run.releaseSavepoint(savepoint), "Failed to release savepoint")
+	}
+}
+
+// compensateAfterRollback runs all kept compensations once the transaction of the action was rolled back
+func (run *outcomeRun) compensateAfterRollback() {
+	if len(run.compensations) == 0 {
+		return
+	}
+	transaction, err := run.db.Connection.Beginx()
+	if err != nil {
+		CheckErr(err, "Failed to begin transaction to compensate action [%v]", run.actionRequest.Action)
+		return
+	}
+	run.transaction = transaction
+	run.compensateFrom(0)
+	CheckErr(transaction.Commit(), "Failed to commit compensations of action [%v]", run.actionRequest.Action)
+}
@@ -0,0 +1,122 @@
+package resource
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/artpar/api2go"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type testStepPerformer struct {
+	fail bool
+}
+
+func (performer testStepPerformer) Name() string {
+	if performer.fail {
+		return "test_fail"
+	}
+	return "test_step"
+}
+
+func (performer testStepPerformer) DoAction(request Outcome, inFields map[string]interface{}, transaction *sqlx.Tx) (api2go.Responder, []ActionResponse, []error) {
+	_, err := transaction.Exec("insert into step (name) values (?)", inFields["name"])
+	if err == nil && performer.fail {
+		err = errors.New("step failed")
+	}
+	if err != nil {
+		return nil, nil, []error{err}
+	}
+	return nil, []ActionResponse{}, nil
+}
+
+func testStep(name string, compensate ...Outcome) Outcome {
+	return Outcome{
+		Type:       "test_step",
+		Attributes: map[string]interface{}{"name": name},
+		Compensate: compensate,
+	}
+}
+
+func TestOutcomeSavepointsAndCompensations(t *testing.T) {
+	db, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	db.MustExec("create table step (name varchar(20))")
+
+	dbResource := &DbResource{
+		Connection: db,
+		ActionHandlerMap: map[string]ActionPerformerInterface{
+			"test_step": testStepPerformer{},
+			"test_fail": testStepPerformer{fail: true},
+		},
+	}
+	newRun := func() *outcomeRun {
+		return &outcomeRun{
+			db:          dbResource,
+			req:         api2go.Request{PlainRequest: &http.Request{Method: "POST"}},
+			transaction: db.MustBegin(),
+		}
+	}
+	steps := func() []string {
+		var names []string
+		if err := db.Select(&names, "select name from step order by rowid"); err != nil {
+			t.Fatalf("failed to read steps: %v", err)
+		}
+		db.MustExec("delete from step")
+		return names
+	}
+
+	run := newRun()
+	failing := Outcome{Type: "test_fail", Attributes: map[string]interface{}{"name": "failed"}, ContinueOnError: true}
+	_, _, err = run.runOutcomes([]Outcome{testStep("first"), failing, testStep("last")}, map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("expected the failing outcome to be skipped, got %v", err)
+	}
+	run.transaction.Commit()
+	if names := steps(); !reflect.DeepEqual(names, []string{"first", "last"}) {
+		t.Errorf("expected only the writes of the failed outcome to be rolled back, got %v", names)
+	}
+
+	run = newRun()
+	failing.ContinueOnError = false
+	_, _, err = run.runOutcomes([]Outcome{
+		testStep("first", testStep("undo first")),
+		testStep("second", testStep("undo second")),
+		failing,
+	}, map[string]interface{}{})
+	if err == nil {
+		t.Fatalf("expected the action to fail")
+	}
+	run.transaction.Rollback()
+	run.compensateAfterRollback()
+	if names := steps(); !reflect.DeepEqual(names, []string{"undo second", "undo first"}) {
+		t.Errorf("expected the compensations to run in reverse order, got %v", names)
+	}
+
+	run = newRun()
+	_, _, err = run.runOutcomes([]Outcome{
+		{
+			ForEach:         "$items",
+			ContinueOnError: true,
+			Outcomes: []Outcome{
+				{Type: "test_step", Attributes: map[string]interface{}{"name": "$item"},
+					Compensate: []Outcome{testStep("undo")}},
+				{Type: "test_fail", Condition: "!item == 'b'", Attributes: map[string]interface{}{"name": "$item"}},
+			},
+		},
+	}, map[string]interface{}{"items": []interface{}{"a", "b", "c"}})
+	if err != nil {
+		t.Fatalf("expected the failing item to be skipped, got %v", err)
+	}
+	run.transaction.Commit()
+	if names := steps(); !reflect.DeepEqual(names, []string{"a", "undo", "c"}) {
+		t.Errorf("expected the failed item to be rolled back and compensated, got %v", names)
+	}
+}
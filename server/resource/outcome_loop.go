@@ -0,0 +1,176 @@
+package resource
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// An outcome with ForEach is a loop. ForEach names a list in the fields, like "$items" for an input or the
+// reference of an earlier outcome, or is a script which returns a list. The Outcomes of the loop run once for each
+// item, with the item and its index added to the fields. References set by those outcomes are only seen by the
+// outcomes of the same item. The loop collects one result per item under its own reference, holding the item, the
+// index and the value of each reference set for that item.
+//
+// The Condition of a loop is evaluated for each item and skips the items it is false for. With ContinueOnError a
+// failing item is rolled back to a savepoint, recorded with its error and the loop goes on with the next item.
+
+const (
+	defaultLoopItemName  = "item"
+	defaultLoopIndexName = "index"
+)
+
+var loopItemsPathPattern = regexp.MustCompile(`^\$[a-zA-Z0-9_]*(\.[a-zA-Z0-9_]+)*$`)
+
+// evaluateLoopItems gives the list a loop runs over. A "$path" gives the value at that path as it is, anything else
+// is evaluated like an outcome attribute.
+func evaluateLoopItems(expression string, inFieldMap map[string]interface{}) ([]interface{}, error) {
+	var value interface{}
+	if loopItemsPathPattern.MatchString(expression) {
+		parts := strings.Split(expression[1:], ".")
+		if parts[0] == "" {
+			parts[0] = "subject"
+		}
+		value = interface{}(inFieldMap)
+		for _, part := range parts {
+			valueMap, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("[%v] is not an object", part)
+			}
+			value = valueMap[part]
+		}
+	} else {
+		var err error
+		value, err = evaluateString(expression, inFieldMap)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if value == nil {
+		return []interface{}{}, nil
+	}
+	if items, ok := value.([]interface{}); ok {
+		return items, nil
+	}
+	if text, ok := value.(string); ok {
+		// a list passed as a json string, for instance in a form field
+		var items []interface{}
+		if err := json.Unmarshal([]byte(text), &items); err != nil {
+			return nil, fmt.Errorf("value [%v] is not a list", text)
+		}
+		return items, nil
+	}
+
+	reflected := reflect.ValueOf(value)
+	if reflected.Kind() != reflect.Slice && reflected.Kind() != reflect.Array {
+		return nil, fmt.Errorf("value of type %T is not a list", value)
+	}
+	items := make([]interface{}, reflected.Len())
+	for i := range items {
+		items[i] = reflected.Index(i).Interface()
+	}
+	return items, nil
+}
+
+// runLoop runs the outcomes of the loop for each of its items, stop is true when an outcome ended the action early
+func (run *outcomeRun) runLoop(loop Outcome, inFieldMap map[string]interface{}, step string) ([]ActionResponse, bool, error) {
+	items, err := evaluateLoopItems(loop.ForEach, inFieldMap)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to evaluate items of loop [%v]: %w", loop.ForEach, err)
+	}
+
+	itemName := loop.As
+	if itemName == "" {
+		itemName = defaultLoopItemName
+	}
+	indexName := loop.IndexAs
+	if indexName == "" {
+		indexName = defaultLoopIndexName
+	}
+
+	responses := make([]ActionResponse, 0)
+	results := make([]map[string]interface{}, 0, len(items))
+	stop := false
+	for index, item := range items {
+		itemFields := make(map[string]interface{}, len(inFieldMap)+2)
+		for key, value := range inFieldMap {
+			itemFields[key] = value
+		}
+		itemFields[itemName] = item
+		itemFields[indexName] = index
+
+		itemStep := fmt.Sprintf("%v.%d", step, index)
+		holds, err := outcomeConditionHolds(loop, itemFields)
+		if err != nil {
+			return responses, false, err
+		}
+		if !holds {
+			run.traceOutcome(OutcomeTrace{Step: itemStep, ForEach: loop.ForEach, Condition: loop.Condition, Skipped: true})
+			continue
+		}
+
+		result := map[string]interface{}{
+			itemName:  item,
+			indexName: index,
+		}
+		savepoint := ""
+		if loop.ContinueOnError {
+			savepoint, err = run.savepoint()
+			if err != nil {
+				return responses, false, fmt.Errorf("failed to start savepoint for item [%d] of loop [%v]: %w", index, loop.ForEach, err)
+			}
+		}
+		compensationMark := len(run.compensations)
+
+		run.step = itemStep + "."
+		itemResponses, itemStop, err := run.runOutcomes(loop.Outcomes, itemFields)
+		if err != nil {
+			if !loop.ContinueOnError {
+				return responses, false, err
+			}
+			log.Warnf("Item [%d] of loop [%v] failed: %v", index, loop.ForEach, err)
+			rollbackErr := run.rollbackToSavepoint(savepoint)
+			if rollbackErr != nil {
+				return responses, false, fmt.Errorf("failed to roll back item [%d] of loop [%v] after %v: %w", index, loop.ForEach, err, rollbackErr)
+			}
+			run.compensateFrom(compensationMark)
+			result["error"] = err.Error()
+			responses = append(responses, NewActionResponse("client.notify",
+				NewClientNotification("error", fmt.Sprintf("Item %d of %v failed: %v", index, loop.ForEach, err), "Failed")))
+		} else {
+			if savepoint != "" {
+				err = run.releaseSavepoint(savepoint)
+				if err != nil {
+					return responses, false, fmt.Errorf("failed to release savepoint for item [%d] of loop [%v]: %w", index, loop.ForEach, err)
+				}
+			}
+			if !loop.SkipInResponse {
+				responses = append(responses, itemResponses...)
+			}
+		}
+
+		for _, outcome := range loop.Outcomes {
+			if outcome.Reference != "" {
+				result[outcome.Reference] = itemFields[outcome.Reference]
+			}
+		}
+		results = append(results, result)
+
+		if itemStop {
+			stop = true
+			break
+		}
+	}
+
+	if loop.Reference != "" {
+		for i, result := range results {
+			inFieldMap[fmt.Sprintf("%v[%v]", loop.Reference, i)] = result
+		}
+		inFieldMap[loop.Reference] = results
+	}
+	return responses, stop, nil
+}
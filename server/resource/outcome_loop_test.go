@@ -0,0 +1,60 @@
+package resource
+
+import (
+	"testing"
+)
+
+func TestEvaluateLoopItems(t *testing.T) {
+	fields := map[string]interface{}{
+		"items": []interface{}{"a", "b"},
+		"rows":  []map[string]interface{}{{"name": "first"}},
+		"json":  `[1, 2, 3]`,
+		"subject": map[string]interface{}{
+			"tags": []string{"x"},
+		},
+	}
+
+	for expression, expected := range map[string]int{
+		"$items":               2,
+		"$rows":                1,
+		"$json":                3,
+		"$.tags":               1,
+		"$missing":             0,
+		"!items.concat(['c'])": 3,
+	} {
+		items, err := evaluateLoopItems(expression, fields)
+		if err != nil || len(items) != expected {
+			t.Errorf("expected %d items for %v, got %v %v", expected, expression, items, err)
+		}
+	}
+
+	if _, err := evaluateLoopItems("$subject", fields); err == nil {
+		t.Errorf("expected an object to be refused as a list")
+	}
+}
+
+func TestRunLoopCollectsResults(t *testing.T) {
+	run := &outcomeRun{}
+	fields := map[string]interface{}{
+		"numbers": []interface{}{1, 2, 3},
+	}
+	_, stop, err := run.runOutcomes([]Outcome{
+		{
+			ForEach:   "$numbers",
+			As:        "number",
+			Condition: "!number > 1",
+			Reference: "large",
+		},
+	}, fields)
+	if err != nil || stop {
+		t.Fatalf("failed to run loop: %v %v", stop, err)
+	}
+
+	results := fields["large"].([]map[string]interface{})
+	if len(results) != 2 || results[0]["number"] != 2 || results[1]["index"] != 2 {
+		t.Errorf("unexpected loop results %v", results)
+	}
+	if _, ok := fields["number"]; ok {
+		t.Errorf("expected the item to be bound only inside the loop")
+	}
+}
@@ -0,0 +1,697 @@
+package resource
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/artpar/api2go"
+	"github.com/daptin/daptin/server/auth"
+	daptinid "github.com/daptin/daptin/server/id"
+	"github.com/daptin/daptin/server/statementbuilder"
+	"github.com/doug-martin/goqu/v9"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/google/uuid"
+	"github.com/icrowley/fake"
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+)
+
+// maxRelationDepth limits how far parents of parents are generated
+const maxRelationDepth = 3
+
+// maxValueAttempts is the number of values tried for a unique column, and the number of rows tried for a row which
+// fails its validations, before giving up
+const maxValueAttempts = 10
+
+// randomDataLock serialises the generators, the fake package has a single random source which is seeded for
+// reproducible runs
+var randomDataLock sync.Mutex
+
+// namedColumnFakers pick the fake value by the column name for text columns. Names are matched against the whole
+// column name or its last words, so contact_email and home_phone are matched as well.
+var namedColumnFakers = []struct {
+	names []string
+	exact bool
+	fake  func() string
+}{
+	{names: []string{"first_name", "firstname", "given_name"}, fake: fake.FirstName},
+	{names: []string{"last_name", "lastname", "surname", "family_name"}, fake: fake.LastName},
+	{names: []string{"full_name", "fullname", "display_name"}, fake: fake.FullName},
+	{names: []string{"username", "user_name", "login", "handle"}, fake: fake.UserName},
+	{names: []string{"email", "email_address", "mail"}, fake: fake.EmailAddress},
+	{names: []string{"phone", "phone_number", "mobile", "mobile_number", "telephone"}, fake: fake.Phone},
+	{names: []string{"company", "company_name", "organization", "organisation"}, fake: fake.Company},
+	{names: []string{"job_title", "designation", "occupation"}, fake: fake.JobTitle},
+	{names: []string{"street", "street_address", "address"}, fake: fake.StreetAddress},
+	{names: []string{"city", "town"}, fake: fake.City},
+	{names: []string{"state", "province"}, fake: fake.State},
+	{names: []string{"country"}, fake: fake.Country},
+	{names: []string{"zip", "zipcode", "zip_code", "postal_code", "postcode"}, fake: fake.Zip},
+	{names: []string{"url", "website", "homepage", "link"}, fake: func() string { return "https://" + fake.DomainName() }},
+	{names: []string{"domain", "domain_name", "hostname"}, fake: fake.DomainName},
+	{names: []string{"ip", "ip_address"}, fake: fake.IPv4},
+	{names: []string{"user_agent"}, fake: fake.UserAgent},
+	{names: []string{"color", "colour"}, fake: fake.HexColor},
+	{names: []string{"currency", "currency_code"}, fake: fake.CurrencyCode},
+	{names: []string{"language"}, fake: fake.Language},
+	{names: []string{"gender"}, fake: fake.Gender},
+	{names: []string{"product", "product_name"}, fake: fake.ProductName},
+	{names: []string{"brand"}, fake: fake.Brand},
+	{names: []string{"industry"}, fake: fake.Industry},
+	{names: []string{"title", "subject", "headline"}, fake: fake.Title},
+	{names: []string{"description", "summary", "bio", "about", "body", "comment", "notes"}, fake: fake.Paragraph},
+	{names: []string{"tag", "keyword", "slug", "word"}, fake: fake.Word},
+	{names: []string{"name"}, exact: true, fake: fake.FullName},
+}
+
+// textColumnTypes are the column types whose value can be picked by the column name
+var textColumnTypes = []string{"label", "name", "content", "hidden", "markdown", "html"}
+
+// fakeValueHints are the constraints on a value collected from the validation tags of a column
+type fakeValueHints struct {
+	OneOf  []string
+	Email  bool
+	Url    bool
+	Uuid   bool
+	Min    *float64
+	Max    *float64
+	Length *float64
+}
+
+func parseFakeValueHints(tags string) fakeValueHints {
+	hints := fakeValueHints{}
+	for _, tag := range strings.Split(tags, ",") {
+		parts := strings.SplitN(strings.TrimSpace(tag), "=", 2)
+		param := ""
+		if len(parts) > 1 {
+			param = parts[1]
+		}
+		number, numberErr := strconv.ParseFloat(param, 64)
+		switch parts[0] {
+		case "oneof":
+			hints.OneOf = strings.Fields(param)
+		case "email":
+			hints.Email = true
+		case "url", "uri":
+			hints.Url = true
+		case "uuid", "uuid4":
+			hints.Uuid = true
+		case "len", "eq":
+			if numberErr == nil {
+				hints.Length = &number
+			}
+		case "min", "gte":
+			if numberErr == nil {
+				hints.Min = &number
+			}
+		case "gt":
+			if numberErr == nil {
+				number = number + 1
+				hints.Min = &number
+			}
+		case "max", "lte":
+			if numberErr == nil {
+				hints.Max = &number
+			}
+		case "lt":
+			if numberErr == nil {
+				number = number - 1
+				hints.Max = &number
+			}
+		}
+	}
+	return hints
+}
+
+// dataTypeSize is the length of a varchar(n) or char(n) column, 0 when the data type has no length
+func dataTypeSize(dataType string) int {
+	dataType = strings.ToLower(dataType)
+	if !strings.HasPrefix(dataType, "varchar(") && !strings.HasPrefix(dataType, "char(") {
+		return 0
+	}
+	start := strings.Index(dataType, "(")
+	end := strings.Index(dataType, ")")
+	if end < start {
+		return 0
+	}
+	size, err := strconv.Atoi(dataType[start+1 : end])
+	if err != nil {
+		return 0
+	}
+	return size
+}
+
+// RandomDataGenerator creates rows of fake data along with the rows they refer to. Parent rows are created before
+// their children, rows are linked over belongs_to, has_one and has_many relations, enum and unique columns get
+// acceptable values and rows which fail the validations of the table are generated again.
+type RandomDataGenerator struct {
+	rand         *rand.Rand
+	tables       map[string]TableInfo
+	relations    []api2go.TableRelation
+	cruds        map[string]*DbResource
+	transaction  *sqlx.Tx
+	request      api2go.Request
+	translator   ut.Translator
+	relatedCount int
+	// values already used for each table.column, so unique columns do not repeat a value
+	usedValues map[string]map[string]bool
+	// reference ids of the rows generated in this run for each table
+	generated map[string][]string
+	// tables whose rows are being generated, a relation back to one of these is a cycle
+	inProgress map[string]bool
+	Failures   []string
+}
+
+// NewRandomDataGenerator returns a generator seeded with seed, the same seed generates the same values on the same
+// schema. The fake package is seeded as well, so only one generator runs at a time, Close releases it.
+func NewRandomDataGenerator(seed int64, relatedCount int, tables []TableInfo, relations []api2go.TableRelation,
+	cruds map[string]*DbResource, request api2go.Request, transaction *sqlx.Tx) *RandomDataGenerator {
+
+	randomDataLock.Lock()
+	fake.Seed(seed)
+
+	tableMap := make(map[string]TableInfo)
+	for _, table := range tables {
+		tableMap[table.TableName] = table
+	}
+
+	return &RandomDataGenerator{
+		rand:         rand.New(rand.NewSource(seed)),
+		tables:       tableMap,
+		relations:    relations,
+		cruds:        cruds,
+		transaction:  transaction,
+		request:      request,
+		translator:   GetValidationTranslator(nil),
+		relatedCount: relatedCount,
+		usedValues:   make(map[string]map[string]bool),
+		generated:    make(map[string][]string),
+		inProgress:   make(map[string]bool),
+		Failures:     make([]string, 0),
+	}
+}
+
+func (g *RandomDataGenerator) Close() {
+	randomDataLock.Unlock()
+}
+
+// Generated is the number of rows generated for each table
+func (g *RandomDataGenerator) Generated() map[string]int {
+	counts := make(map[string]int)
+	for tableName, referenceIds := range g.generated {
+		counts[tableName] = len(referenceIds)
+	}
+	return counts
+}
+
+// Generate creates count rows in the table. The parents of the rows are created first, when relatedCount is more
+// than zero every relation gets relatedCount new rows, otherwise existing rows are linked and a parent is only
+// created when its table is empty. Tables which belong to this table get relatedCount rows for each new row.
+func (g *RandomDataGenerator) Generate(tableName string, count int) error {
+	err := g.createRows(tableName, count, 0, nil)
+	if err != nil {
+		return err
+	}
+	if g.relatedCount < 1 {
+		return nil
+	}
+
+	parentReferenceIds := g.generated[tableName]
+	for _, child := range g.childRelations(tableName) {
+		for _, parentReferenceId := range parentReferenceIds {
+			err = g.createRows(child.GetSubject(), g.relatedCount, 1, map[string]interface{}{
+				child.GetObjectName(): parentReferenceId,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// childRelations are the belongs_to and has_one relations of other tables to this table, except ownership and the
+// state tracking table
+func (g *RandomDataGenerator) childRelations(tableName string) []api2go.TableRelation {
+	children := make([]api2go.TableRelation, 0)
+	for _, relation := range g.relations {
+		childName := relation.GetSubject()
+		if relation.GetObject() != tableName || childName == tableName || childName == tableName+"_state" ||
+			!isGeneratedRelation(relation) || g.tables[childName].IsJoinTable {
+			continue
+		}
+		if relation.GetRelation() == "belongs_to" || relation.GetRelation() == "has_one" {
+			children = append(children, relation)
+		}
+	}
+	return children
+}
+
+// isGeneratedRelation is false for the owner and usergroup relations, which are set up by the create request
+func isGeneratedRelation(relation api2go.TableRelation) bool {
+	return relation.GetObjectName() != USER_ACCOUNT_ID_COLUMN && relation.GetObject() != "usergroup" &&
+		relation.GetSubject() != "usergroup"
+}
+
+func (g *RandomDataGenerator) createRows(tableName string, count int, depth int, fixedValues map[string]interface{}) error {
+	table, ok := g.tables[tableName]
+	dbResource := g.cruds[tableName]
+	if !ok || dbResource == nil {
+		return fmt.Errorf("table [%v] is not created yet", tableName)
+	}
+	g.inProgress[tableName] = true
+	defer delete(g.inProgress, tableName)
+
+	for _, column := range table.Columns {
+		if !isGeneratedForeignKey(column) || fixedValues[column.ColumnName] != nil {
+			continue
+		}
+		err := g.ensureRows(column.ForeignKeyData.Namespace, depth)
+		if err != nil {
+			return err
+		}
+	}
+
+	linkedRelations := make([]api2go.TableRelation, 0)
+	for _, relation := range g.relations {
+		if relation.GetSubject() != tableName || !isGeneratedRelation(relation) {
+			continue
+		}
+		if relation.GetRelation() != "has_many" && relation.GetRelation() != "has_many_and_belongs_to_many" {
+			continue
+		}
+		err := g.ensureRows(relation.GetObject(), depth)
+		if err != nil {
+			return err
+		}
+		linkedRelations = append(linkedRelations, relation)
+	}
+
+	for i := 0; i < count; i++ {
+
+		var row map[string]interface{}
+		var validationErrors []api2go.Error
+		for attempt := 0; attempt < maxValueAttempts; attempt++ {
+			row = g.fakeRow(table)
+			for _, column := range table.Columns {
+				if isGeneratedForeignKey(column) {
+					parentReferenceId := g.pickReferenceId(column.ForeignKeyData.Namespace)
+					if parentReferenceId != "" {
+						row[column.ColumnName] = parentReferenceId
+					}
+				}
+			}
+			for columnName, value := range fixedValues {
+				row[columnName] = value
+			}
+
+			var err error
+			validationErrors, err = ValidateObject(&table, table.Validations, row, false, "/", g.translator, g.transaction)
+			if err != nil {
+				return err
+			}
+			if len(validationErrors) == 0 {
+				break
+			}
+		}
+		if len(validationErrors) > 0 {
+			g.Failures = append(g.Failures, fmt.Sprintf("%v: %v", tableName, validationErrors[0].Detail))
+			continue
+		}
+
+		referenceId := g.newRowReferenceId(tableName)
+		row["reference_id"] = referenceId
+		row["permission"] = auth.DEFAULT_PERMISSION
+
+		err := g.insertRow(dbResource, tableName, row)
+		if err != nil {
+			log.Errorf("[211] Failed to insert random row into [%v]: %v", tableName, err)
+			g.Failures = append(g.Failures, fmt.Sprintf("%v: %v", tableName, err))
+			continue
+		}
+		g.generated[tableName] = append(g.generated[tableName], referenceId)
+
+		for _, relation := range linkedRelations {
+			for _, objectReferenceId := range g.pickReferenceIds(relation.GetObject()) {
+				err = g.createRows(relation.GetJoinTableName(), 1, depth, map[string]interface{}{
+					relation.GetSubjectName(): referenceId,
+					relation.GetObjectName():  objectReferenceId,
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// insertRow creates the row inside a savepoint, so the rest of the rows can be created when one is rejected
+func (g *RandomDataGenerator) insertRow(dbResource *DbResource, tableName string, row map[string]interface{}) error {
+	_, err := g.transaction.Exec("SAVEPOINT random_data_row")
+	if err != nil {
+		return err
+	}
+	_, err = dbResource.CreateWithTransaction(api2go.NewApi2GoModelWithData(tableName, nil, 0, nil, row), g.request, g.transaction)
+	if err != nil {
+		_, rollbackErr := g.transaction.Exec("ROLLBACK TO SAVEPOINT random_data_row")
+		CheckErr(rollbackErr, "Failed to roll back rejected random row")
+		return err
+	}
+	_, err = g.transaction.Exec("RELEASE SAVEPOINT random_data_row")
+	return err
+}
+
+// isGeneratedForeignKey is true for the columns referring to rows of another table, except the owner column
+func isGeneratedForeignKey(column api2go.ColumnInfo) bool {
+	return column.IsForeignKey && column.ForeignKeyData.DataSource == "self" && column.ColumnName != USER_ACCOUNT_ID_COLUMN
+}
+
+// ensureRows makes sure there are rows to link to in the table, generating them when needed
+func (g *RandomDataGenerator) ensureRows(tableName string, depth int) error {
+	if len(g.generated[tableName]) > 0 || g.inProgress[tableName] || depth >= maxRelationDepth {
+		return nil
+	}
+	if _, ok := g.tables[tableName]; !ok {
+		return nil
+	}
+	if g.relatedCount > 0 {
+		return g.createRows(tableName, g.relatedCount, depth+1, nil)
+	}
+	if len(g.existingReferenceIds(tableName)) > 0 {
+		return nil
+	}
+	return g.createRows(tableName, 1, depth+1, nil)
+}
+
+// pickReferenceId picks one of the rows generated in this run, or one of the existing rows
+func (g *RandomDataGenerator) pickReferenceId(tableName string) string {
+	referenceIds := g.generated[tableName]
+	if len(referenceIds) == 0 {
+		referenceIds = g.existingReferenceIds(tableName)
+	}
+	if len(referenceIds) == 0 {
+		return ""
+	}
+	return referenceIds[g.rand.Intn(len(referenceIds))]
+}
+
+// pickReferenceIds picks one to three distinct rows of the table to link over a has_many relation
+func (g *RandomDataGenerator) pickReferenceIds(tableName string) []string {
+	links := make([]string, 0)
+	picked := make(map[string]bool)
+	for i := g.rand.Intn(3); i >= 0; i-- {
+		referenceId := g.pickReferenceId(tableName)
+		if referenceId == "" || picked[referenceId] {
+			continue
+		}
+		picked[referenceId] = true
+		links = append(links, referenceId)
+	}
+	return links
+}
+
+// existingReferenceIds are the reference ids of the first rows of the table, in the order of their id so a seeded
+// generator picks the same rows every time
+func (g *RandomDataGenerator) existingReferenceIds(tableName string) []string {
+	query, args, err := statementbuilder.Squirrel.Select("reference_id").Prepared(true).
+		From(tableName).Order(goqu.C("id").Asc()).Limit(1000).ToSQL()
+	if err != nil {
+		return nil
+	}
+	rows, err := g.transaction.Queryx(query, args...)
+	if err != nil {
+		CheckErr(err, "[212] Failed to query existing rows of [%v]", tableName)
+		return nil
+	}
+	defer rows.Close()
+
+	referenceIds := make([]string, 0)
+	for rows.Next() {
+		var referenceId daptinid.DaptinReferenceId
+		err = rows.Scan(&referenceId)
+		if err != nil {
+			CheckErr(err, "[213] Failed to scan reference id of [%v]", tableName)
+			continue
+		}
+		referenceIds = append(referenceIds, referenceId.String())
+	}
+	return referenceIds
+}
+
+// newRowReferenceId is a reference id not used in the table yet, running the same seed again on a database which has
+// the rows of the earlier run gives new rows
+func (g *RandomDataGenerator) newRowReferenceId(tableName string) string {
+	for {
+		referenceId := uuid.MustParse(g.newReferenceId())
+		if !g.valueExists(tableName, "reference_id", referenceId[:]) {
+			return referenceId.String()
+		}
+	}
+}
+
+func (g *RandomDataGenerator) newReferenceId() string {
+	u, err := uuid.NewRandomFromReader(g.rand)
+	if err != nil {
+		u, _ = uuid.NewV7()
+	}
+	return u.String()
+}
+
+// fakeRow has a value for every column of the table except the standard and foreign key columns
+func (g *RandomDataGenerator) fakeRow(table TableInfo) map[string]interface{} {
+	row := make(map[string]interface{})
+	for _, column := range table.Columns {
+		if column.IsForeignKey || column.ColumnType == "sequence" || isStandardColumn(column.ColumnName) {
+			continue
+		}
+		tags := make([]string, 0)
+		isUnique := column.IsUnique
+		for _, validation := range table.Validations {
+			if validation.ColumnName == column.ColumnName {
+				tags = append(tags, validation.Tags)
+				isUnique = isUnique || strings.Contains(validation.Tags, "unique_column")
+			}
+		}
+		row[column.ColumnName] = g.uniqueValue(table.TableName, column, strings.Join(tags, ","), isUnique)
+	}
+	return row
+}
+
+func isStandardColumn(columnName string) bool {
+	for _, c := range StandardColumns {
+		if columnName == c.ColumnName {
+			return true
+		}
+	}
+	return false
+}
+
+// uniqueValue is a fake value which was not used before in this run, and for unique columns is not present in the
+// table either. Text values get a random suffix when the faker keeps repeating itself.
+func (g *RandomDataGenerator) uniqueValue(tableName string, column api2go.ColumnInfo, tags string, isUnique bool) interface{} {
+	key := tableName + "." + column.ColumnName
+	used, ok := g.usedValues[key]
+	if !ok {
+		used = make(map[string]bool)
+		g.usedValues[key] = used
+	}
+
+	var value interface{}
+	for attempt := 0; attempt < maxValueAttempts; attempt++ {
+		value = g.FakeColumnValue(column, tags)
+		if !isUnique || (!used[fmt.Sprintf("%v", value)] && !g.valueExists(tableName, column.ColumnName, value)) {
+			break
+		}
+		if text, isText := value.(string); isText && attempt == maxValueAttempts-2 {
+			value = fmt.Sprintf("%v%d", text, g.rand.Intn(1000000))
+			if !used[fmt.Sprintf("%v", value)] && !g.valueExists(tableName, column.ColumnName, value) {
+				break
+			}
+		}
+	}
+	used[fmt.Sprintf("%v", value)] = true
+	return value
+}
+
+func (g *RandomDataGenerator) valueExists(tableName string, columnName string, value interface{}) bool {
+	if g.transaction == nil {
+		return false
+	}
+	query, args, err := statementbuilder.Squirrel.Select(goqu.COUNT("*")).Prepared(true).
+		From(tableName).Where(goqu.Ex{columnName: value}).ToSQL()
+	if err != nil {
+		return false
+	}
+	var count int
+	err = g.transaction.QueryRowx(query, args...).Scan(&count)
+	if err != nil {
+		CheckErr(err, "[214] Failed to check existing value of [%v][%v]", tableName, columnName)
+		return false
+	}
+	return count > 0
+}
+
+// FakeColumnValue is a value for the column which is one of the enum options, satisfies the validation tags and
+// fits in the column. Text columns get a value matching their name when there is one, like an email address for
+// contact_email.
+func (g *RandomDataGenerator) FakeColumnValue(column api2go.ColumnInfo, tags string) interface{} {
+	if enumValues := EnumValues(column); len(enumValues) > 0 {
+		return enumValues[g.rand.Intn(len(enumValues))]
+	}
+
+	hints := parseFakeValueHints(tags)
+	if len(hints.OneOf) > 0 {
+		return hints.OneOf[g.rand.Intn(len(hints.OneOf))]
+	}
+
+	kind := dataTypeKind(column.DataType)
+	if kind == "int" || kind == "float" {
+		if hints.Min != nil || hints.Max != nil {
+			return g.numberInRange(kind, hints)
+		}
+		return g.fakeTypeValue(column)
+	}
+
+	var value string
+	switch {
+	case hints.Email:
+		value = fake.EmailAddress()
+	case hints.Url:
+		value = "https://" + fake.DomainName()
+	case hints.Uuid:
+		value = g.newReferenceId()
+	default:
+		value = ""
+		if InStringArray(textColumnTypes, column.ColumnType) {
+			value = fakeValueByColumnName(column.ColumnName)
+		}
+		if value == "" {
+			value = fmt.Sprintf("%v", g.fakeTypeValue(column))
+		}
+	}
+	return fitText(value, hints, dataTypeSize(column.DataType))
+}
+
+// fakeValueByColumnName is a value matching the column name, empty when the name is not a known one
+func fakeValueByColumnName(columnName string) string {
+	for _, namedFaker := range namedColumnFakers {
+		if columnNameMatches(columnName, namedFaker.names, namedFaker.exact) {
+			return namedFaker.fake()
+		}
+	}
+	return ""
+}
+
+// columnNameMatches is true when the column is one of the names, or when its last words are one of the names
+func columnNameMatches(columnName string, names []string, exact bool) bool {
+	columnName = strings.ToLower(columnName)
+	for _, name := range names {
+		if columnName == name || (!exact && strings.HasSuffix(columnName, "_"+name)) {
+			return true
+		}
+	}
+	return false
+}
+
+// fitText pads or cuts the text to the lengths allowed by the validations and the column size
+func fitText(value string, hints fakeValueHints, size int) string {
+	minLength, maxLength := 0, size
+	if hints.Length != nil {
+		minLength, maxLength = int(*hints.Length), int(*hints.Length)
+	}
+	if hints.Min != nil {
+		minLength = int(*hints.Min)
+	}
+	if hints.Max != nil && (maxLength == 0 || int(*hints.Max) < maxLength) {
+		maxLength = int(*hints.Max)
+	}
+	for len(value) < minLength {
+		value = value + fake.Word()
+	}
+	if maxLength > 0 && len(value) > maxLength {
+		value = strings.TrimSpace(value[:maxLength])
+		for len(value) < minLength {
+			value = value + "x"
+		}
+	}
+	return value
+}
+
+func (g *RandomDataGenerator) numberInRange(kind string, hints fakeValueHints) interface{} {
+	minValue, maxValue := 0.0, 1000.0
+	if hints.Min != nil {
+		minValue = *hints.Min
+		if hints.Max == nil {
+			maxValue = minValue + 1000
+		}
+	}
+	if hints.Max != nil {
+		maxValue = *hints.Max
+		if hints.Min == nil && maxValue < minValue {
+			minValue = maxValue - 1000
+		}
+	}
+	if kind == "int" {
+		low, high := int64(math.Ceil(minValue)), int64(math.Floor(maxValue))
+		if high <= low {
+			return low
+		}
+		return low + g.rand.Int63n(high-low+1)
+	}
+	return minValue + g.rand.Float64()*(maxValue-minValue)
+}
+
+// fakeTypeValue is a value for the column type. The values which the column types pick at random are picked from
+// the seeded source here, the rest come from the seeded fake package.
+func (g *RandomDataGenerator) fakeTypeValue(column api2go.ColumnInfo) interface{} {
+	switch column.ColumnType {
+	case "id", "alias":
+		return g.newReferenceId()
+	case "date":
+		return g.randomDate().Format("2006-01-02")
+	case "time":
+		return g.randomDate().Format("15:04:05")
+	case "datetime":
+		return g.randomDate().Format(time.RFC3339)
+	case "timestamp":
+		return g.randomDate().Unix()
+	case "minute":
+		return g.rand.Intn(60)
+	case "hour":
+		return g.rand.Intn(24)
+	case "value":
+		return g.rand.Intn(1000)
+	case "truefalse":
+		return g.rand.Intn(2)
+	case "rating", "rating.10":
+		return g.rand.Intn(11)
+	case "measurement":
+		return g.rand.Intn(5000)
+	case "float":
+		return g.rand.Float64() * 1000
+	case "location.altitude":
+		return g.rand.Intn(10000)
+	case "money":
+		return fmt.Sprintf("%d.%02d", g.rand.Intn(10000), g.rand.Intn(100))
+	case "url":
+		return "https://example.com/?q=" + fmt.Sprintf("%d", g.rand.Int())
+	}
+	columnType, ok := ColumnManager.ColumnMap[column.ColumnType]
+	if !ok {
+		return fake.Word()
+	}
+	return columnType.Fake()
+}
+
+// randomDate is a date between 1980 and 2050
+func (g *RandomDataGenerator) randomDate() time.Time {
+	min := time.Date(1980, 1, 0, 0, 0, 0, 0, time.UTC).Unix()
+	max := time.Date(2050, 1, 0, 0, 0, 0, 0, time.UTC).Unix()
+	return time.Unix(g.rand.Int63n(max-min)+min, 0).UTC()
+}
@@ -0,0 +1,56 @@
+package resource
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/artpar/api2go"
+)
+
+func TestRandomDataColumnValues(t *testing.T) {
+	InitialiseColumnManager()
+
+	columns := []api2go.ColumnInfo{
+		{ColumnName: "contact_email", ColumnType: "label", DataType: "varchar(100)"},
+		{ColumnName: "status", ColumnType: "enum", DataType: "varchar(20)", Options: []api2go.ValueOptions{{Value: "open"}, {Value: "closed"}}},
+		{ColumnName: "quantity", ColumnType: "measurement", DataType: "int(11)"},
+		{ColumnName: "code", ColumnType: "label", DataType: "varchar(5)"},
+	}
+	tags := map[string]string{
+		"quantity": "gte=10,lte=20",
+		"code":     "min=3",
+	}
+
+	generate := func() []interface{} {
+		generator := NewRandomDataGenerator(42, 0, nil, nil, nil, api2go.Request{}, nil)
+		defer generator.Close()
+		values := make([]interface{}, 0)
+		for i := 0; i < 20; i++ {
+			for _, column := range columns {
+				values = append(values, generator.FakeColumnValue(column, tags[column.ColumnName]))
+			}
+		}
+		return values
+	}
+
+	values := generate()
+	for i := 0; i < len(values); i += len(columns) {
+		if !strings.Contains(values[i].(string), "@") {
+			t.Errorf("expected an email address, got %v", values[i])
+		}
+		if values[i+1] != "open" && values[i+1] != "closed" {
+			t.Errorf("expected an enum option, got %v", values[i+1])
+		}
+		if quantity := values[i+2].(int64); quantity < 10 || quantity > 20 {
+			t.Errorf("expected a quantity between 10 and 20, got %v", quantity)
+		}
+		if code := values[i+3].(string); len(code) < 3 || len(code) > 5 {
+			t.Errorf("expected a code of 3 to 5 characters, got %v", code)
+		}
+	}
+
+	if !reflect.DeepEqual(values, generate()) {
+		t.Errorf("the same seed should generate the same values")
+	}
+}
@@ -0,0 +1,423 @@
+package resource
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/buraksezer/olric"
+	"github.com/daptin/daptin/server/auth"
+	"github.com/daptin/daptin/server/database"
+	daptinid "github.com/daptin/daptin/server/id"
+	"github.com/daptin/daptin/server/statementbuilder"
+	"github.com/doug-martin/goqu/v9"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// Rate limits are defined in the Limits of the schema files and in the limit.rules config as a json list. A limit
+// applies to the calls of an action, to the requests on a table (optionally of one method), or to every action and
+// api request when it names neither. It allows Limit requests in a sliding Window and/or Quota requests a day,
+// counted from midnight UTC. Per says who is counted on their own, the signed in user by default, and Group or User
+// narrow the limit to the members of a usergroup or to one user account. The counters are kept in olric and shared
+// by every node of the cluster. A request over a limit fails with 429 and a Retry-After header.
+
+const (
+	RateLimitPerUser   = "user"
+	RateLimitPerApiKey = "api_key"
+	RateLimitPerGroup  = "group"
+	RateLimitPerIp     = "ip"
+	RateLimitPerAll    = "all"
+)
+
+// RateLimit limits the number of requests of a kind
+type RateLimit struct {
+	Name   string // Identifies the counters of the limit, and is shown when it is exceeded
+	Action string // Name of the action limited
+	OnType string // Type of the action limited, any type when empty
+	Table  string // Table limited, for requests on /api/<table>
+	Method string // Http method of the requests on the table limited, any method when empty
+	Per    string // Counted on its own for each "user" (default), "api_key", "group", "ip", or for "all" together
+	Group  string // Only limits the members of the usergroup, counted together with Per "group"
+	User   string // Only limits the user account with this email
+	Limit  int    // Requests allowed in Window
+	Window string // Length of the sliding window, like "1m" or "1h"
+	Quota  int    // Requests allowed a day
+}
+
+// RateLimitCounters keeps the number of requests counted for a key until the key expires. Incr gives the count
+// including the request, which is what a limit is checked against so concurrent requests cannot all pass it.
+type RateLimitCounters interface {
+	Get(key string) (int, error)
+	Incr(key string, expiry time.Duration) (int, error)
+	Decr(key string) error
+}
+
+// olricRateLimitCounters shares the counters with the cluster
+type olricRateLimitCounters struct {
+	dmap olric.DMap
+}
+
+func NewOlricRateLimitCounters(olricDb *olric.EmbeddedClient) (RateLimitCounters, error) {
+	dmap, err := olricDb.NewDMap("rate-limits")
+	if err != nil {
+		return nil, err
+	}
+	return &olricRateLimitCounters{dmap: dmap}, nil
+}
+
+func (counters *olricRateLimitCounters) Get(key string) (int, error) {
+	value, err := counters.dmap.Get(context.Background(), key)
+	if errors.Is(err, olric.ErrKeyNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return value.Int()
+}
+
+func (counters *olricRateLimitCounters) Incr(key string, expiry time.Duration) (int, error) {
+	count, err := counters.dmap.Incr(context.Background(), key, 1)
+	if err != nil {
+		return count, err
+	}
+	// the expiry is set on every increment, a key whose first expiry failed would otherwise count forever. The keys
+	// are of a single window or day, moving the expiry on keeps them only until the next window is counted.
+	return count, counters.dmap.Expire(context.Background(), key, expiry)
+}
+
+func (counters *olricRateLimitCounters) Decr(key string) error {
+	_, err := counters.dmap.Decr(context.Background(), key, 1)
+	return err
+}
+
+// localRateLimitCounters keeps the counters of a single node
+type localRateLimitCounters struct {
+	lock     sync.Mutex
+	counts   map[string]int
+	expireAt map[string]time.Time
+}
+
+func NewLocalRateLimitCounters() RateLimitCounters {
+	return &localRateLimitCounters{
+		counts:   make(map[string]int),
+		expireAt: make(map[string]time.Time),
+	}
+}
+
+func (counters *localRateLimitCounters) Get(key string) (int, error) {
+	counters.lock.Lock()
+	defer counters.lock.Unlock()
+	if time.Now().After(counters.expireAt[key]) {
+		delete(counters.counts, key)
+		delete(counters.expireAt, key)
+	}
+	return counters.counts[key], nil
+}
+
+func (counters *localRateLimitCounters) Incr(key string, expiry time.Duration) (int, error) {
+	counters.lock.Lock()
+	defer counters.lock.Unlock()
+	now := time.Now()
+	if now.After(counters.expireAt[key]) {
+		counters.counts[key] = 0
+		counters.expireAt[key] = now.Add(expiry)
+	}
+	counters.counts[key]++
+	return counters.counts[key], nil
+}
+
+func (counters *localRateLimitCounters) Decr(key string) error {
+	counters.lock.Lock()
+	defer counters.lock.Unlock()
+	if counters.counts[key] > 0 {
+		counters.counts[key]--
+	}
+	return nil
+}
+
+// rateLimitRule is a RateLimit ready to be checked
+type rateLimitRule struct {
+	RateLimit
+	window           time.Duration
+	groupReferenceId daptinid.DaptinReferenceId
+	userReferenceId  daptinid.DaptinReferenceId
+}
+
+type RateLimiter struct {
+	rules    []rateLimitRule
+	counters RateLimitCounters
+}
+
+// rateLimitRequest is what the limits are matched against
+type rateLimitRequest struct {
+	kind        string // "action" or "api"
+	typeName    string
+	actionName  string
+	method      string
+	sessionUser *auth.SessionUser
+	apiKey      string
+	clientIp    string
+}
+
+// NewRateLimiter checks the limits, looking up the usergroups and user accounts they name. A limit which is not valid
+// is logged and left out.
+func NewRateLimiter(limits []RateLimit, counters RateLimitCounters, db database.DatabaseConnection) *RateLimiter {
+	limiter := &RateLimiter{
+		rules:    make([]rateLimitRule, 0, len(limits)),
+		counters: counters,
+	}
+	for _, limit := range limits {
+		rule, err := newRateLimitRule(limit, db)
+		if err != nil {
+			log.Errorf("Invalid rate limit [%v]: %v", limit.Name, err)
+			continue
+		}
+		limiter.rules = append(limiter.rules, rule)
+	}
+	log.Printf("Loaded %d rate limits", len(limiter.rules))
+	return limiter
+}
+
+func newRateLimitRule(limit RateLimit, db database.DatabaseConnection) (rateLimitRule, error) {
+	rule := rateLimitRule{RateLimit: limit}
+	if rule.Per == "" {
+		rule.Per = RateLimitPerUser
+	}
+	switch rule.Per {
+	case RateLimitPerUser, RateLimitPerApiKey, RateLimitPerIp, RateLimitPerAll:
+	case RateLimitPerGroup:
+		if rule.Group == "" {
+			return rule, fmt.Errorf("a limit per group needs the Group it counts")
+		}
+	default:
+		return rule, fmt.Errorf("unknown Per [%v]", rule.Per)
+	}
+	rule.Method = strings.ToUpper(rule.Method)
+	if rule.Name == "" {
+		rule.Name = strings.Join([]string{rule.OnType, rule.Action, rule.Table, rule.Method, rule.Per, rule.Group, rule.User}, ":")
+	}
+
+	if rule.Window != "" {
+		window, err := time.ParseDuration(rule.Window)
+		if err != nil || window <= 0 {
+			return rule, fmt.Errorf("invalid Window [%v]", rule.Window)
+		}
+		rule.window = window
+	}
+	if rule.Limit < 0 || rule.Quota < 0 {
+		return rule, fmt.Errorf("Limit and Quota cannot be negative")
+	}
+	if rule.Limit > 0 && rule.window == 0 {
+		return rule, fmt.Errorf("a Limit needs a Window")
+	}
+	if (rule.Limit <= 0 || rule.window == 0) && rule.Quota <= 0 {
+		return rule, fmt.Errorf("a limit needs a Limit and a Window, or a Quota")
+	}
+
+	var err error
+	if rule.Group != "" {
+		rule.groupReferenceId, err = lookupReferenceId(db, "usergroup", "name", rule.Group)
+		if err != nil {
+			return rule, fmt.Errorf("unknown usergroup [%v]: %v", rule.Group, err)
+		}
+	}
+	if rule.User != "" {
+		rule.userReferenceId, err = lookupReferenceId(db, USER_ACCOUNT_TABLE_NAME, "email", rule.User)
+		if err != nil {
+			return rule, fmt.Errorf("unknown user account [%v]: %v", rule.User, err)
+		}
+	}
+	return rule, nil
+}
+
+func lookupReferenceId(db database.DatabaseConnection, tableName string, column string, value string) (daptinid.DaptinReferenceId, error) {
+	s, v, err := statementbuilder.Squirrel.Select("reference_id").Prepared(true).From(tableName).
+		Where(goqu.Ex{column: value}).ToSQL()
+	if err != nil {
+		return daptinid.NullReferenceId, err
+	}
+	var referenceId daptinid.DaptinReferenceId
+	err = db.Get(&referenceId, db.Rebind(s), v...)
+	return referenceId, err
+}
+
+// matches is true when the limit applies to the request
+func (rule rateLimitRule) matches(request rateLimitRequest) bool {
+	if rule.Action != "" && (request.kind != "action" || rule.Action != request.actionName) {
+		return false
+	}
+	if rule.OnType != "" && rule.OnType != request.typeName {
+		return false
+	}
+	if rule.Table != "" && (request.kind != "api" || rule.Table != request.typeName) {
+		return false
+	}
+	if rule.Method != "" && rule.Method != request.method {
+		return false
+	}
+	if rule.userReferenceId != daptinid.NullReferenceId &&
+		(request.sessionUser == nil || request.sessionUser.UserReferenceId != rule.userReferenceId) {
+		return false
+	}
+	if rule.groupReferenceId != daptinid.NullReferenceId {
+		if request.sessionUser == nil {
+			return false
+		}
+		member := false
+		for _, group := range request.sessionUser.Groups {
+			if group.GroupReferenceId == rule.groupReferenceId {
+				member = true
+				break
+			}
+		}
+		if !member {
+			return false
+		}
+	}
+	return true
+}
+
+// counterKey is who the request is counted for, guests are counted by their ip
+func (rule rateLimitRule) counterKey(request rateLimitRequest) string {
+	signedIn := request.sessionUser != nil && request.sessionUser.UserReferenceId != daptinid.NullReferenceId
+	switch rule.Per {
+	case RateLimitPerAll:
+		return "all"
+	case RateLimitPerGroup:
+		return "group:" + rule.Group
+	case RateLimitPerIp:
+		return "ip:" + request.clientIp
+	case RateLimitPerApiKey:
+		if request.apiKey != "" {
+			return "api_key:" + request.apiKey
+		}
+	}
+	if signedIn {
+		return "user:" + request.sessionUser.UserReferenceId.String()
+	}
+	return "ip:" + request.clientIp
+}
+
+// take counts the request for every limit it matches, and gives the limit exceeded and how long to wait. The
+// counters are incremented first and the limits checked against the counts returned, a request which exceeds a
+// limit is taken back from the counters it was added to.
+func (limiter *RateLimiter) take(request rateLimitRequest, now time.Time) (*rateLimitRule, time.Duration, error) {
+	taken := make([]string, 0)
+	release := func() {
+		for _, key := range taken {
+			CheckErr(limiter.counters.Decr(key), "Failed to take back request from rate limit counter [%v]", key)
+		}
+	}
+
+	for i := range limiter.rules {
+		rule := &limiter.rules[i]
+		if !rule.matches(request) {
+			continue
+		}
+		prefix := "limit:" + rule.Name + ":" + rule.counterKey(request) + ":"
+
+		if rule.Limit > 0 && rule.window > 0 {
+			// the count of the previous window is weighed by how much of it is still inside the sliding window
+			start := now.Truncate(rule.window)
+			currentKey := prefix + strconv.FormatInt(start.Unix(), 10)
+			previousKey := prefix + strconv.FormatInt(start.Add(-rule.window).Unix(), 10)
+			current, err := limiter.counters.Incr(currentKey, 2*rule.window)
+			if err != nil {
+				release()
+				return nil, 0, err
+			}
+			taken = append(taken, currentKey)
+			previous, err := limiter.counters.Get(previousKey)
+			if err != nil {
+				release()
+				return nil, 0, err
+			}
+			elapsed := now.Sub(start)
+			weight := float64(rule.window-elapsed) / float64(rule.window)
+			if float64(previous)*weight+float64(current) > float64(rule.Limit) {
+				release()
+				return rule, rule.window - elapsed, nil
+			}
+		}
+
+		if rule.Quota > 0 {
+			day := now.UTC().Truncate(24 * time.Hour)
+			quotaKey := prefix + "day:" + day.Format("2006-01-02")
+			used, err := limiter.counters.Incr(quotaKey, 48*time.Hour)
+			if err != nil {
+				release()
+				return nil, 0, err
+			}
+			taken = append(taken, quotaKey)
+			if used > rule.Quota {
+				release()
+				return rule, day.Add(24 * time.Hour).Sub(now), nil
+			}
+		}
+	}
+	return nil, 0, nil
+}
+
+// newRateLimitRequest reads what the request is from its path, only action and api requests are limited
+func newRateLimitRequest(c *gin.Context) (rateLimitRequest, bool) {
+	request := rateLimitRequest{
+		method:   c.Request.Method,
+		clientIp: c.ClientIP(),
+	}
+	parts := strings.Split(strings.Trim(c.Request.URL.Path, "/"), "/")
+	switch {
+	case len(parts) == 3 && parts[0] == "action":
+		request.kind = "action"
+		request.typeName = parts[1]
+		request.actionName = parts[2]
+	case len(parts) >= 2 && parts[0] == "api":
+		request.kind = "api"
+		request.typeName = parts[1]
+	default:
+		return request, false
+	}
+
+	if sessionUser, ok := c.Request.Context().Value("user").(*auth.SessionUser); ok {
+		request.sessionUser = sessionUser
+	}
+	if authorization := c.GetHeader("Authorization"); authorization != "" {
+		hash := sha256.Sum256([]byte(authorization))
+		request.apiKey = hex.EncodeToString(hash[:16])
+	}
+	return request, true
+}
+
+func (limiter *RateLimiter) RateLimiterMiddlewareFunc(c *gin.Context) {
+	if len(limiter.rules) == 0 {
+		c.Next()
+		return
+	}
+	request, ok := newRateLimitRequest(c)
+	if !ok {
+		c.Next()
+		return
+	}
+
+	exceeded, retryAfter, err := limiter.take(request, time.Now())
+	if err != nil {
+		// the requests are let through rather than failing them all when the counters cannot be read
+		log.Errorf("Failed to check rate limits: %v", err)
+		c.Next()
+		return
+	}
+	if exceeded != nil {
+		c.Header("Retry-After", strconv.Itoa(int(math.Max(1, math.Ceil(retryAfter.Seconds())))))
+		c.AbortWithStatusJSON(429, gin.H{"error": fmt.Sprintf("rate limit [%v] exceeded", exceeded.Name)})
+		return
+	}
+	c.Next()
+}
@@ -0,0 +1,161 @@
+package resource
+
+import (
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/daptin/daptin/server/auth"
+	daptinid "github.com/daptin/daptin/server/id"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func TestRateLimitSlidingWindowAndQuota(t *testing.T) {
+	limiter := NewRateLimiter([]RateLimit{
+		{Name: "orders", Action: "place", Limit: 2, Window: "1m"},
+		{Name: "daily", Table: "order", Method: "post", Quota: 3},
+	}, NewLocalRateLimitCounters(), nil)
+	if len(limiter.rules) != 2 {
+		t.Fatalf("expected 2 limits, got %d", len(limiter.rules))
+	}
+
+	user := &auth.SessionUser{UserReferenceId: daptinid.DaptinReferenceId(uuid.New())}
+	other := &auth.SessionUser{UserReferenceId: daptinid.DaptinReferenceId(uuid.New())}
+	action := rateLimitRequest{kind: "action", typeName: "order", actionName: "place", method: "POST", sessionUser: user}
+	start := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	allow := func(request rateLimitRequest, now time.Time) (bool, time.Duration) {
+		exceeded, retryAfter, err := limiter.take(request, now)
+		if err != nil {
+			t.Fatalf("failed to check limits: %v", err)
+		}
+		if exceeded != nil {
+			return false, retryAfter
+		}
+		return true, 0
+	}
+
+	if ok, _ := allow(action, start); !ok {
+		t.Errorf("expected the first request to be allowed")
+	}
+	if ok, _ := allow(action, start.Add(10*time.Second)); !ok {
+		t.Errorf("expected the second request to be allowed")
+	}
+	if ok, retryAfter := allow(action, start.Add(20*time.Second)); ok || retryAfter != 40*time.Second {
+		t.Errorf("expected the third request to wait 40s, got %v %v", ok, retryAfter)
+	}
+	otherAction := action
+	otherAction.sessionUser = other
+	if ok, _ := allow(otherAction, start.Add(20*time.Second)); !ok {
+		t.Errorf("expected another user to be counted on their own")
+	}
+	// half of the previous window still counts, one of its two requests
+	if ok, _ := allow(action, start.Add(90*time.Second)); !ok {
+		t.Errorf("expected a request to be allowed once the window slid")
+	}
+	if ok, _ := allow(action, start.Add(91*time.Second)); ok {
+		t.Errorf("expected the sliding window to count the previous window")
+	}
+
+	create := rateLimitRequest{kind: "api", typeName: "order", method: "POST", sessionUser: user}
+	for i := 0; i < 3; i++ {
+		if ok, _ := allow(create, start); !ok {
+			t.Errorf("expected request %d of the quota to be allowed", i)
+		}
+	}
+	if ok, retryAfter := allow(create, start); ok || retryAfter != 14*time.Hour {
+		t.Errorf("expected the quota to be used up until midnight, got %v %v", ok, retryAfter)
+	}
+	read := create
+	read.method = "GET"
+	if ok, _ := allow(read, start); !ok {
+		t.Errorf("expected other methods not to be limited")
+	}
+	if ok, _ := allow(create, start.Add(14*time.Hour)); !ok {
+		t.Errorf("expected the quota to start again the next day")
+	}
+}
+
+func TestRateLimitConcurrentRequests(t *testing.T) {
+	limiter := NewRateLimiter([]RateLimit{
+		{Name: "burst", Action: "place", Per: RateLimitPerAll, Limit: 5, Window: "1m"},
+		{Name: "daily", Action: "place", Per: RateLimitPerAll, Quota: 8},
+	}, NewLocalRateLimitCounters(), nil)
+	request := rateLimitRequest{kind: "action", typeName: "order", actionName: "place", method: "POST"}
+	now := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	take := func(now time.Time, requests int) int {
+		var lock sync.Mutex
+		var wait sync.WaitGroup
+		allowed := 0
+		for i := 0; i < requests; i++ {
+			wait.Add(1)
+			go func() {
+				defer wait.Done()
+				exceeded, _, err := limiter.take(request, now)
+				if err == nil && exceeded == nil {
+					lock.Lock()
+					allowed++
+					lock.Unlock()
+				}
+			}()
+		}
+		wait.Wait()
+		return allowed
+	}
+
+	if allowed := take(now, 50); allowed != 5 {
+		t.Errorf("expected 5 of the concurrent requests to be allowed, got %d", allowed)
+	}
+	// the rejected requests were taken back, only the allowed ones count for the quota
+	if allowed := take(now.Add(2*time.Minute), 50); allowed != 3 {
+		t.Errorf("expected the 3 requests left of the quota to be allowed, got %d", allowed)
+	}
+}
+
+func TestRateLimitMiddlewareRespondsWithRetryAfter(t *testing.T) {
+	limiter := NewRateLimiter([]RateLimit{{Action: "place", Per: RateLimitPerIp, Limit: 1, Window: "1h"}},
+		NewLocalRateLimitCounters(), nil)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(limiter.RateLimiterMiddlewareFunc)
+	router.POST("/action/order/place", func(c *gin.Context) {
+		c.JSON(200, gin.H{})
+	})
+
+	post := func() *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest("POST", "/action/order/place", nil))
+		return recorder
+	}
+	if first := post(); first.Code != 200 {
+		t.Fatalf("expected the first request to pass, got %d", first.Code)
+	}
+	limited := post()
+	if limited.Code != 429 {
+		t.Fatalf("expected 429, got %d", limited.Code)
+	}
+	if limited.Header().Get("Retry-After") == "" {
+		t.Errorf("expected a Retry-After header")
+	}
+}
+
+func TestRateLimitRejectsInvalidLimits(t *testing.T) {
+	limiter := NewRateLimiter([]RateLimit{
+		{Name: "no window", Limit: 10},
+		{Name: "bad window", Limit: 10, Window: "soon"},
+		{Name: "unknown per", Quota: 10, Per: "planet"},
+		{Name: "group without name", Quota: 10, Per: RateLimitPerGroup},
+		{Name: "no limit", Limit: 0, Window: "1m"},
+		{Name: "zero window", Limit: 10, Window: "0s"},
+		{Name: "negative window", Limit: 10, Window: "-1m"},
+		{Name: "negative limit", Limit: -1, Window: "1m", Quota: 10},
+		{Name: "negative quota", Limit: 10, Window: "1m", Quota: -1},
+	}, NewLocalRateLimitCounters(), nil)
+	if len(limiter.rules) != 0 {
+		t.Errorf("expected every limit to be rejected, got %d", len(limiter.rules))
+	}
+}
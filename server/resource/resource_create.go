@@ -80,6 +80,17 @@ func (dbResource *DbResource) CreateWithoutFilter(obj interface{}, req api2go.Re
 			continue
 		}
 
+		if col.ColumnType == "sequence" {
+			sequenceValue, err := NextSequenceValue(dbResource.model.GetName(), col, time.Now(), createTransaction)
+			if err != nil {
+				return nil, err
+			}
+			dataToInsert[col.ColumnName] = sequenceValue
+			colsList = append(colsList, col.ColumnName)
+			valsList = append(valsList, sequenceValue)
+			continue
+		}
+
 		//log.Printf("Check column: %v", col.ColumnName)
 
 		columnValue, columnValueOk := attrs[col.ColumnName]
@@ -141,8 +152,9 @@ func (dbResource *DbResource) CreateWithoutFilter(obj interface{}, req api2go.Re
 				if ok {
 					var err error
 
+					dryRun := isDryRunRequest(req)
 					columnAssetCache, ok := dbResource.AssetFolderCache[dbResource.tableInfo.TableName][col.ColumnName]
-					if ok {
+					if ok && !dryRun {
 						err = columnAssetCache.UploadFiles(files)
 					}
 
@@ -198,10 +210,14 @@ func (dbResource *DbResource) CreateWithoutFilter(obj interface{}, req api2go.Re
 					actionRequestParameters["store_provider"] = cloudStore.StoreProvider
 					actionRequestParameters["root_path"] = cloudStore.RootPath + "/" + col.ForeignKeyData.KeyName
 
-					log.Printf("Initiate file upload action from resource create")
-					_, _, errs := uploadActionPerformer.DoAction(Outcome{}, actionRequestParameters, createTransaction)
-					if errs != nil && len(errs) > 0 {
-						log.Errorf("Failed to upload attachments: %v", errs)
+					if dryRun {
+						log.Infof("Dry run, not uploading files of column [%v]", col.ColumnName)
+					} else {
+						log.Printf("Initiate file upload action from resource create")
+						_, _, errs := uploadActionPerformer.DoAction(Outcome{}, actionRequestParameters, createTransaction)
+						if errs != nil && len(errs) > 0 {
+							log.Errorf("Failed to upload attachments: %v", errs)
+						}
 					}
 					for i := range files {
 						file := files[i].(map[string]interface{})
@@ -289,22 +305,18 @@ func (dbResource *DbResource) CreateWithoutFilter(obj interface{}, req api2go.Re
 				columnValue = parsedTime
 			}
 
-		} else if col.ColumnType == "enum" {
-			valString, ok := columnValue.(string)
-			if !ok {
-				valString = fmt.Sprintf("%v", columnValue)
+		} else if col.ColumnType == "money" && columnValue != nil {
+			columnValue, err = ParseMoney(columnValue, MoneyScale(col))
+			if err != nil {
+				return nil, fmt.Errorf("invalid value for %s: %v", col.Name, err)
 			}
-
-			isEnumOption := false
-			valString = strings.ToLower(valString)
-			for _, enumVal := range col.Options {
-
-				if valString == enumVal.Value {
-					isEnumOption = true
-					break
-				}
+		} else if col.ColumnType == "currency" && columnValue != nil {
+			columnValue, err = ParseCurrencyCode(columnValue)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value for %s: %v", col.Name, err)
 			}
-
+		} else if col.ColumnType == "enum" {
+			valString, isEnumOption := EnumOption(col, columnValue)
 			if !isEnumOption {
 				log.Printf("Provided value is not a valid enum option, reject request [%v] [%v]", valString, col.Options)
 				return nil, errors.New(fmt.Sprintf("invalid value for %s", col.Name))
@@ -393,13 +405,6 @@ func (dbResource *DbResource) CreateWithoutFilter(obj interface{}, req api2go.Re
 		colsList = append(colsList, "reference_id")
 		valsList = append(valsList, newObjectReferenceId[:])
 	}
-	languagePreferences := make([]string, 0)
-	if dbResource.tableInfo.TranslationsEnabled {
-		prefs := req.PlainRequest.Context().Value("language_preference")
-		if prefs != nil {
-			languagePreferences = prefs.([]string)
-		}
-	}
 
 	colsList = append(colsList, "permission")
 	valsList = append(valsList, dbResource.model.GetDefaultPermission())
@@ -439,31 +444,16 @@ func (dbResource *DbResource) CreateWithoutFilter(obj interface{}, req api2go.Re
 		return nil, err
 	}
 
-	if len(languagePreferences) > 0 {
-
-		for _, languagePreference := range languagePreferences {
-
-			colsList = append(colsList, "language_id")
-			valsList = append(valsList, languagePreference)
-
-			colsList = append(colsList, "translation_reference_id")
-			valsList = append(valsList, createdResource["id"])
-
-			query, vals, err := statementbuilder.Squirrel.Insert(dbResource.model.GetName() + "_i18n").
-				Cols(colsList...).Prepared(true).Vals(valsList).ToSQL()
-			if err != nil {
-				log.Errorf("469 Failed to create insert query: %v", err)
-				return nil, err
-			}
-
-			_, err = createTransaction.Exec(query, vals...)
-			if err != nil {
-				log.Infof("Insert query 468: %v", query)
-				log.Errorf("Failed to execute insert query 469: %v", err)
-				log.Errorf("%v", vals)
-				return nil, err
-
-			}
+	if translationLanguage := dbResource.TranslationLanguage(req.PlainRequest.Context()); translationLanguage != "" {
+		// the row keeps the values as its default until they are written in the default language
+		translatedValues := make(map[string]interface{})
+		for i, colName := range colsList {
+			translatedValues[colName.(string)] = valsList[i]
+		}
+		err = dbResource.SaveTranslation(createdResource["id"], translationLanguage, translatedValues, createTransaction)
+		if err != nil {
+			log.Errorf("[469] Failed to save translation: %v", err)
+			return nil, err
 		}
 	}
 
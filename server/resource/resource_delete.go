@@ -29,6 +29,11 @@ func (dbResource *DbResource) DeleteWithoutFilters(id daptinid.DaptinReferenceId
 	}
 	apiModel := api2go.NewApi2GoModelWithData(dbResource.model.GetTableName(), nil, 0, nil, data)
 
+	if translationLanguage := dbResource.TranslationLanguage(req.PlainRequest.Context()); translationLanguage != "" {
+		// only the translation is removed, the row falls back to the next language
+		return dbResource.DeleteTranslation(data["id"], translationLanguage, transaction)
+	}
+
 	user := req.PlainRequest.Context().Value("user")
 	sessionUser := &auth.SessionUser{}
 
@@ -72,7 +77,7 @@ func (dbResource *DbResource) DeleteWithoutFilters(id daptinid.DaptinReferenceId
 	parentReferenceId := data["reference_id"].(daptinid.DaptinReferenceId)
 
 	for _, column := range dbResource.model.GetColumns() {
-		if column.IsForeignKey && column.ForeignKeyData.DataSource == "cloud_store" {
+		if column.IsForeignKey && column.ForeignKeyData.DataSource == "cloud_store" && !isDryRunRequest(req) {
 
 			cloudStoreData, err := dbResource.GetCloudStoreByNameWithTransaction(column.ForeignKeyData.Namespace, transaction)
 			if err != nil {
@@ -408,50 +413,18 @@ func (dbResource *DbResource) DeleteWithoutFilters(id daptinid.DaptinReferenceId
 
 	}
 
-	languagePreferences := make([]string, 0)
-	if dbResource.tableInfo.TranslationsEnabled {
-		prefs := req.PlainRequest.Context().Value("language_preference")
-		if prefs != nil {
-			languagePreferences = prefs.([]string)
-		}
-	}
-
-	if len(languagePreferences) > 0 {
-
-		for _, lang := range languagePreferences {
-
-			queryBuilder := statementbuilder.Squirrel.Delete(m.GetTableName() + "_i18n").
-				Where(goqu.Ex{"translation_reference_id": parentId}).Prepared(true).
-				Where(goqu.Ex{"language_id": lang})
-
-			sql1, args, err := queryBuilder.ToSQL()
-			if err != nil {
-				log.Printf("Error: %v", err)
-				return err
-			}
-
-			log.Printf("Delete Sql: %v\n", sql1)
+	queryBuilder := statementbuilder.Squirrel.
+		Delete(m.GetTableName()).Prepared(true).Where(goqu.Ex{"reference_id": id[:]})
 
-			_, err = transaction.Exec(sql1, args...)
-
-		}
-	} else {
-
-		queryBuilder := statementbuilder.Squirrel.
-			Delete(m.GetTableName()).Prepared(true).Where(goqu.Ex{"reference_id": id[:]})
-
-		sql1, args, err := queryBuilder.ToSQL()
-		if err != nil {
-			log.Printf("Error: %v", err)
-			return err
-		}
-
-		log.Printf("Delete Sql: %v\n", sql1)
-
-		_, err = transaction.Exec(sql1, args...)
+	sql1, args, err := queryBuilder.ToSQL()
+	if err != nil {
+		log.Printf("Error: %v", err)
 		return err
 	}
 
+	log.Printf("Delete Sql: %v\n", sql1)
+
+	_, err = transaction.Exec(sql1, args...)
 	return err
 
 }
@@ -204,13 +204,7 @@ func (dbResource *DbResource) PaginatedFindAllWithoutFilters(req api2go.Request,
 		}
 	}
 
-	languagePreferences := make([]string, 0)
-	if dbResource.tableInfo.TranslationsEnabled {
-		prefs := req.PlainRequest.Context().Value("language_preference")
-		if prefs != nil {
-			languagePreferences = prefs.([]string)
-		}
-	}
+	languagePreferences := dbResource.TranslationLanguagePreferences(req.PlainRequest.Context())
 
 	pageNumber := uint64(0)
 	if len(req.QueryParams["page[number]"]) > 0 {
@@ -423,6 +417,11 @@ func (dbResource *DbResource) PaginatedFindAllWithoutFilters(req api2go.Request,
 			sort = sort[1:]
 		}
 
+		if jsonPath, isJsonPath := dbResource.tableInfo.GetJsonPath(sort); isJsonPath {
+			idQueryCols = append(idQueryCols, goqu.L(jsonPath.SortSql(transaction.DriverName())).As(jsonPath.Alias()))
+			continue
+		}
+
 		if strings.Index(sort, "(") == -1 {
 			sort = prefix + sort
 		}
@@ -890,6 +889,14 @@ func (dbResource *DbResource) PaginatedFindAllWithoutFilters(req api2go.Request,
 			continue
 		}
 		//log.Printf("Sort order: %v", so)
+		if jsonPath, isJsonPath := dbResource.tableInfo.GetJsonPath(strings.TrimLeft(so, "-+")); isJsonPath {
+			if so[0] == '-' {
+				orders = append(orders, goqu.L(jsonPath.SortSql(transaction.DriverName())).Desc())
+			} else {
+				orders = append(orders, goqu.L(jsonPath.SortSql(transaction.DriverName())).Asc())
+			}
+			continue
+		}
 		if so[0] == '-' {
 			//ord := prefix + so[1:] + " desc"
 			// queryBuilder = queryBuilder.OrderBy(ord)
@@ -1034,46 +1041,55 @@ func (dbResource *DbResource) PaginatedFindAllWithoutFilters(req api2go.Request,
 		}).Order(orders...)
 
 	} else {
-		var preferredLanguage = languagePreferences[0]
-		translateTableName := tableModel.GetTableName() + "_i18n"
+		translateTableName := TranslationTableName(tableModel.GetTableName())
+
+		// one join for each language of the preferences, a column takes the value of the first language which has
+		// it translated, and the value in the table otherwise
+		translationAliases := make([]string, len(languagePreferences))
+		for i := range languagePreferences {
+			translationAliases[i] = fmt.Sprintf("%s_%d", translateTableName, i)
+		}
 
-		ifNullFunctionName := "IFNULL"
-		if dbResource.Connection.DriverName() == "postgres" {
-			ifNullFunctionName = "COALESCE"
-		} else if dbResource.Connection.DriverName() == "mssql" {
-			ifNullFunctionName = "ISNULL"
+		translatedColumns := make(map[string]bool)
+		for _, columnName := range dbResource.translatedColumns() {
+			translatedColumns[columnName] = true
 		}
 
-		//translatedColumns := make([]string, 0)
 		for i, columnValue := range finalCols {
-			if IsStandardColumn(columnValue.reference) {
+			if strings.Index(columnValue.reference, ".") > -1 {
+				continue
+			}
+			if !translatedColumns[columnValue.reference] {
 				finalCols[i] = column{
 					originalvalue: goqu.I(prefix + columnValue.reference),
 					reference:     columnValue.reference,
 				}
-			} else {
-				if strings.Index(columnValue.reference, ".") == -1 {
-					finalCols[i] = column{
-						originalvalue: goqu.L(ifNullFunctionName + "(" + translateTableName + "." + columnValue.reference + "," + prefix + columnValue.reference + ") as " + columnValue.reference),
-						reference:     columnValue.reference,
-					}
-				} else {
-					finalCols[i] = columnValue
-				}
+				continue
+			}
+			coalesceArgs := make([]string, 0, len(translationAliases)+1)
+			for _, alias := range translationAliases {
+				coalesceArgs = append(coalesceArgs, alias+"."+columnValue.reference)
+			}
+			coalesceArgs = append(coalesceArgs, prefix+columnValue.reference)
+			finalCols[i] = column{
+				originalvalue: goqu.L("COALESCE(" + strings.Join(coalesceArgs, ", ") + ") as " + columnValue.reference),
+				reference:     columnValue.reference,
 			}
 		}
 
 		queryBuilder = statementbuilder.Squirrel.Select(ColumnToInterfaceArray(finalCols)...).
-			From(tableModel.GetTableName()).Prepared(true).
-			LeftJoin(
-				goqu.T(translateTableName),
+			From(tableModel.GetTableName()).Prepared(true)
+		for i, language := range languagePreferences {
+			queryBuilder = queryBuilder.LeftJoin(
+				goqu.T(translateTableName).As(translationAliases[i]),
 				goqu.On(goqu.Ex{
-					translateTableName + ".translation_reference_id": tableModel.GetTableName() + ".id",
-					translateTableName + ".language_id":              "'" + preferredLanguage + "'",
-				})).
-			Where(goqu.Ex{
-				idColumn: ids,
-			}).Order(orders...)
+					translationAliases[i] + ".translation_reference_id": goqu.I(tableModel.GetTableName() + ".id"),
+					translationAliases[i] + ".language_id":              language,
+				}))
+		}
+		queryBuilder = queryBuilder.Where(goqu.Ex{
+			idColumn: ids,
+		}).Order(orders...)
 
 	}
 
@@ -1298,7 +1314,18 @@ func (dbResource *DbResource) addFilters(queryBuilder *goqu.SelectDataset, count
 		colInfo, ok := tableInfo.GetColumnByName(columnName)
 
 		if !ok {
-			log.Printf("warn: invalid column [%v] in query, skipping", columnName)
+			jsonPath, isJsonPath := tableInfo.GetJsonPath(columnName)
+			if !isJsonPath {
+				log.Printf("warn: invalid column [%v] in query, skipping", columnName)
+				continue
+			}
+			condition, err := jsonPath.Condition(transaction.DriverName(), filterQuery.Operator, filterQuery.Value)
+			if err != nil {
+				log.Printf("warn: %v, skipping", err)
+				continue
+			}
+			queryBuilder = queryBuilder.Where(condition)
+			countQueryBuilder = countQueryBuilder.Where(condition)
 			continue
 		}
 
@@ -7,7 +7,6 @@ import (
 	"github.com/buraksezer/olric"
 	"github.com/daptin/daptin/server/auth"
 	daptinid "github.com/daptin/daptin/server/id"
-	"github.com/doug-martin/goqu/v9"
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
@@ -72,13 +71,7 @@ func (dbResource *DbResource) FindOne(referenceIdString string, req api2go.Reque
 	modelName := dbResource.model.GetName()
 	//log.Debugf("Find [%s] by id [%s]", modelName, referenceId)
 
-	languagePreferences := make([]string, 0)
-	if dbResource.tableInfo.TranslationsEnabled {
-		prefs := req.PlainRequest.Context().Value("language_preference")
-		if prefs != nil {
-			languagePreferences = prefs.([]string)
-		}
-	}
+	languagePreferences := dbResource.TranslationLanguagePreferences(req.PlainRequest.Context())
 
 	includedRelations := make(map[string]bool, 0)
 	if len(req.QueryParams["included_relations"]) > 0 {
@@ -111,38 +104,12 @@ func (dbResource *DbResource) FindOne(referenceIdString string, req api2go.Reque
 		_ = OlricCache.Put(context.Background(), cacheKey2, data["reference_id"], olric.EX(5*time.Minute), olric.NX())
 	}
 
-	if len(languagePreferences) > 0 {
-		for _, lang := range languagePreferences {
-			data_i18n_id, err := dbResource.GetIdByWhereClause(modelName+"_i18n", transaction, goqu.Ex{
-				"translation_reference_id": data["id"],
-				"language_id":              lang,
-			})
-			if err == nil && len(data_i18n_id) > 0 {
-				for _, data_i18n := range data_i18n_id {
-					translatedObj, err := dbResource.GetIdToObjectWithTransaction(modelName+"_i18n", data_i18n, transaction)
-					CheckErr(err, "Failed to fetch translated object for [%v][%v][%v]", modelName, lang, data["id"])
-					if err != nil {
-						rollbackErr := transaction.Rollback()
-						CheckErr(rollbackErr, "Failed to rollback")
-						return nil, err
-					}
-					if translatedObj != nil {
-						for colName, valName := range translatedObj {
-							if IsStandardColumn(colName) {
-								continue
-							}
-							if valName == nil {
-								continue
-							}
-							data[colName] = valName
-						}
-					}
-				}
-				break
-			} else {
-				CheckErr(err, "No translated rows for [%v][%v][%v]", modelName, referenceId, lang)
-			}
-		}
+	err = dbResource.TranslateRow(data, languagePreferences, transaction)
+	if err != nil {
+		CheckErr(err, "Failed to fetch translations for [%v][%v][%v]", modelName, languagePreferences, referenceId)
+		rollbackErr := transaction.Rollback()
+		CheckErr(rollbackErr, "Failed to rollback")
+		return nil, err
 	}
 
 	//log.Tracef("Single row result: %v", data)
@@ -243,13 +210,7 @@ func (dbResource *DbResource) FindOneWithTransaction(referenceId daptinid.Daptin
 	modelName := dbResource.model.GetName()
 	//log.Debugf("Find [%s] by id [%s]", modelName, referenceId)
 
-	languagePreferences := make([]string, 0)
-	if dbResource.tableInfo.TranslationsEnabled {
-		prefs := req.PlainRequest.Context().Value("language_preference")
-		if prefs != nil {
-			languagePreferences = prefs.([]string)
-		}
-	}
+	languagePreferences := dbResource.TranslationLanguagePreferences(req.PlainRequest.Context())
 
 	includedRelations := make(map[string]bool, 0)
 	if len(req.QueryParams["included_relations"]) > 0 {
@@ -279,36 +240,10 @@ func (dbResource *DbResource) FindOneWithTransaction(referenceId daptinid.Daptin
 		_ = OlricCache.Put(context.Background(), cacheKey2, data["reference_id"], olric.EX(5*time.Minute), olric.NX())
 	}
 
-	if len(languagePreferences) > 0 {
-		for _, lang := range languagePreferences {
-			data_i18n_id, err := dbResource.GetIdByWhereClause(modelName+"_i18n", transaction, goqu.Ex{
-				"translation_reference_id": data["id"],
-				"language_id":              lang,
-			})
-			if err == nil && len(data_i18n_id) > 0 {
-				for _, data_i18n := range data_i18n_id {
-					translatedObj, err := dbResource.GetIdToObjectWithTransaction(modelName+"_i18n", data_i18n, transaction)
-					CheckErr(err, "Failed to fetch translated object for [%v][%v][%v]", modelName, lang, data["id"])
-					if err != nil {
-						return nil, err
-					}
-					if translatedObj != nil {
-						for colName, valName := range translatedObj {
-							if IsStandardColumn(colName) {
-								continue
-							}
-							if valName == nil {
-								continue
-							}
-							data[colName] = valName
-						}
-					}
-				}
-				break
-			} else {
-				CheckErr(err, "No translated rows for [%v][%v][%v]", modelName, referenceId, lang)
-			}
-		}
+	err = dbResource.TranslateRow(data, languagePreferences, transaction)
+	if err != nil {
+		CheckErr(err, "Failed to fetch translations for [%v][%v][%v]", modelName, languagePreferences, referenceId)
+		return nil, err
 	}
 
 	//log.Tracef("Single row result: %v", data)
@@ -129,18 +129,39 @@ func (dbResource *DbResource) DataStats(req AggregationRequest, transaction *sql
 	}
 	projections = updatedProjections
 
+	rootTableInfo := &TableInfo{}
+	if dbResource.Cruds[req.RootEntity] != nil {
+		rootTableInfo = dbResource.Cruds[req.RootEntity].TableInfo()
+	}
+	sqlDriverName := transaction.DriverName()
+
+	moneySums := make([]*MoneySum, 0)
 	for i, project := range projections {
 		if project == "count" {
 			projections[i] = "count(*) as count"
 			projectionsAdded = append(projectionsAdded, goqu.L("count(*)").As("count"))
+		} else if moneySum, isMoneySum := rootTableInfo.GetMoneySum(project, req.GroupBy); isMoneySum {
+			projectionsAdded = append(projectionsAdded, moneySum.Projections(sqlDriverName)...)
+			moneySums = append(moneySums, moneySum)
+		} else if jsonPath, isJsonPath := rootTableInfo.GetJsonPath(project); isJsonPath {
+			projectionsAdded = append(projectionsAdded, goqu.L(jsonPath.Sql(sqlDriverName, false)).As(jsonPath.Alias()))
+		} else if projectSql, hasJsonPath := rootTableInfo.ReplaceJsonPaths(project, sqlDriverName); hasJsonPath {
+			projectionsAdded = append(projectionsAdded, goqu.L(projectSql))
 		} else {
 			projectionsAdded = append(projectionsAdded, goqu.L(project))
 		}
 	}
 
+	groupByColumns := make([]interface{}, 0)
 	for _, group := range req.GroupBy {
 		projections = append(projections, group)
+		if jsonPath, isJsonPath := rootTableInfo.GetJsonPath(group); isJsonPath {
+			projectionsAdded = append(projectionsAdded, goqu.L(jsonPath.Sql(sqlDriverName, false)).As(jsonPath.Alias()))
+			groupByColumns = append(groupByColumns, goqu.L(jsonPath.Sql(sqlDriverName, false)))
+			continue
+		}
 		projectionsAdded = append(projectionsAdded, goqu.L(group))
+		groupByColumns = append(groupByColumns, group)
 	}
 
 	if len(projections) == 0 {
@@ -150,9 +171,21 @@ func (dbResource *DbResource) DataStats(req AggregationRequest, transaction *sql
 	selectBuilder := statementbuilder.Squirrel.Select(projectionsAdded...).Prepared(true)
 	builder := selectBuilder.From(req.RootEntity)
 
-	builder = builder.GroupBy(ToInterfaceArray(req.GroupBy)...)
+	builder = builder.GroupBy(groupByColumns...)
 
-	builder = builder.Order(ToOrderedExpressionArray(req.Order)...)
+	orders := make([]exp.OrderedExpression, 0)
+	for _, order := range req.Order {
+		if jsonPath, isJsonPath := rootTableInfo.GetJsonPath(strings.TrimLeft(order, "-")); isJsonPath {
+			if order[0] == '-' {
+				orders = append(orders, goqu.L(jsonPath.SortSql(sqlDriverName)).Desc())
+			} else {
+				orders = append(orders, goqu.L(jsonPath.SortSql(sqlDriverName)).Asc())
+			}
+			continue
+		}
+		orders = append(orders, ToOrderedExpressionArray([]string{order})...)
+	}
+	builder = builder.Order(orders...)
 
 	// functionName(param1, param2)
 	querySyntax, err := regexp.Compile("([a-zA-Z0-9=<>]+)\\(([^,]+?),(.+)\\)")
@@ -183,6 +216,18 @@ func (dbResource *DbResource) DataStats(req AggregationRequest, transaction *sql
 
 			}
 
+			if jsonPath, isJsonPath := rootTableInfo.GetJsonPath(leftVal); isJsonPath {
+				if functionName == "in" || functionName == "notin" {
+					rightVal = strings.Split(rightVal.(string), ",")
+				}
+				whereClause, err := jsonPath.Condition(sqlDriverName, functionName, rightVal)
+				if err != nil {
+					return nil, err
+				}
+				whereExpressions = append(whereExpressions, whereClause)
+				continue
+			}
+
 			//function := builder.Where
 			whereClause, err := BuildWhereClause(functionName, leftVal, rightVal)
 			if err != nil {
@@ -345,10 +390,23 @@ func (dbResource *DbResource) DataStats(req AggregationRequest, transaction *sql
 	rows, err := RowsToMap(res, returnModelName)
 	CheckErr(err, "Failed to scan ")
 
+	for _, row := range rows {
+		for _, moneySum := range moneySums {
+			err = moneySum.Complete(row)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
 	for _, groupedColumn := range req.GroupBy {
 		var columnInfo *api2go.ColumnInfo
 		var ok bool
 
+		if _, isJsonPath := rootTableInfo.GetJsonPath(groupedColumn); isJsonPath {
+			continue
+		}
+
 		if strings.Index(groupedColumn, ".") > -1 {
 			groupedColumn = strings.Split(groupedColumn, ".")[1]
 		}
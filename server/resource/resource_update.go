@@ -58,6 +58,17 @@ func (dbResource *DbResource) UpdateWithoutFilters(obj interface{}, req api2go.R
 
 	attrs := data.GetAllAsAttributes()
 
+	jsonChanges, err := dbResource.jsonPatchChanges(attrs, req, daptinid.DaptinReferenceId(updateObjectReferenceId), updateTransaction)
+	if err != nil {
+		return nil, err
+	}
+	if len(jsonChanges) > 0 {
+		for columnName, document := range jsonChanges {
+			attrs[columnName] = document
+		}
+		data.SetAttributes(jsonChanges)
+	}
+
 	if !data.HasVersion() {
 		originalData, err := dbResource.GetReferenceIdToObjectWithTransaction(dbResource.model.GetTableName(),
 			daptinid.DaptinReferenceId(updateObjectReferenceId), updateTransaction)
@@ -74,13 +85,7 @@ func (dbResource *DbResource) UpdateWithoutFilters(obj interface{}, req api2go.R
 
 	//dataToInsert := make(map[string]interface{})
 
-	languagePreferences := make([]string, 0)
-	if dbResource.tableInfo.TranslationsEnabled {
-		prefs := req.PlainRequest.Context().Value("language_preference")
-		if prefs != nil {
-			languagePreferences = prefs.([]string)
-		}
-	}
+	translationLanguage := dbResource.TranslationLanguage(req.PlainRequest.Context())
 
 	var colsList []string
 	var valsList []interface{}
@@ -108,6 +113,11 @@ func (dbResource *DbResource) UpdateWithoutFilters(obj interface{}, req api2go.R
 				continue
 			}
 
+			// sequence numbers are assigned once, on create
+			if col.ColumnType == "sequence" {
+				continue
+			}
+
 			change, ok := allChanges[col.ColumnName]
 			if !ok {
 				continue
@@ -211,14 +221,19 @@ func (dbResource *DbResource) UpdateWithoutFilters(obj interface{}, req api2go.R
 					actionRequestParameters["store_provider"] = cloudStore.StoreProvider
 					actionRequestParameters["root_path"] = cloudStore.RootPath + "/" + col.ForeignKeyData.KeyName
 
-					log.Printf("Initiate file upload action from resource update")
-					_, _, errs := uploadActionPerformer.DoAction(Outcome{}, actionRequestParameters, updateTransaction)
-					if errs != nil && len(errs) > 0 {
-						log.Errorf("Failed to upload attachments: %v", errs)
+					dryRun := isDryRunRequest(req)
+					if dryRun {
+						log.Infof("Dry run, not uploading files of column [%v]", col.ColumnName)
+					} else {
+						log.Printf("Initiate file upload action from resource update")
+						_, _, errs := uploadActionPerformer.DoAction(Outcome{}, actionRequestParameters, updateTransaction)
+						if errs != nil && len(errs) > 0 {
+							log.Errorf("Failed to upload attachments: %v", errs)
+						}
 					}
 
 					columnAssetCache, ok := dbResource.AssetFolderCache[dbResource.tableInfo.TableName][col.ColumnName]
-					if ok {
+					if ok && !dryRun {
 						err = columnAssetCache.UploadFiles(val.([]interface{}))
 						CheckErr(err, "Failed to store uploaded file in column [%v]", col.ColumnName)
 						if err != nil {
@@ -276,24 +291,18 @@ func (dbResource *DbResource) UpdateWithoutFilters(obj interface{}, req api2go.R
 				}
 				// 2017-07-13T18:30:00.000Z
 
-			} else if col.ColumnType == "enum" {
-				valString, ok := val.(string)
-				if !ok {
-					valString = fmt.Sprintf("%v", val)
+			} else if col.ColumnType == "money" && val != nil {
+				val, err = ParseMoney(val, MoneyScale(col))
+				if err != nil {
+					return nil, fmt.Errorf("invalid value for %s: %v", col.Name, err)
 				}
-
-				isEnumOption := false
-				valString = strings.ToLower(valString)
-				for _, enumVal := range col.Options {
-
-					if valString == enumVal.Value {
-						isEnumOption = true
-						break
-
-					}
-
+			} else if col.ColumnType == "currency" && val != nil {
+				val, err = ParseCurrencyCode(val)
+				if err != nil {
+					return nil, fmt.Errorf("invalid value for %s: %v", col.Name, err)
 				}
-
+			} else if col.ColumnType == "enum" {
+				valString, isEnumOption := EnumOption(col, val)
 				if !isEnumOption {
 					log.Printf("Provided value is not a valid enum option, reject request [%v] [%v]", valString, col.Options)
 					return nil, errors.New(fmt.Sprintf("invalid value for %s", col.Name))
@@ -406,7 +415,7 @@ func (dbResource *DbResource) UpdateWithoutFilters(obj interface{}, req api2go.R
 		colsList = append(colsList, "version")
 		valsList = append(valsList, data.GetNextVersion())
 
-		if len(languagePreferences) == 0 {
+		if translationLanguage == "" {
 
 			builder := statementbuilder.Squirrel.Update(dbResource.model.GetName()).Prepared(true)
 
@@ -432,56 +441,16 @@ func (dbResource *DbResource) UpdateWithoutFilters(obj interface{}, req api2go.R
 				return nil, err
 			}
 
-		} else if len(languagePreferences) > 0 {
-
-			for _, lang := range languagePreferences {
-
-				langTableCols := make([]interface{}, 0)
-				langTableVals := make([]interface{}, 0)
-
-				for _, col := range colsList {
-					langTableCols = append(langTableCols, col)
-				}
-
-				for _, val := range valsList {
-					langTableVals = append(langTableVals, val)
-				}
-
-				builder := statementbuilder.Squirrel.Update(dbResource.model.GetName() + "_i18n").Prepared(true)
-
-				updateMap := make(map[string]interface{})
-				for i := range langTableCols {
-					updateMap[langTableCols[i].(string)] = langTableVals[i]
-				}
-				builder = builder.Set(updateMap)
-
-				query, vals, err := builder.
-					Where(goqu.Ex{"translation_reference_id": idInt}).Where(goqu.Ex{"language_id": lang}).ToSQL()
-				log.Infof("Update query [455]: %v", query)
-				if err != nil {
-					log.Errorf("Failed to create update query: %v", err)
-				}
-
-				//log.Printf("Update query: %v == %v", query, vals)
-				res, err := updateTransaction.Exec(query, vals...)
-				rowsAffected, err := res.RowsAffected()
-				if err != nil || rowsAffected == 0 {
-					log.Errorf("Failed to execute update query: %v", err)
-
-					nuuid, _ := uuid.NewV7()
-
-					langTableCols = append(langTableCols, "language_id", "translation_reference_id", "reference_id")
-					langTableVals = append(langTableVals, lang, idInt, nuuid[:])
-
-					insert := statementbuilder.Squirrel.Insert(dbResource.model.GetName() + "_i18n").Prepared(true)
-					insert = insert.Cols(langTableCols...)
-					insert = insert.Vals(langTableVals)
-					query, vals, err := insert.ToSQL()
-
-					_, err = updateTransaction.Exec(query, vals...)
+		} else {
 
-					return nil, err
-				}
+			// the row itself keeps its values in the default language
+			translatedValues := make(map[string]interface{})
+			for i, colName := range colsList {
+				translatedValues[colName] = valsList[i]
+			}
+			err = dbResource.SaveTranslation(idInt, translationLanguage, translatedValues, updateTransaction)
+			if err != nil {
+				return nil, err
 			}
 		}
 
@@ -0,0 +1,526 @@
+package resource
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/daptin/daptin/server/auth"
+	"github.com/daptin/daptin/server/database"
+	"github.com/daptin/daptin/server/statementbuilder"
+	"github.com/doug-martin/goqu/v9"
+	uuid "github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+)
+
+// SchemaSystemVersion is the version of the system schema (standard tables and columns) known to this binary.
+// Bump it whenever StandardTables or StandardColumns change in a way an older binary cannot work with.
+// Startup is refused if the database was migrated by a binary with a higher system version.
+const SchemaSystemVersion = 1
+
+const SchemaMigrationTableName = "schema_migration"
+
+// schemaStatementSeparator separates statements in up_sql and down_sql columns
+const schemaStatementSeparator = ";\n"
+
+// SchemaChange is a single DDL statement applied to the database
+// Down is the statement which reverts it, empty if it cannot be reverted
+type SchemaChange struct {
+	Up   string
+	Down string
+}
+
+// SchemaMigration is a row of the schema_migration table
+type SchemaMigration struct {
+	Version       int64
+	Checksum      string
+	Author        string
+	Source        string
+	Status        string
+	SystemVersion int64
+	SchemaJson    string
+	UpSql         string
+	DownSql       string
+	CreatedAt     interface{}
+}
+
+// SchemaChangeLog collects the DDL statements executed by a single load of the schema, along with who asked for the
+// change and where it came from. They are stored together as one schema migration.
+type SchemaChangeLog struct {
+	lock    sync.Mutex
+	changes []SchemaChange
+	author  string
+	source  string
+}
+
+// pendingSchemaChanges collects the statements executed by actions, and who invoked them, until the next load takes
+// them with TakePendingSchemaChanges
+var pendingSchemaChanges = &SchemaChangeLog{}
+var pendingSchemaChangesLock sync.Mutex
+
+// Record collects a DDL statement which was successfully executed, a nil log records nothing
+func (scl *SchemaChangeLog) Record(up string, down string) {
+	if scl == nil {
+		return
+	}
+	scl.lock.Lock()
+	defer scl.lock.Unlock()
+	scl.changes = append(scl.changes, SchemaChange{
+		Up:   up,
+		Down: down,
+	})
+}
+
+// TakePendingSchemaChanges hands the changes made by actions since the last load to the load which is starting,
+// changes made by actions while it runs are kept for the next one
+func TakePendingSchemaChanges() *SchemaChangeLog {
+	fresh := &SchemaChangeLog{}
+	pendingSchemaChangesLock.Lock()
+	defer pendingSchemaChangesLock.Unlock()
+	taken := pendingSchemaChanges
+	pendingSchemaChanges = fresh
+	return taken
+}
+
+func currentPendingSchemaChanges() *SchemaChangeLog {
+	pendingSchemaChangesLock.Lock()
+	defer pendingSchemaChangesLock.Unlock()
+	return pendingSchemaChanges
+}
+
+// RecordSchemaChange collects a DDL statement which was successfully executed by an action, it will be stored as part
+// of the schema migration of the next load
+func RecordSchemaChange(up string, down string) {
+	currentPendingSchemaChanges().Record(up, down)
+}
+
+// SetSchemaMigrationAuthor marks the user responsible for the schema change which will be applied on next restart
+func SetSchemaMigrationAuthor(author string) {
+	changes := currentPendingSchemaChanges()
+	changes.lock.Lock()
+	defer changes.lock.Unlock()
+	changes.author = author
+}
+
+// SchemaMigrationAuthorFromOutcome identifies the user who invoked the action performing a schema change
+func SchemaMigrationAuthorFromOutcome(request Outcome) string {
+	sessionUser, ok := request.Attributes["user"].(*auth.SessionUser)
+	if !ok || sessionUser == nil {
+		return ""
+	}
+	return sessionUser.UserReferenceId.String()
+}
+
+// SetSchemaMigrationSource marks where the schema change which will be applied on next restart comes from
+func SetSchemaMigrationSource(source string) {
+	changes := currentPendingSchemaChanges()
+	changes.lock.Lock()
+	defer changes.lock.Unlock()
+	changes.source = source
+}
+
+func (scl *SchemaChangeLog) drain() ([]SchemaChange, string, string) {
+	if scl == nil {
+		return nil, "", ""
+	}
+	scl.lock.Lock()
+	defer scl.lock.Unlock()
+	changes := scl.changes
+	author := scl.author
+	source := scl.source
+	scl.changes = nil
+	scl.author = ""
+	scl.source = ""
+	return changes, author, source
+}
+
+func dropIndexStatement(indexName string, tableName string, sqlDriverName string) string {
+	if sqlDriverName == "mysql" {
+		return fmt.Sprintf("drop index %v on %v", indexName, tableName)
+	}
+	return fmt.Sprintf("drop index %v", indexName)
+}
+
+// SchemaChecksum is the sha256 of the table definitions, independent of the order in which tables were loaded
+func SchemaChecksum(tables []TableInfo) (string, string, error) {
+	sortedTables := make([]TableInfo, len(tables))
+	copy(sortedTables, tables)
+	sort.SliceStable(sortedTables, func(i, j int) bool {
+		return sortedTables[i].TableName < sortedTables[j].TableName
+	})
+
+	schemaJson, err := json.Marshal(sortedTables)
+	if err != nil {
+		return "", "", err
+	}
+	hash := sha256.Sum256(schemaJson)
+	return hex.EncodeToString(hash[:]), string(schemaJson), nil
+}
+
+// CheckSchemaSystemVersion refuses to continue if the database has been migrated by a newer binary
+func CheckSchemaSystemVersion(db database.DatabaseConnection) error {
+
+	s, v, err := statementbuilder.Squirrel.Select(goqu.MAX("system_version")).
+		Prepared(true).From(SchemaMigrationTableName).ToSQL()
+	if err != nil {
+		return err
+	}
+
+	stmt1, err := db.Preparex(s)
+	if err != nil {
+		// table does not exist yet, nothing was migrated before
+		log.Tracef("schema migration table not present: %v", err)
+		return nil
+	}
+	defer stmt1.Close()
+
+	var systemVersion *int64
+	err = stmt1.QueryRowx(v...).Scan(&systemVersion)
+	if err != nil || systemVersion == nil {
+		return nil
+	}
+
+	if *systemVersion > SchemaSystemVersion {
+		return fmt.Errorf("database schema is at system version [%v] which is ahead of this binary [%v], "+
+			"upgrade daptin before starting", *systemVersion, SchemaSystemVersion)
+	}
+	return nil
+}
+
+// RecordSchemaMigration stores the schema applied by a load as a new version, along with the DDL statements collected
+// in its change log. Nothing is recorded if the schema did not change since the last version.
+func RecordSchemaMigration(initConfig *CmsConfig, schemaChanges *SchemaChangeLog, source string, transaction *sqlx.Tx) error {
+
+	changes, author, recordedSource := schemaChanges.drain()
+	if recordedSource != "" {
+		source = recordedSource
+	}
+	checksum, schemaJson, err := SchemaChecksum(initConfig.Tables)
+	if err != nil {
+		return err
+	}
+
+	lastMigration, err := GetLatestSchemaMigration(transaction)
+	if err == nil && lastMigration.Checksum == checksum && len(changes) == 0 {
+		log.Debugf("Schema unchanged since version [%v]", lastMigration.Version)
+		return nil
+	}
+
+	if author == "" {
+		author = "system"
+	}
+
+	upStatements := make([]string, 0)
+	downStatements := make([]string, 0)
+	for i := len(changes) - 1; i >= 0; i-- {
+		if changes[i].Down != "" {
+			downStatements = append(downStatements, changes[i].Down)
+		}
+	}
+	for _, change := range changes {
+		upStatements = append(upStatements, change.Up)
+	}
+
+	return insertSchemaMigration(SchemaMigration{
+		Checksum:      checksum,
+		Author:        author,
+		Source:        source,
+		Status:        "applied",
+		SystemVersion: SchemaSystemVersion,
+		SchemaJson:    schemaJson,
+		UpSql:         strings.Join(upStatements, schemaStatementSeparator),
+		DownSql:       strings.Join(downStatements, schemaStatementSeparator),
+	}, transaction)
+
+}
+
+func insertSchemaMigration(migration SchemaMigration, transaction *sqlx.Tx) error {
+
+	var lastVersion *int64
+	s, v, err := statementbuilder.Squirrel.Select(goqu.MAX("version")).Prepared(true).
+		From(SchemaMigrationTableName).ToSQL()
+	if err != nil {
+		return err
+	}
+	stmt1, err := transaction.Preparex(s)
+	if err != nil {
+		log.Errorf("[175] failed to prepare statment: %v", err)
+		return err
+	}
+	defer stmt1.Close()
+	err = stmt1.QueryRowx(v...).Scan(&lastVersion)
+	if err != nil {
+		return err
+	}
+
+	migration.Version = 1
+	if lastVersion != nil {
+		migration.Version = *lastVersion + 1
+	}
+
+	u, _ := uuid.NewV7()
+	s, v, err = statementbuilder.Squirrel.Insert(SchemaMigrationTableName).Prepared(true).
+		Cols("version", "checksum", "author", "source", "status", "system_version", "schema_json",
+			"up_sql", "down_sql", "reference_id", "permission", "created_at").
+		Vals([]interface{}{migration.Version, migration.Checksum, migration.Author, migration.Source, migration.Status,
+			migration.SystemVersion, migration.SchemaJson, migration.UpSql, migration.DownSql, u[:],
+			auth.DEFAULT_PERMISSION, time.Now()}).ToSQL()
+	if err != nil {
+		return err
+	}
+
+	_, err = transaction.Exec(s, v...)
+	if err != nil {
+		return err
+	}
+	log.Infof("Recorded schema migration version [%v] by [%v] from [%v]", migration.Version, migration.Author, migration.Source)
+	return nil
+}
+
+var schemaMigrationColumns = []interface{}{"version", "checksum", "author", "source", "status", "system_version",
+	"schema_json", "up_sql", "down_sql", "created_at"}
+
+func scanSchemaMigrations(rows *sqlx.Rows) ([]SchemaMigration, error) {
+	migrations := make([]SchemaMigration, 0)
+	for rows.Next() {
+		var migration SchemaMigration
+		err := rows.Scan(&migration.Version, &migration.Checksum, &migration.Author, &migration.Source,
+			&migration.Status, &migration.SystemVersion, &migration.SchemaJson, &migration.UpSql,
+			&migration.DownSql, &migration.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, migration)
+	}
+	return migrations, nil
+}
+
+// GetSchemaMigrations returns migrations matching the where clause, newest first
+func GetSchemaMigrations(transaction *sqlx.Tx, where ...goqu.Ex) ([]SchemaMigration, error) {
+
+	query := statementbuilder.Squirrel.Select(schemaMigrationColumns...).Prepared(true).
+		From(SchemaMigrationTableName).Order(goqu.C("version").Desc())
+	for _, w := range where {
+		query = query.Where(w)
+	}
+	s, v, err := query.ToSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	stmt1, err := transaction.Preparex(s)
+	if err != nil {
+		log.Errorf("[239] failed to prepare statment: %v", err)
+		return nil, err
+	}
+	defer stmt1.Close()
+
+	rows, err := stmt1.Queryx(v...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanSchemaMigrations(rows)
+}
+
+func GetLatestSchemaMigration(transaction *sqlx.Tx) (SchemaMigration, error) {
+	migrations, err := GetSchemaMigrations(transaction, goqu.Ex{"status": "applied"})
+	if err != nil {
+		return SchemaMigration{}, err
+	}
+	if len(migrations) == 0 {
+		return SchemaMigration{}, fmt.Errorf("no schema migrations recorded")
+	}
+	return migrations[0], nil
+}
+
+// RollbackSchemaToVersion runs the down statements of every migration applied after the target version, newest first,
+// and restores the table definitions in the world table to the ones recorded with the target version.
+// Migrations which were applied without a down statement are reported as irreversible but their definitions are
+// still restored. The rollback is refused when tables added after the target version would be left in the database
+// without a definition.
+// Postgres and sqlite run the statements as part of the transaction, a failure leaves the schema as it was. MySQL
+// commits every DDL statement on its own, so a failure there leaves the statements executed before it in place,
+// the error lists them so the schema can be fixed by hand.
+func RollbackSchemaToVersion(targetVersion int64, author string, transaction *sqlx.Tx) ([]string, error) {
+
+	targets, err := GetSchemaMigrations(transaction, goqu.Ex{"version": targetVersion, "status": "applied"})
+	if err != nil {
+		return nil, err
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no applied schema migration with version [%v]", targetVersion)
+	}
+	target := targets[0]
+
+	newerMigrations, err := GetSchemaMigrations(transaction, goqu.Ex{
+		"version": goqu.Op{"gt": targetVersion},
+		"status":  "applied",
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(newerMigrations) == 0 {
+		return nil, fmt.Errorf("schema is already at version [%v]", targetVersion)
+	}
+
+	var targetTables []TableInfo
+	err = json.Unmarshal([]byte(target.SchemaJson), &targetTables)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read table definitions of version [%v]: %v", targetVersion, err)
+	}
+
+	leftTables, err := tablesLeftByRollback(targetTables, newerMigrations, transaction)
+	if err != nil {
+		return nil, err
+	}
+	if len(leftTables) > 0 {
+		return nil, fmt.Errorf("tables [%v] were added after version [%v] without a statement to drop them, "+
+			"delete them before rolling back", strings.Join(leftTables, ", "), targetVersion)
+	}
+
+	executed := make([]string, 0)
+	for _, migration := range newerMigrations {
+		if migration.DownSql == "" && migration.UpSql != "" {
+			log.Warnf("Schema migration [%v] has no down statements, only table definitions will be restored", migration.Version)
+		}
+		for _, statement := range strings.Split(migration.DownSql, schemaStatementSeparator) {
+			if strings.TrimSpace(statement) == "" {
+				continue
+			}
+			_, err = transaction.Exec(statement)
+			if err != nil {
+				if transaction.DriverName() == "mysql" && len(executed) > 0 {
+					return executed, fmt.Errorf("failed to rollback migration [%v] using [%v]: %v, mysql has already "+
+						"committed the statements executed before it: [%v]", migration.Version, statement, err,
+						strings.Join(executed, schemaStatementSeparator))
+				}
+				return executed, fmt.Errorf("failed to rollback migration [%v] using [%v]: %v", migration.Version, statement, err)
+			}
+			executed = append(executed, statement)
+		}
+
+		s, v, err := statementbuilder.Squirrel.Update(SchemaMigrationTableName).Prepared(true).
+			Set(goqu.Record{"status": "rolled_back", "updated_at": time.Now()}).
+			Where(goqu.Ex{"version": migration.Version}).ToSQL()
+		if err != nil {
+			return executed, err
+		}
+		_, err = transaction.Exec(s, v...)
+		if err != nil {
+			return executed, err
+		}
+	}
+
+	targetTableNames := make([]interface{}, 0)
+	for _, table := range targetTables {
+		schema, err := json.Marshal(table)
+		if err != nil {
+			return executed, err
+		}
+		targetTableNames = append(targetTableNames, table.TableName)
+		s, v, err := statementbuilder.Squirrel.Update("world").Prepared(true).
+			Set(goqu.Record{"world_schema_json": string(schema)}).
+			Where(goqu.Ex{"table_name": table.TableName}).ToSQL()
+		if err != nil {
+			return executed, err
+		}
+		_, err = transaction.Exec(s, v...)
+		if err != nil {
+			return executed, err
+		}
+	}
+
+	s, v, err := statementbuilder.Squirrel.Delete("world").Prepared(true).
+		Where(goqu.Ex{"table_name": goqu.Op{"notIn": targetTableNames}}).ToSQL()
+	if err != nil {
+		return executed, err
+	}
+	_, err = transaction.Exec(s, v...)
+	if err != nil {
+		return executed, err
+	}
+
+	err = insertSchemaMigration(SchemaMigration{
+		Checksum:      target.Checksum,
+		Author:        author,
+		Source:        fmt.Sprintf("rollback to %v", targetVersion),
+		Status:        "applied",
+		SystemVersion: SchemaSystemVersion,
+		SchemaJson:    target.SchemaJson,
+		UpSql:         strings.Join(executed, schemaStatementSeparator),
+	}, transaction)
+
+	return executed, err
+}
+
+// tablesLeftByRollback are the tables known to the world table which are not part of the target version and are not
+// dropped by the down statements of the newer migrations. Their definitions would be removed while the tables stay
+// in the database, in the way of any later migration creating them again.
+func tablesLeftByRollback(targetTables []TableInfo, newerMigrations []SchemaMigration, transaction *sqlx.Tx) ([]string, error) {
+
+	keptTables := make(map[string]bool)
+	for _, table := range targetTables {
+		keptTables[table.TableName] = true
+	}
+	for _, migration := range newerMigrations {
+		for _, statement := range strings.Split(migration.DownSql, schemaStatementSeparator) {
+			statement = strings.TrimSpace(statement)
+			if strings.HasPrefix(statement, "drop table ") {
+				keptTables[strings.TrimSpace(strings.TrimPrefix(statement, "drop table "))] = true
+			}
+		}
+	}
+
+	s, v, err := statementbuilder.Squirrel.Select("table_name").Prepared(true).From("world").ToSQL()
+	if err != nil {
+		return nil, err
+	}
+	stmt1, err := transaction.Preparex(s)
+	if err != nil {
+		log.Errorf("[402] failed to prepare statment: %v", err)
+		return nil, err
+	}
+	defer stmt1.Close()
+
+	rows, err := stmt1.Queryx(v...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	leftTables := make([]string, 0)
+	for rows.Next() {
+		var tableName string
+		err = rows.Scan(&tableName)
+		if err != nil {
+			return nil, err
+		}
+		if !keptTables[tableName] {
+			leftTables = append(leftTables, tableName)
+		}
+	}
+	sort.Strings(leftTables)
+	return leftTables, rows.Err()
+}
+
+func (sm SchemaMigration) ToMap() map[string]interface{} {
+	return map[string]interface{}{
+		"version":        sm.Version,
+		"checksum":       sm.Checksum,
+		"author":         sm.Author,
+		"source":         sm.Source,
+		"status":         sm.Status,
+		"system_version": sm.SystemVersion,
+		"up_sql":         sm.UpSql,
+		"down_sql":       sm.DownSql,
+		"reversible":     sm.DownSql != "" || sm.UpSql == "",
+		"created_at":     sm.CreatedAt,
+	}
+}
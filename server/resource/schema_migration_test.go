@@ -0,0 +1,144 @@
+package resource
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/artpar/api2go"
+	"github.com/doug-martin/goqu/v9"
+)
+
+func TestTakePendingSchemaChanges(t *testing.T) {
+	TakePendingSchemaChanges()
+	RecordSchemaChange("alter table book add column isbn varchar(20)", "alter table book drop column isbn")
+	SetSchemaMigrationAuthor("author-1")
+
+	taken := TakePendingSchemaChanges()
+	// an action running while the load records its changes belongs to the next load
+	RecordSchemaChange("drop table author", "")
+
+	changes, author, _ := taken.drain()
+	if len(changes) != 1 || changes[0].Up != "alter table book add column isbn varchar(20)" || author != "author-1" {
+		t.Errorf("expected the change made before the load, got %v by [%v]", changes, author)
+	}
+	changes, author, _ = TakePendingSchemaChanges().drain()
+	if len(changes) != 1 || changes[0].Up != "drop table author" || author != "" {
+		t.Errorf("expected the change made during the load to be kept for the next one, got %v by [%v]", changes, author)
+	}
+
+	var missing *SchemaChangeLog
+	missing.Record("create table book (id int)", "drop table book")
+	if changes, _, _ = missing.drain(); len(changes) != 0 {
+		t.Errorf("expected a nil log to record nothing")
+	}
+}
+
+func TestRecordAndRollbackSchemaMigration(t *testing.T) {
+	db, cruds := newTestCruds(t, CmsConfig{})
+	config := CmsConfig{}
+	for _, crud := range cruds {
+		config.Tables = append(config.Tables, *crud.tableInfo)
+	}
+
+	record := func(config CmsConfig, changes *SchemaChangeLog) {
+		transaction := db.MustBegin()
+		if err := RecordSchemaMigration(&config, changes, "startup", transaction); err != nil {
+			transaction.Rollback()
+			t.Fatalf("failed to record schema migration: %v", err)
+		}
+		transaction.Commit()
+	}
+	record(config, &SchemaChangeLog{})
+	// nothing changed, no new version
+	record(config, &SchemaChangeLog{})
+
+	note := TableInfo{TableName: "note", Columns: []api2go.ColumnInfo{
+		{Name: "body", ColumnName: "body", ColumnType: "content", DataType: "text"},
+	}}
+	changes := &SchemaChangeLog{}
+	if err := CreateTable(&note, db, changes); err != nil {
+		t.Fatalf("failed to create table: %v", err)
+	}
+	record(CmsConfig{Tables: append(config.Tables, note)}, changes)
+
+	transaction := db.MustBegin()
+	migrations, err := GetSchemaMigrations(transaction)
+	transaction.Rollback()
+	if err != nil {
+		t.Fatalf("failed to list schema migrations: %v", err)
+	}
+	if len(migrations) != 2 || migrations[0].Version != 2 || migrations[0].DownSql != "drop table note" {
+		t.Fatalf("expected the create table to be recorded as version 2, got %v", migrations)
+	}
+
+	transaction = db.MustBegin()
+	executed, err := RollbackSchemaToVersion(1, "admin", transaction)
+	if err != nil {
+		transaction.Rollback()
+		t.Fatalf("failed to rollback: %v", err)
+	}
+	transaction.Commit()
+	if len(executed) != 1 || executed[0] != "drop table note" {
+		t.Errorf("expected the table to be dropped, got %v", executed)
+	}
+	if _, err = db.Exec("select * from note"); err == nil {
+		t.Errorf("expected the note table to be gone")
+	}
+
+	transaction = db.MustBegin()
+	defer transaction.Rollback()
+	rolledBack, err := GetSchemaMigrations(transaction, goqu.Ex{"status": "rolled_back"})
+	if err != nil || len(rolledBack) != 1 || rolledBack[0].Version != 2 {
+		t.Errorf("expected version 2 to be rolled back, got %v %v", rolledBack, err)
+	}
+	latest, err := GetLatestSchemaMigration(transaction)
+	if err != nil || latest.Version != 3 || latest.Source != "rollback to 1" || latest.Author != "admin" {
+		t.Errorf("expected the rollback to be recorded as version 3, got %v %v", latest, err)
+	}
+	if _, err = RollbackSchemaToVersion(3, "admin", transaction); err == nil {
+		t.Errorf("expected a rollback to the current version to fail")
+	}
+}
+
+func TestRollbackRefusesTablesLeftBehind(t *testing.T) {
+	db, cruds := newTestCruds(t, CmsConfig{})
+	config := CmsConfig{}
+	for _, crud := range cruds {
+		config.Tables = append(config.Tables, *crud.tableInfo)
+	}
+
+	transaction := db.MustBegin()
+	if err := RecordSchemaMigration(&config, &SchemaChangeLog{}, "startup", transaction); err != nil {
+		t.Fatalf("failed to record schema migration: %v", err)
+	}
+	transaction.Commit()
+
+	// the table is created by a statement which has no down statement recorded
+	memo := TableInfo{TableName: "memo", Columns: []api2go.ColumnInfo{
+		{Name: "body", ColumnName: "body", ColumnType: "content", DataType: "text"},
+	}}
+	if err := CreateTable(&memo, db, nil); err != nil {
+		t.Fatalf("failed to create table: %v", err)
+	}
+	changes := &SchemaChangeLog{}
+	changes.Record(MakeCreateTableQuery(&memo, "sqlite3"), "")
+	withMemo := CmsConfig{Tables: append(config.Tables, memo)}
+	transaction = db.MustBegin()
+	if err := UpdateWorldTable(&withMemo, transaction); err != nil {
+		t.Fatalf("failed to update world table: %v", err)
+	}
+	if err := RecordSchemaMigration(&withMemo, changes, "startup", transaction); err != nil {
+		t.Fatalf("failed to record schema migration: %v", err)
+	}
+	transaction.Commit()
+
+	transaction = db.MustBegin()
+	defer transaction.Rollback()
+	executed, err := RollbackSchemaToVersion(1, "admin", transaction)
+	if err == nil || !strings.Contains(err.Error(), "[memo]") {
+		t.Fatalf("expected the rollback to be refused because of the memo table, got %v", err)
+	}
+	if len(executed) != 0 {
+		t.Errorf("expected nothing to be executed, got %v", executed)
+	}
+}
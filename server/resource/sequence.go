@@ -0,0 +1,141 @@
+package resource
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/artpar/api2go"
+	"github.com/daptin/daptin/server/auth"
+	"github.com/daptin/daptin/server/statementbuilder"
+	"github.com/doug-martin/goqu/v9"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// SequenceCounterTableName holds the last number handed out for each sequence key
+const SequenceCounterTableName = "sequence_counter"
+
+// SequenceConfig is read from the options of a sequence column, the ValueType of an option is the name of the
+// setting:
+//
+//	prefix:    text before the number, eg INV
+//	padding:   minimum number of digits, zero padded (default 6)
+//	reset:     never, yearly, monthly or daily (default never)
+//	separator: between the prefix, the period and the number (default -)
+//
+// A column with prefix INV and a yearly reset generates INV-2026-000001, INV-2026-000002 ... and starts again from 1
+// in 2027.
+type SequenceConfig struct {
+	Prefix    string
+	Padding   int
+	Reset     string
+	Separator string
+}
+
+// GetSequenceConfig reads the sequence settings of a column
+func GetSequenceConfig(column api2go.ColumnInfo) SequenceConfig {
+	config := SequenceConfig{
+		Padding:   6,
+		Reset:     "never",
+		Separator: "-",
+	}
+	for _, option := range column.Options {
+		value := fmt.Sprintf("%v", option.Value)
+		switch strings.ToLower(option.ValueType) {
+		case "prefix":
+			config.Prefix = value
+		case "padding":
+			padding, err := strconv.ParseFloat(value, 64)
+			if err == nil && padding >= 0 {
+				config.Padding = int(padding)
+			}
+		case "reset":
+			config.Reset = strings.ToLower(value)
+		case "separator":
+			config.Separator = value
+		}
+	}
+	return config
+}
+
+// Period is the part of the sequence which changes when the counter is reset
+func (sc SequenceConfig) Period(now time.Time) string {
+	switch sc.Reset {
+	case "yearly":
+		return now.Format("2006")
+	case "monthly":
+		return now.Format("200601")
+	case "daily":
+		return now.Format("20060102")
+	}
+	return ""
+}
+
+// Format renders the number of the sequence in the period
+func (sc SequenceConfig) Format(period string, number int64) string {
+	parts := make([]string, 0, 3)
+	if sc.Prefix != "" {
+		parts = append(parts, sc.Prefix)
+	}
+	if period != "" {
+		parts = append(parts, period)
+	}
+	parts = append(parts, fmt.Sprintf("%0*d", sc.Padding, number))
+	return strings.Join(parts, sc.Separator)
+}
+
+// NextSequenceValue takes the next number of the sequence of a column. The counter row stays locked until the
+// transaction ends, so concurrent creates on any node wait for each other and a rolled back create gives its
+// number back, which keeps the sequence free of gaps.
+func NextSequenceValue(tableName string, column api2go.ColumnInfo, now time.Time, transaction *sqlx.Tx) (string, error) {
+	config := GetSequenceConfig(column)
+	period := config.Period(now)
+	sequenceKey := strings.Join([]string{tableName, column.ColumnName, config.Prefix, period}, ".")
+
+	u, _ := uuid.NewV7()
+	s, v, err := statementbuilder.Squirrel.Insert(SequenceCounterTableName).Prepared(true).
+		Cols("sequence_key", "table_name", "column_name", "period", "last_value", "reference_id", "permission", "created_at").
+		Vals([]interface{}{sequenceKey, tableName, column.ColumnName, period, 0, u[:], auth.DEFAULT_PERMISSION, now}).
+		OnConflict(goqu.DoNothing()).ToSQL()
+	if err != nil {
+		return "", err
+	}
+	_, err = transaction.Exec(s, v...)
+	if err != nil {
+		return "", fmt.Errorf("failed to create sequence counter [%v]: %v", sequenceKey, err)
+	}
+
+	s, v, err = statementbuilder.Squirrel.Update(SequenceCounterTableName).Prepared(true).
+		Set(goqu.Record{
+			"last_value": goqu.L("last_value + 1"),
+			"updated_at": now,
+		}).Where(goqu.Ex{"sequence_key": sequenceKey}).ToSQL()
+	if err != nil {
+		return "", err
+	}
+	_, err = transaction.Exec(s, v...)
+	if err != nil {
+		return "", fmt.Errorf("failed to increment sequence counter [%v]: %v", sequenceKey, err)
+	}
+
+	s, v, err = statementbuilder.Squirrel.Select("last_value").Prepared(true).
+		From(SequenceCounterTableName).Where(goqu.Ex{"sequence_key": sequenceKey}).ToSQL()
+	if err != nil {
+		return "", err
+	}
+	stmt1, err := transaction.Preparex(s)
+	if err != nil {
+		return "", err
+	}
+	defer stmt1.Close()
+
+	var lastValue int64
+	err = stmt1.QueryRowx(v...).Scan(&lastValue)
+	if err != nil {
+		return "", fmt.Errorf("failed to read sequence counter [%v]: %v", sequenceKey, err)
+	}
+
+	return config.Format(period, lastValue), nil
+}
@@ -0,0 +1,134 @@
+package resource
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/artpar/api2go"
+)
+
+func TestSequenceFormat(t *testing.T) {
+
+	column := api2go.ColumnInfo{
+		ColumnName: "invoice_number",
+		ColumnType: "sequence",
+		Options: []api2go.ValueOptions{
+			{ValueType: "prefix", Value: "INV"},
+			{ValueType: "padding", Value: float64(6)},
+			{ValueType: "reset", Value: "yearly"},
+		},
+	}
+
+	config := GetSequenceConfig(column)
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	if value := config.Format(config.Period(now), 123); value != "INV-2026-000123" {
+		t.Errorf("unexpected sequence value: %v", value)
+	}
+
+	config = GetSequenceConfig(api2go.ColumnInfo{ColumnType: "sequence"})
+	if value := config.Format(config.Period(now), 7); value != "000007" {
+		t.Errorf("unexpected sequence value without prefix: %v", value)
+	}
+
+}
+
+var testSequenceColumn = api2go.ColumnInfo{
+	ColumnName: "invoice_number",
+	ColumnType: "sequence",
+	Options: []api2go.ValueOptions{
+		{ValueType: "prefix", Value: "INV"},
+		{ValueType: "padding", Value: float64(3)},
+		{ValueType: "reset", Value: "yearly"},
+	},
+}
+
+func TestNextSequenceValueConcurrentIsGapFree(t *testing.T) {
+	db, _ := newTestCruds(t, CmsConfig{})
+	// the transactions wait for each other on the database and not on the connection pool
+	db.SetMaxOpenConns(5)
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	var lock sync.Mutex
+	var wait sync.WaitGroup
+	committed := make([]string, 0)
+	for i := 0; i < 20; i++ {
+		wait.Add(1)
+		go func(i int) {
+			defer wait.Done()
+			transaction, err := db.Beginx()
+			if err != nil {
+				t.Errorf("failed to begin transaction: %v", err)
+				return
+			}
+			value, err := NextSequenceValue("invoice", testSequenceColumn, now, transaction)
+			if err != nil {
+				transaction.Rollback()
+				t.Errorf("failed to take sequence value: %v", err)
+				return
+			}
+			// a failed create gives its number back
+			if i%5 == 0 {
+				transaction.Rollback()
+				return
+			}
+			if err = transaction.Commit(); err != nil {
+				t.Errorf("failed to commit: %v", err)
+				return
+			}
+			lock.Lock()
+			committed = append(committed, value)
+			lock.Unlock()
+		}(i)
+	}
+	wait.Wait()
+
+	sort.Strings(committed)
+	if len(committed) != 16 {
+		t.Fatalf("expected 16 committed values, got %v", committed)
+	}
+	for i, value := range committed {
+		if expected := fmt.Sprintf("INV-2026-%03d", i+1); value != expected {
+			t.Fatalf("expected unique values without gaps, got %v", committed)
+		}
+	}
+}
+
+func TestNextSequenceValueResetsEachPeriod(t *testing.T) {
+	db, _ := newTestCruds(t, CmsConfig{})
+
+	next := func(now time.Time) string {
+		transaction := db.MustBegin()
+		value, err := NextSequenceValue("invoice", testSequenceColumn, now, transaction)
+		if err != nil {
+			transaction.Rollback()
+			t.Fatalf("failed to take sequence value: %v", err)
+		}
+		transaction.Commit()
+		return value
+	}
+
+	lastYear := time.Date(2025, 12, 31, 23, 0, 0, 0, time.UTC)
+	thisYear := time.Date(2026, 1, 1, 1, 0, 0, 0, time.UTC)
+	if value := next(lastYear); value != "INV-2025-001" {
+		t.Errorf("unexpected first value %v", value)
+	}
+	if value := next(lastYear); value != "INV-2025-002" {
+		t.Errorf("unexpected second value %v", value)
+	}
+	if value := next(thisYear); value != "INV-2026-001" {
+		t.Errorf("expected the sequence to start again in a new year, got %v", value)
+	}
+
+	never := testSequenceColumn
+	never.Options = []api2go.ValueOptions{{ValueType: "prefix", Value: "ORD"}}
+	transaction := db.MustBegin()
+	defer transaction.Rollback()
+	first, _ := NextSequenceValue("order", never, lastYear, transaction)
+	second, err := NextSequenceValue("order", never, thisYear, transaction)
+	if err != nil || first != "ORD-000001" || second != "ORD-000002" {
+		t.Errorf("expected a sequence without reset to continue, got %v %v %v", first, second, err)
+	}
+}
@@ -107,7 +107,7 @@ func (ati *ActiveTaskInstance) Run() {
 		Method: "EXECUTE",
 	}
 
-	pr := pr1.WithContext(context.WithValue(context.Background(), "user", sessionUser))
+	pr := pr1.WithContext(WithActionChannel(context.WithValue(context.Background(), "user", sessionUser), ActionChannelTask))
 	req := api2go.Request{
 		PlainRequest: pr,
 	}
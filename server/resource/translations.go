@@ -1,17 +1,100 @@
 package resource
 
 import (
+	"sync"
+
 	english "github.com/go-playground/locales/en"
+	french "github.com/go-playground/locales/fr"
+	indonesian "github.com/go-playground/locales/id"
+	japanese "github.com/go-playground/locales/ja"
+	dutch "github.com/go-playground/locales/nl"
+	portuguese "github.com/go-playground/locales/pt_BR"
+	turkish "github.com/go-playground/locales/tr"
+	chinese "github.com/go-playground/locales/zh"
 	"github.com/go-playground/universal-translator"
+	"gopkg.in/go-playground/validator.v9"
 	en2 "gopkg.in/go-playground/validator.v9/translations/en"
+	fr2 "gopkg.in/go-playground/validator.v9/translations/fr"
+	id2 "gopkg.in/go-playground/validator.v9/translations/id"
+	ja2 "gopkg.in/go-playground/validator.v9/translations/ja"
+	nl2 "gopkg.in/go-playground/validator.v9/translations/nl"
+	pt2 "gopkg.in/go-playground/validator.v9/translations/pt_BR"
+	tr2 "gopkg.in/go-playground/validator.v9/translations/tr"
+	zh2 "gopkg.in/go-playground/validator.v9/translations/zh"
 )
 
+var validationTranslators = make(map[string]ut.Translator)
+var validationTranslatorsLock sync.RWMutex
+
 func RegisterTranslations() {
 
 	eng := english.New()
-	uni := ut.New(eng, eng)
-	trans, _ := uni.GetTranslator("en")
+	uni := ut.New(eng, eng, french.New(), indonesian.New(), japanese.New(), dutch.New(), portuguese.New(), turkish.New(), chinese.New())
+
+	// validation messages are looked up by the base language of the Accept-Language header
+	languages := map[string]struct {
+		locale   string
+		register func(*validator.Validate, ut.Translator) error
+	}{
+		"en": {"en", en2.RegisterDefaultTranslations},
+		"fr": {"fr", fr2.RegisterDefaultTranslations},
+		"id": {"id", id2.RegisterDefaultTranslations},
+		"ja": {"ja", ja2.RegisterDefaultTranslations},
+		"nl": {"nl", nl2.RegisterDefaultTranslations},
+		"pt": {"pt_BR", pt2.RegisterDefaultTranslations},
+		"tr": {"tr", tr2.RegisterDefaultTranslations},
+		"zh": {"zh", zh2.RegisterDefaultTranslations},
+	}
+
+	translators := make(map[string]ut.Translator)
+	for language, translation := range languages {
+		trans, _ := uni.GetTranslator(translation.locale)
+		err := translation.register(ValidatorInstance, trans)
+		CheckErr(err, "Failed to register translactions for [%v]", language)
+
+		// messages of the daptin validations are only available in english
+		for tag, message := range validationMessages {
+			err = ValidatorInstance.RegisterTranslation(tag, trans, registerValidationMessage(tag, message), translateValidationMessage)
+			CheckErr(err, "Failed to register translaction of [%v] for [%v]", tag, language)
+		}
+		translators[language] = trans
+	}
+
+	validationTranslatorsLock.Lock()
+	validationTranslators = translators
+	validationTranslatorsLock.Unlock()
+}
 
-	err := en2.RegisterDefaultTranslations(ValidatorInstance, trans)
-	CheckErr(err, "Failed to register translactions")
+func registerValidationMessage(tag string, message string) validator.RegisterTranslationsFunc {
+	return func(trans ut.Translator) error {
+		return trans.Add(tag, message, false)
+	}
+}
+
+func translateValidationMessage(trans ut.Translator, fe validator.FieldError) string {
+	message, err := trans.T(fe.Tag(), fe.Field(), fe.Param())
+	if err != nil {
+		return fe.(error).Error()
+	}
+	return message
+}
+
+// GetValidationTranslator picks the translator of the first preferred language which has validation messages,
+// english otherwise
+func GetValidationTranslator(languagePreferences []string) ut.Translator {
+	validationTranslatorsLock.RLock()
+	defer validationTranslatorsLock.RUnlock()
+
+	for _, language := range languagePreferences {
+		if trans, ok := validationTranslators[language]; ok {
+			return trans
+		}
+	}
+	if trans, ok := validationTranslators["en"]; ok {
+		return trans
+	}
+
+	eng := english.New()
+	trans, _ := ut.New(eng, eng).GetTranslator("en")
+	return trans
 }
@@ -0,0 +1,300 @@
+package resource
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/artpar/api2go"
+	daptinid "github.com/daptin/daptin/server/id"
+	"github.com/daptin/daptin/server/statementbuilder"
+	"github.com/doug-martin/goqu/v9"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/go-playground/validator.v9"
+)
+
+// Validations on a column can refer to the other values of the same object (cross field) and to the rows already
+// in the table (database backed), in addition to the tags of go-playground/validator:
+//
+//	eqcol=password_confirm       equal to the value of another column
+//	necol=old_email              not equal to the value of another column
+//	gtcol=start_date             greater than the value of another column, also gtecol, ltcol and ltecol
+//	required_with_col=country    required when the other column has a value
+//	unique_column                no other row has the same value
+//	unique_within=project_id     no other row with the same value of project_id has the same value
+var crossFieldComparisons = map[string]func(int) bool{
+	"eqcol":  func(c int) bool { return c == 0 },
+	"necol":  func(c int) bool { return c != 0 },
+	"gtcol":  func(c int) bool { return c > 0 },
+	"gtecol": func(c int) bool { return c >= 0 },
+	"ltcol":  func(c int) bool { return c < 0 },
+	"ltecol": func(c int) bool { return c <= 0 },
+}
+
+var validationMessages = map[string]string{
+	"eqcol":             "{0} must be equal to {1}",
+	"necol":             "{0} must not be equal to {1}",
+	"gtcol":             "{0} must be greater than {1}",
+	"gtecol":            "{0} must be greater than or equal to {1}",
+	"ltcol":             "{0} must be less than {1}",
+	"ltecol":            "{0} must be less than or equal to {1}",
+	"required_with_col": "{0} is required when {1} is set",
+	"unique_column":     "{0} is already in use",
+	"unique_within":     "{0} is already in use for this {1}",
+}
+
+func init() {
+	for tag, comparison := range crossFieldComparisons {
+		err := ValidatorInstance.RegisterValidation(tag, crossFieldValidation(comparison))
+		CheckErr(err, "Failed to register validation [%v]", tag)
+	}
+	err := ValidatorInstance.RegisterValidation("required_with_col", requiredWithColumn, true)
+	CheckErr(err, "Failed to register validation [required_with_col]")
+	err = ValidatorInstance.RegisterValidationCtx("unique_column", uniqueValidation)
+	CheckErr(err, "Failed to register validation [unique_column]")
+	err = ValidatorInstance.RegisterValidationCtx("unique_within", uniqueValidation)
+	CheckErr(err, "Failed to register validation [unique_within]")
+}
+
+// validationScope tells the database backed validations which row is being validated
+type validationScope struct {
+	TableInfo   *TableInfo
+	ColumnName  string
+	Object      map[string]interface{}
+	Transaction *sqlx.Tx
+}
+
+type validationScopeKey struct{}
+
+func otherColumnValue(fl validator.FieldLevel) (interface{}, bool) {
+	parent := fl.Parent()
+	if parent.Kind() != reflect.Map {
+		return nil, false
+	}
+	value := parent.MapIndex(reflect.ValueOf(fl.Param()))
+	if !value.IsValid() || value.IsNil() {
+		return nil, false
+	}
+	return value.Interface(), true
+}
+
+// compareValues compares numbers as numbers and everything else as text, which also orders ISO 8601 dates
+func compareValues(a interface{}, b interface{}) int {
+	aString := fmt.Sprintf("%v", a)
+	bString := fmt.Sprintf("%v", b)
+	aNumber, errA := strconv.ParseFloat(aString, 64)
+	bNumber, errB := strconv.ParseFloat(bString, 64)
+	if errA == nil && errB == nil {
+		switch {
+		case aNumber < bNumber:
+			return -1
+		case aNumber > bNumber:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(aString, bString)
+}
+
+func crossFieldValidation(comparison func(int) bool) validator.Func {
+	return func(fl validator.FieldLevel) bool {
+		otherValue, ok := otherColumnValue(fl)
+		if !ok {
+			// nothing to compare with, required_with_col makes the other column mandatory
+			return true
+		}
+		return comparison(compareValues(fl.Field().Interface(), otherValue))
+	}
+}
+
+func requiredWithColumn(fl validator.FieldLevel) bool {
+	otherValue, ok := otherColumnValue(fl)
+	if !ok || fmt.Sprintf("%v", otherValue) == "" {
+		return true
+	}
+	field := fl.Field()
+	if !field.IsValid() || (field.Kind() == reflect.Interface && field.IsNil()) {
+		return false
+	}
+	return fmt.Sprintf("%v", field.Interface()) != ""
+}
+
+func referenceIdBytes(value interface{}) ([]byte, bool) {
+	switch v := value.(type) {
+	case daptinid.DaptinReferenceId:
+		return v[:], v != daptinid.NullReferenceId
+	case uuid.UUID:
+		return v[:], true
+	case []byte:
+		if len(v) == 16 {
+			return v, true
+		}
+		return referenceIdBytes(string(v))
+	case string:
+		parsed, err := uuid.Parse(v)
+		if err != nil {
+			return nil, false
+		}
+		return parsed[:], true
+	}
+	return nil, false
+}
+
+// uniqueValidation checks that no other row of the table has the value, among the rows with the same value in
+// the column named by the param (unique_within) or in the whole table (unique_column)
+func uniqueValidation(ctx context.Context, fl validator.FieldLevel) bool {
+	scope, ok := ctx.Value(validationScopeKey{}).(validationScope)
+	if !ok || scope.Transaction == nil || scope.TableInfo == nil {
+		log.Warnf("[96] unique validation on [%v] skipped, no database in scope", scope.ColumnName)
+		return true
+	}
+
+	tableName := scope.TableInfo.TableName
+	query := statementbuilder.Squirrel.Select(goqu.L("count(*)")).Prepared(true).From(tableName).
+		Where(goqu.Ex{scope.ColumnName: fl.Field().Interface()})
+
+	referenceId, hasReferenceId := referenceIdBytes(scope.Object["reference_id"])
+	if hasReferenceId {
+		query = query.Where(goqu.C("reference_id").Neq(referenceId))
+	}
+
+	if parentColumn := fl.Param(); parentColumn != "" {
+		parentValue, ok := scope.Object[parentColumn]
+		if !ok && hasReferenceId {
+			// the parent is not being changed, compare with the value already stored
+			s, v, err := statementbuilder.Squirrel.Select(parentColumn).Prepared(true).From(tableName).
+				Where(goqu.Ex{"reference_id": referenceId}).ToSQL()
+			if err != nil {
+				log.Errorf("[112] failed to build query for unique validation: %v", err)
+				return false
+			}
+			stmt1, err := scope.Transaction.Preparex(s)
+			if err != nil {
+				log.Errorf("[117] failed to prepare statment: %v", err)
+				return false
+			}
+			err = stmt1.QueryRowx(v...).Scan(&parentValue)
+			stmt1.Close()
+			if err != nil {
+				log.Errorf("[123] failed to read [%v] for unique validation: %v", parentColumn, err)
+				return false
+			}
+		}
+		if parentValue == nil {
+			query = query.Where(goqu.C(parentColumn).IsNull())
+		} else {
+			parentColumnInfo, isColumn := scope.TableInfo.GetColumnByName(parentColumn)
+			if ok && isColumn && parentColumnInfo.IsForeignKey && parentColumnInfo.ForeignKeyData.DataSource == "self" {
+				// the request refers to the parent by its reference id, the table stores its id
+				if parentReferenceId, isReferenceId := referenceIdBytes(parentValue); isReferenceId {
+					var parentDaptinId daptinid.DaptinReferenceId
+					copy(parentDaptinId[:], parentReferenceId)
+					parentId, err := GetReferenceIdToIdWithTransaction(parentColumnInfo.ForeignKeyData.Namespace, parentDaptinId, scope.Transaction)
+					if err != nil {
+						log.Errorf("[131] unknown [%v] for unique validation: %v", parentColumn, err)
+						return false
+					}
+					parentValue = parentId
+				}
+			}
+			query = query.Where(goqu.Ex{parentColumn: parentValue})
+		}
+	}
+
+	s, v, err := query.ToSQL()
+	if err != nil {
+		log.Errorf("[138] failed to build query for unique validation: %v", err)
+		return false
+	}
+	stmt1, err := scope.Transaction.Preparex(s)
+	if err != nil {
+		log.Errorf("[143] failed to prepare statment: %v", err)
+		return false
+	}
+	defer stmt1.Close()
+
+	var count int
+	err = stmt1.QueryRowx(v...).Scan(&count)
+	if err != nil {
+		log.Errorf("[151] failed to count rows for unique validation on [%v]: %v", scope.ColumnName, err)
+		return false
+	}
+	return count == 0
+}
+
+// fieldMessage puts the column name in a translated message, validations of a single value are reported without a
+// field name
+func fieldMessage(message string, columnName string) string {
+	if strings.HasPrefix(message, " ") {
+		return columnName + message
+	}
+	if strings.Contains(message, "for ''") {
+		return strings.Replace(message, "for ''", fmt.Sprintf("for '%v'", columnName), 1)
+	}
+	if strings.Contains(message, "  ") {
+		return strings.Replace(message, "  ", " "+columnName+" ", 1)
+	}
+	return message
+}
+
+// ValidateObject runs the validations of the columns of the object and returns an error for each value which fails,
+// pointing at the attribute with pointerPrefix + column name. Columns missing from the object are skipped when
+// partial is set.
+func ValidateObject(tableInfo *TableInfo, validations []ColumnTag, obj map[string]interface{}, partial bool,
+	pointerPrefix string, translator ut.Translator, transaction *sqlx.Tx) ([]api2go.Error, error) {
+
+	validationErrors := make([]api2go.Error, 0)
+	for _, validate := range validations {
+
+		colValue, ok := obj[validate.ColumnName]
+		if !ok && partial {
+			continue
+		}
+
+		ctx := context.WithValue(context.Background(), validationScopeKey{}, validationScope{
+			TableInfo:   tableInfo,
+			ColumnName:  validate.ColumnName,
+			Object:      obj,
+			Transaction: transaction,
+		})
+		errs := ValidatorInstance.VarWithValueCtx(ctx, colValue, obj, validate.Tags)
+		if errs == nil {
+			continue
+		}
+
+		fieldErrors, ok := errs.(validator.ValidationErrors)
+		if !ok {
+			return nil, errs
+		}
+
+		for _, fieldError := range fieldErrors {
+			validationErrors = append(validationErrors, api2go.Error{
+				Status: "400",
+				Code:   fieldError.Tag(),
+				Title:  "invalid value",
+				Detail: fieldMessage(fieldError.Translate(translator), validate.ColumnName),
+				Source: &api2go.ErrorSource{
+					Pointer: pointerPrefix + validate.ColumnName,
+				},
+				Meta: map[string]interface{}{
+					"tag":   fieldError.Tag(),
+					"param": fieldError.Param(),
+					"value": colValue,
+				},
+			})
+		}
+	}
+	return validationErrors, nil
+}
+
+// NewValidationError is a 400 response listing every invalid value
+func NewValidationError(validationErrors []api2go.Error) api2go.HTTPError {
+	httpErr := api2go.NewHTTPError(nil, validationErrors[0].Detail, 400)
+	httpErr.Errors = validationErrors
+	return httpErr
+}
@@ -0,0 +1,49 @@
+package resource
+
+import (
+	"testing"
+)
+
+func TestValidateObjectCrossField(t *testing.T) {
+
+	RegisterTranslations()
+
+	validations := []ColumnTag{
+		{ColumnName: "end_date", Tags: "gtcol=start_date"},
+		{ColumnName: "password_confirm", Tags: "eqcol=password"},
+		{ColumnName: "state", Tags: "required_with_col=country"},
+	}
+
+	obj := map[string]interface{}{
+		"start_date":       "2026-02-01",
+		"end_date":         "2026-01-01",
+		"password":         "secret",
+		"password_confirm": "secret",
+		"country":          "FR",
+	}
+
+	validationErrors, err := ValidateObject(nil, validations, obj, false, "/data/attributes/", GetValidationTranslator(nil), nil)
+	if err != nil {
+		t.Fatalf("failed to validate: %v", err)
+	}
+	if len(validationErrors) != 2 {
+		t.Fatalf("expected 2 errors, got %v", validationErrors)
+	}
+
+	if validationErrors[0].Code != "gtcol" || validationErrors[0].Source.Pointer != "/data/attributes/end_date" {
+		t.Errorf("unexpected error for end_date: %v", validationErrors[0])
+	}
+	if validationErrors[0].Detail != "end_date must be greater than start_date" {
+		t.Errorf("unexpected message for end_date: %v", validationErrors[0].Detail)
+	}
+	if validationErrors[1].Code != "required_with_col" || validationErrors[1].Meta.(map[string]interface{})["param"] != "country" {
+		t.Errorf("unexpected error for state: %v", validationErrors[1])
+	}
+
+	obj["end_date"] = "2026-03-01"
+	obj["state"] = "IDF"
+	validationErrors, _ = ValidateObject(nil, validations, obj, true, "/data/attributes/", GetValidationTranslator([]string{"fr"}), nil)
+	if len(validationErrors) != 0 {
+		t.Errorf("valid object rejected: %v", validationErrors)
+	}
+}
@@ -0,0 +1,657 @@
+package resource
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/artpar/api2go"
+	"github.com/daptin/daptin/server/auth"
+	"github.com/daptin/daptin/server/database"
+	daptinid "github.com/daptin/daptin/server/id"
+	"github.com/daptin/daptin/server/statementbuilder"
+	"github.com/doug-martin/goqu/v9"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// Webhooks are the endpoints other services call to run an action, at /webhook/<Name>. A delivery is accepted only
+// with a valid signature of its body, made with the shared Secret of the webhook in the format of the Signature.
+// The Attributes of the action are evaluated from the delivery the way outcome attributes are, with ~body, ~headers
+// and ~query, the body is passed on as it is when there are none. A delivery is run once for its DeliveryId, the
+// deliveries are recorded in the webhook_delivery table. A delivery still received after the lease is taken to have
+// failed, the server stopped while it ran, and its retry runs again. The hmac-sha256 format signs the body only, a
+// captured delivery can be sent again unless the webhook has a DeliveryId.
+
+const (
+	WebhookDeliveryTableName            = "webhook_delivery"
+	DefaultWebhookDeliveryRetentionDays = 30
+	defaultWebhookTolerance             = 5 * time.Minute
+	webhookDeliveryLease                = 5 * time.Minute
+	maxWebhookBodySize                  = 5 << 20
+)
+
+// The signature formats of the webhooks
+const (
+	// WebhookSignatureHmac is the hex or base64 hmac-sha256 of the body in SignatureHeader, X-Signature by default,
+	// with an optional sha256= prefix
+	WebhookSignatureHmac = "hmac-sha256"
+	// WebhookSignatureGithub is the X-Hub-Signature-256 header of GitHub and Gitea
+	WebhookSignatureGithub = "github"
+	// WebhookSignatureStripe is the Stripe-Signature header, with the timestamp signed along with the body
+	WebhookSignatureStripe = "stripe"
+	// WebhookSignatureSlack is the X-Slack-Signature header, with the timestamp of X-Slack-Request-Timestamp
+	WebhookSignatureSlack = "slack"
+	// WebhookSignatureStandard is the webhook-signature header of the Standard Webhooks spec, used by Svix
+	WebhookSignatureStandard = "standard"
+)
+
+// The states of a delivery
+const (
+	WebhookDeliveryReceived  = "received"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// webhookDeliveryIds is where the delivery id is read from by default for each signature format
+var webhookDeliveryIds = map[string]string{
+	WebhookSignatureGithub:   "~headers.x-github-delivery",
+	WebhookSignatureStripe:   "~body.id",
+	WebhookSignatureSlack:    "~body.event_id",
+	WebhookSignatureStandard: "~headers.webhook-id",
+}
+
+type Webhook struct {
+	Name            string
+	Action          string
+	OnType          string
+	Secret          string
+	SecretEnv       string // name of the environment variable with the secret, when Secret is not set
+	Signature       string // format of the signature, hmac-sha256 by default
+	SignatureHeader string // header of a hmac-sha256 signature, X-Signature by default
+	Tolerance       string // age of a timestamped delivery after which it is refused, 5m by default
+	DeliveryId      string // expression of the id a delivery is run once for, the signature format default if not set
+	AsUserEmail     string // user the action is run as, a guest when not set
+	Attributes      map[string]interface{}
+}
+
+type webhookEndpoint struct {
+	Webhook
+	secret    []byte
+	tolerance time.Duration
+}
+
+// webhookDelivery is a verified request to a webhook
+type webhookDelivery struct {
+	webhook     *webhookEndpoint
+	deliveryId  string
+	body        interface{}
+	referenceId daptinid.DaptinReferenceId
+	startedAt   time.Time
+}
+
+type webhookDeliveryRecord struct {
+	ReferenceId daptinid.DaptinReferenceId `db:"reference_id"`
+	Status      string                     `db:"status"`
+}
+
+type WebhookHandler struct {
+	webhooks  map[string]*webhookEndpoint
+	db        database.DatabaseConnection
+	cruds     map[string]*DbResource
+	runAction func(webhook *webhookEndpoint, attributes map[string]interface{}) ([]ActionResponse, error)
+}
+
+// webhookDeliveryPurge removes the old deliveries once an hour, with the database and the retention of the latest
+// handler
+type webhookDeliveryPurge struct {
+	lock      sync.RWMutex
+	db        database.DatabaseConnection
+	retention time.Duration
+	started   bool
+}
+
+var webhookDeliveries = &webhookDeliveryPurge{}
+
+// NewWebhookHandler serves the webhooks, the deliveries are kept for the retention days, forever when it is 0. The
+// server is created again on every reload, the old deliveries are removed by a single loop.
+func NewWebhookHandler(webhooks []Webhook, cruds map[string]*DbResource, retentionDays int) *WebhookHandler {
+	handler := &WebhookHandler{
+		webhooks: make(map[string]*webhookEndpoint),
+		cruds:    cruds,
+	}
+	if world, ok := cruds["world"]; ok {
+		handler.db = world.Connection
+	}
+	handler.runAction = handler.handleAction
+	for _, webhook := range webhooks {
+		endpoint, err := newWebhookEndpoint(webhook)
+		if err != nil {
+			log.Errorf("Invalid webhook [%v]: %v", webhook.Name, err)
+			continue
+		}
+		log.Infof("Webhook [%v] runs [%v][%v]", webhook.Name, webhook.OnType, webhook.Action)
+		if endpoint.Signature == WebhookSignatureHmac && endpoint.DeliveryId == "" {
+			log.Warnf("Webhook [%v] has no DeliveryId, a captured delivery can be sent to it again", webhook.Name)
+		}
+		handler.webhooks[webhook.Name] = endpoint
+	}
+
+	webhookDeliveries.lock.Lock()
+	webhookDeliveries.db = handler.db
+	webhookDeliveries.retention = time.Duration(retentionDays) * 24 * time.Hour
+	started := webhookDeliveries.started
+	webhookDeliveries.started = true
+	webhookDeliveries.lock.Unlock()
+
+	if !started {
+		go func() {
+			for range time.Tick(time.Hour) {
+				webhookDeliveries.lock.RLock()
+				db, retention := webhookDeliveries.db, webhookDeliveries.retention
+				webhookDeliveries.lock.RUnlock()
+				if retention <= 0 || db == nil {
+					continue
+				}
+				CheckErr(purgeWebhookDeliveries(db, time.Now().Add(-retention)),
+					"Failed to remove old webhook deliveries")
+			}
+		}()
+	}
+	return handler
+}
+
+func newWebhookEndpoint(webhook Webhook) (*webhookEndpoint, error) {
+	if webhook.Name == "" || webhook.Action == "" {
+		return nil, errors.New("a webhook needs a Name and an Action")
+	}
+	if webhook.Signature == "" {
+		webhook.Signature = WebhookSignatureHmac
+	}
+	switch webhook.Signature {
+	case WebhookSignatureHmac, WebhookSignatureGithub, WebhookSignatureStripe, WebhookSignatureSlack,
+		WebhookSignatureStandard:
+	default:
+		return nil, fmt.Errorf("unknown signature format [%v]", webhook.Signature)
+	}
+	if webhook.SignatureHeader == "" {
+		webhook.SignatureHeader = "X-Signature"
+	}
+	if webhook.DeliveryId == "" {
+		webhook.DeliveryId = webhookDeliveryIds[webhook.Signature]
+	}
+
+	secret := webhook.Secret
+	if secret == "" && webhook.SecretEnv != "" {
+		secret = os.Getenv(webhook.SecretEnv)
+	}
+	if secret == "" {
+		return nil, errors.New("a webhook needs a Secret")
+	}
+	endpoint := &webhookEndpoint{
+		Webhook:   webhook,
+		secret:    []byte(secret),
+		tolerance: defaultWebhookTolerance,
+	}
+	if webhook.Signature == WebhookSignatureStandard && strings.HasPrefix(secret, "whsec_") {
+		key, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(secret, "whsec_"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid whsec_ secret: %v", err)
+		}
+		endpoint.secret = key
+	}
+	if webhook.Tolerance != "" {
+		tolerance, err := time.ParseDuration(webhook.Tolerance)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Tolerance [%v]: %v", webhook.Tolerance, err)
+		}
+		endpoint.tolerance = tolerance
+	}
+	return endpoint, nil
+}
+
+func (handler *WebhookHandler) HandleWebhook(c *gin.Context) {
+	webhook, ok := handler.webhooks[c.Param("name")]
+	if !ok {
+		c.AbortWithStatusJSON(404, gin.H{"error": "no such webhook"})
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebhookBodySize+1))
+	if err != nil {
+		c.AbortWithStatusJSON(400, gin.H{"error": "failed to read request: " + err.Error()})
+		return
+	}
+	if len(body) > maxWebhookBodySize {
+		c.AbortWithStatusJSON(413, gin.H{"error": "request is too large"})
+		return
+	}
+
+	if err = webhook.verify(c.Request.Header, body, time.Now()); err != nil {
+		log.Warnf("Refused delivery to webhook [%v] from [%v]: %v", webhook.Name, c.ClientIP(), err)
+		c.AbortWithStatusJSON(401, gin.H{"error": "invalid signature"})
+		return
+	}
+
+	inFieldMap := webhookInFields(c.Request, body)
+	delivery := &webhookDelivery{
+		webhook:   webhook,
+		body:      inFieldMap["body"],
+		startedAt: time.Now(),
+	}
+	if webhook.DeliveryId != "" {
+		deliveryId, err := evaluateWebhookString(webhook.DeliveryId, inFieldMap)
+		if err != nil {
+			log.Warnf("Failed to read delivery id of webhook [%v]: %v", webhook.Name, err)
+		} else if deliveryId != nil {
+			delivery.deliveryId = fmt.Sprintf("%v", deliveryId)
+		}
+	}
+
+	started, status, err := handler.start(delivery)
+	if err != nil {
+		log.Errorf("Failed to record delivery to webhook [%v]: %v", webhook.Name, err)
+		c.AbortWithStatusJSON(500, gin.H{"error": "failed to record delivery"})
+		return
+	}
+	if !started {
+		if status == WebhookDeliverySucceeded {
+			c.JSON(200, gin.H{"delivery_id": delivery.deliveryId, "duplicate": true})
+		} else {
+			c.AbortWithStatusJSON(409, gin.H{"error": "this delivery is still running"})
+		}
+		return
+	}
+
+	responses, err := handler.run(delivery, inFieldMap)
+	CheckErr(handler.finish(delivery, webhookResponseStatus(err), err), "Failed to record delivery to webhook [%v]",
+		webhook.Name)
+	if err != nil {
+		c.AbortWithStatusJSON(webhookResponseStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, responses)
+}
+
+func webhookResponseStatus(err error) int {
+	if err == nil {
+		return 200
+	}
+	if httpErr, ok := err.(api2go.HTTPError); ok {
+		return httpErr.Status()
+	}
+	return 500
+}
+
+// verify checks the signature of the body with the secret of the webhook
+func (webhook *webhookEndpoint) verify(header http.Header, body []byte, now time.Time) error {
+	switch webhook.Signature {
+	case WebhookSignatureGithub:
+		signature := header.Get("X-Hub-Signature-256")
+		if !strings.HasPrefix(signature, "sha256=") {
+			return errors.New("no X-Hub-Signature-256 header")
+		}
+		return webhook.verifyHex(strings.TrimPrefix(signature, "sha256="), body)
+
+	case WebhookSignatureStripe:
+		timestamp := ""
+		signatures := make([]string, 0, 1)
+		for _, part := range strings.Split(header.Get("Stripe-Signature"), ",") {
+			key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+			if key == "t" {
+				timestamp = value
+			} else if key == "v1" {
+				signatures = append(signatures, value)
+			}
+		}
+		if err := webhook.checkTimestamp(timestamp, now); err != nil {
+			return err
+		}
+		payload := append([]byte(timestamp+"."), body...)
+		for _, signature := range signatures {
+			if webhook.verifyHex(signature, payload) == nil {
+				return nil
+			}
+		}
+		return errors.New("no matching v1 signature in Stripe-Signature header")
+
+	case WebhookSignatureSlack:
+		timestamp := header.Get("X-Slack-Request-Timestamp")
+		if err := webhook.checkTimestamp(timestamp, now); err != nil {
+			return err
+		}
+		signature := header.Get("X-Slack-Signature")
+		if !strings.HasPrefix(signature, "v0=") {
+			return errors.New("no X-Slack-Signature header")
+		}
+		return webhook.verifyHex(strings.TrimPrefix(signature, "v0="), append([]byte("v0:"+timestamp+":"), body...))
+
+	case WebhookSignatureStandard:
+		timestamp := header.Get("webhook-timestamp")
+		if err := webhook.checkTimestamp(timestamp, now); err != nil {
+			return err
+		}
+		payload := append([]byte(header.Get("webhook-id")+"."+timestamp+"."), body...)
+		expected := webhook.sign(payload)
+		for _, signature := range strings.Fields(header.Get("webhook-signature")) {
+			version, value, _ := strings.Cut(signature, ",")
+			if version != "v1" {
+				continue
+			}
+			decoded, err := base64.StdEncoding.DecodeString(value)
+			if err == nil && hmac.Equal(decoded, expected) {
+				return nil
+			}
+		}
+		return errors.New("no matching v1 signature in webhook-signature header")
+	}
+
+	signature := strings.TrimPrefix(header.Get(webhook.SignatureHeader), "sha256=")
+	if signature == "" {
+		return fmt.Errorf("no %v header", webhook.SignatureHeader)
+	}
+	if webhook.verifyHex(signature, body) == nil {
+		return nil
+	}
+	expected := webhook.sign(body)
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		decoded, err := encoding.DecodeString(signature)
+		if err == nil && hmac.Equal(decoded, expected) {
+			return nil
+		}
+	}
+	return errors.New("signature does not match")
+}
+
+func (webhook *webhookEndpoint) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, webhook.secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+func (webhook *webhookEndpoint) verifyHex(signature string, payload []byte) error {
+	decoded, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(decoded, webhook.sign(payload)) {
+		return errors.New("signature does not match")
+	}
+	return nil
+}
+
+// checkTimestamp refuses deliveries signed too long ago, or too far ahead, so a captured delivery cannot be replayed
+func (webhook *webhookEndpoint) checkTimestamp(timestamp string, now time.Time) error {
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.New("no signature timestamp")
+	}
+	age := now.Sub(time.Unix(seconds, 0))
+	if webhook.tolerance > 0 && (age > webhook.tolerance || age < -webhook.tolerance) {
+		return fmt.Errorf("signature timestamp is %v old", age.Round(time.Second))
+	}
+	return nil
+}
+
+// webhookInFields are the values the expressions of a webhook are evaluated with: the body, json or form values
+// when it can be read as such, the headers by their lower case names and the query
+func webhookInFields(request *http.Request, body []byte) map[string]interface{} {
+	headers := make(map[string]interface{}, len(request.Header))
+	for name := range request.Header {
+		headers[strings.ToLower(name)] = request.Header.Get(name)
+	}
+	query := make(map[string]interface{})
+	for name := range request.URL.Query() {
+		query[name] = request.URL.Query().Get(name)
+	}
+
+	var parsedBody interface{} = string(body)
+	if strings.HasPrefix(request.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		if values, err := url.ParseQuery(string(body)); err == nil {
+			form := make(map[string]interface{}, len(values))
+			for name := range values {
+				form[name] = values.Get(name)
+			}
+			parsedBody = form
+		}
+	} else {
+		var jsonBody interface{}
+		if err := json.Unmarshal(body, &jsonBody); err == nil {
+			parsedBody = jsonBody
+		}
+	}
+
+	return map[string]interface{}{
+		"body":    parsedBody,
+		"headers": headers,
+		"query":   query,
+	}
+}
+
+// evaluateWebhookString evaluates an expression against a delivery, which is not always of the shape expected
+func evaluateWebhookString(expression string, inFieldMap map[string]interface{}) (value interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			value, err = nil, fmt.Errorf("failed to evaluate [%v]: %v", expression, r)
+		}
+	}()
+	return evaluateString(expression, inFieldMap)
+}
+
+// webhookAttributes are the inputs of the action
+func (webhook *webhookEndpoint) webhookAttributes(inFieldMap map[string]interface{}) (attributes map[string]interface{},
+	err error) {
+	if len(webhook.Attributes) == 0 {
+		if body, ok := inFieldMap["body"].(map[string]interface{}); ok {
+			return body, nil
+		}
+		return map[string]interface{}{"body": inFieldMap["body"]}, nil
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			attributes, err = nil, fmt.Errorf("failed to evaluate attributes: %v", r)
+		}
+	}()
+	evaluated, err := BuildActionContext(webhook.Attributes, inFieldMap)
+	if err != nil {
+		return nil, err
+	}
+	attributes, _ = evaluated.(map[string]interface{})
+	if attributes == nil {
+		attributes = make(map[string]interface{})
+	}
+	return attributes, nil
+}
+
+func (handler *WebhookHandler) run(delivery *webhookDelivery, inFieldMap map[string]interface{}) (
+	responses []ActionResponse, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			responses, err = nil, fmt.Errorf("%v", r)
+		}
+	}()
+	attributes, err := delivery.webhook.webhookAttributes(inFieldMap)
+	if err != nil {
+		return nil, api2go.NewHTTPError(err, err.Error(), 400)
+	}
+	return handler.runAction(delivery.webhook, attributes)
+}
+
+// handleAction runs the action of the webhook as its user
+func (handler *WebhookHandler) handleAction(webhook *webhookEndpoint, attributes map[string]interface{}) (
+	[]ActionResponse, error) {
+	crud, ok := handler.cruds[webhook.OnType]
+	if !ok {
+		crud = handler.cruds["world"]
+	}
+
+	transaction, err := handler.db.Beginx()
+	if err != nil {
+		return nil, err
+	}
+	sessionUser := &auth.SessionUser{}
+	if webhook.AsUserEmail != "" {
+		user, err := crud.GetObjectByWhereClause(USER_ACCOUNT_TABLE_NAME, "email", webhook.AsUserEmail, transaction)
+		if err != nil {
+			transaction.Rollback()
+			return nil, fmt.Errorf("failed to find user [%v] of webhook: %v", webhook.AsUserEmail, err)
+		}
+		sessionUser.UserReferenceId = user["reference_id"].(daptinid.DaptinReferenceId)
+		sessionUser.UserId = user["id"].(int64)
+		sessionUser.Groups = crud.GetObjectUserGroupsByWhereWithTransaction(USER_ACCOUNT_TABLE_NAME, transaction,
+			"reference_id", sessionUser.UserReferenceId[:])
+	}
+
+	ctx := WithActionChannel(context.WithValue(context.Background(), "user", sessionUser), ActionChannelWebhook)
+	req := api2go.Request{
+		PlainRequest: (&http.Request{Method: "POST"}).WithContext(ctx),
+	}
+	responses, err := crud.HandleActionRequest(ActionRequest{
+		Type:       webhook.OnType,
+		Action:     webhook.Action,
+		Attributes: attributes,
+	}, req, transaction)
+	if err != nil {
+		// not every failure rolls back, an unknown action leaves the transaction open
+		transaction.Rollback()
+		return responses, err
+	}
+	// the action committed the transaction
+	return responses, nil
+}
+
+// deliveryKey is unique for a delivery id of a webhook
+func (delivery *webhookDelivery) deliveryKey() interface{} {
+	if delivery.deliveryId == "" {
+		return nil
+	}
+	hash := sha256.Sum256([]byte(delivery.webhook.Name + "\n" + delivery.deliveryId))
+	return hex.EncodeToString(hash[:])
+}
+
+// start records the delivery, it is false with the status of the earlier delivery of the same id, unless that one
+// failed, or was still received after the lease, and this is its retry
+func (handler *WebhookHandler) start(delivery *webhookDelivery) (bool, string, error) {
+	u, _ := uuid.NewV7()
+	delivery.referenceId = daptinid.DaptinReferenceId(u)
+	payload, err := json.Marshal(sanitiseLogValue(delivery.body))
+	if err != nil {
+		return false, "", err
+	}
+	s, v, err := statementbuilder.Squirrel.Insert(WebhookDeliveryTableName).Prepared(true).Rows(goqu.Record{
+		"webhook_name":    delivery.webhook.Name,
+		"delivery_id":     delivery.deliveryId,
+		"delivery_key":    delivery.deliveryKey(),
+		"status":          WebhookDeliveryReceived,
+		"response_status": 0,
+		"payload":         string(payload),
+		"reference_id":    u[:],
+		"permission":      auth.DEFAULT_PERMISSION,
+		"created_at":      delivery.startedAt,
+		"updated_at":      delivery.startedAt,
+	}).ToSQL()
+	if err != nil {
+		return false, "", err
+	}
+	_, insertErr := handler.db.Exec(s, v...)
+	if insertErr == nil {
+		return true, WebhookDeliveryReceived, nil
+	}
+	if delivery.deliveryId == "" {
+		return false, "", insertErr
+	}
+
+	// the delivery key is unique, the insert fails for a delivery seen before
+	var earlier webhookDeliveryRecord
+	s, v, err = statementbuilder.Squirrel.Select("reference_id", "status").Prepared(true).
+		From(WebhookDeliveryTableName).Where(goqu.Ex{"delivery_key": delivery.deliveryKey()}).ToSQL()
+	if err != nil {
+		return false, "", err
+	}
+	err = handler.db.Get(&earlier, s, v...)
+	if err == sql.ErrNoRows {
+		return false, "", insertErr
+	}
+	if err != nil {
+		return false, "", err
+	}
+	if earlier.Status == WebhookDeliverySucceeded {
+		return false, earlier.Status, nil
+	}
+
+	leaseStart := delivery.startedAt.Add(-webhookDeliveryLease)
+	s, v, err = statementbuilder.Squirrel.Update(WebhookDeliveryTableName).Prepared(true).Set(goqu.Record{
+		"status":     WebhookDeliveryReceived,
+		"payload":    string(payload),
+		"updated_at": delivery.startedAt,
+	}).Where(goqu.Ex{"delivery_key": delivery.deliveryKey()}, goqu.Or(
+		goqu.Ex{"status": WebhookDeliveryFailed},
+		goqu.And(goqu.Ex{"status": WebhookDeliveryReceived}, goqu.Or(
+			goqu.C("updated_at").Lt(leaseStart),
+			goqu.And(goqu.C("updated_at").IsNull(), goqu.C("created_at").Lt(leaseStart)),
+		)),
+	)).ToSQL()
+	if err != nil {
+		return false, "", err
+	}
+	result, err := handler.db.Exec(s, v...)
+	if err != nil {
+		return false, "", err
+	}
+	if retried, _ := result.RowsAffected(); retried == 0 {
+		// the delivery is still running, or another retry of it took it first
+		return false, WebhookDeliveryReceived, nil
+	}
+	delivery.referenceId = earlier.ReferenceId
+	return true, WebhookDeliveryReceived, nil
+}
+
+func (handler *WebhookHandler) finish(delivery *webhookDelivery, responseStatus int, runErr error) error {
+	record := goqu.Record{
+		"status":          WebhookDeliverySucceeded,
+		"response_status": responseStatus,
+		"error":           nil,
+		"duration_ms":     time.Since(delivery.startedAt).Milliseconds(),
+		"updated_at":      time.Now(),
+	}
+	if runErr != nil {
+		record["status"] = WebhookDeliveryFailed
+		record["error"] = runErr.Error()
+	}
+	s, v, err := statementbuilder.Squirrel.Update(WebhookDeliveryTableName).Prepared(true).Set(record).
+		Where(goqu.Ex{"reference_id": delivery.referenceId[:]}).ToSQL()
+	if err != nil {
+		return err
+	}
+	_, err = handler.db.Exec(s, v...)
+	return err
+}
+
+// purgeWebhookDeliveries removes the deliveries received before the time
+func purgeWebhookDeliveries(db database.DatabaseConnection, before time.Time) error {
+	s, v, err := statementbuilder.Squirrel.Delete(WebhookDeliveryTableName).Prepared(true).
+		Where(goqu.C("created_at").Lt(before)).ToSQL()
+	if err != nil {
+		return err
+	}
+	result, err := db.Exec(s, v...)
+	if err != nil {
+		return err
+	}
+	if removed, _ := result.RowsAffected(); removed > 0 {
+		log.Infof("Removed %d webhook deliveries from before %v", removed, before.Format(time.RFC3339))
+	}
+	return nil
+}
@@ -0,0 +1,283 @@
+package resource
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/artpar/api2go"
+	"github.com/gin-gonic/gin"
+	"github.com/jmoiron/sqlx"
+)
+
+func testWebhookSign(secret []byte, payload string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+func TestWebhookRunsVerifiedDeliveriesOnce(t *testing.T) {
+	db, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	db.MustExec(`create table webhook_delivery (id integer primary key, webhook_name varchar(100),
+		delivery_id varchar(255), delivery_key varchar(64) unique, status varchar(20), response_status int default 0,
+		payload text, error text, duration_ms int default 0, reference_id blob, permission int,
+		created_at timestamp, updated_at timestamp)`)
+
+	handler := NewWebhookHandler([]Webhook{{
+		Name:      "github",
+		Action:    "sync_repository",
+		OnType:    "repository",
+		Secret:    "shared",
+		Signature: WebhookSignatureGithub,
+		Attributes: map[string]interface{}{
+			"repository": "~body.repository",
+			"event":      "~headers.x-github-event",
+		},
+	}, {Name: "no-secret", Action: "sync_repository"}}, nil, 0)
+	handler.db = db
+	if _, ok := handler.webhooks["no-secret"]; ok {
+		t.Errorf("expected a webhook without a secret to be refused")
+	}
+
+	runs := make([]map[string]interface{}, 0)
+	fail := true
+	handler.runAction = func(webhook *webhookEndpoint, attributes map[string]interface{}) ([]ActionResponse, error) {
+		runs = append(runs, attributes)
+		if fail {
+			fail = false
+			return nil, errors.New("repository is locked")
+		}
+		return []ActionResponse{{ResponseType: "client.notify"}}, nil
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/webhook/:name", handler.HandleWebhook)
+	deliver := func(deliveryId string, body string, signature string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest("POST", "/webhook/github", strings.NewReader(body))
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set("X-GitHub-Event", "push")
+		request.Header.Set("X-GitHub-Delivery", deliveryId)
+		request.Header.Set("X-Hub-Signature-256", signature)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+	body := `{"repository": "daptin/daptin"}`
+	signature := "sha256=" + hex.EncodeToString(testWebhookSign([]byte("shared"), body))
+
+	if refused := deliver("d-1", body, "sha256="+hex.EncodeToString(testWebhookSign([]byte("other"), body))); refused.Code != 401 || len(runs) != 0 {
+		t.Errorf("expected a delivery signed with another secret to be refused, got %d", refused.Code)
+	}
+	if refused := deliver("d-1", `{"repository": "other"}`, signature); refused.Code != 401 || len(runs) != 0 {
+		t.Errorf("expected a changed body to be refused, got %d", refused.Code)
+	}
+
+	if failed := deliver("d-1", body, signature); failed.Code != 500 || len(runs) != 1 {
+		t.Fatalf("expected the failed run to be reported, got %d after %d runs", failed.Code, len(runs))
+	}
+	if runs[0]["repository"] != "daptin/daptin" || runs[0]["event"] != "push" {
+		t.Errorf("expected the attributes from the body and the headers, got %v", runs[0])
+	}
+	if retried := deliver("d-1", body, signature); retried.Code != 200 || len(runs) != 2 {
+		t.Errorf("expected the retry of a failed delivery to run, got %d after %d runs", retried.Code, len(runs))
+	}
+	if duplicate := deliver("d-1", body, signature); duplicate.Code != 200 || len(runs) != 2 {
+		t.Errorf("expected the same delivery not to run again, got %d after %d runs", duplicate.Code, len(runs))
+	}
+	if next := deliver("d-2", body, signature); next.Code != 200 || len(runs) != 3 {
+		t.Errorf("expected another delivery to run, got %d after %d runs", next.Code, len(runs))
+	}
+
+	var statuses []string
+	if err = db.Select(&statuses, "select status from webhook_delivery order by id"); err != nil {
+		t.Fatalf("failed to read deliveries: %v", err)
+	}
+	if strings.Join(statuses, ",") != "succeeded,succeeded" {
+		t.Errorf("expected the two deliveries to be recorded as succeeded, got %v", statuses)
+	}
+}
+
+func TestWebhookSignatureFormats(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	body := `{"id": "evt_1"}`
+
+	endpoint := func(webhook Webhook) *webhookEndpoint {
+		webhook.Name = "test"
+		webhook.Action = "test"
+		if webhook.Secret == "" {
+			webhook.Secret = "shared"
+		}
+		created, err := newWebhookEndpoint(webhook)
+		if err != nil {
+			t.Fatalf("failed to create webhook: %v", err)
+		}
+		return created
+	}
+	secret := []byte("shared")
+
+	stripe := endpoint(Webhook{Signature: WebhookSignatureStripe})
+	header := http.Header{}
+	header.Set("Stripe-Signature", "t="+timestamp+",v1=00ff,v1="+hex.EncodeToString(testWebhookSign(secret, timestamp+"."+body)))
+	if err := stripe.verify(header, []byte(body), now); err != nil {
+		t.Errorf("expected the stripe signature to match: %v", err)
+	}
+	if err := stripe.verify(header, []byte(body), now.Add(10*time.Minute)); err == nil {
+		t.Errorf("expected an old stripe signature to be refused")
+	}
+
+	slack := endpoint(Webhook{Signature: WebhookSignatureSlack})
+	header = http.Header{}
+	header.Set("X-Slack-Request-Timestamp", timestamp)
+	header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(testWebhookSign(secret, "v0:"+timestamp+":"+body)))
+	if err := slack.verify(header, []byte(body), now); err != nil {
+		t.Errorf("expected the slack signature to match: %v", err)
+	}
+
+	key := []byte("standard webhook key")
+	standard := endpoint(Webhook{Signature: WebhookSignatureStandard,
+		Secret: "whsec_" + base64.StdEncoding.EncodeToString(key)})
+	header = http.Header{}
+	header.Set("webhook-id", "msg_1")
+	header.Set("webhook-timestamp", timestamp)
+	header.Set("webhook-signature", "v1,"+base64.StdEncoding.EncodeToString(testWebhookSign(key, "msg_1."+timestamp+"."+body)))
+	if err := standard.verify(header, []byte(body), now); err != nil {
+		t.Errorf("expected the standard webhook signature to match: %v", err)
+	}
+
+	generic := endpoint(Webhook{SignatureHeader: "X-Payload-Signature"})
+	header = http.Header{}
+	header.Set("X-Payload-Signature", base64.StdEncoding.EncodeToString(testWebhookSign(secret, body)))
+	if err := generic.verify(header, []byte(body), now); err != nil {
+		t.Errorf("expected the base64 signature to match: %v", err)
+	}
+	header.Set("X-Payload-Signature", "sha256="+hex.EncodeToString(testWebhookSign(secret, body)))
+	if err := generic.verify(header, []byte(body), now); err != nil {
+		t.Errorf("expected the hex signature to match: %v", err)
+	}
+	if err := generic.verify(http.Header{}, []byte(body), now); err == nil {
+		t.Errorf("expected a delivery without a signature to be refused")
+	}
+}
+
+func TestWebhookRunsTheAction(t *testing.T) {
+	db, cruds := newTestCruds(t, CmsConfig{
+		Actions: []Action{{
+			Name:             "record_step",
+			Label:            "Record a step",
+			OnType:           "world",
+			InstanceOptional: true,
+			InFields:         []api2go.ColumnInfo{{Name: "name", ColumnName: "name", ColumnType: "label"}},
+			OutFields: []Outcome{{
+				Type:       "test_step",
+				Attributes: map[string]interface{}{"name": "~name"},
+			}},
+		}},
+	})
+	db.MustExec("create table step (name varchar(20))")
+	cruds["world"].ActionHandlerMap = map[string]ActionPerformerInterface{"test_step": testStepPerformer{}}
+
+	handler := NewWebhookHandler([]Webhook{{
+		Name:       "steps",
+		Action:     "record_step",
+		OnType:     "world",
+		Secret:     "shared",
+		DeliveryId: "~body.id",
+	}}, cruds, 0)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/webhook/:name", handler.HandleWebhook)
+
+	body := `{"id": "delivery-1", "name": "first"}`
+	request := httptest.NewRequest("POST", "/webhook/steps", strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("X-Signature", hex.EncodeToString(testWebhookSign([]byte("shared"), body)))
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	if recorder.Code != 200 {
+		t.Fatalf("expected the delivery to succeed, got %d %v", recorder.Code, recorder.Body.String())
+	}
+
+	var names []string
+	if err := db.Select(&names, "select name from step"); err != nil || len(names) != 1 || names[0] != "first" {
+		t.Errorf("expected the outcome of the action to be kept, got %v %v", names, err)
+	}
+	var status string
+	if err := db.Get(&status, "select status from webhook_delivery where delivery_id = ?", "delivery-1"); err != nil ||
+		status != WebhookDeliverySucceeded {
+		t.Errorf("expected the delivery to be recorded as succeeded, got %v %v", status, err)
+	}
+}
+
+func TestWebhookRetriesDeliveriesLeftReceived(t *testing.T) {
+	db, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	db.MustExec(`create table webhook_delivery (id integer primary key, webhook_name varchar(100),
+		delivery_id varchar(255), delivery_key varchar(64) unique, status varchar(20), response_status int default 0,
+		payload text, error text, duration_ms int default 0, reference_id blob, permission int,
+		created_at timestamp, updated_at timestamp)`)
+
+	handler := NewWebhookHandler([]Webhook{{
+		Name:      "github",
+		Action:    "sync_repository",
+		Secret:    "shared",
+		Signature: WebhookSignatureGithub,
+	}}, nil, 0)
+	handler.db = db
+	runs := 0
+	handler.runAction = func(webhook *webhookEndpoint, attributes map[string]interface{}) ([]ActionResponse, error) {
+		runs++
+		return []ActionResponse{{ResponseType: "client.notify"}}, nil
+	}
+
+	// the server stopped while the delivery ran, it was never finished
+	started, _, err := handler.start(&webhookDelivery{webhook: handler.webhooks["github"], deliveryId: "d-1",
+		startedAt: time.Now()})
+	if !started || err != nil {
+		t.Fatalf("expected the delivery to be recorded, got %v %v", started, err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/webhook/:name", handler.HandleWebhook)
+	body := `{"repository": "daptin/daptin"}`
+	deliver := func() *httptest.ResponseRecorder {
+		request := httptest.NewRequest("POST", "/webhook/github", strings.NewReader(body))
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set("X-GitHub-Delivery", "d-1")
+		request.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(testWebhookSign([]byte("shared"), body)))
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	if running := deliver(); running.Code != 409 || runs != 0 {
+		t.Errorf("expected a delivery received within the lease to be still running, got %d after %d runs", running.Code, runs)
+	}
+	db.MustExec("update webhook_delivery set updated_at = ?", time.Now().Add(-webhookDeliveryLease-time.Minute))
+	if retried := deliver(); retried.Code != 200 || runs != 1 {
+		t.Errorf("expected a delivery received before the lease to be run again, got %d after %d runs", retried.Code, runs)
+	}
+	var status string
+	if err = db.Get(&status, "select status from webhook_delivery"); err != nil || status != WebhookDeliverySucceeded {
+		t.Errorf("expected the retried delivery to succeed, got %v %v", status, err)
+	}
+}
@@ -74,7 +74,7 @@ func GetResource() (*InMemoryTestDatabase, *resource.DbResource) {
 
 	tx, errb := wrapper.Beginx()
 	resource.CheckErr(errb, "Failed to begin transaction [76]")
-	resource.CheckAllTableStatus(&initConfig, wrapper)
+	resource.CheckAllTableStatus(&initConfig, wrapper, nil)
 	errc := tx.Commit()
 	resource.CheckErr(errc, "Failed to commit transaction after creating tables")
 
@@ -86,13 +86,13 @@ func GetResource() (*InMemoryTestDatabase, *resource.DbResource) {
 
 	tx, errb = wrapper.Beginx()
 	resource.CheckErr(errb, "Failed to begin transaction [88]")
-	resource.CreateUniqueConstraints(&initConfig, tx)
+	resource.CreateUniqueConstraints(&initConfig, tx, nil)
 	errc = tx.Commit()
 	resource.CheckErr(errc, "Failed to commit transaction after creating unique constrains")
 
 	tx, errb = wrapper.Beginx()
 	resource.CheckErr(errb, "Failed to begin transaction [94]")
-	resource.CreateIndexes(&initConfig, wrapper)
+	resource.CreateIndexes(&initConfig, wrapper, nil)
 	errc = tx.Commit()
 	resource.CheckErr(errc, "Failed to commit transaction after creating indexes")
 
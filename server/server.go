@@ -48,16 +48,31 @@ var TaskScheduler resource.TaskScheduler
 var Stats = stats.New()
 
 type RateConfig struct {
-	version string
-	limits  map[string]int
+	Version string         `json:"version"`
+	Limits  map[string]int `json:"limits"`
 }
 
 var defaultRateConfig = RateConfig{
-	version: "default",
-	limits:  map[string]int{},
+	Version: "default",
+	Limits:  map[string]int{},
 }
 
-func Main(boxRoot http.FileSystem, db database.DatabaseConnection, localStoragePath string, olricDb *olric.EmbeddedClient) (
+// dropInvalidLimits leaves out the rates which are not a positive number of requests a second, the gap between two
+// requests cannot be worked out from them
+func (rateConfig *RateConfig) dropInvalidLimits() {
+	for requestPath, ratePerSecond := range rateConfig.Limits {
+		if ratePerSecond <= 0 {
+			log.Errorf("Invalid rate [%v] for [%v] in limit.rate, expected a positive number of requests a second",
+				ratePerSecond, requestPath)
+			delete(rateConfig.Limits, requestPath)
+		}
+	}
+}
+
+// Main builds the router and all resources from the database. When running is not nil the server is being reloaded
+// in place and the SMTP, IMAP and FTP servers from the previous run are reused instead of being started again.
+func Main(boxRoot http.FileSystem, db database.DatabaseConnection, localStoragePath string, olricDb *olric.EmbeddedClient,
+	running *RunningServices) (
 	HostSwitch, *guerrilla.Daemon, resource.TaskScheduler, *resource.ConfigStore, *resource.CertificateManager,
 	*server2.FtpServer, *server.Server, *olric.EmbeddedClient) {
 
@@ -79,6 +94,11 @@ func Main(boxRoot http.FileSystem, db database.DatabaseConnection, localStorageP
 `)
 
 	/// Start system initialise
+	err := resource.CheckSchemaSystemVersion(db)
+	if err != nil {
+		log.Fatalf("Refusing to start: %v", err)
+	}
+
 	log.Printf("Load config files")
 
 	initConfig, errs := LoadConfigFiles()
@@ -238,13 +258,14 @@ func Main(boxRoot http.FileSystem, db database.DatabaseConnection, localStorageP
 	}
 
 	var rateConfig RateConfig
-	err = json.Unmarshal([]byte(rateConfigJson), rateConfig)
-	if err != nil || rateConfig.version == "" {
+	err = json.Unmarshal([]byte(rateConfigJson), &rateConfig)
+	if err != nil || rateConfig.Version == "" {
 		rateConfig = defaultRateConfig
 		rateConfigJson = "{\"version\":\"default\"}"
 		err = configStore.SetConfigValueFor("limit.rate", rateConfigJson, "backend", transaction)
 		resource.CheckErr(err, "Failed to store limit.rate default value in db")
 	}
+	rateConfig.dropInvalidLimits()
 	transaction.Commit()
 
 	defaultRouter.Use(rateLimit.NewRateLimiter(func(c *gin.Context) string {
@@ -252,7 +273,7 @@ func Main(boxRoot http.FileSystem, db database.DatabaseConnection, localStorageP
 		return c.ClientIP() + requestPath // limit rate by client ip + url
 	}, func(c *gin.Context) (*rate.Limiter, time.Duration) {
 		requestPath := strings.Split(c.Request.RequestURI, "?")[0]
-		ratePerSecond, ok := rateConfig.limits[requestPath]
+		ratePerSecond, ok := rateConfig.Limits[requestPath]
 		if !ok {
 			ratePerSecond = 500
 		}
@@ -306,10 +327,40 @@ func Main(boxRoot http.FileSystem, db database.DatabaseConnection, localStorageP
 		jwtTokenIssuer = "daptin-" + uid.String()[0:6]
 		err = configStore.SetConfigValueFor("jwt.token.issuer", jwtTokenIssuer, "backend", transaction)
 	}
+	limitRulesJson, err := configStore.GetConfigValueFor("limit.rules", "backend", transaction)
+	if err != nil {
+		limitRulesJson = "[]"
+		_ = configStore.SetConfigValueFor("limit.rules", limitRulesJson, "backend", transaction)
+	}
+	rateLimits := make([]resource.RateLimit, 0)
+	err = json.Unmarshal([]byte(limitRulesJson), &rateLimits)
+	resource.CheckErr(err, "Failed to read limit.rules, expected a list of limits")
+	rateLimits = append(rateLimits, initConfig.Limits...)
+	idempotencyWindowHours, err := configStore.GetConfigIntValueFor("idempotency.window.hours", "backend", transaction)
+	if err != nil {
+		idempotencyWindowHours = resource.DefaultIdempotencyWindowHours
+		_ = configStore.SetConfigIntValueFor("idempotency.window.hours", idempotencyWindowHours, "backend", transaction)
+	}
+	webhookRetentionDays, err := configStore.GetConfigIntValueFor("webhook.delivery.retention_days", "backend", transaction)
+	if err != nil {
+		webhookRetentionDays = resource.DefaultWebhookDeliveryRetentionDays
+		_ = configStore.SetConfigIntValueFor("webhook.delivery.retention_days", webhookRetentionDays, "backend", transaction)
+	}
 	transaction.Commit()
 	authMiddleware := auth.NewAuthMiddlewareBuilder(db, jwtTokenIssuer, olricDb)
 	auth.InitJwtMiddleware([]byte(jwtSecret), jwtTokenIssuer, olricDb)
 	defaultRouter.Use(authMiddleware.AuthCheckMiddleware)
+	// after the auth check, the limits and the keys are of the signed in user. The rate limiter comes first, a 429
+	// would otherwise be kept for the idempotency key and replayed to every retry.
+	rateLimitCounters, err := resource.NewOlricRateLimitCounters(olricDb)
+	if err != nil {
+		log.Errorf("Failed to share rate limit counters through olric, counting on this node: %v", err)
+		rateLimitCounters = resource.NewLocalRateLimitCounters()
+	}
+	rateLimiter := resource.NewRateLimiter(rateLimits, rateLimitCounters, db)
+	defaultRouter.Use(rateLimiter.RateLimiterMiddlewareFunc)
+	idempotencyMiddleware := resource.NewIdempotencyMiddleware(db, time.Duration(idempotencyWindowHours)*time.Hour)
+	defaultRouter.Use(idempotencyMiddleware.IdempotencyMiddlewareFunc)
 
 	cruds := make(map[string]*resource.DbResource)
 	defaultRouter.GET("/actions", resource.CreateGuestActionListHandler(&initConfig))
@@ -419,6 +470,53 @@ func Main(boxRoot http.FileSystem, db database.DatabaseConnection, localStorageP
 		_ = configStore.SetConfigIntValueFor("rclone.retries", rcloneRetries, "backend", transaction)
 	}
 
+	scriptLimits := resource.DefaultScriptLimits
+	scriptTimeoutMs, err := configStore.GetConfigIntValueFor("action.script.timeout_ms", "backend", transaction)
+	if err != nil {
+		scriptTimeoutMs = int(scriptLimits.Timeout / time.Millisecond)
+		_ = configStore.SetConfigIntValueFor("action.script.timeout_ms", scriptTimeoutMs, "backend", transaction)
+	}
+	scriptLimits.Timeout = time.Duration(scriptTimeoutMs) * time.Millisecond
+	scriptMaxAllocation, err := configStore.GetConfigIntValueFor("action.script.max_allocation_mb", "backend", transaction)
+	if err != nil {
+		scriptMaxAllocation = scriptLimits.MaxAllocationMB
+		_ = configStore.SetConfigIntValueFor("action.script.max_allocation_mb", scriptMaxAllocation, "backend", transaction)
+	}
+	scriptLimits.MaxAllocationMB = scriptMaxAllocation
+	scriptHelpers, err := configStore.GetConfigValueFor("action.script.helpers", "backend", transaction)
+	if err != nil {
+		scriptHelpers = strings.Join(scriptLimits.Helpers, ",")
+		_ = configStore.SetConfigValueFor("action.script.helpers", scriptHelpers, "backend", transaction)
+	}
+	scriptLimits.Helpers = resource.SelectedColumns(scriptHelpers)
+	scriptCache, err := configStore.GetConfigValueFor("action.script.cache", "backend", transaction)
+	if err != nil {
+		scriptCache = "true"
+		_ = configStore.SetConfigValueFor("action.script.cache", scriptCache, "backend", transaction)
+	}
+	scriptLimits.CacheCompiled = scriptCache == "true"
+	resource.SetScriptLimits(scriptLimits)
+	log.Printf("Action scripts are limited to %v and %d MB of heap growth", scriptLimits.Timeout, scriptLimits.MaxAllocationMB)
+
+	actionJobWorkers, err := configStore.GetConfigIntValueFor("action.job.workers", "backend", transaction)
+	if err != nil {
+		actionJobWorkers = resource.DefaultActionJobWorkers
+		_ = configStore.SetConfigIntValueFor("action.job.workers", actionJobWorkers, "backend", transaction)
+	}
+	resource.StartActionJobWorkers(cruds, actionJobWorkers)
+
+	actionExecutionLog, err := configStore.GetConfigValueFor("action.execution.log", "backend", transaction)
+	if err != nil {
+		actionExecutionLog = "true"
+		_ = configStore.SetConfigValueFor("action.execution.log", actionExecutionLog, "backend", transaction)
+	}
+	actionExecutionRetentionDays, err := configStore.GetConfigIntValueFor("action.execution.retention_days", "backend", transaction)
+	if err != nil {
+		actionExecutionRetentionDays = resource.DefaultActionExecutionRetentionDays
+		_ = configStore.SetConfigIntValueFor("action.execution.retention_days", actionExecutionRetentionDays, "backend", transaction)
+	}
+	resource.StartActionExecutionLog(cruds["world"].Connection, actionExecutionLog == "true", actionExecutionRetentionDays)
+
 	certificateManager, err := resource.NewCertificateManager(cruds, configStore, transaction)
 	resource.CheckErr(err, "Failed to create certificate manager")
 	if err != nil {
@@ -432,26 +530,35 @@ func Main(boxRoot http.FileSystem, db database.DatabaseConnection, localStorageP
 
 	})
 
-	mailDaemon, err := StartSMTPMailServer(cruds["mail"], certificateManager, hostname, transaction)
-	transaction.Commit()
+	runningResources.set(cruds)
 
-	if err == nil {
-		disableSmtp := os.Getenv("DAPTIN_DISABLE_SMTP")
-		if disableSmtp != "true" && len(mailDaemon.Config.Servers) > 0 {
-			log.Infof("Starting SMTP server at port: [%v], set DAPTIN_DISABLE_SMTP=true in environment to disable SMTP server",
-				mailDaemon.Config.Servers)
-			err = mailDaemon.Start()
-		} else {
-			log.Infof("SMTP server is disabled since DAPTIN_DISABLE_SMTP=true or no servers configured")
-		}
+	var mailDaemon *guerrilla.Daemon
+	if running != nil && running.MailDaemon != nil {
+		log.Infof("Reusing running SMTP server")
+		mailDaemon = running.MailDaemon
+		transaction.Commit()
+	} else {
+		mailDaemon, err = StartSMTPMailServer(runningResources.mail, certificateManager, hostname, transaction)
+		transaction.Commit()
 
-		if err != nil {
-			log.Errorf("Failed to mail daemon start: %s", err)
+		if err == nil {
+			disableSmtp := os.Getenv("DAPTIN_DISABLE_SMTP")
+			if disableSmtp != "true" && len(mailDaemon.Config.Servers) > 0 {
+				log.Infof("Starting SMTP server at port: [%v], set DAPTIN_DISABLE_SMTP=true in environment to disable SMTP server",
+					mailDaemon.Config.Servers)
+				err = mailDaemon.Start()
+			} else {
+				log.Infof("SMTP server is disabled since DAPTIN_DISABLE_SMTP=true or no servers configured")
+			}
+
+			if err != nil {
+				log.Errorf("Failed to mail daemon start: %s", err)
+			} else {
+				log.Printf("Started mail server")
+			}
 		} else {
-			log.Printf("Started mail server")
+			log.Errorf("Failed to start mail daemon: %s", err)
 		}
-	} else {
-		log.Errorf("Failed to start mail daemon: %s", err)
 	}
 
 	var imapServer *server.Server
@@ -463,7 +570,10 @@ func Main(boxRoot http.FileSystem, db database.DatabaseConnection, localStorageP
 	}
 
 	enableImapServer, err := configStore.GetConfigValueFor("imap.enabled", "backend", transaction)
-	if err == nil && enableImapServer == "true" {
+	if running != nil && running.ImapServer != nil {
+		log.Infof("Reusing running IMAP server")
+		imapServer = running.ImapServer
+	} else if err == nil && enableImapServer == "true" {
 		imapListenInterface, err := configStore.GetConfigValueFor("imap.listen_interface", "backend", transaction)
 		if err != nil {
 			err = configStore.SetConfigValueFor("imap.listen_interface", ":1143", "backend", transaction)
@@ -473,7 +583,7 @@ func Main(boxRoot http.FileSystem, db database.DatabaseConnection, localStorageP
 
 		hostname, err := configStore.GetConfigValueFor("hostname", "backend", transaction)
 		hostname = "imap." + hostname
-		imapBackend := resource.NewImapServer(cruds)
+		imapBackend := resource.NewImapServer(runningResources.get)
 
 		// Create a new server
 		imapServer = server.New(imapBackend)
@@ -637,6 +747,29 @@ func Main(boxRoot http.FileSystem, db database.DatabaseConnection, localStorageP
 		AsUserEmail: cruds[resource.USER_ACCOUNT_TABLE_NAME].GetAdminEmailId(transaction),
 		Schedule:    "@every 1h",
 	})
+
+	// scheduled backups are written to the cloud store named in backup.cloud_store
+	backupSchedule, _ := configStore.GetConfigValueFor("backup.schedule", "backend", transaction)
+	backupStoreName, _ := configStore.GetConfigValueFor("backup.cloud_store", "backend", transaction)
+	if backupSchedule != "" && backupStoreName != "" {
+		backupStore, err := cruds["cloud_store"].GetObjectByWhereClause("cloud_store", "name", backupStoreName, transaction)
+		if err != nil || backupStore["reference_id"] == nil {
+			log.Errorf("[659] Cloud store [%v] for scheduled backups not found: %v", backupStoreName, err)
+		} else {
+			backupPath, _ := configStore.GetConfigValueFor("backup.path", "backend", transaction)
+			err = TaskScheduler.AddTask(resource.Task{
+				EntityName: "cloud_store",
+				ActionName: "backup_instance",
+				Attributes: map[string]interface{}{
+					"cloud_store_id": backupStore["reference_id"].(daptinid.DaptinReferenceId).String(),
+					"path":           backupPath,
+				},
+				AsUserEmail: cruds[resource.USER_ACCOUNT_TABLE_NAME].GetAdminEmailId(transaction),
+				Schedule:    backupSchedule,
+			})
+			resource.CheckErr(err, "Failed to schedule backups at [%v]", backupSchedule)
+		}
+	}
 	transaction.Rollback()
 
 	TaskScheduler.StartTasks()
@@ -663,7 +796,10 @@ func Main(boxRoot http.FileSystem, db database.DatabaseConnection, localStorageP
 	}
 
 	var ftpServer *server2.FtpServer
-	if enableFtp == "true" {
+	if running != nil && running.FtpServer != nil {
+		log.Infof("Reusing running FTP server")
+		ftpServer = running.FtpServer
+	} else if enableFtp == "true" {
 
 		ftp_interface, err := configStore.GetConfigValueFor("ftp.listen_interface", "backend", transaction)
 		if err != nil {
@@ -673,7 +809,7 @@ func Main(boxRoot http.FileSystem, db database.DatabaseConnection, localStorageP
 		}
 		// ftpListener, err := net.Listen("tcp", ftp_interface)
 		// resource.CheckErr(err, "Failed to create listener for FTP")
-		ftpServer, err = CreateFtpServers(cruds, certificateManager, ftp_interface, transaction)
+		ftpServer, err = CreateFtpServers(runningResources.get, certificateManager, ftp_interface, transaction)
 		auth.CheckErr(err, "Failed to creat FTP server")
 		go func() {
 			log.Printf("FTP server started at %v", ftp_interface)
@@ -705,6 +841,15 @@ func Main(boxRoot http.FileSystem, db database.DatabaseConnection, localStorageP
 
 	defaultRouter.GET("/feed/:feedname", feedHandler)
 
+	gitOpsWatcher := StartGitOpsWatcher(cruds, configStore, db)
+	if gitOpsWatcher != nil {
+		defaultRouter.GET("/gitops/drift", CreateGitOpsDriftHandler(gitOpsWatcher))
+	}
+
+	translationCoverageHandler := CreateTranslationCoverageHandler(&initConfig, db)
+	defaultRouter.GET("/translations/coverage", translationCoverageHandler)
+	defaultRouter.GET("/translations/coverage/:typename", translationCoverageHandler)
+
 	configHandler := CreateConfigHandler(&initConfig, cruds, configStore)
 	defaultRouter.GET("/_config/:end/:key", configHandler)
 	defaultRouter.GET("/_config", configHandler)
@@ -757,9 +902,13 @@ func Main(boxRoot http.FileSystem, db database.DatabaseConnection, localStorageP
 	defaultRouter.OPTIONS("/openapi.yaml", blueprintHandler)
 
 	actionHandler := resource.CreatePostActionHandler(&initConfig, cruds, actionPerformers)
+	defaultRouter.GET("/action/job/:id", resource.CreateActionJobStatusHandler(cruds))
 	defaultRouter.POST("/action/:typename/:actionName", actionHandler)
 	defaultRouter.GET("/action/:typename/:actionName", actionHandler)
 
+	webhookHandler := resource.NewWebhookHandler(initConfig.Webhooks, cruds, webhookRetentionDays)
+	defaultRouter.POST("/webhook/:name", webhookHandler.HandleWebhook)
+
 	defaultRouter.POST("/track/start/:stateMachineId", CreateEventStartHandler(fsmManager, cruds, db))
 	defaultRouter.POST("/track/event/:typename/:objectStateId/:eventName", CreateEventHandler(&initConfig, fsmManager, cruds, db))
 
@@ -965,13 +1114,16 @@ func Main(boxRoot http.FileSystem, db database.DatabaseConnection, localStorageP
 
 }
 
-func CreateFtpServers(resources map[string]*resource.DbResource, certManager *resource.CertificateManager, ftp_interface string, transaction *sqlx.Tx) (*server2.FtpServer, error) {
+// CreateFtpServers serves the sites with ftp enabled, the driver reads the resources of the latest load through
+// resources
+func CreateFtpServers(resources func() map[string]*resource.DbResource, certManager *resource.CertificateManager, ftp_interface string, transaction *sqlx.Tx) (*server2.FtpServer, error) {
 
-	subsites, err := resources["site"].GetAllSites(transaction)
+	cruds := resources()
+	subsites, err := cruds["site"].GetAllSites(transaction)
 	if err != nil {
 		return nil, err
 	}
-	cloudStores, err := resources["cloud_store"].GetAllCloudStores(transaction)
+	cloudStores, err := cruds["cloud_store"].GetAllCloudStores(transaction)
 
 	if err != nil {
 		return nil, err
@@ -990,7 +1142,7 @@ func CreateFtpServers(resources map[string]*resource.DbResource, certManager *re
 			continue
 		}
 
-		assetCacheFolder, ok := resources["site"].SubsiteFolderCache[ftpServer.ReferenceId]
+		assetCacheFolder, ok := cruds["site"].SubsiteFolderCache[ftpServer.ReferenceId]
 		if !ok {
 			continue
 		}
@@ -1048,15 +1200,18 @@ func (c *Crammd5) Next(response []byte) (challenge []byte, done bool, err error)
 }
 
 func initialiseResources(initConfig *resource.CmsConfig, db database.DatabaseConnection) {
+	// the changes made by actions until now are recorded with this load, those made while it runs with the next one
+	schemaChanges := resource.TakePendingSchemaChanges()
 	resource.CheckRelations(initConfig)
 	resource.CheckAuditTables(initConfig)
 	resource.CheckTranslationTables(initConfig)
+	resource.CheckMoneyColumns(initConfig)
 	//lock := new(sync.Mutex)
 	//AddStateMachines(&initConfig, db)
 
 	var errc error
 
-	resource.CheckAllTableStatus(initConfig, db)
+	resource.CheckAllTableStatus(initConfig, db, schemaChanges)
 	resource.CheckErr(errc, "Failed to commit transaction after creating tables")
 
 	//resource.CreateRelations(initConfig, db)
@@ -1069,12 +1224,16 @@ func initialiseResources(initConfig *resource.CmsConfig, db database.DatabaseCon
 	}
 
 	if transaction != nil {
-		resource.CreateUniqueConstraints(initConfig, transaction)
+		resource.CreateUniqueConstraints(initConfig, transaction, schemaChanges)
 		errc = transaction.Commit()
 		resource.CheckErr(errc, "Failed to commit transaction after creating unique constrains")
 	}
 
-	resource.CreateIndexes(initConfig, db)
+	resource.CreateIndexes(initConfig, db, schemaChanges)
+	resource.CreateEnumConstraints(initConfig, db, schemaChanges)
+
+	err = resource.RestorePendingBackup(db)
+	resource.CheckErr(err, "[1111] Failed to restore backup")
 
 	var errb error
 	transaction, err = db.Beginx()
@@ -1109,6 +1268,42 @@ func initialiseResources(initConfig *resource.CmsConfig, db database.DatabaseCon
 	}
 	//}()
 
+	transaction, err = db.Beginx()
+	if err != nil {
+		resource.CheckErr(err, "Failed to begin transaction [1114]")
+		return
+	}
+	err = resource.RecordSchemaMigration(initConfig, schemaChanges, "startup", transaction)
+	if resource.CheckErr(err, "Failed to record schema migration") {
+		transaction.Rollback()
+	} else {
+		transaction.Commit()
+	}
+
+	transaction, err = db.Beginx()
+	if err != nil {
+		resource.CheckErr(err, "Failed to begin transaction [1122]")
+		return
+	}
+	err = resource.FailInterruptedImportJobs(transaction)
+	if resource.CheckErr(err, "Failed to mark interrupted import jobs") {
+		transaction.Rollback()
+	} else {
+		transaction.Commit()
+	}
+
+	transaction, err = db.Beginx()
+	if err != nil {
+		resource.CheckErr(err, "Failed to begin transaction [1129]")
+		return
+	}
+	err = resource.FailInterruptedActionJobs(transaction)
+	if resource.CheckErr(err, "Failed to mark interrupted action jobs") {
+		transaction.Rollback()
+	} else {
+		transaction.Commit()
+	}
+
 }
 
 func actionPerformersListToMap(interfaces []resource.ActionPerformerInterface) map[string]resource.ActionPerformerInterface {
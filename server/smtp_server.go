@@ -12,9 +12,11 @@ import (
 	"strconv"
 )
 
-func StartSMTPMailServer(resource *resource.DbResource, certificateManager *resource.CertificateManager, primaryHostname string, transaction *sqlx.Tx) (*guerrilla.Daemon, error) {
+// StartSMTPMailServer creates the smtp servers of the mail_server rows, the mails are stored through the mail resource
+// of the latest load
+func StartSMTPMailServer(mailResource func() *resource.DbResource, certificateManager *resource.CertificateManager, primaryHostname string, transaction *sqlx.Tx) (*guerrilla.Daemon, error) {
 
-	servers, err := resource.GetAllObjects("mail_server", transaction)
+	servers, err := mailResource().GetAllObjects("mail_server", transaction)
 
 	if err != nil {
 		return nil, err
@@ -129,10 +131,10 @@ func StartSMTPMailServer(resource *resource.DbResource, certificateManager *reso
 		},
 	}
 
-	smtpResource := DaptinSmtpDbResource(resource, certificateManager)
+	smtpResource := DaptinSmtpDbResource(mailResource, certificateManager)
 
 	d.AddProcessor("DaptinSql", smtpResource)
-	d.AddAuthenticator(DaptinSmtpAuthenticatorCreator(resource))
+	d.AddAuthenticator(DaptinSmtpAuthenticatorCreator(mailResource))
 
 	return &d, nil
 }
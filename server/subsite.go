@@ -235,7 +235,7 @@ func CreateSubSites(cmsConfig *resource.CmsConfig, transaction *sqlx.Tx,
 			return c.ClientIP() + requestPath // limit rate by client ip
 		}, func(c *gin.Context) (*rate.Limiter, time.Duration) {
 			requestPath := c.Request.Host + "/" + strings.Split(c.Request.RequestURI, "?")[0]
-			limitValue, ok := rateConfig.limits[requestPath]
+			limitValue, ok := rateConfig.Limits[requestPath]
 			if !ok {
 				limitValue = 100
 			}
@@ -187,6 +187,7 @@ func BuildMiddlewareSet(cmsConfig *resource.CmsConfig,
 	tablePermissionChecker := &resource.TableAccessPermissionChecker{}
 	objectPermissionChecker := &resource.ObjectAccessPermissionChecker{}
 	dataValidationMiddleware := resource.NewDataValidationMiddleware(cmsConfig, cruds)
+	gitOpsReadOnlyMiddleware := resource.NewGitOpsReadOnlyMiddleware()
 
 	createEventHandler := resource.NewCreateEventHandler(cruds, dtopicMap)
 	updateEventHandler := resource.NewUpdateEventHandler(cruds, dtopicMap)
@@ -214,6 +215,7 @@ func BuildMiddlewareSet(cmsConfig *resource.CmsConfig,
 	ms.BeforeCreate = []resource.DatabaseRequestInterceptor{
 		tablePermissionChecker,
 		objectPermissionChecker,
+		gitOpsReadOnlyMiddleware,
 		dataValidationMiddleware,
 		createEventHandler,
 		exchangeMiddleware,
@@ -228,6 +230,7 @@ func BuildMiddlewareSet(cmsConfig *resource.CmsConfig,
 	ms.BeforeDelete = []resource.DatabaseRequestInterceptor{
 		tablePermissionChecker,
 		objectPermissionChecker,
+		gitOpsReadOnlyMiddleware,
 		deleteEventHandler,
 		exchangeMiddleware,
 	}
@@ -242,6 +245,7 @@ func BuildMiddlewareSet(cmsConfig *resource.CmsConfig,
 		ms.BeforeUpdate = []resource.DatabaseRequestInterceptor{
 			tablePermissionChecker,
 			objectPermissionChecker,
+			gitOpsReadOnlyMiddleware,
 			dataValidationMiddleware,
 			yhsHandler,
 			updateEventHandler,
@@ -251,6 +255,7 @@ func BuildMiddlewareSet(cmsConfig *resource.CmsConfig,
 		ms.BeforeUpdate = []resource.DatabaseRequestInterceptor{
 			tablePermissionChecker,
 			objectPermissionChecker,
+			gitOpsReadOnlyMiddleware,
 			dataValidationMiddleware,
 			updateEventHandler,
 			exchangeMiddleware,
@@ -271,7 +271,7 @@ func createServer() (server.HostSwitch, *guerrilla.Daemon, resource.TaskSchedule
 	//configStore.SetConfigValueFor("limit.rate", "5000", "backend", transaction)
 	transaction.Commit()
 
-	hostSwitch, mailDaemon, taskScheduler, configStore, certManager, ftpServer, imapServer, olricDb = server.Main(boxRoot, db, "./local", olricDb)
+	hostSwitch, mailDaemon, taskScheduler, configStore, certManager, ftpServer, imapServer, olricDb = server.Main(boxRoot, db, "./local", olricDb, nil)
 
 	rhs := TestRestartHandlerServer{
 		HostSwitch: &hostSwitch,
@@ -282,9 +282,6 @@ func createServer() (server.HostSwitch, *guerrilla.Daemon, resource.TaskSchedule
 
 		taskScheduler.StopTasks()
 
-		mailDaemon.Shutdown()
-		ftpServer.Stop()
-		imapServer.Close()
 		//err = db.Close()
 		//if err != nil {
 		//	log.Printf("Failed to close DB connections: %v", err)
@@ -292,7 +289,12 @@ func createServer() (server.HostSwitch, *guerrilla.Daemon, resource.TaskSchedule
 
 		//db, err = server.GetDbConnection(*dbType, *connectionString)
 
-		hostSwitch, mailDaemon, taskScheduler, configStore, certManager, ftpServer, imapServer, olricDb = server.Main(boxRoot, db, "./local", olricDb)
+		hostSwitch, mailDaemon, taskScheduler, configStore, certManager, ftpServer, imapServer, olricDb = server.Main(boxRoot, db, "./local", olricDb,
+			&server.RunningServices{
+				MailDaemon: mailDaemon,
+				FtpServer:  ftpServer,
+				ImapServer: imapServer,
+			})
 		rhs.HostSwitch = &hostSwitch
 	})
 